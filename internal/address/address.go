@@ -0,0 +1,151 @@
+/*
+address описывает схемы адресов кошельков: как адрес создаётся, проверяется и
+приводится к каноническому виду.
+
+Схема, выбранная при запуске (ADDRESS_SCHEME), определяет только формат новых
+кошельков. Поиск по адресу принимает адреса любой известной схемы, поэтому
+кошельки, созданные до смены схемы, продолжают работать.
+
+Functions:
+  - Lookup: Возвращает схему по имени (hex64, uuidv4).
+  - Names: Перечисляет имена всех известных схем.
+  - Normalize: Приводит адрес любой известной схемы к каноническому виду.
+  - Hex64: 32 случайных байта в hex, 64 символа в нижнем регистре (по умолчанию).
+  - UUIDv4: Случайный UUID версии 4 в каноническом виде с дефисами.
+*/
+package address
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalid возвращается, если адрес не подходит ни под одну известную схему.
+var ErrInvalid = errors.New("некорректный адрес кошелька")
+
+// Scheme - формат адресов кошельков.
+type Scheme interface {
+	// Name возвращает имя схемы для конфигурации и /api/meta/address-scheme.
+	Name() string
+	// Generate создаёт новый случайный адрес в каноническом виде.
+	Generate() (string, error)
+	// Validate проверяет, что адрес соответствует схеме (в любом регистре).
+	Validate(address string) error
+	// Normalize приводит адрес, прошедший Validate, к каноническому виду.
+	Normalize(address string) string
+}
+
+var (
+	Hex64  Scheme = hex64{}
+	UUIDv4 Scheme = uuidv4{}
+)
+
+// Известные схемы в порядке, в котором их проверяет Normalize.
+var schemes = []Scheme{Hex64, UUIDv4}
+
+// Lookup возвращает схему по имени.
+func Lookup(name string) (Scheme, error) {
+	for _, s := range schemes {
+		if s.Name() == name {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("неизвестная схема адресов %q (допустимы: %s)", name, strings.Join(Names(), ", "))
+}
+
+// Names перечисляет имена всех известных схем.
+func Names() []string {
+	names := make([]string, len(schemes))
+	for i, s := range schemes {
+		names[i] = s.Name()
+	}
+	return names
+}
+
+// Normalize приводит адрес к каноническому виду первой схемы, которой он
+// соответствует. Адреса, не подходящие ни под одну схему, дают ErrInvalid.
+func Normalize(address string) (string, error) {
+	for _, s := range schemes {
+		if s.Validate(address) == nil {
+			return s.Normalize(address), nil
+		}
+	}
+	return "", ErrInvalid
+}
+
+// Длина адреса hex64 в байтах до кодирования.
+const hex64Bytes = 32
+
+type hex64 struct{}
+
+func (hex64) Name() string { return "hex64" }
+
+func (hex64) Generate() (string, error) {
+	bytes := make([]byte, hex64Bytes)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("не удалось сгенерировать адрес кошелька: %w", err)
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+func (hex64) Validate(address string) error {
+	if len(address) != 2*hex64Bytes || !isHex(address) {
+		return ErrInvalid
+	}
+	return nil
+}
+
+func (hex64) Normalize(address string) string {
+	return strings.ToLower(address)
+}
+
+type uuidv4 struct{}
+
+func (uuidv4) Name() string { return "uuidv4" }
+
+func (uuidv4) Generate() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("не удалось сгенерировать адрес кошелька: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40 // версия 4
+	b[8] = b[8]&0x3f | 0x80 // вариант RFC 4122
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32], nil
+}
+
+// Validate принимает UUID версии 4 варианта RFC 4122 в виде 8-4-4-4-12.
+func (uuidv4) Validate(address string) error {
+	if len(address) != 36 {
+		return ErrInvalid
+	}
+	for _, i := range []int{8, 13, 18, 23} {
+		if address[i] != '-' {
+			return ErrInvalid
+		}
+	}
+	if !isHex(strings.ReplaceAll(address, "-", "")) {
+		return ErrInvalid
+	}
+	if address[14] != '4' || !strings.ContainsRune("89abAB", rune(address[19])) {
+		return ErrInvalid
+	}
+	return nil
+}
+
+func (uuidv4) Normalize(address string) string {
+	return strings.ToLower(address)
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
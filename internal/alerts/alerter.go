@@ -0,0 +1,111 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"go-payments/internal/models"
+	"go-payments/internal/money"
+	"go-payments/internal/redact"
+)
+
+// DrainAlert - предупреждение о падении баланса кошелька.
+type DrainAlert struct {
+	Address         string               `json:"address"`
+	PreviousBalance money.Amount         `json:"previous_balance"`
+	CurrentBalance  money.Amount         `json:"current_balance"`
+	DropPercent     float64              `json:"drop_percent"`
+	Window          string               `json:"window"`
+	Transactions    []models.Transaction `json:"transactions"`
+}
+
+// Alerter доставляет предупреждения.
+type Alerter interface {
+	Alert(ctx context.Context, alert DrainAlert) error
+}
+
+// ThresholdAlert - предупреждение о переходе баланса кошелька через порог правила.
+// Transaction - перевод, после которого баланс оказался за порогом.
+type ThresholdAlert struct {
+	ThresholdID int                       `json:"threshold_id"`
+	Address     string                    `json:"address"`
+	Direction   models.ThresholdDirection `json:"direction"`
+	Threshold   money.Amount              `json:"threshold"`
+	Balance     money.Amount              `json:"balance"`
+	Transaction models.Transaction        `json:"transaction"`
+}
+
+// ThresholdAlerter доставляет предупреждения о порогах баланса.
+type ThresholdAlerter interface {
+	AlertThreshold(ctx context.Context, alert ThresholdAlert) error
+}
+
+// Channel - канал предупреждений сервиса, который доставляет оба вида предупреждений.
+type Channel interface {
+	Alerter
+	ThresholdAlerter
+}
+
+// LogAlerter пишет предупреждения в лог.
+type LogAlerter struct{}
+
+func (LogAlerter) Alert(ctx context.Context, alert DrainAlert) error {
+	log.Printf("предупреждение: баланс кошелька %s за %s упал на %.2f%% (%s -> %s), транзакций: %d",
+		redact.Address(alert.Address), alert.Window, alert.DropPercent,
+		money.FormatAmount(float64(alert.PreviousBalance)), money.FormatAmount(float64(alert.CurrentBalance)),
+		len(alert.Transactions))
+	return nil
+}
+
+func (LogAlerter) AlertThreshold(ctx context.Context, alert ThresholdAlert) error {
+	log.Printf("предупреждение: баланс кошелька %s (%s) перешёл порог %s %s после перевода %d",
+		redact.Address(alert.Address), money.FormatAmount(float64(alert.Balance)), alert.Direction,
+		money.FormatAmount(float64(alert.Threshold)), alert.Transaction.ID)
+	return nil
+}
+
+// WebhookAlerter отправляет предупреждения POST-запросом в формате JSON.
+type WebhookAlerter struct {
+	URL    string
+	Client *http.Client
+}
+
+func (a *WebhookAlerter) Alert(ctx context.Context, alert DrainAlert) error {
+	return a.post(ctx, alert)
+}
+
+func (a *WebhookAlerter) AlertThreshold(ctx context.Context, alert ThresholdAlert) error {
+	return a.post(ctx, alert)
+}
+
+func (a *WebhookAlerter) post(ctx context.Context, alert any) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать предупреждение: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("не удалось создать запрос предупреждения: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("не удалось отправить предупреждение: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("получатель предупреждения ответил %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go-payments/internal/models"
+	"go-payments/internal/money"
+	"go-payments/internal/redact"
+)
+
+// Сколько транзакций за окно прикладывается к предупреждению.
+const maxAlertTransactions = 50
+
+// Store - данные, нужные для проверки правил.
+type Store interface {
+	GetSetting(ctx context.Context, key string) (string, bool, error)
+	GetBalanceChanges(ctx context.Context, since time.Time, address string) ([]models.BalanceChange, error)
+	GetLastTransactions(ctx context.Context, n int, filter models.TransactionFilter) ([]models.Transaction, int, error)
+}
+
+// Checker проверяет правила и отправляет предупреждения.
+type Checker struct {
+	store   Store
+	alerter Alerter
+
+	mu        sync.Mutex
+	lastFired map[string]time.Time
+}
+
+func NewChecker(store Store, alerter Alerter) *Checker {
+	return &Checker{store: store, alerter: alerter, lastFired: make(map[string]time.Time)}
+}
+
+// Check выполняет одну проверку всех правил.
+func (c *Checker) Check(ctx context.Context) error {
+	value, ok, err := c.store.GetSetting(ctx, SettingKey)
+	if err != nil || !ok {
+		return err
+	}
+	var rules Rules
+	if err := json.Unmarshal([]byte(value), &rules); err != nil {
+		return fmt.Errorf("некорректные сохранённые правила предупреждений: %w", err)
+	}
+
+	now := time.Now()
+	if rules.Default != nil {
+		changes, err := c.store.GetBalanceChanges(ctx, now.Add(-rules.Default.window()), "")
+		if err != nil {
+			return err
+		}
+		for _, change := range changes {
+			if _, overridden := rules.Wallets[change.Address]; overridden {
+				continue
+			}
+			c.evaluate(ctx, now, change, *rules.Default, rules.cooldown())
+		}
+	}
+
+	for address, rule := range rules.Wallets {
+		changes, err := c.store.GetBalanceChanges(ctx, now.Add(-rule.window()), address)
+		if err != nil {
+			return err
+		}
+		for _, change := range changes {
+			c.evaluate(ctx, now, change, rule, rules.cooldown())
+		}
+	}
+	return nil
+}
+
+// evaluate отправляет предупреждение, если падение баланса превышает порог правила
+// и по кошельку не было предупреждения в течение cooldown.
+func (c *Checker) evaluate(ctx context.Context, now time.Time, change models.BalanceChange, rule DrainRule, cooldown time.Duration) {
+	previous := change.Balance - change.Net
+	if change.Net >= 0 || previous <= 0 {
+		return
+	}
+	drop := -change.Net / previous * 100
+	if drop < rule.DropPercent {
+		return
+	}
+
+	c.mu.Lock()
+	last, fired := c.lastFired[change.Address]
+	c.mu.Unlock()
+	if fired && now.Sub(last) < cooldown {
+		return
+	}
+
+	since := now.Add(-rule.window())
+	transactions, _, err := c.store.GetLastTransactions(ctx, maxAlertTransactions, models.TransactionFilter{
+		Address: change.Address,
+		Status:  models.StatusSuccess,
+		Since:   &since,
+	})
+	if err != nil {
+		log.Printf("ошибка получения транзакций для предупреждения по кошельку %s: %v", redact.Address(change.Address), err)
+	}
+	if transactions == nil {
+		transactions = []models.Transaction{}
+	}
+
+	alert := DrainAlert{
+		Address:         change.Address,
+		PreviousBalance: money.Amount(previous),
+		CurrentBalance:  money.Amount(change.Balance),
+		DropPercent:     drop,
+		Window:          rule.Window,
+		Transactions:    transactions,
+	}
+	if err := c.alerter.Alert(ctx, alert); err != nil {
+		log.Printf("ошибка отправки предупреждения по кошельку %s: %v", redact.Address(change.Address), err)
+		return
+	}
+
+	c.mu.Lock()
+	c.lastFired[change.Address] = now
+	c.mu.Unlock()
+}
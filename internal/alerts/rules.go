@@ -0,0 +1,89 @@
+/*
+alerts предупреждает о быстро опустошаемых кошельках.
+
+Правило задаёт порог падения баланса в процентах и окно времени: если за окно
+баланс кошелька упал больше чем на порог, Checker отправляет Alerter предупреждение
+с адресом, балансом до и после и транзакциями за окно. Правило по умолчанию
+действует на все кошельки, для отдельных кошельков его можно переопределить.
+Повторные предупреждения по одному кошельку подавляются на время cooldown.
+
+Правила хранятся в таблице settings под ключом SettingKey и меняются во время
+работы через `PUT /api/admin/alert-rules`; Checker перечитывает их при каждой проверке.
+Проверка выполняется периодически через leader.Run, поэтому из нескольких
+экземпляров сервиса её выполняет только один.
+
+Канал предупреждений (Channel) доставляет также предупреждения о переходе
+балансов через пороги (ThresholdAlert), которые отправляет пакет thresholds.
+*/
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go-payments/internal/redact"
+)
+
+// Ключ правил в таблице settings.
+const SettingKey = "alert_rules"
+
+// Максимальное окно правила: изменения считаются по истории транзакций за окно.
+const maxWindow = 7 * 24 * time.Hour
+
+// DrainRule срабатывает, если баланс кошелька за Window упал больше чем на DropPercent процентов.
+type DrainRule struct {
+	DropPercent float64 `json:"drop_percent"`
+	Window      string  `json:"window"`
+}
+
+func (r DrainRule) window() time.Duration {
+	d, _ := time.ParseDuration(r.Window)
+	return d
+}
+
+func (r DrainRule) validate() error {
+	if r.DropPercent <= 0 || r.DropPercent > 100 {
+		return errors.New("drop_percent должен быть больше 0 и не больше 100")
+	}
+	d, err := time.ParseDuration(r.Window)
+	if err != nil || d <= 0 || d > maxWindow {
+		return fmt.Errorf("window должен быть длительностью от 1s до %s, например 1h", maxWindow)
+	}
+	return nil
+}
+
+// Rules - набор правил предупреждений.
+type Rules struct {
+	// Default действует на все кошельки без собственного правила; nil - правила нет.
+	Default *DrainRule `json:"default,omitempty"`
+	// Wallets переопределяет правило для отдельных кошельков.
+	Wallets map[string]DrainRule `json:"wallets,omitempty"`
+	// Cooldown - время, в течение которого повторные предупреждения по кошельку не отправляются.
+	Cooldown string `json:"cooldown,omitempty"`
+}
+
+// Validate проверяет правила и называет первое некорректное.
+func (r *Rules) Validate() error {
+	if r.Default != nil {
+		if err := r.Default.validate(); err != nil {
+			return fmt.Errorf("правило default: %w", err)
+		}
+	}
+	for address, rule := range r.Wallets {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("правило для кошелька %s: %w", redact.Address(address), err)
+		}
+	}
+	if r.Cooldown != "" {
+		if d, err := time.ParseDuration(r.Cooldown); err != nil || d < 0 {
+			return errors.New("cooldown должен быть неотрицательной длительностью, например 1h")
+		}
+	}
+	return nil
+}
+
+func (r *Rules) cooldown() time.Duration {
+	d, _ := time.ParseDuration(r.Cooldown)
+	return d
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	// Образ сервиса собирается FROM scratch, без системной базы часовых поясов.
+	_ "time/tzdata"
+
+	"go-payments/internal/redact"
+	"go-payments/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// Период активности по умолчанию и наибольший допустимый период.
+	defaultActivityRange = 30 * 24 * time.Hour
+	maxActivityRange     = 366 * 24 * time.Hour
+)
+
+// GetWalletActivity возвращает матрицу 7x24 успешных переводов кошелька по дням
+// недели и часам суток в часовом поясе tz (по умолчанию UTC) за период
+// [since, until) - по умолчанию последние 30 дней.
+func (a *API) GetWalletActivity(w http.ResponseWriter, r *http.Request) {
+	address := chi.URLParam(r, "address")
+	query := r.URL.Query()
+
+	until := time.Now().UTC()
+	if v := query.Get("until"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			badRequest(w, "параметр 'until' должен быть в формате RFC 3339")
+			return
+		}
+		until = t
+	}
+	since := until.Add(-defaultActivityRange)
+	if v := query.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			badRequest(w, "параметр 'since' должен быть в формате RFC 3339")
+			return
+		}
+		since = t
+	}
+	if !since.Before(until) {
+		badRequest(w, "параметр 'since' должен быть раньше 'until'")
+		return
+	}
+	if until.Sub(since) > maxActivityRange {
+		badRequest(w, "период активности не может быть длиннее 366 дней")
+		return
+	}
+
+	timezone := "UTC"
+	if v := query.Get("tz"); v != "" {
+		// "Local" - часовой пояс сервера, а не имя из базы IANA.
+		loc, err := time.LoadLocation(v)
+		if err != nil || v == "Local" {
+			badRequest(w, "параметр 'tz' должен быть именем часового пояса IANA, например Europe/Moscow")
+			return
+		}
+		timezone = loc.String()
+	}
+
+	activity, err := a.db.GetWalletActivity(r.Context(), address, since, until, timezone)
+	if err != nil {
+		if !errors.Is(err, storage.ErrWalletNotFound) {
+			log.Printf("ошибка получения активности кошелька %s: %v", redact.Address(address), err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+	writeJSON(w, r, activity)
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"go-payments/internal/models"
+)
+
+// activityStorage запоминает параметры последнего запроса активности.
+type activityStorage struct {
+	*fakeStorage
+
+	since, until time.Time
+	timezone     string
+}
+
+func (s *activityStorage) GetWalletActivity(ctx context.Context, address string, since, until time.Time, timezone string) (*models.WalletActivity, error) {
+	if err := s.call("GetWalletActivity"); err != nil {
+		return nil, err
+	}
+	s.since, s.until, s.timezone = since, until, timezone
+	return &models.WalletActivity{Address: address, Since: since, Until: until, Timezone: timezone}, nil
+}
+
+func TestWalletActivityParams(t *testing.T) {
+	path := "/api/wallet/" + testAddress(1) + "/activity"
+
+	// Без параметров - последние 30 дней в UTC.
+	db := &activityStorage{fakeStorage: &fakeStorage{}}
+	_, router := newTestRouter(db, testConfig())
+	if w := serve(router, http.MethodGet, path, "", nil); w.Code != http.StatusOK {
+		t.Fatalf("без параметров: статус %d, want 200; тело %s", w.Code, truncate(w.Body.String()))
+	}
+	if db.timezone != "UTC" || db.until.Sub(db.since) != defaultActivityRange || time.Since(db.until) > time.Minute {
+		t.Errorf("по умолчанию: %s - %s в %s, want последние 30 дней в UTC", db.since, db.until, db.timezone)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		status   int
+		timezone string
+	}{
+		{"часовой пояс IANA", "?tz=Europe/Moscow", http.StatusOK, "Europe/Moscow"},
+		{"явный период", "?since=2026-01-04T00:00:00Z&until=2026-01-11T00:00:00Z&tz=America/New_York", http.StatusOK, "America/New_York"},
+		{"период ровно 366 дней", "?since=2025-01-01T00:00:00Z&until=2026-01-02T00:00:00Z", http.StatusOK, "UTC"},
+		{"неизвестный часовой пояс", "?tz=Mars/Olympus", http.StatusBadRequest, ""},
+		{"часовой пояс сервера", "?tz=Local", http.StatusBadRequest, ""},
+		{"смещение вместо имени", "?tz=%2B03:00", http.StatusBadRequest, ""},
+		{"since равно until", "?since=2026-01-04T00:00:00Z&until=2026-01-04T00:00:00Z", http.StatusBadRequest, ""},
+		{"since позже until", "?since=2026-01-05T00:00:00Z&until=2026-01-04T00:00:00Z", http.StatusBadRequest, ""},
+		{"период длиннее 366 дней", "?since=2025-01-01T00:00:00Z&until=2026-01-03T00:00:00Z", http.StatusBadRequest, ""},
+		{"since не RFC 3339", "?since=2026-01-04", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		db := &activityStorage{fakeStorage: &fakeStorage{}}
+		_, router := newTestRouter(db, testConfig())
+		w := serve(router, http.MethodGet, path+tt.query, "", nil)
+		if w.Code != tt.status {
+			t.Errorf("%s: статус %d, want %d; тело %s", tt.name, w.Code, tt.status, truncate(w.Body.String()))
+			continue
+		}
+		if tt.status != http.StatusOK {
+			checkErrorEnvelope(t, tt.name, w, CodeInvalidRequest)
+			if len(db.calls) != 0 {
+				t.Errorf("%s: некорректный запрос дошёл до хранилища", tt.name)
+			}
+			continue
+		}
+		if db.timezone != tt.timezone {
+			t.Errorf("%s: в хранилище передан часовой пояс %q, want %q", tt.name, db.timezone, tt.timezone)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go-payments/internal/address"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// invalidAddress отвечает 400 с кодом invalid_address и именем поля в details.
+func invalidAddress(w http.ResponseWriter, field string) {
+	writeError(w, http.StatusBadRequest, CodeInvalidAddress,
+		fmt.Sprintf("поле '%s' не является адресом кошелька (допустимые схемы: %s)", field, strings.Join(address.Names(), ", ")),
+		map[string]string{"field": field})
+}
+
+// normalizeAddress приводит адрес из тела или query-параметра к каноническому
+// виду. Принимаются адреса любой известной схемы, а не только текущей, чтобы
+// кошельки, созданные до смены ADDRESS_SCHEME, оставались доступны.
+// При некорректном адресе отвечает 400 и возвращает false.
+func normalizeAddress(w http.ResponseWriter, field string, addr *string) bool {
+	normalized, err := address.Normalize(*addr)
+	if err != nil {
+		invalidAddress(w, field)
+		return false
+	}
+	*addr = normalized
+	return true
+}
+
+// validAddress проверяет и нормализует параметр пути {address} на маршрутах,
+// где он есть. Middleware группы выполняется после сопоставления маршрута,
+// поэтому параметры пути уже известны.
+func validAddress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rctx := chi.RouteContext(r.Context())
+		if rctx != nil {
+			for i, key := range rctx.URLParams.Keys {
+				if key != "address" {
+					continue
+				}
+				if !normalizeAddress(w, key, &rctx.URLParams.Values[i]) {
+					return
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type addressSchemeInfo struct {
+	Scheme   string   `json:"scheme"`
+	Accepted []string `json:"accepted"`
+}
+
+// GetAddressScheme сообщает схему адресов новых кошельков и схемы, которые
+// принимаются при поиске.
+func (a *API) GetAddressScheme(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, addressSchemeInfo{Scheme: a.cfg.AddressScheme, Accepted: address.Names()})
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"go-payments/internal/models"
+	"go-payments/internal/redact"
+	"go-payments/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultAdminLimit = 50
+	maxAdminLimit     = 1000
+)
+
+func (a *API) ListWallets(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	page, err := parseOffsetPagination(r, pagination{Limit: defaultAdminLimit}, maxAdminLimit)
+	if err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+
+	filter := models.WalletFilter{
+		Sort:   query.Get("sort"),
+		Order:  query.Get("order"),
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	}
+
+	if v := query.Get("frozen"); v != "" {
+		frozen, err := strconv.ParseBool(v)
+		if err != nil {
+			badRequest(w, "параметр 'frozen' должен быть true или false")
+			return
+		}
+		filter.Frozen = &frozen
+	}
+	if v := query.Get("include_archived"); v != "" {
+		includeArchived, err := strconv.ParseBool(v)
+		if err != nil {
+			badRequest(w, "параметр 'include_archived' должен быть true или false")
+			return
+		}
+		filter.IncludeArchived = includeArchived
+	}
+	if v := query.Get("min_balance"); v != "" {
+		minBalance, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			badRequest(w, "параметр 'min_balance' должен быть числом")
+			return
+		}
+		filter.MinBalance = &minBalance
+	}
+	if v := query.Get("max_balance"); v != "" {
+		maxBalance, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			badRequest(w, "параметр 'max_balance' должен быть числом")
+			return
+		}
+		filter.MaxBalance = &maxBalance
+	}
+
+	if err := filter.Validate(); err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+
+	walletPage, err := a.db.ListWallets(r.Context(), filter)
+	if err != nil {
+		log.Printf("ошибка получения списка кошельков: %v", err)
+		writeStorageError(w, r, err)
+		return
+	}
+
+	setSkippedRows(w, walletPage.Skipped)
+	writeJSON(w, r, walletPage)
+}
+
+func (a *API) GetConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a.cfg.Redacted())
+}
+
+type purgeResponse struct {
+	Address string `json:"address"`
+	Status  string `json:"status"`
+}
+
+func (a *API) PurgeWallet(w http.ResponseWriter, r *http.Request) {
+	address := chi.URLParam(r, "address")
+
+	if err := a.db.PurgeWallet(r.Context(), address); err != nil {
+		if !errors.Is(err, storage.ErrWalletNotFound) &&
+			!errors.Is(err, storage.ErrWalletNotArchived) &&
+			!errors.Is(err, storage.ErrWalletNotEmpty) &&
+			!errors.Is(err, storage.ErrWalletReferenced) {
+			log.Printf("ошибка удаления кошелька %s: %v", redact.Address(address), err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+	log.Printf("кошелёк %s удалён", redact.Address(address))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(purgeResponse{Address: address, Status: "purged"})
+}
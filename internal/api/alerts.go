@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"go-payments/internal/alerts"
+)
+
+func (a *API) GetAlertRules(w http.ResponseWriter, r *http.Request) {
+	value, ok, err := a.db.GetSetting(r.Context(), alerts.SettingKey)
+	if err != nil {
+		log.Printf("ошибка получения правил предупреждений: %v", err)
+		writeStorageError(w, r, err)
+		return
+	}
+
+	rules := alerts.Rules{}
+	if ok {
+		if err := json.Unmarshal([]byte(value), &rules); err != nil {
+			log.Printf("некорректные сохранённые правила предупреждений: %v", err)
+			internalError(w)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// SetAlertRules заменяет правила предупреждений целиком и сохраняет их в settings.
+func (a *API) SetAlertRules(w http.ResponseWriter, r *http.Request) {
+	var rules alerts.Rules
+	if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+		badRequest(w, "неверный формат запроса")
+		return
+	}
+	defer r.Body.Close()
+
+	if err := rules.Validate(); err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+
+	value, err := json.Marshal(rules)
+	if err != nil {
+		internalError(w)
+		return
+	}
+	if err := a.db.SetSetting(r.Context(), alerts.SettingKey, string(value)); err != nil {
+		log.Printf("ошибка сохранения правил предупреждений: %v", err)
+		writeStorageError(w, r, err)
+		return
+	}
+	log.Printf("правила предупреждений обновлены")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
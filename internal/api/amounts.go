@@ -0,0 +1,204 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"go-payments/internal/money"
+)
+
+// Значения query-параметра amount_format.
+const (
+	amountFormatString = "string"
+	amountFormatNumber = "number"
+)
+
+var (
+	amountType        = reflect.TypeOf(money.Amount(0))
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// amountFormat проверяет параметр amount_format и сохраняет выбранный формат
+// в контексте запроса. По умолчанию суммы выводятся строками с фиксированной
+// точкой; amount_format=number сохраняет числовой вывод для старых клиентов.
+func amountFormat(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get("amount_format")
+		switch format {
+		case "", amountFormatString, amountFormatNumber:
+		default:
+			badRequest(w, "параметр 'amount_format' должен быть string или number")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), amountFormatKey, format)))
+	})
+}
+
+// writeJSON отправляет v в формате JSON с суммами в формате, выбранном клиентом.
+// Ответ сериализуется целиком до записи, поэтому ошибка сериализации даёт 500,
+// а не обрезанное тело.
+func writeJSON(w http.ResponseWriter, r *http.Request, v any) {
+	data, err := marshalResponse(r, v)
+	if err != nil {
+		log.Printf("ошибка сериализации ответа: %v", err)
+		internalError(w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// marshalResponse сериализует v в JSON (с переводом строки в конце, как
+// json.Encoder) с суммами в формате, выбранном клиентом параметром amount_format.
+func marshalResponse(r *http.Request, v any) ([]byte, error) {
+	var out bytes.Buffer
+	if format, _ := r.Context().Value(amountFormatKey).(string); format != amountFormatNumber {
+		if err := json.NewEncoder(&out).Encode(v); err != nil {
+			return nil, err
+		}
+		return out.Bytes(), nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		return nil, fmt.Errorf("ошибка преобразования ответа: %w", err)
+	}
+	if err := json.NewEncoder(&out).Encode(amountsToNumbers(reflect.ValueOf(v), tree)); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// amountsToNumbers заменяет числами строки сумм в дереве JSON node, полученном
+// сериализацией v. Суммы находятся по типу money.Amount: v обходится параллельно
+// дереву, поэтому строки, которые лишь называются как суммы (например, значения
+// metadata), не меняются, а новые поля-суммы не нужно нигде регистрировать.
+func amountsToNumbers(v reflect.Value, node any) any {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return node
+		}
+		v = v.Elem()
+	}
+	if v.Type() == amountType {
+		if s, ok := node.(string); ok {
+			return json.Number(s)
+		}
+		return node
+	}
+	// Типы со своей сериализацией (time.Time, json.RawMessage) устроены иначе, чем их поля.
+	if implementsMarshaler(v.Type()) {
+		return node
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if obj, ok := node.(map[string]any); ok {
+			for name, field := range jsonFields(v) {
+				if child, ok := obj[name]; ok {
+					obj[name] = amountsToNumbers(field, child)
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if arr, ok := node.([]any); ok {
+			for i := 0; i < len(arr) && i < v.Len(); i++ {
+				arr[i] = amountsToNumbers(v.Index(i), arr[i])
+			}
+		}
+	case reflect.Map:
+		if obj, ok := node.(map[string]any); ok {
+			iter := v.MapRange()
+			for iter.Next() {
+				key, ok := jsonMapKey(iter.Key())
+				if child, found := obj[key]; ok && found {
+					obj[key] = amountsToNumbers(iter.Value(), child)
+				}
+			}
+		}
+	}
+	return node
+}
+
+func implementsMarshaler(t reflect.Type) bool {
+	for _, m := range []reflect.Type{jsonMarshalerType, textMarshalerType} {
+		if t.Implements(m) || reflect.PointerTo(t).Implements(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonFields возвращает поля структуры v по их JSON-именам, включая поля
+// встроенных структур без тега. Как и в encoding/json, поле внешней структуры
+// скрывает одноимённое поле встроенной.
+func jsonFields(v reflect.Value) map[string]reflect.Value {
+	fields := make(map[string]reflect.Value)
+	var embedded []reflect.Value
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			fv := v.Field(i)
+			for fv.Kind() == reflect.Pointer && !fv.IsNil() {
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				embedded = append(embedded, fv)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = v.Field(i)
+	}
+	for _, e := range embedded {
+		for name, field := range jsonFields(e) {
+			if _, ok := fields[name]; !ok {
+				fields[name] = field
+			}
+		}
+	}
+	return fields
+}
+
+// jsonMapKey возвращает ключ объекта JSON, в который encoding/json превращает ключ map.
+func jsonMapKey(k reflect.Value) (string, bool) {
+	if k.Kind() == reflect.String {
+		return k.String(), true
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		b, err := tm.MarshalText()
+		return string(b), err == nil
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), true
+	}
+	return "", false
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-payments/internal/models"
+	"go-payments/internal/money"
+	"go-payments/internal/storage"
+)
+
+// serveAmountFormat выполняет h за middleware amountFormat с query-строкой query
+// и возвращает ответ.
+func serveAmountFormat(h http.HandlerFunc, query string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	amountFormat(h).ServeHTTP(w, httptest.NewRequest("GET", "/?"+query, nil))
+	return w
+}
+
+func TestWriteJSONAmountFormat(t *testing.T) {
+	maxAmount := money.Amount(999999999999.9999)
+	wallet := models.Wallet{Address: "a", Balance: 1e-8, MaxBalance: &maxAmount}
+	transaction := models.Transaction{ID: 1, Amount: -0.5, Metadata: map[string]string{"amount": "7"}}
+	tests := []struct {
+		query string
+		v     any
+		want  string
+	}{
+		{"", wallet, `"balance":"0.00000001"`},
+		{"amount_format=string", wallet, `"balance":"0.00000001"`},
+		{"amount_format=number", wallet, `"balance":0.00000001`},
+		{"amount_format=number", wallet, `"max_balance":999999999999.99987793`},
+		{"amount_format=number", models.Wallet{Balance: 0}, `"balance":0.00000000`},
+		{"amount_format=number", transaction, `"amount":-0.50000000`},
+		// Строки метаданных с именем суммы остаются строками.
+		{"amount_format=number", transaction, `"metadata":{"amount":"7"}`},
+		{"amount_format=number", []models.Transaction{transaction}, `[{"amount":-0.50000000,`},
+	}
+	for _, tt := range tests {
+		w := serveAmountFormat(func(w http.ResponseWriter, r *http.Request) { writeJSON(w, r, tt.v) }, tt.query)
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("%q: %d %s, want 200 с %s", tt.query, w.Code, w.Body, tt.want)
+		}
+	}
+}
+
+func TestWriteStorageErrorAmountFormat(t *testing.T) {
+	insufficient := &storage.TransactionError{
+		Code:        storage.CodeInsufficientFunds,
+		OriginalErr: storage.ErrInsufficientFunds,
+		Balance:     0,
+		Shortfall:   1e-8,
+	}
+	limitExceeded := &storage.TransactionError{
+		Code:             storage.CodeRecipientLimitExceeded,
+		OriginalErr:      storage.ErrRecipientLimitExceeded,
+		RecipientBalance: -1,
+		MaxBalance:       999999999999.9999,
+	}
+	tests := []struct {
+		query      string
+		err        error
+		wantStatus int
+		want       string
+	}{
+		{"", insufficient, http.StatusPaymentRequired, `"details":{"balance":"0.00000000","shortfall":"0.00000001"}`},
+		{"amount_format=number", insufficient, http.StatusPaymentRequired, `"details":{"balance":0.00000000,"shortfall":0.00000001}`},
+		{"", limitExceeded, http.StatusUnprocessableEntity, `"details":{"balance":"-1.00000000","max_balance":"999999999999.99987793"}`},
+		{"amount_format=number", limitExceeded, http.StatusUnprocessableEntity, `"details":{"balance":-1.00000000,"max_balance":999999999999.99987793}`},
+	}
+	for _, tt := range tests {
+		w := serveAmountFormat(func(w http.ResponseWriter, r *http.Request) { writeStorageError(w, r, tt.err) }, tt.query)
+		if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("%q, %v: %d %s, want %d с %s", tt.query, tt.err, w.Code, w.Body, tt.wantStatus, tt.want)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%q, %v: Content-Type %q", tt.query, tt.err, ct)
+		}
+	}
+}
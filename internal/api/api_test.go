@@ -0,0 +1,449 @@
+package api
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go-payments/internal/checkpoint"
+	"go-payments/internal/config"
+	"go-payments/internal/models"
+	"go-payments/internal/money"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// fakeStorage - хранилище для тестов обработчиков. Каждый метод запоминает своё
+// имя и возвращает err; при err == nil результат - пустое значение нужного типа.
+type fakeStorage struct {
+	err error
+
+	mu    sync.Mutex
+	calls []string
+}
+
+var _ Storage = (*fakeStorage)(nil)
+
+func (f *fakeStorage) call(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, name)
+	return f.err
+}
+
+// called возвращает имена вызванных методов по порядку.
+func (f *fakeStorage) called() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.calls)
+}
+
+func (f *fakeStorage) GetWalletBalance(ctx context.Context, address string) (*models.Wallet, error) {
+	if err := f.call("GetWalletBalance"); err != nil {
+		return nil, err
+	}
+	return &models.Wallet{Address: address}, nil
+}
+
+func (f *fakeStorage) GetLastTransactions(ctx context.Context, n int, filter models.TransactionFilter) ([]models.Transaction, int, error) {
+	return []models.Transaction{}, 0, f.call("GetLastTransactions")
+}
+
+func (f *fakeStorage) GetWallets(ctx context.Context, n int) ([]models.Wallet, int, error) {
+	return []models.Wallet{}, 0, f.call("GetWallets")
+}
+
+func (f *fakeStorage) GetWalletsByAddress(ctx context.Context, addresses []string) (map[string]models.Wallet, error) {
+	return map[string]models.Wallet{}, f.call("GetWalletsByAddress")
+}
+
+func (f *fakeStorage) ListWallets(ctx context.Context, filter models.WalletFilter) (*models.WalletPage, error) {
+	if err := f.call("ListWallets"); err != nil {
+		return nil, err
+	}
+	return &models.WalletPage{}, nil
+}
+
+func (f *fakeStorage) GetStatement(ctx context.Context, address string, from, to time.Time) (*models.Statement, error) {
+	if err := f.call("GetStatement"); err != nil {
+		return nil, err
+	}
+	return &models.Statement{Address: address, PeriodStart: from, PeriodEnd: to}, nil
+}
+
+func (f *fakeStorage) GetWalletActivity(ctx context.Context, address string, since, until time.Time, timezone string) (*models.WalletActivity, error) {
+	if err := f.call("GetWalletActivity"); err != nil {
+		return nil, err
+	}
+	return &models.WalletActivity{}, nil
+}
+
+func (f *fakeStorage) GetVolumeSeries(ctx context.Context, since, until time.Time, bucket models.BucketSize, status models.TransactionStatus) ([]models.VolumeBucket, error) {
+	return []models.VolumeBucket{}, f.call("GetVolumeSeries")
+}
+
+func (f *fakeStorage) Execute(ctx context.Context, t models.Transfer) (*models.Transaction, error) {
+	if err := f.call("Execute"); err != nil {
+		return nil, err
+	}
+	return &models.Transaction{ID: 1, From: t.From, To: t.To, Amount: money.Amount(t.Amount), Status: models.StatusSuccess}, nil
+}
+
+func (f *fakeStorage) RecordZeroNote(ctx context.Context, t models.Transfer) (*models.Transaction, error) {
+	if err := f.call("RecordZeroNote"); err != nil {
+		return nil, err
+	}
+	return &models.Transaction{ID: 1, From: t.From, To: t.To, Status: models.StatusZeroNote}, nil
+}
+
+func (f *fakeStorage) Ping(ctx context.Context) error {
+	return f.call("Ping")
+}
+
+func (f *fakeStorage) GetSetting(ctx context.Context, key string) (string, bool, error) {
+	return "", false, f.call("GetSetting")
+}
+
+func (f *fakeStorage) SetSetting(ctx context.Context, key, value string) error {
+	return f.call("SetSetting")
+}
+
+func (f *fakeStorage) GetWalletByLabel(ctx context.Context, label string) (*models.Wallet, error) {
+	if err := f.call("GetWalletByLabel"); err != nil {
+		return nil, err
+	}
+	return &models.Wallet{Label: label}, nil
+}
+
+func (f *fakeStorage) SetWalletLabel(ctx context.Context, address, label string) error {
+	return f.call("SetWalletLabel")
+}
+
+func (f *fakeStorage) SetWalletMaxBalance(ctx context.Context, address string, maxBalance *float64) error {
+	return f.call("SetWalletMaxBalance")
+}
+
+func (f *fakeStorage) SetWalletSystem(ctx context.Context, address string, system, nonReceivable bool) error {
+	return f.call("SetWalletSystem")
+}
+
+func (f *fakeStorage) PurgeWallet(ctx context.Context, address string) error {
+	return f.call("PurgeWallet")
+}
+
+func (f *fakeStorage) CreatePaymentRequest(ctx context.Context, pr models.PaymentRequest) (*models.PaymentRequest, error) {
+	if err := f.call("CreatePaymentRequest"); err != nil {
+		return nil, err
+	}
+	pr.Token = "token"
+	pr.Status = models.PaymentRequestOpen
+	return &pr, nil
+}
+
+func (f *fakeStorage) GetPaymentRequest(ctx context.Context, token string) (*models.PaymentRequest, error) {
+	if err := f.call("GetPaymentRequest"); err != nil {
+		return nil, err
+	}
+	return &models.PaymentRequest{Token: token, Amount: 1, Status: models.PaymentRequestOpen}, nil
+}
+
+func (f *fakeStorage) FulfillPaymentRequest(ctx context.Context, token string, transactionID int) (*models.PaymentRequest, error) {
+	if err := f.call("FulfillPaymentRequest"); err != nil {
+		return nil, err
+	}
+	return &models.PaymentRequest{Token: token, Status: models.PaymentRequestFulfilled, TransactionID: &transactionID}, nil
+}
+
+func (f *fakeStorage) AddWalletNote(ctx context.Context, wallet, author, text string) (*models.WalletNote, error) {
+	if err := f.call("AddWalletNote"); err != nil {
+		return nil, err
+	}
+	return &models.WalletNote{ID: 1, Wallet: wallet, Author: author, Text: text}, nil
+}
+
+func (f *fakeStorage) ListWalletNotes(ctx context.Context, wallet string) ([]models.WalletNote, error) {
+	return []models.WalletNote{}, f.call("ListWalletNotes")
+}
+
+func (f *fakeStorage) RedactWalletNote(ctx context.Context, wallet string, id int) (*models.WalletNote, error) {
+	if err := f.call("RedactWalletNote"); err != nil {
+		return nil, err
+	}
+	return &models.WalletNote{ID: id, Wallet: wallet}, nil
+}
+
+func (f *fakeStorage) CreateBalanceThreshold(ctx context.Context, th models.BalanceThreshold) (*models.BalanceThreshold, error) {
+	if err := f.call("CreateBalanceThreshold"); err != nil {
+		return nil, err
+	}
+	th.ID = 1
+	return &th, nil
+}
+
+func (f *fakeStorage) ListBalanceThresholds(ctx context.Context, wallet string) ([]models.BalanceThreshold, error) {
+	return []models.BalanceThreshold{}, f.call("ListBalanceThresholds")
+}
+
+func (f *fakeStorage) GetBalanceThreshold(ctx context.Context, wallet string, id int) (*models.BalanceThreshold, error) {
+	if err := f.call("GetBalanceThreshold"); err != nil {
+		return nil, err
+	}
+	return &models.BalanceThreshold{ID: id, Wallet: wallet}, nil
+}
+
+func (f *fakeStorage) UpdateBalanceThreshold(ctx context.Context, th models.BalanceThreshold) (*models.BalanceThreshold, error) {
+	if err := f.call("UpdateBalanceThreshold"); err != nil {
+		return nil, err
+	}
+	return &th, nil
+}
+
+func (f *fakeStorage) DeleteBalanceThreshold(ctx context.Context, wallet string, id int) error {
+	return f.call("DeleteBalanceThreshold")
+}
+
+func (f *fakeStorage) CreateWithdrawal(ctx context.Context, w models.Withdrawal, idempotencyKey string) (*models.Withdrawal, error) {
+	if err := f.call("CreateWithdrawal"); err != nil {
+		return nil, err
+	}
+	w.ID = 1
+	w.Status = models.WithdrawalPending
+	return &w, nil
+}
+
+func (f *fakeStorage) GetWithdrawal(ctx context.Context, id int) (*models.Withdrawal, error) {
+	if err := f.call("GetWithdrawal"); err != nil {
+		return nil, err
+	}
+	return &models.Withdrawal{ID: id, Status: models.WithdrawalPending}, nil
+}
+
+func (f *fakeStorage) ListWithdrawals(ctx context.Context, wallet string, status models.WithdrawalStatus, limit int) ([]models.Withdrawal, error) {
+	return []models.Withdrawal{}, f.call("ListWithdrawals")
+}
+
+func (f *fakeStorage) ConfirmWithdrawal(ctx context.Context, id int) (*models.Withdrawal, error) {
+	if err := f.call("ConfirmWithdrawal"); err != nil {
+		return nil, err
+	}
+	return &models.Withdrawal{ID: id, Status: models.WithdrawalConfirmed}, nil
+}
+
+func (f *fakeStorage) FailWithdrawal(ctx context.Context, id int, reason string) (*models.Withdrawal, error) {
+	if err := f.call("FailWithdrawal"); err != nil {
+		return nil, err
+	}
+	reversal := 2
+	return &models.Withdrawal{ID: id, Status: models.WithdrawalFailed, Reason: reason, ReversalTransactionID: &reversal}, nil
+}
+
+func (f *fakeStorage) ReviewExpiredWithdrawals(ctx context.Context, olderThan time.Duration) ([]models.Withdrawal, error) {
+	return nil, f.call("ReviewExpiredWithdrawals")
+}
+
+func (f *fakeStorage) CreateCheckpoint(ctx context.Context, date time.Time, key ed25519.PrivateKey) (*checkpoint.Checkpoint, bool, error) {
+	if err := f.call("CreateCheckpoint"); err != nil {
+		return nil, false, err
+	}
+	return &checkpoint.Checkpoint{}, true, nil
+}
+
+func (f *fakeStorage) GetCheckpoint(ctx context.Context, date time.Time, withBalances bool) (*checkpoint.Bundle, error) {
+	if err := f.call("GetCheckpoint"); err != nil {
+		return nil, err
+	}
+	return &checkpoint.Bundle{}, nil
+}
+
+func (f *fakeStorage) GetCheckpointProof(ctx context.Context, address string, date time.Time) (*checkpoint.Proof, error) {
+	if err := f.call("GetCheckpointProof"); err != nil {
+		return nil, err
+	}
+	return &checkpoint.Proof{}, nil
+}
+
+func (f *fakeStorage) GetTransaction(ctx context.Context, id int) (*models.Transaction, error) {
+	if err := f.call("GetTransaction"); err != nil {
+		return nil, err
+	}
+	return &models.Transaction{ID: id, Status: models.StatusSuccess}, nil
+}
+
+func (f *fakeStorage) TransactionIDByPublicID(ctx context.Context, publicID string) (int, error) {
+	return 1, f.call("TransactionIDByPublicID")
+}
+
+func (f *fakeStorage) GetGroupSummary(ctx context.Context, groupID string) (*models.GroupSummary, error) {
+	if err := f.call("GetGroupSummary"); err != nil {
+		return nil, err
+	}
+	return &models.GroupSummary{GroupID: groupID}, nil
+}
+
+func (f *fakeStorage) AcknowledgeTransaction(ctx context.Context, id int, recipient string) (*models.Transaction, error) {
+	if err := f.call("AcknowledgeTransaction"); err != nil {
+		return nil, err
+	}
+	return &models.Transaction{ID: id, To: recipient, Status: models.StatusSuccess}, nil
+}
+
+func (f *fakeStorage) AcknowledgeTransactions(ctx context.Context, recipient string, ids []int) (map[int]error, error) {
+	return map[int]error{}, f.call("AcknowledgeTransactions")
+}
+
+func (f *fakeStorage) AcknowledgeTransactionsThrough(ctx context.Context, recipient string, throughID int) (int, error) {
+	return 0, f.call("AcknowledgeTransactionsThrough")
+}
+
+func (f *fakeStorage) GetIncomingCursor(ctx context.Context, wallet, consumer string) (*models.IncomingCursor, error) {
+	if err := f.call("GetIncomingCursor"); err != nil {
+		return nil, err
+	}
+	return &models.IncomingCursor{Wallet: wallet, Consumer: consumer}, nil
+}
+
+func (f *fakeStorage) SetIncomingCursor(ctx context.Context, wallet, consumer string, lastTransactionID int) (*models.IncomingCursor, error) {
+	if err := f.call("SetIncomingCursor"); err != nil {
+		return nil, err
+	}
+	return &models.IncomingCursor{Wallet: wallet, Consumer: consumer, LastTransactionID: lastTransactionID}, nil
+}
+
+func (f *fakeStorage) SchemaVersion(ctx context.Context) (int, error) {
+	return 1, f.call("SchemaVersion")
+}
+
+func (f *fakeStorage) CheckSchema(ctx context.Context) (*models.SchemaDiff, error) {
+	if err := f.call("CheckSchema"); err != nil {
+		return nil, err
+	}
+	return &models.SchemaDiff{}, nil
+}
+
+func (f *fakeStorage) CreateWallets(ctx context.Context, n int, balance float64, progress func(batch []string)) (created, skipped int, err error) {
+	if err := f.call("CreateWallets"); err != nil {
+		return 0, 0, err
+	}
+	progress(make([]string, n))
+	return n, 0, nil
+}
+
+func (f *fakeStorage) AddTransactionTag(ctx context.Context, id int, tag, addedBy string) error {
+	return f.call("AddTransactionTag")
+}
+
+func (f *fakeStorage) RemoveTransactionTag(ctx context.Context, id int, tag string) error {
+	return f.call("RemoveTransactionTag")
+}
+
+func (f *fakeStorage) ListTransactionTags(ctx context.Context) ([]models.TagCount, error) {
+	return []models.TagCount{}, f.call("ListTransactionTags")
+}
+
+func (f *fakeStorage) PoolStats() models.PoolStats {
+	f.call("PoolStats")
+	return models.PoolStats{}
+}
+
+func (f *fakeStorage) StorageInfo(ctx context.Context) (*models.StorageInfo, error) {
+	if err := f.call("StorageInfo"); err != nil {
+		return nil, err
+	}
+	return &models.StorageInfo{}, nil
+}
+
+func (f *fakeStorage) GetPeriodClose(ctx context.Context) (*models.PeriodClose, error) {
+	return nil, f.call("GetPeriodClose")
+}
+
+func (f *fakeStorage) ClosePeriod(ctx context.Context, through time.Time, closedBy string, force bool) (*models.PeriodClose, error) {
+	if err := f.call("ClosePeriod"); err != nil {
+		return nil, err
+	}
+	return &models.PeriodClose{ClosedThrough: through, ClosedBy: closedBy, Forced: force}, nil
+}
+
+func (f *fakeStorage) GetWalletSummary(ctx context.Context) (*models.WalletSummary, error) {
+	if err := f.call("GetWalletSummary"); err != nil {
+		return nil, err
+	}
+	return &models.WalletSummary{}, nil
+}
+
+func (f *fakeStorage) RefreshWalletSummary(ctx context.Context) error {
+	return f.call("RefreshWalletSummary")
+}
+
+func (f *fakeStorage) SnapshotWallets(ctx context.Context, fn func(models.Wallet) error) error {
+	return f.call("SnapshotWallets")
+}
+
+func (f *fakeStorage) RestoreWallets(ctx context.Context, wallets []models.Wallet) error {
+	return f.call("RestoreWallets")
+}
+
+func (f *fakeStorage) GetIdempotencyKey(ctx context.Context, key string) (*models.IdempotencyKey, error) {
+	if err := f.call("GetIdempotencyKey"); err != nil {
+		return nil, err
+	}
+	return &models.IdempotencyKey{Key: key, TransactionID: 1}, nil
+}
+
+func (f *fakeStorage) DeleteIdempotencyKey(ctx context.Context, key string) error {
+	return f.call("DeleteIdempotencyKey")
+}
+
+func (f *fakeStorage) Fsck(ctx context.Context, limit int, report func(models.FsckFinding) error) (models.FsckSummary, error) {
+	return models.FsckSummary{}, f.call("Fsck")
+}
+
+// testAddress возвращает адрес схемы hex64, различный для разных n.
+func testAddress(n int) string {
+	return fmt.Sprintf("%064x", n)
+}
+
+// testConfig - конфигурация с включёнными выводами средств и контрольными точками
+// и значениями по умолчанию для остальных полей.
+func testConfig() *config.Config {
+	return &config.Config{
+		HTTP:                 config.HTTP{MaxBodyBytes: 1 << 20, MaxRestoreBytes: 1 << 22},
+		SendQueueWait:        100 * time.Millisecond,
+		PaymentRequestTTL:    24 * time.Hour,
+		WithdrawalWallet:     testAddress(0xc1ea12),
+		CursorSecret:         "test-cursor-secret",
+		MaxAmount:            1000000000,
+		CheckpointSigningKey: "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60",
+		AddressScheme:        "hex64",
+	}
+}
+
+// newTestRouter возвращает роутер со всеми маршрутами API поверх db.
+func newTestRouter(db Storage, cfg *config.Config) (*API, chi.Router) {
+	a := New(db, cfg)
+	r := chi.NewRouter()
+	a.RegisterRoutes(r)
+	return a, r
+}
+
+// serve выполняет запрос method path с телом body (пустое - без тела) и возвращает ответ.
+func serve(h http.Handler, method, path, body string, header http.Header) *httptest.ResponseRecorder {
+	var req *http.Request
+	if body == "" {
+		req = httptest.NewRequest(method, path, nil)
+	} else {
+		req = httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Через сколько секунд клиенту предлагается повторить отклонённый из-за перегрузки перевод.
+const overloadedRetryAfter = "1"
+
+var (
+	sendInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "payments_send_in_flight",
+		Help: "Количество выполняющихся сейчас запросов на перевод.",
+	})
+	sendConcurrencyLimit = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "payments_send_concurrency_limit",
+		Help: "Максимум одновременных запросов на перевод (SEND_CONCURRENCY); 0 - без ограничения.",
+	})
+	sendRejected = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "payments_send_rejected_total",
+		Help: "Количество запросов на перевод, отклонённых из-за перегрузки.",
+	})
+)
+
+// sendLimit ограничивает количество одновременно выполняющихся запросов на перевод
+// значением SEND_CONCURRENCY. Запрос ждёт свободного места не дольше SEND_QUEUE_WAIT,
+// затем отклоняется с кодом 503 overloaded и заголовком Retry-After: без очереди
+// всплеск нагрузки не исчерпывает пул подключений, и чтение продолжает работать.
+// При SEND_CONCURRENCY=0 ограничения нет.
+func (a *API) sendLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.sendSlots == nil {
+			sendInFlight.Inc()
+			defer sendInFlight.Dec()
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		timer := time.NewTimer(a.cfg.SendQueueWait)
+		defer timer.Stop()
+		select {
+		case a.sendSlots <- struct{}{}:
+		case <-timer.C:
+			sendRejected.Inc()
+			w.Header().Set("Retry-After", overloadedRetryAfter)
+			writeError(w, http.StatusServiceUnavailable, CodeOverloaded, "сервис перегружен, повторите перевод позже",
+				map[string]int{"max_concurrent": cap(a.sendSlots)})
+			return
+		case <-r.Context().Done():
+			// Истёк X-Request-Timeout (ответ 504) или клиент отключился (отвечать некому).
+			deadlineExceeded(w, r, r.Context().Err())
+			return
+		}
+		defer func() { <-a.sendSlots }()
+
+		sendInFlight.Inc()
+		defer sendInFlight.Dec()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go-payments/internal/models"
+)
+
+// blockingStorage держит каждый Execute до закрытия release и сообщает о начале
+// перевода в started.
+type blockingStorage struct {
+	*fakeStorage
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStorage) Execute(ctx context.Context, t models.Transfer) (*models.Transaction, error) {
+	s.started <- struct{}{}
+	<-s.release
+	return s.fakeStorage.Execute(ctx, t)
+}
+
+func TestSendLimit(t *testing.T) {
+	const concurrency = 3
+	cfg := testConfig()
+	cfg.SendConcurrency = concurrency
+	cfg.SendQueueWait = 50 * time.Millisecond
+	db := &blockingStorage{fakeStorage: &fakeStorage{}, started: make(chan struct{}, 10), release: make(chan struct{})}
+	a, router := newTestRouter(db, cfg)
+	transfer := `{"from":"` + testAddress(1) + `","to":"` + testAddress(2) + `","amount":"10"}`
+
+	// Заняты все слоты: cap(sendSlots) переводов выполняются в хранилище.
+	var wg sync.WaitGroup
+	inFlight := make(chan *httptest.ResponseRecorder, concurrency+1)
+	for range cap(a.sendSlots) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			inFlight <- serve(router, http.MethodPost, "/api/send", transfer, nil)
+		}()
+	}
+	for range concurrency {
+		select {
+		case <-db.started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("переводы не начались")
+		}
+	}
+
+	// Следующий перевод ждёт SEND_QUEUE_WAIT и отклоняется, не доходя до хранилища.
+	w := serve(router, http.MethodPost, "/api/send", transfer, nil)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("перевод сверх лимита: статус %d, want 503; тело %s", w.Code, truncate(w.Body.String()))
+	}
+	checkErrorEnvelope(t, "перевод сверх лимита", w, CodeOverloaded)
+	if ra := w.Header().Get("Retry-After"); ra != overloadedRetryAfter {
+		t.Errorf("Retry-After %q, want %q", ra, overloadedRetryAfter)
+	}
+	var body struct {
+		Details struct {
+			MaxConcurrent int `json:"max_concurrent"`
+		} `json:"details"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if body.Details.MaxConcurrent != concurrency {
+		t.Errorf("details.max_concurrent %d, want %d", body.Details.MaxConcurrent, concurrency)
+	}
+	select {
+	case <-db.started:
+		t.Error("отклонённый перевод дошёл до хранилища")
+	default:
+	}
+
+	// Дедлайн запроса раньше SEND_QUEUE_WAIT - 504, а не 503.
+	w = serve(router, http.MethodPost, "/api/send", transfer, http.Header{requestTimeoutHeader: {"10ms"}})
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("перевод с коротким дедлайном в очереди: статус %d, want 504", w.Code)
+	}
+
+	// Чтение лимит не затрагивает.
+	if w := serve(router, http.MethodGet, "/api/wallet/"+testAddress(1)+"/balance", "", nil); w.Code != http.StatusOK {
+		t.Errorf("чтение при занятых слотах: статус %d, want 200", w.Code)
+	}
+
+	// После освобождения слотов переводы снова выполняются.
+	close(db.release)
+	wg.Wait()
+	close(inFlight)
+	for w := range inFlight {
+		if w.Code != http.StatusOK {
+			t.Errorf("перевод в слоте: статус %d, want 200", w.Code)
+		}
+	}
+	if w := serve(router, http.MethodPost, "/api/send", transfer, nil); w.Code != http.StatusOK {
+		t.Errorf("перевод после освобождения слотов: статус %d, want 200", w.Code)
+	}
+	if len(a.sendSlots) != 0 {
+		t.Errorf("занято слотов %d после завершения переводов, want 0", len(a.sendSlots))
+	}
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-payments/internal/checkpoint"
+	"go-payments/internal/redact"
+	"go-payments/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// checkpointsEnabled отвечает 501, если ключ подписи контрольных точек не задан.
+func (a *API) checkpointsEnabled(w http.ResponseWriter) bool {
+	if a.cfg.CheckpointSigningKey == "" {
+		writeError(w, http.StatusNotImplemented, CodeCheckpointsDisabled, "контрольные точки отключены: не задан CHECKPOINT_SIGNING_KEY", nil)
+		return false
+	}
+	return true
+}
+
+// parseCheckpointDate разбирает дату контрольной точки name в формате YYYY-MM-DD.
+// При ошибке отвечает 400 и возвращает false.
+func parseCheckpointDate(w http.ResponseWriter, name, value string) (time.Time, bool) {
+	date, err := time.Parse(checkpoint.DateLayout, value)
+	if err != nil {
+		badRequest(w, "параметр '"+name+"' должен быть датой в формате YYYY-MM-DD")
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// GetCheckpoint возвращает подписанную контрольную точку балансов за дату {date},
+// а с include_balances=true - и балансы всех кошельков в порядке листьев дерева.
+func (a *API) GetCheckpoint(w http.ResponseWriter, r *http.Request) {
+	if !a.checkpointsEnabled(w) {
+		return
+	}
+	date, ok := parseCheckpointDate(w, "date", chi.URLParam(r, "date"))
+	if !ok {
+		return
+	}
+	var withBalances bool
+	if v := r.URL.Query().Get("include_balances"); v != "" {
+		var err error
+		if withBalances, err = strconv.ParseBool(v); err != nil {
+			badRequest(w, "параметр 'include_balances' должен быть true или false")
+			return
+		}
+	}
+
+	bundle, err := a.db.GetCheckpoint(r.Context(), date, withBalances)
+	if err != nil {
+		if !errors.Is(err, storage.ErrCheckpointNotFound) {
+			log.Printf("ошибка получения контрольной точки за %s: %v", date.Format(checkpoint.DateLayout), err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+	writeJSON(w, r, bundle)
+}
+
+// GetWalletProof возвращает доказательство включения баланса кошелька в
+// контрольную точку за дату date (по умолчанию - последнюю).
+func (a *API) GetWalletProof(w http.ResponseWriter, r *http.Request) {
+	if !a.checkpointsEnabled(w) {
+		return
+	}
+	address := chi.URLParam(r, "address")
+	var date time.Time
+	if v := r.URL.Query().Get("date"); v != "" {
+		var ok bool
+		if date, ok = parseCheckpointDate(w, "date", v); !ok {
+			return
+		}
+	}
+
+	proof, err := a.db.GetCheckpointProof(r.Context(), address, date)
+	if err != nil {
+		if !errors.Is(err, storage.ErrCheckpointNotFound) && !errors.Is(err, storage.ErrWalletNotFound) {
+			log.Printf("ошибка получения доказательства для кошелька %s: %v", redact.Address(address), err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+	writeJSON(w, r, proof)
+}
@@ -0,0 +1,575 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"go-payments/internal/models"
+	"go-payments/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// contractCase - строка матрицы контракта: запрос к маршруту и ожидаемый ответ.
+type contractCase struct {
+	path string
+	body string
+	// header - дополнительные заголовки запроса.
+	header http.Header
+	// err - ошибка, которую возвращает каждый метод хранилища; nil - успех.
+	err error
+	// setup меняет API перед запросом: конфигурацию или режим обслуживания.
+	setup func(a *API)
+	// status и code - ожидаемый ответ; пустой code - ответ без ошибки.
+	status int
+	code   string
+}
+
+var (
+	errStorageFailure = errors.New("соединение с базой разорвано")
+	errInsufficient   = &storage.TransactionError{Code: storage.CodeInsufficientFunds, OriginalErr: storage.ErrInsufficientFunds}
+	errSenderNotFound = &storage.TransactionError{Code: storage.CodeSenderNotFound, OriginalErr: storage.ErrWalletNotFound}
+	errRecipientGone  = &storage.TransactionError{Code: storage.CodeRecipientNotFound, OriginalErr: storage.ErrWalletNotFound}
+	errLimitExceeded  = &storage.TransactionError{Code: storage.CodeRecipientLimitExceeded, OriginalErr: storage.ErrRecipientLimitExceeded}
+	errRetries        = &storage.TransactionError{Code: storage.CodeRetriesExhausted, OriginalErr: errors.New("конфликт сериализации")}
+)
+
+func maintenanceOn(a *API)  { a.maintenance.Store(true) }
+func withdrawalsOff(a *API) { a.cfg.WithdrawalWallet = "" }
+func checkpointsOff(a *API) { a.cfg.CheckpointSigningKey = "" }
+
+// sendSaturated занимает единственный слот одновременных переводов.
+func sendSaturated(a *API) {
+	a.sendSlots = make(chan struct{}, 1)
+	a.sendSlots <- struct{}{}
+}
+
+// contractMatrix - ожидаемые ответы каждого маршрута по ключу "МЕТОД шаблон".
+// У каждого зарегистрированного маршрута должна быть хотя бы одна строка.
+func contractMatrix() map[string][]contractCase {
+	a1, a2 := testAddress(1), testAddress(2)
+	wallet := "/api/wallet/" + a1
+	admin := "/api/admin/wallet/" + a1
+	snapshot := fmt.Sprintf(`{"version":%d,"wallets":[]}`, models.SnapshotVersion)
+
+	return map[string][]contractCase{
+		"GET /api/transactions": {
+			{path: "/api/transactions", status: http.StatusOK},
+			{path: "/api/transactions?limit=0", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/transactions?offset=10", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/transactions?status=bogus", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/transactions?between=" + a1 + ",xyz", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/transactions?cursor=garbage", status: http.StatusBadRequest, code: CodeInvalidCursor},
+			{path: "/api/transactions?embed=everything", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/transactions?bogus=1", header: http.Header{strictParamsHeader: {"true"}}, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/transactions", err: errStorageFailure, status: http.StatusInternalServerError, code: CodeInternalError},
+			{path: "/api/transactions", err: context.DeadlineExceeded, status: http.StatusGatewayTimeout, code: CodeDeadlineExceeded},
+		},
+		"GET /api/transactions/groups/{group_id}": {
+			{path: "/api/transactions/groups/batch-1", status: http.StatusOK},
+			{path: "/api/transactions/groups/batch-1", err: storage.ErrGroupNotFound, status: http.StatusNotFound, code: CodeGroupNotFound},
+		},
+		"GET /api/transactions/{id}": {
+			{path: "/api/transactions/1", status: http.StatusOK},
+			{path: "/api/transactions/01ARZ3NDEKTSV4RRFFQ69G5FAV", status: http.StatusOK},
+			{path: "/api/transactions/abc", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/transactions/0", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/transactions/1", err: storage.ErrTxNotFound, status: http.StatusNotFound, code: CodeTxNotFound},
+			{path: "/api/transactions/01ARZ3NDEKTSV4RRFFQ69G5FAV", err: storage.ErrTxNotFound, status: http.StatusNotFound, code: CodeTxNotFound},
+		},
+		"GET /api/wallet/{address}/balance": {
+			{path: wallet + "/balance", status: http.StatusOK},
+			{path: "/api/wallet/xyz/balance", status: http.StatusBadRequest, code: CodeInvalidAddress},
+			{path: wallet + "/balance", err: storage.ErrWalletNotFound, status: http.StatusNotFound, code: CodeWalletNotFound},
+			{path: wallet + "/balance", err: errStorageFailure, status: http.StatusInternalServerError, code: CodeInternalError},
+		},
+		"GET /api/wallet/{address}/statement": {
+			{path: wallet + "/statement?month=2024-01", status: http.StatusOK},
+			{path: wallet + "/statement?month=2024-13", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: wallet + "/statement", err: storage.ErrWalletNotFound, status: http.StatusNotFound, code: CodeWalletNotFound},
+		},
+		"GET /api/wallet/{address}/activity": {
+			{path: wallet + "/activity?tz=Europe/Moscow", status: http.StatusOK},
+			{path: wallet + "/activity?tz=Local", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: wallet + "/activity?since=2024-02-01T00:00:00Z&until=2024-01-01T00:00:00Z", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: wallet + "/activity", err: storage.ErrWalletNotFound, status: http.StatusNotFound, code: CodeWalletNotFound},
+		},
+		"GET /api/wallet/{address}/proof": {
+			{path: wallet + "/proof?date=2024-01-01", status: http.StatusOK},
+			{path: wallet + "/proof?date=01.01.2024", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: wallet + "/proof", err: storage.ErrCheckpointNotFound, status: http.StatusNotFound, code: CodeCheckpointNotFound},
+			{path: wallet + "/proof", err: storage.ErrWalletNotFound, status: http.StatusNotFound, code: CodeWalletNotFound},
+			{path: wallet + "/proof", setup: checkpointsOff, status: http.StatusNotImplemented, code: CodeCheckpointsDisabled},
+		},
+		"GET /api/wallet/{address}/incoming": {
+			{path: wallet + "/incoming?unacknowledged=true", status: http.StatusOK},
+			{path: wallet + "/incoming?after_id=1&consumer=erp", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: wallet + "/incoming?consumer=bad%20name", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: wallet + "/incoming?after_id=-1", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: wallet + "/incoming?cursor=garbage", status: http.StatusBadRequest, code: CodeInvalidCursor},
+			{path: wallet + "/incoming", err: errStorageFailure, status: http.StatusInternalServerError, code: CodeInternalError},
+		},
+		"GET /api/wallet/{address}/incoming/cursors/{consumer}": {
+			{path: wallet + "/incoming/cursors/erp", status: http.StatusOK},
+			{path: wallet + "/incoming/cursors/bad%20name", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: wallet + "/incoming/cursors/erp", err: storage.ErrIncomingCursorNotFound, status: http.StatusNotFound, code: CodeIncomingCursorNotFound},
+		},
+		"GET /api/wallets": {
+			{path: "/api/wallets?limit=5", status: http.StatusOK},
+			{path: "/api/wallets?offset=5", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/wallets?count=5&limit=6", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/wallets", err: errStorageFailure, status: http.StatusInternalServerError, code: CodeInternalError},
+		},
+		"GET /api/wallets/by-label/{label}": {
+			{path: "/api/wallets/by-label/payroll", status: http.StatusOK},
+			{path: "/api/wallets/by-label/payroll", err: storage.ErrLabelNotFound, status: http.StatusNotFound, code: CodeLabelNotFound},
+		},
+		"GET /api/stats/volume": {
+			{path: "/api/stats/volume?bucket=hour", status: http.StatusOK},
+			{path: "/api/stats/volume?bucket=week", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/stats/volume?bucket=hour&since=2000-01-01T00:00:00Z", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/stats/volume", err: errStorageFailure, status: http.StatusInternalServerError, code: CodeInternalError},
+		},
+		"GET /api/stats/wallets": {
+			{path: "/api/stats/wallets", status: http.StatusOK},
+			{path: "/api/stats/wallets", err: errStorageFailure, status: http.StatusInternalServerError, code: CodeInternalError},
+		},
+		"GET /api/meta/error-codes": {
+			{path: "/api/meta/error-codes", status: http.StatusOK},
+		},
+		"GET /api/meta/transaction-statuses": {
+			{path: "/api/meta/transaction-statuses", status: http.StatusOK},
+		},
+		"GET /api/meta/address-scheme": {
+			{path: "/api/meta/address-scheme", status: http.StatusOK},
+		},
+		"GET /api/meta/sandbox": {
+			{path: "/api/meta/sandbox", status: http.StatusOK},
+		},
+		"GET /api/meta/examples": {
+			{path: "/api/meta/examples", status: http.StatusOK},
+		},
+		"GET /api/meta/examples/{name}": {
+			{path: "/api/meta/examples/get-balance", status: http.StatusOK},
+			{path: "/api/meta/examples/no-such-route", status: http.StatusNotFound, code: CodeExampleNotFound},
+		},
+		"GET /api/payment-requests/{token}": {
+			{path: "/api/payment-requests/token", status: http.StatusOK},
+			{path: "/api/payment-requests/token", err: storage.ErrPaymentRequestNotFound, status: http.StatusNotFound, code: CodePaymentRequestNotFound},
+		},
+		"GET /api/wallet/{address}/withdrawals": {
+			{path: wallet + "/withdrawals?status=pending", status: http.StatusOK},
+			{path: wallet + "/withdrawals?status=bogus", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: wallet + "/withdrawals", err: errStorageFailure, status: http.StatusInternalServerError, code: CodeInternalError},
+		},
+		"GET /api/withdrawals/{id}": {
+			{path: "/api/withdrawals/1", status: http.StatusOK},
+			{path: "/api/withdrawals/abc", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/withdrawals/1", err: storage.ErrWithdrawalNotFound, status: http.StatusNotFound, code: CodeWithdrawalNotFound},
+		},
+
+		"POST /api/send": {
+			{path: "/api/send", body: `{"from":"` + a1 + `","to":"` + a2 + `","amount":"10"}`, status: http.StatusOK},
+			{path: "/api/send", body: `{"from":`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/send", body: `{"from":"xyz","to":"` + a2 + `","amount":"10"}`, status: http.StatusBadRequest, code: CodeInvalidAddress},
+			{path: "/api/send", body: `{"from":"` + a1 + `","to":"` + a2 + `","amount":"0"}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/send", body: `{"from":"` + a1 + `","to":"` + a1 + `","amount":"10"}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/send", body: `{"from":"` + a1 + `","to":"` + a2 + `","amount":1e400}`, status: http.StatusBadRequest, code: CodeAmountOutOfRange},
+			{path: "/api/send", body: `{"from":"` + a1 + `","to":"` + a2 + `","amount":1e308}`, status: http.StatusBadRequest, code: CodeAmountOutOfRange},
+			{path: "/api/send", body: `{"from_label":"payroll","to":"` + a2 + `","amount":"10"}`, err: storage.ErrLabelNotFound, status: http.StatusNotFound, code: CodeLabelNotFound},
+			{path: "/api/send", body: `{"from":"` + a1 + `","to":"` + a2 + `","amount":"10"}`, err: errInsufficient, status: http.StatusPaymentRequired, code: CodeInsufficientFunds},
+			{path: "/api/send", body: `{"from":"` + a1 + `","to":"` + a2 + `","amount":"10"}`, err: errSenderNotFound, status: http.StatusNotFound, code: CodeSenderNotFound},
+			{path: "/api/send", body: `{"from":"` + a1 + `","to":"` + a2 + `","amount":"10"}`, err: errRecipientGone, status: http.StatusNotFound, code: CodeRecipientNotFound},
+			{path: "/api/send", body: `{"from":"` + a1 + `","to":"` + a2 + `","amount":"10"}`, err: errLimitExceeded, status: http.StatusUnprocessableEntity, code: CodeRecipientLimitExceeded},
+			{path: "/api/send", body: `{"from":"` + a1 + `","to":"` + a2 + `","amount":"10"}`, err: errRetries, status: http.StatusServiceUnavailable, code: CodeRetriesExhausted},
+			{path: "/api/send", body: `{"from":"` + a1 + `","to":"` + a2 + `","amount":"10"}`, err: storage.ErrIdempotencyKeyReused, status: http.StatusUnprocessableEntity, code: CodeIdempotencyKeyReused},
+			{path: "/api/send", body: `{"from":"` + a1 + `","to":"` + a2 + `","amount":"10"}`, err: storage.ErrSystemWallet, status: http.StatusForbidden, code: CodeSystemWallet},
+			{path: "/api/send", body: `{"from":"` + a1 + `","to":"` + a2 + `","amount":"10"}`, setup: maintenanceOn, status: http.StatusServiceUnavailable, code: CodeMaintenance},
+			{path: "/api/send", body: `{"from":"` + a1 + `","to":"` + a2 + `","amount":"10"}`, setup: sendSaturated, status: http.StatusServiceUnavailable, code: CodeOverloaded},
+			{path: "/api/send", body: `{"memo":"` + string(make([]byte, 2<<20)) + `"}`, status: http.StatusRequestEntityTooLarge, code: CodeRequestTooLarge},
+		},
+		"POST /api/transactions/{id}/ack": {
+			{path: "/api/transactions/1/ack", body: `{"address":"` + a1 + `"}`, status: http.StatusOK},
+			{path: "/api/transactions/1/ack", body: `{}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/transactions/1/ack", body: `{"address":"xyz"}`, status: http.StatusBadRequest, code: CodeInvalidAddress},
+			{path: "/api/transactions/1/ack", body: `{"address":"` + a1 + `"}`, err: storage.ErrNotRecipient, status: http.StatusForbidden, code: CodeNotRecipient},
+			{path: "/api/transactions/1/ack", body: `{"address":"` + a1 + `"}`, err: storage.ErrPeriodClosed, status: http.StatusLocked, code: CodePeriodClosed},
+			{path: "/api/transactions/1/ack", body: `{"address":"` + a1 + `"}`, err: storage.ErrTxNotFound, status: http.StatusNotFound, code: CodeTxNotFound},
+		},
+		"POST /api/wallet/{address}/incoming/ack": {
+			{path: wallet + "/incoming/ack", body: `{"ids":[1,2,2]}`, status: http.StatusOK},
+			{path: wallet + "/incoming/ack", body: `{"through_id":3}`, status: http.StatusOK},
+			{path: wallet + "/incoming/ack", body: `{}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: wallet + "/incoming/ack", body: `{"ids":[1],"through_id":3}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: wallet + "/incoming/ack", body: `{"ids":[0]}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: wallet + "/incoming/ack", body: `{"through_id":3}`, err: errStorageFailure, status: http.StatusInternalServerError, code: CodeInternalError},
+		},
+		"PUT /api/wallet/{address}/incoming/cursors/{consumer}": {
+			{path: wallet + "/incoming/cursors/erp", body: `{"last_transaction_id":5}`, status: http.StatusOK},
+			{path: wallet + "/incoming/cursors/erp", body: `{}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: wallet + "/incoming/cursors/erp", body: `{"last_transaction_id":5}`, err: storage.ErrWalletNotFound, status: http.StatusNotFound, code: CodeWalletNotFound},
+		},
+		"POST /api/payment-requests": {
+			{path: "/api/payment-requests", body: `{"to":"` + a1 + `","amount":"5"}`, status: http.StatusCreated},
+			{path: "/api/payment-requests", body: `{"to":"` + a1 + `","amount":"-5"}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/payment-requests", body: `{"to":"` + a1 + `","amount":"5","expires_at":"2000-01-01T00:00:00Z"}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/payment-requests", body: `{"to":"` + a1 + `","amount":-1e400}`, status: http.StatusBadRequest, code: CodeAmountOutOfRange},
+			{path: "/api/payment-requests", body: `{"to":"` + a1 + `","amount":"5"}`, err: storage.ErrWalletNotFound, status: http.StatusNotFound, code: CodeWalletNotFound},
+		},
+		"POST /api/payment-requests/{token}/pay": {
+			{path: "/api/payment-requests/token/pay", body: `{"from":"` + a2 + `"}`, status: http.StatusOK},
+			{path: "/api/payment-requests/token/pay", body: `{"from":"xyz"}`, status: http.StatusBadRequest, code: CodeInvalidAddress},
+			{path: "/api/payment-requests/token/pay", body: `{"from":"` + a2 + `"}`, err: storage.ErrPaymentRequestNotFound, status: http.StatusNotFound, code: CodePaymentRequestNotFound},
+			{path: "/api/payment-requests/token/pay", body: `{"from":"` + a2 + `"}`, err: storage.ErrPaymentRequestFulfilled, status: http.StatusConflict, code: CodePaymentRequestFulfilled},
+			{path: "/api/payment-requests/token/pay", body: `{"from":"` + a2 + `"}`, err: storage.ErrPaymentRequestExpired, status: http.StatusGone, code: CodePaymentRequestExpired},
+			{path: "/api/payment-requests/token/pay", body: `{"from":"` + a2 + `"}`, setup: maintenanceOn, status: http.StatusServiceUnavailable, code: CodeMaintenance},
+		},
+		"POST /api/wallet/{address}/withdraw": {
+			{path: wallet + "/withdraw", body: `{"amount":"5"}`, status: http.StatusCreated},
+			{path: wallet + "/withdraw", body: `{"amount":"0"}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: wallet + "/withdraw", body: `{"amount":"5"}`, setup: withdrawalsOff, status: http.StatusNotImplemented, code: CodeWithdrawalsDisabled},
+			{path: wallet + "/withdraw", body: `{"amount":"5"}`, err: errInsufficient, status: http.StatusPaymentRequired, code: CodeInsufficientFunds},
+			{path: wallet + "/withdraw", body: `{"amount":"5"}`, err: storage.ErrWalletNotFound, status: http.StatusNotFound, code: CodeWalletNotFound},
+		},
+
+		"POST /api/admin/sweep": {
+			{path: "/api/admin/sweep", body: `{"to":"` + a1 + `"}`, status: http.StatusOK},
+			{path: "/api/admin/sweep", body: `{}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/admin/sweep", body: `{"to":"` + a1 + `","max_wallets":5000}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/admin/sweep", body: `{"to":"` + a1 + `"}`, err: storage.ErrWalletNotFound, status: http.StatusNotFound, code: CodeWalletNotFound},
+		},
+		"POST /api/admin/seed": {
+			// Ход создания передаётся потоком, поэтому сбой хранилища - в последней строке, а не в статусе.
+			{path: "/api/admin/seed", body: `{"count":2}`, status: http.StatusOK},
+			{path: "/api/admin/seed", body: `{"count":2}`, err: errStorageFailure, status: http.StatusOK},
+			{path: "/api/admin/seed", body: `{"count":0}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/admin/seed", body: `{"count":1,"balance":1e400}`, status: http.StatusBadRequest, code: CodeAmountOutOfRange},
+		},
+		"POST /api/admin/stats/refresh": {
+			{path: "/api/admin/stats/refresh", status: http.StatusOK},
+			{path: "/api/admin/stats/refresh", err: errStorageFailure, status: http.StatusInternalServerError, code: CodeInternalError},
+		},
+		"POST /api/admin/restore": {
+			{path: "/api/admin/restore", body: snapshot, status: http.StatusOK},
+			{path: "/api/admin/restore", body: `{"version":999}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/admin/restore", body: snapshot, err: storage.ErrWalletsExist, status: http.StatusConflict, code: CodeWalletsExist},
+		},
+		"PUT /api/admin/period-close": {
+			{path: "/api/admin/period-close", body: `{"closed_through":"2024-01-01T00:00:00Z"}`, status: http.StatusOK},
+			{path: "/api/admin/period-close", body: `{}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/admin/period-close", body: `{"closed_through":"2999-01-01T00:00:00Z"}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/admin/period-close", body: `{"closed_through":"2024-01-01T00:00:00Z"}`, err: storage.ErrPeriodCloseBackward, status: http.StatusConflict, code: CodePeriodCloseBackward},
+		},
+		"POST /api/admin/withdrawals/{id}/confirm": {
+			{path: "/api/admin/withdrawals/1/confirm", status: http.StatusOK},
+			{path: "/api/admin/withdrawals/0/confirm", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/admin/withdrawals/1/confirm", err: storage.ErrWithdrawalNotFound, status: http.StatusNotFound, code: CodeWithdrawalNotFound},
+			{path: "/api/admin/withdrawals/1/confirm", err: storage.ErrWithdrawalSettled, status: http.StatusConflict, code: CodeWithdrawalSettled},
+		},
+		"POST /api/admin/withdrawals/{id}/fail": {
+			{path: "/api/admin/withdrawals/1/fail", body: `{"reason":"банк отклонил"}`, status: http.StatusOK},
+			{path: "/api/admin/withdrawals/1/fail", body: `{"reason":" "}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/admin/withdrawals/1/fail", body: `{"reason":"банк отклонил"}`, err: storage.ErrWithdrawalSettled, status: http.StatusConflict, code: CodeWithdrawalSettled},
+			{path: "/api/admin/withdrawals/1/fail", body: `{"reason":"банк отклонил"}`, err: storage.ErrPeriodClosed, status: http.StatusLocked, code: CodePeriodClosed},
+		},
+		"POST /api/admin/zero-notes": {
+			{path: "/api/admin/zero-notes", body: `{"from":"` + a1 + `","to":"` + a2 + `","amount":"0","memo":"сверка"}`, status: http.StatusCreated},
+			{path: "/api/admin/zero-notes", body: `{"from":"` + a1 + `","to":"` + a2 + `","amount":"1","memo":"сверка"}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/admin/zero-notes", body: `{"from":"` + a1 + `","to":"` + a2 + `","amount":"0","memo":"сверка"}`, err: errSenderNotFound, status: http.StatusNotFound, code: CodeSenderNotFound},
+		},
+		"POST /api/admin/transactions/{id}/tags/{tag}": {
+			{path: "/api/admin/transactions/1/tags/vip", status: http.StatusNoContent},
+			{path: "/api/admin/transactions/1/tags/v!p", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/admin/transactions/1/tags/vip", err: storage.ErrTxNotFound, status: http.StatusNotFound, code: CodeTxNotFound},
+			{path: "/api/admin/transactions/1/tags/vip", err: storage.ErrPeriodClosed, status: http.StatusLocked, code: CodePeriodClosed},
+		},
+		"DELETE /api/admin/transactions/{id}/tags/{tag}": {
+			{path: "/api/admin/transactions/1/tags/vip", status: http.StatusNoContent},
+			{path: "/api/admin/transactions/1/tags/vip", err: storage.ErrTxNotFound, status: http.StatusNotFound, code: CodeTxNotFound},
+		},
+		"DELETE /api/admin/idempotency-keys/{key}": {
+			{path: "/api/admin/idempotency-keys/order-1", status: http.StatusNoContent},
+			{path: "/api/admin/idempotency-keys/order-1", err: storage.ErrIdempotencyKeyNotFound, status: http.StatusNotFound, code: CodeIdempotencyKeyNotFound},
+		},
+		"POST /api/admin/wallet/{address}/purge": {
+			{path: admin + "/purge", status: http.StatusOK},
+			{path: admin + "/purge", err: storage.ErrWalletNotFound, status: http.StatusNotFound, code: CodeWalletNotFound},
+			{path: admin + "/purge", err: storage.ErrWalletNotArchived, status: http.StatusConflict, code: CodeWalletNotPurgeable},
+			{path: admin + "/purge", err: storage.ErrWalletNotEmpty, status: http.StatusConflict, code: CodeWalletNotPurgeable},
+			{path: admin + "/purge", err: storage.ErrWalletReferenced, status: http.StatusConflict, code: CodeWalletNotPurgeable},
+			{path: admin + "/purge", setup: maintenanceOn, status: http.StatusServiceUnavailable, code: CodeMaintenance},
+		},
+		"PUT /api/admin/wallet/{address}/label": {
+			{path: admin + "/label", body: `{"label":"payroll"}`, status: http.StatusOK},
+			{path: admin + "/label", body: `{"label":"Pay Roll"}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: admin + "/label", body: `{"label":"payroll"}`, err: storage.ErrLabelTaken, status: http.StatusConflict, code: CodeLabelTaken},
+			{path: admin + "/label", body: `{"label":"payroll"}`, err: storage.ErrWalletNotFound, status: http.StatusNotFound, code: CodeWalletNotFound},
+		},
+		"PUT /api/admin/wallet/{address}/max-balance": {
+			{path: admin + "/max-balance", body: `{"max_balance":"100"}`, status: http.StatusOK},
+			{path: admin + "/max-balance", body: `{"max_balance":null}`, status: http.StatusOK},
+			{path: admin + "/max-balance", body: `{"max_balance":"-1"}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: admin + "/max-balance", body: `{"max_balance":1e400}`, status: http.StatusBadRequest, code: CodeAmountOutOfRange},
+			{path: admin + "/max-balance", body: `{"max_balance":"100"}`, err: storage.ErrWalletNotFound, status: http.StatusNotFound, code: CodeWalletNotFound},
+		},
+		"PUT /api/admin/wallet/{address}/system": {
+			{path: admin + "/system", body: `{"system":true,"non_receivable":true}`, status: http.StatusOK},
+			{path: admin + "/system", body: `{"non_receivable":true}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: admin + "/system", body: `{"system":true}`, err: storage.ErrWalletNotFound, status: http.StatusNotFound, code: CodeWalletNotFound},
+		},
+		"POST /api/admin/wallet/{address}/notes": {
+			{path: admin + "/notes", body: `{"text":"проверен"}`, status: http.StatusCreated},
+			{path: admin + "/notes", body: `{"text":" "}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: admin + "/notes", body: `{"text":"проверен"}`, err: storage.ErrWalletNotFound, status: http.StatusNotFound, code: CodeWalletNotFound},
+		},
+		"POST /api/admin/wallet/{address}/notes/{id}/redact": {
+			{path: admin + "/notes/1/redact", status: http.StatusOK},
+			{path: admin + "/notes/abc/redact", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: admin + "/notes/1/redact", err: storage.ErrNoteNotFound, status: http.StatusNotFound, code: CodeNoteNotFound},
+		},
+		"POST /api/admin/wallet/{address}/thresholds": {
+			{path: admin + "/thresholds", body: `{"direction":"below","amount":"10"}`, status: http.StatusCreated},
+			{path: admin + "/thresholds", body: `{"direction":"sideways","amount":"10"}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: admin + "/thresholds", body: `{"direction":"below","amount":"10","target":"ftp://example.com"}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: admin + "/thresholds", body: `{"direction":"below","amount":"10"}`, err: storage.ErrTooManyThresholds, status: http.StatusConflict, code: CodeTooManyThresholds},
+			{path: admin + "/thresholds", body: `{"direction":"below","amount":"10"}`, err: storage.ErrWalletNotFound, status: http.StatusNotFound, code: CodeWalletNotFound},
+		},
+		"PUT /api/admin/wallet/{address}/thresholds/{id}": {
+			{path: admin + "/thresholds/1", body: `{"direction":"above","amount":"10","hysteresis":"1"}`, status: http.StatusOK},
+			{path: admin + "/thresholds/1", body: `{"direction":"above","amount":"10","hysteresis":"11"}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: admin + "/thresholds/1", body: `{"direction":"above","amount":"10"}`, err: storage.ErrThresholdNotFound, status: http.StatusNotFound, code: CodeThresholdNotFound},
+		},
+		"DELETE /api/admin/wallet/{address}/thresholds/{id}": {
+			{path: admin + "/thresholds/1", status: http.StatusNoContent},
+			{path: admin + "/thresholds/0", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: admin + "/thresholds/1", err: storage.ErrThresholdNotFound, status: http.StatusNotFound, code: CodeThresholdNotFound},
+		},
+		"PUT /api/admin/alert-rules": {
+			{path: "/api/admin/alert-rules", body: `{"cooldown":"1h"}`, status: http.StatusOK},
+			{path: "/api/admin/alert-rules", body: `{"cooldown":"скоро"}`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/admin/alert-rules", body: `{}`, err: errStorageFailure, status: http.StatusInternalServerError, code: CodeInternalError},
+		},
+
+		"GET /api/admin/wallets": {
+			{path: "/api/admin/wallets?limit=20&offset=40&frozen=false", status: http.StatusOK},
+			{path: "/api/admin/wallets?offset=-1", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/admin/wallets?frozen=maybe", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/admin/wallets?min_balance=abc", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/admin/wallets", err: errStorageFailure, status: http.StatusInternalServerError, code: CodeInternalError},
+		},
+		"GET /api/admin/transactions": {
+			{path: "/api/admin/transactions?tag=vip&limit=500", status: http.StatusOK},
+			{path: "/api/admin/transactions?tag=v!p", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/admin/transactions?limit=500&embed=wallets", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/admin/transactions?cursor=garbage", status: http.StatusBadRequest, code: CodeInvalidCursor},
+			{path: "/api/admin/transactions", err: errStorageFailure, status: http.StatusInternalServerError, code: CodeInternalError},
+		},
+		"GET /api/admin/tags": {
+			{path: "/api/admin/tags", status: http.StatusOK},
+			{path: "/api/admin/tags", err: errStorageFailure, status: http.StatusInternalServerError, code: CodeInternalError},
+		},
+		"GET /api/admin/withdrawals": {
+			{path: "/api/admin/withdrawals?wallet=" + a1 + "&status=review", status: http.StatusOK},
+			{path: "/api/admin/withdrawals?wallet=xyz", status: http.StatusBadRequest, code: CodeInvalidAddress},
+			{path: "/api/admin/withdrawals", err: errStorageFailure, status: http.StatusInternalServerError, code: CodeInternalError},
+		},
+		"GET /api/admin/checkpoints/{date}": {
+			{path: "/api/admin/checkpoints/2024-01-01?include_balances=true", status: http.StatusOK},
+			{path: "/api/admin/checkpoints/yesterday", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/admin/checkpoints/2024-01-01?include_balances=maybe", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/admin/checkpoints/2024-01-01", err: storage.ErrCheckpointNotFound, status: http.StatusNotFound, code: CodeCheckpointNotFound},
+			{path: "/api/admin/checkpoints/2024-01-01", setup: checkpointsOff, status: http.StatusNotImplemented, code: CodeCheckpointsDisabled},
+		},
+		"GET /api/admin/config": {
+			{path: "/api/admin/config", status: http.StatusOK},
+		},
+		"GET /api/admin/features": {
+			{path: "/api/admin/features", status: http.StatusOK},
+			{path: "/api/admin/features", err: errStorageFailure, status: http.StatusInternalServerError, code: CodeInternalError},
+		},
+		"GET /api/admin/storage-info": {
+			{path: "/api/admin/storage-info", status: http.StatusOK},
+			{path: "/api/admin/storage-info", err: errStorageFailure, status: http.StatusInternalServerError, code: CodeInternalError},
+		},
+		"GET /api/admin/period-close": {
+			{path: "/api/admin/period-close", status: http.StatusOK},
+			{path: "/api/admin/period-close", err: errStorageFailure, status: http.StatusInternalServerError, code: CodeInternalError},
+		},
+		"GET /api/admin/schema": {
+			{path: "/api/admin/schema", status: http.StatusOK},
+			{path: "/api/admin/schema", err: errStorageFailure, status: http.StatusInternalServerError, code: CodeInternalError},
+		},
+		"GET /api/admin/fsck": {
+			// Находки передаются потоком, поэтому сбой хранилища - в последней строке, а не в статусе.
+			{path: "/api/admin/fsck?limit=10", status: http.StatusOK},
+			{path: "/api/admin/fsck", err: errStorageFailure, status: http.StatusOK},
+			{path: "/api/admin/fsck?limit=0", status: http.StatusBadRequest, code: CodeInvalidRequest},
+		},
+		"GET /api/admin/snapshot": {
+			{path: "/api/admin/snapshot", status: http.StatusOK},
+			{path: "/api/admin/snapshot", err: errStorageFailure, status: http.StatusInternalServerError, code: CodeInternalError},
+		},
+		"GET /api/admin/idempotency-keys/{key}": {
+			{path: "/api/admin/idempotency-keys/order-1", status: http.StatusOK},
+			{path: "/api/admin/idempotency-keys/order-1", err: storage.ErrIdempotencyKeyNotFound, status: http.StatusNotFound, code: CodeIdempotencyKeyNotFound},
+		},
+		"GET /api/admin/wallet/{address}/notes": {
+			{path: admin + "/notes", status: http.StatusOK},
+			{path: "/api/admin/wallet/xyz/notes", status: http.StatusBadRequest, code: CodeInvalidAddress},
+			{path: admin + "/notes", err: errStorageFailure, status: http.StatusInternalServerError, code: CodeInternalError},
+		},
+		"GET /api/admin/wallet/{address}/thresholds": {
+			{path: admin + "/thresholds", status: http.StatusOK},
+			{path: admin + "/thresholds", err: errStorageFailure, status: http.StatusInternalServerError, code: CodeInternalError},
+		},
+		"GET /api/admin/wallet/{address}/thresholds/{id}": {
+			{path: admin + "/thresholds/1", status: http.StatusOK},
+			{path: admin + "/thresholds/abc", status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: admin + "/thresholds/1", err: storage.ErrThresholdNotFound, status: http.StatusNotFound, code: CodeThresholdNotFound},
+		},
+		"GET /api/admin/alert-rules": {
+			{path: "/api/admin/alert-rules", status: http.StatusOK},
+			{path: "/api/admin/alert-rules", err: errStorageFailure, status: http.StatusInternalServerError, code: CodeInternalError},
+		},
+		"GET /api/admin/maintenance": {
+			{path: "/api/admin/maintenance", status: http.StatusOK},
+			{path: "/api/admin/maintenance", setup: maintenanceOn, status: http.StatusOK},
+		},
+		"POST /api/admin/maintenance": {
+			// Режим обслуживания можно выключить и в самом режиме обслуживания.
+			{path: "/api/admin/maintenance", body: `{"enabled":false}`, setup: maintenanceOn, status: http.StatusOK},
+			{path: "/api/admin/maintenance", body: `{"enabled":`, status: http.StatusBadRequest, code: CodeInvalidRequest},
+			{path: "/api/admin/maintenance", body: `{"enabled":true,"persist":true}`, err: errStorageFailure, status: http.StatusInternalServerError, code: CodeInternalError},
+		},
+		"GET /readyz": {
+			{path: "/readyz", status: http.StatusOK},
+			// Ответ проверки готовности - не конверт ошибки, а состояние сервиса.
+			{path: "/readyz", err: errStorageFailure, status: http.StatusServiceUnavailable},
+		},
+	}
+}
+
+// TestRouteContract выполняет матрицу контракта на настоящем роутере и проверяет,
+// что в ней есть строки для каждого зарегистрированного маршрута и нет строк для
+// незарегистрированных.
+func TestRouteContract(t *testing.T) {
+	matrix := contractMatrix()
+
+	_, router := newTestRouter(&fakeStorage{}, testConfig())
+	registered := make(map[string]bool)
+	err := chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		registered[method+" "+route] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("chi.Walk: %v", err)
+	}
+	var missing, stale []string
+	for route := range registered {
+		if len(matrix[route]) == 0 {
+			missing = append(missing, route)
+		}
+	}
+	for route := range matrix {
+		if !registered[route] {
+			stale = append(stale, route)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(stale)
+	for _, route := range missing {
+		t.Errorf("маршрут %s без строк в матрице контракта", route)
+	}
+	for _, route := range stale {
+		t.Errorf("строки матрицы контракта для незарегистрированного маршрута %s", route)
+	}
+
+	// Каждый документированный ответ с ошибкой проверяется хотя бы одной строкой.
+	catalog := make(map[errorMapping]bool)
+	covered := make(map[errorMapping]bool)
+	for _, rows := range matrix {
+		for _, tc := range rows {
+			covered[errorMapping{tc.status, tc.code}] = true
+		}
+	}
+	for _, info := range errorCatalog() {
+		m := errorMapping{info.HTTPStatus, info.Code}
+		catalog[m] = true
+		if !covered[m] {
+			t.Errorf("ответ %d %s из каталога кодов ошибок без строки в матрице контракта", m.Status, m.Code)
+		}
+	}
+
+	routes := make([]string, 0, len(matrix))
+	for route := range matrix {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	for _, route := range routes {
+		method, _, _ := strings.Cut(route, " ")
+		for i, tc := range matrix[route] {
+			name := fmt.Sprintf("%s/%d", route, i)
+			db := &fakeStorage{err: tc.err}
+			a, router := newTestRouter(db, testConfig())
+			if tc.setup != nil {
+				tc.setup(a)
+			}
+			w := serve(router, method, tc.path, tc.body, tc.header)
+
+			if w.Code != tc.status {
+				t.Errorf("%s: %s %s: статус %d, want %d; тело %s", name, method, tc.path, w.Code, tc.status, truncate(w.Body.String()))
+				continue
+			}
+			if tc.code == "" {
+				continue
+			}
+			if !catalog[errorMapping{tc.status, tc.code}] {
+				t.Errorf("%s: пары %d %s нет в каталоге кодов ошибок", name, tc.status, tc.code)
+			}
+			checkErrorEnvelope(t, name, w, tc.code)
+		}
+	}
+}
+
+// checkErrorEnvelope проверяет конверт ошибки: JSON-объект только с полями code,
+// error и details, где code равен want, а error - непустое описание.
+func checkErrorEnvelope(t *testing.T, name string, w *httptest.ResponseRecorder, want string) {
+	t.Helper()
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("%s: Content-Type %q, want application/json", name, ct)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &fields); err != nil {
+		t.Errorf("%s: тело ошибки не JSON-объект: %v; тело %s", name, err, truncate(w.Body.String()))
+		return
+	}
+	for field := range fields {
+		if field != "code" && field != "error" && field != "details" {
+			t.Errorf("%s: лишнее поле %q в конверте ошибки", name, field)
+		}
+	}
+	var body errorResponse
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if body.Code != want {
+		t.Errorf("%s: код %q, want %q; тело %s", name, body.Code, want, truncate(w.Body.String()))
+	}
+	if body.Error == "" {
+		t.Errorf("%s: пустое описание ошибки", name)
+	}
+}
+
+// truncate укорачивает тело ответа для сообщения об ошибке теста.
+func truncate(s string) string {
+	const max = 300
+	if len(s) > max {
+		return s[:max] + "..."
+	}
+	return s
+}
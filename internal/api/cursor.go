@@ -0,0 +1,188 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-payments/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Заголовок, в котором возвращается курсор следующей страницы транзакций.
+const nextCursorHeader = "X-Next-Cursor"
+
+// Версия формата курсора; курсоры другой версии отклоняются.
+const cursorVersion = "1"
+
+// Query-параметры, которые не входят в отпечаток фильтра: их можно менять
+// между страницами, не теряя курсор.
+var cursorFreeParams = map[string]bool{
+	"cursor":        true,
+	"limit":         true,
+	"count":         true,
+	"fields":        true,
+	"embed":         true,
+	"amount_format": true,
+}
+
+// Причины отклонения курсора в details.reason ответа invalid_cursor.
+const (
+	cursorReasonFormat    = "format"
+	cursorReasonSignature = "signature"
+	cursorReasonEndpoint  = "endpoint"
+	cursorReasonFilter    = "filter"
+	cursorReasonExpired   = "expired"
+)
+
+// cursorError - причина, по которой курсор отклонён.
+type cursorError struct {
+	reason  string
+	message string
+}
+
+// cursorCodec подписывает курсоры пагинации HMAC-SHA256. Подписанная часть
+// содержит позицию, время выдачи, маршрут и отпечаток фильтра, поэтому клиент не
+// может подделать позицию или применить курсор к другому списку или фильтру.
+type cursorCodec struct {
+	key    []byte
+	maxAge time.Duration
+}
+
+// newCursorCodec создаёт codec с ключом secret. Без ключа используется случайный:
+// курсоры тогда действуют только в этом экземпляре и до его перезапуска.
+func newCursorCodec(secret string, maxAge time.Duration) *cursorCodec {
+	key := []byte(secret)
+	if secret == "" {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			log.Fatalf("не удалось создать ключ курсоров: %v", err)
+		}
+		log.Println("CURSOR_SECRET не задан: курсоры пагинации действуют только в этом экземпляре до перезапуска")
+	}
+	return &cursorCodec{key: key, maxAge: maxAge}
+}
+
+// cursorScope возвращает короткий отпечаток маршрута запроса и отпечаток фильтра:
+// маршрута, параметров пути и query-параметров, кроме cursorFreeParams.
+func cursorScope(r *http.Request) (endpoint, filter string) {
+	pattern := r.URL.Path
+	var params []string
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		pattern = rctx.RoutePattern()
+		for i, key := range rctx.URLParams.Keys {
+			if key != "*" {
+				params = append(params, key+"="+rctx.URLParams.Values[i])
+			}
+		}
+	}
+	for key, values := range r.URL.Query() {
+		if cursorFreeParams[key] {
+			continue
+		}
+		for _, v := range values {
+			params = append(params, "?"+key+"="+v)
+		}
+	}
+	sort.Strings(params)
+
+	e := sha256.Sum256([]byte(pattern))
+	f := sha256.Sum256([]byte(pattern + "\n" + strings.Join(params, "\n")))
+	return hex.EncodeToString(e[:4]), hex.EncodeToString(f[:8])
+}
+
+func (c *cursorCodec) sign(payload string) string {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// encode кодирует позицию (timestamp, id) для списка, запрошенного r.
+func (c *cursorCodec) encode(r *http.Request, pos models.TransactionCursor) string {
+	endpoint, filter := cursorScope(r)
+	payload := strings.Join([]string{
+		cursorVersion, endpoint, filter,
+		strconv.FormatInt(time.Now().Unix(), 10),
+		pos.Timestamp.UTC().Format(time.RFC3339Nano),
+		strconv.Itoa(pos.ID),
+	}, "|")
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + c.sign(payload)
+}
+
+// decode проверяет подпись, маршрут, фильтр и возраст курсора s и возвращает позицию.
+func (c *cursorCodec) decode(r *http.Request, s string) (*models.TransactionCursor, *cursorError) {
+	invalid := &cursorError{cursorReasonFormat, "параметр 'cursor' некорректен"}
+	encoded, signature, ok := strings.Cut(s, ".")
+	if !ok {
+		return nil, invalid
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, invalid
+	}
+	payload := string(raw)
+	if !hmac.Equal([]byte(signature), []byte(c.sign(payload))) {
+		return nil, &cursorError{cursorReasonSignature, "подпись курсора не совпадает: курсор изменён или выдан другим сервером"}
+	}
+
+	parts := strings.Split(payload, "|")
+	if len(parts) != 6 || parts[0] != cursorVersion {
+		return nil, invalid
+	}
+	endpoint, filter := cursorScope(r)
+	if parts[1] != endpoint {
+		return nil, &cursorError{cursorReasonEndpoint, "курсор выдан для другого списка"}
+	}
+	if parts[2] != filter {
+		return nil, &cursorError{cursorReasonFilter, "курсор выдан для других параметров фильтра"}
+	}
+	issued, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return nil, invalid
+	}
+	if c.maxAge > 0 && time.Since(time.Unix(issued, 0)) > c.maxAge {
+		return nil, &cursorError{cursorReasonExpired, "срок действия курсора истёк, начните с первой страницы"}
+	}
+	ts, err := time.Parse(time.RFC3339Nano, parts[4])
+	if err != nil {
+		return nil, invalid
+	}
+	id, err := strconv.Atoi(parts[5])
+	if err != nil {
+		return nil, invalid
+	}
+	return &models.TransactionCursor{Timestamp: ts, ID: id}, nil
+}
+
+// setNextCursor возвращает курсор следующей страницы, если страница из limit строк
+// заполнена (с учётом пропущенных строк) и, значит, за ней могут быть ещё транзакции.
+func (a *API) setNextCursor(w http.ResponseWriter, r *http.Request, transactions []models.Transaction, skipped, limit int) {
+	if len(transactions) > 0 && len(transactions)+skipped == limit {
+		last := transactions[len(transactions)-1]
+		w.Header().Set(nextCursorHeader, a.cursors.encode(r, models.TransactionCursor{Timestamp: last.Timestamp, ID: last.ID}))
+	}
+}
+
+// parseCursor разбирает параметр cursor запроса. Отклонённый курсор - ответ 400
+// invalid_cursor с причиной в details и false. Без параметра возвращает nil, true.
+func (a *API) parseCursor(w http.ResponseWriter, r *http.Request) (*models.TransactionCursor, bool) {
+	v := r.URL.Query().Get("cursor")
+	if v == "" {
+		return nil, true
+	}
+	cursor, cerr := a.cursors.decode(r, v)
+	if cerr != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidCursor, cerr.message, map[string]string{"reason": cerr.reason})
+		return nil, false
+	}
+	return cursor, true
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go-payments/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// issueCursor возвращает курсор, который codec выдал бы списку pattern на запрос
+// target.
+func issueCursor(c *cursorCodec, pattern, target string) string {
+	var cursor string
+	r := chi.NewRouter()
+	r.Get(pattern, func(w http.ResponseWriter, r *http.Request) {
+		cursor = c.encode(r, models.TransactionCursor{Timestamp: time.Unix(1700000000, 0), ID: 42})
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+	return cursor
+}
+
+// cursorField возвращает поле i подписанной части курсора.
+func cursorField(cursor string, i int) string {
+	encoded, _, _ := strings.Cut(cursor, ".")
+	raw, _ := base64.RawURLEncoding.DecodeString(encoded)
+	return strings.Split(string(raw), "|")[i]
+}
+
+// editCursor меняет поле i подписанной части курсора на value и, если resign,
+// подписывает курсор заново ключом codec.
+func editCursor(c *cursorCodec, cursor string, i int, value string, resign bool) string {
+	encoded, signature, _ := strings.Cut(cursor, ".")
+	raw, _ := base64.RawURLEncoding.DecodeString(encoded)
+	parts := strings.Split(string(raw), "|")
+	parts[i] = value
+	payload := strings.Join(parts, "|")
+	if resign {
+		signature = c.sign(payload)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + signature
+}
+
+// flipSignature меняет один байт подписи курсора.
+func flipSignature(cursor string) string {
+	b := []byte(cursor)
+	i := len(b) - 1
+	if b[i] == 'A' {
+		b[i] = 'B'
+	} else {
+		b[i] = 'A'
+	}
+	return string(b)
+}
+
+func TestCursorTampering(t *testing.T) {
+	cfg := testConfig()
+	cfg.CursorMaxAge = time.Hour
+	fake := &fakeStorage{}
+	a, h := newTestRouter(fake, cfg)
+
+	const list = "/api/transactions"
+	valid := issueCursor(a.cursors, list, list+"?status=success")
+	incoming := issueCursor(a.cursors, "/api/wallet/{address}/incoming", "/api/wallet/"+testAddress(1)+"/incoming")
+	tests := []struct {
+		name   string
+		target string
+		cursor string
+		reason string // пусто - курсор принимается
+	}{
+		{"выданный", list + "?status=success", valid, ""},
+		{"другие свободные параметры", list + "?status=success&limit=5&amount_format=number", valid, ""},
+		{"изменён байт подписи", list + "?status=success", flipSignature(valid), cursorReasonSignature},
+		{"изменена позиция без подписи", list + "?status=success", editCursor(a.cursors, valid, 5, "1", false), cursorReasonSignature},
+		{"подписан другим ключом", list + "?status=success", issueCursor(newCursorCodec("other-secret", 0), list, list+"?status=success"), cursorReasonSignature},
+		{"другой список", list, incoming, cursorReasonEndpoint},
+		{"подменён маршрут", list + "?status=success", editCursor(a.cursors, valid, 1, cursorField(incoming, 1), false), cursorReasonSignature},
+		{"подменён маршрут с подписью", list + "?status=success", editCursor(a.cursors, valid, 1, cursorField(incoming, 1), true), cursorReasonEndpoint},
+		{"подменён фильтр", list + "?status=success", editCursor(a.cursors, valid, 2, cursorField(incoming, 2), false), cursorReasonSignature},
+		{"другой фильтр", list + "?status=failed_insufficient_funds", valid, cursorReasonFilter},
+		{"фильтр снят", list, valid, cursorReasonFilter},
+		{"истёк", list + "?status=success", editCursor(a.cursors, valid, 3, strconv.FormatInt(time.Now().Add(-2*time.Hour).Unix(), 10), true), cursorReasonExpired},
+		{"другая версия", list + "?status=success", editCursor(a.cursors, valid, 0, "2", true), cursorReasonFormat},
+		{"без подписи", list + "?status=success", strings.Split(valid, ".")[0], cursorReasonFormat},
+		{"не base64", list + "?status=success", "!!!." + strings.Split(valid, ".")[1], cursorReasonFormat},
+	}
+	for _, tt := range tests {
+		fake.calls = nil
+		sep := "?"
+		if strings.Contains(tt.target, "?") {
+			sep = "&"
+		}
+		w := serve(h, "GET", tt.target+sep+"cursor="+tt.cursor, "", nil)
+		reached := slices.Contains(fake.called(), "GetLastTransactions")
+		if tt.reason == "" {
+			if w.Code != http.StatusOK || !reached {
+				t.Errorf("%s: %d %s, want 200", tt.name, w.Code, truncate(w.Body.String()))
+			}
+			continue
+		}
+		var body struct {
+			Code    string            `json:"code"`
+			Details map[string]string `json:"details"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Errorf("%s: тело ответа %q: %v", tt.name, truncate(w.Body.String()), err)
+			continue
+		}
+		if w.Code != http.StatusBadRequest || body.Code != CodeInvalidCursor || body.Details["reason"] != tt.reason {
+			t.Errorf("%s: %d %s, want 400 %s с reason %q", tt.name, w.Code, truncate(w.Body.String()), CodeInvalidCursor, tt.reason)
+		}
+		if reached {
+			t.Errorf("%s: отклонённый курсор дошёл до хранилища", tt.name)
+		}
+	}
+}
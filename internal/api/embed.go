@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"go-payments/internal/models"
+)
+
+// Значение параметра embed, встраивающее сведения о кошельках сторон перевода.
+const embedWallets = "wallets"
+
+// transactionView - вид элементов списка транзакций: выбранные поля (fields) и
+// встроенные сведения о кошельках (embed=wallets).
+type transactionView struct {
+	// fields - поля транзакций; nil - все поля.
+	fields []string
+	// wallets добавляет к каждой транзакции раздел embedded с кошельками сторон.
+	wallets bool
+}
+
+// embeddedTransaction - транзакция со встроенными сведениями о кошельках.
+type embeddedTransaction struct {
+	models.Transaction
+	Embedded models.TransactionEmbed `json:"embedded"`
+}
+
+// parseTransactionView разбирает параметры fields и embed списка транзакций со
+// страницей из limit строк. embed=wallets ограничен размером публичной страницы
+// (maxListLimit): большие выгрузки им не дополняются. При ошибке отвечает 400 и
+// возвращает false.
+func parseTransactionView(w http.ResponseWriter, r *http.Request, limit int) (transactionView, bool) {
+	var view transactionView
+	fields, err := parseFields(r, transactionFields)
+	if err != nil {
+		badRequest(w, err.Error())
+		return view, false
+	}
+	view.fields = fields
+
+	switch v := r.URL.Query().Get("embed"); v {
+	case "":
+	case embedWallets:
+		if limit > maxListLimit {
+			badRequest(w, fmt.Sprintf("параметр 'embed' допустим только для страниц до %d транзакций", maxListLimit))
+			return view, false
+		}
+		view.wallets = true
+		// Встроенный раздел остаётся в ответе и при выборе полей.
+		if view.fields != nil {
+			view.fields = append(view.fields, "embedded")
+		}
+	default:
+		badRequest(w, fmt.Sprintf("параметр 'embed' поддерживает только значение '%s'", embedWallets))
+		return view, false
+	}
+	return view, true
+}
+
+// embedTransactionWallets добавляет к транзакциям сведения о кошельках отправителя
+// и получателя. Кошельки всей страницы читаются одним запросом к хранилищу.
+func (a *API) embedTransactionWallets(ctx context.Context, transactions []models.Transaction) ([]embeddedTransaction, error) {
+	if len(transactions) == 0 {
+		return nil, nil
+	}
+	seen := make(map[string]bool, 2*len(transactions))
+	var addresses []string
+	for _, t := range transactions {
+		for _, address := range []string{t.From, t.To} {
+			if !seen[address] {
+				seen[address] = true
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	wallets, err := a.db.GetWalletsByAddress(ctx, addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	embed := func(address string) models.EmbeddedWallet {
+		wallet, ok := wallets[address]
+		if !ok {
+			return models.EmbeddedWallet{}
+		}
+		return models.EmbeddedWallet{
+			Exists:   true,
+			Label:    wallet.Label,
+			Frozen:   wallet.Frozen,
+			Archived: wallet.Archived,
+			System:   wallet.System,
+		}
+	}
+	items := make([]embeddedTransaction, len(transactions))
+	for i, t := range transactions {
+		items[i] = embeddedTransaction{
+			Transaction: t,
+			Embedded:    models.TransactionEmbed{From: embed(t.From), To: embed(t.To)},
+		}
+	}
+	return items, nil
+}
+
+// writeTransactionList отдаёт транзакции в виде view.
+func (a *API) writeTransactionList(w http.ResponseWriter, r *http.Request, transactions []models.Transaction, view transactionView) {
+	var (
+		body any
+		err  error
+	)
+	if view.wallets {
+		items, embedErr := a.embedTransactionWallets(r.Context(), transactions)
+		if embedErr != nil {
+			log.Printf("ошибка получения кошельков для списка транзакций: %v", embedErr)
+			writeStorageError(w, r, embedErr)
+			return
+		}
+		if items == nil && transactions != nil {
+			items = []embeddedTransaction{}
+		}
+		body, err = selectFields(items, view.fields)
+	} else {
+		body, err = selectFields(transactions, view.fields)
+	}
+	if err != nil {
+		log.Printf("ошибка выбора полей транзакций: %v", err)
+		internalError(w)
+		return
+	}
+	writeJSON(w, r, body)
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"go-payments/internal/money"
+	"go-payments/internal/storage"
+)
+
+// Машиночитаемые коды ошибок в JSON-ответах.
+const (
+	CodeInvalidRequest          = "invalid_request"
+	CodeInvalidAddress          = "invalid_address"
+	CodeInvalidCursor           = "invalid_cursor"
+	CodeAmountOutOfRange        = "amount_out_of_range"
+	CodeRequestTooLarge         = "request_too_large"
+	CodeExampleNotFound         = "example_not_found"
+	CodeWalletNotFound          = "wallet_not_found"
+	CodeSenderNotFound          = "sender_not_found"
+	CodeRecipientNotFound       = "recipient_not_found"
+	CodeInsufficientFunds       = "insufficient_funds"
+	CodeInternalError           = "internal_error"
+	CodeDeadlineExceeded        = "deadline_exceeded"
+	CodeMaintenance             = "maintenance"
+	CodeOverloaded              = "overloaded"
+	CodeWalletNotPurgeable      = "wallet_not_purgeable"
+	CodeNoteNotFound            = "note_not_found"
+	CodeLabelNotFound           = "label_not_found"
+	CodeLabelTaken              = "label_taken"
+	CodeTxNotFound              = "transaction_not_found"
+	CodeNotRecipient            = "not_recipient"
+	CodeRetriesExhausted        = "retries_exhausted"
+	CodeWalletsExist            = "wallets_exist"
+	CodeIdempotencyKeyNotFound  = "idempotency_key_not_found"
+	CodeGroupNotFound           = "group_not_found"
+	CodeIdempotencyKeyReused    = "idempotency_key_reused"
+	CodePeriodClosed            = "period_closed"
+	CodePeriodCloseBackward     = "period_close_backward"
+	CodeRecipientLimitExceeded  = "recipient_limit_exceeded"
+	CodeSystemWallet            = "system_wallet"
+	CodePaymentRequestNotFound  = "payment_request_not_found"
+	CodePaymentRequestFulfilled = "payment_request_fulfilled"
+	CodePaymentRequestExpired   = "payment_request_expired"
+	CodeIncomingCursorNotFound  = "incoming_cursor_not_found"
+	CodeThresholdNotFound       = "threshold_not_found"
+	CodeTooManyThresholds       = "too_many_thresholds"
+	CodeWithdrawalNotFound      = "withdrawal_not_found"
+	CodeWithdrawalSettled       = "withdrawal_settled"
+	CodeWithdrawalsDisabled     = "withdrawals_disabled"
+	CodeCheckpointNotFound      = "checkpoint_not_found"
+	CodeCheckpointsDisabled     = "checkpoints_disabled"
+)
+
+// errorResponse - JSON-тело ответа с ошибкой.
+type errorResponse struct {
+	Code    string `json:"code"`
+	Error   string `json:"error"`
+	Details any    `json:"details,omitempty"`
+}
+
+// errorMapping - HTTP-статус и код, которыми ошибка хранилища отдаётся клиенту.
+type errorMapping struct {
+	Status int
+	Code   string
+}
+
+// txErrorMappings - единственный источник соответствия кодов TransactionError
+// HTTP-ответам. Каждый storage.TxErrCode должен присутствовать здесь.
+var txErrorMappings = map[storage.TxErrCode]errorMapping{
+	storage.CodeUnknown:                {http.StatusInternalServerError, CodeInternalError},
+	storage.CodeSenderNotFound:         {http.StatusNotFound, CodeSenderNotFound},
+	storage.CodeRecipientNotFound:      {http.StatusNotFound, CodeRecipientNotFound},
+	storage.CodeInsufficientFunds:      {http.StatusPaymentRequired, CodeInsufficientFunds}, // 402 Payment Required - очень подходящий статус
+	storage.CodeInternalError:          {http.StatusInternalServerError, CodeInternalError},
+	storage.CodeRetriesExhausted:       {http.StatusServiceUnavailable, CodeRetriesExhausted},
+	storage.CodeRecipientLimitExceeded: {http.StatusUnprocessableEntity, CodeRecipientLimitExceeded},
+}
+
+// sentinelErrorMappings - соответствие сигнальных ошибок хранилища HTTP-ответам.
+// writeStorageError проверяет их по порядку.
+var sentinelErrorMappings = []struct {
+	Err error
+	errorMapping
+}{
+	{storage.ErrWalletNotFound, errorMapping{http.StatusNotFound, CodeWalletNotFound}},
+	{storage.ErrLabelNotFound, errorMapping{http.StatusNotFound, CodeLabelNotFound}},
+	{storage.ErrLabelTaken, errorMapping{http.StatusConflict, CodeLabelTaken}},
+	{storage.ErrTxNotFound, errorMapping{http.StatusNotFound, CodeTxNotFound}},
+	{storage.ErrNotRecipient, errorMapping{http.StatusForbidden, CodeNotRecipient}},
+	{storage.ErrNoteNotFound, errorMapping{http.StatusNotFound, CodeNoteNotFound}},
+	{storage.ErrWalletNotArchived, errorMapping{http.StatusConflict, CodeWalletNotPurgeable}},
+	{storage.ErrWalletNotEmpty, errorMapping{http.StatusConflict, CodeWalletNotPurgeable}},
+	{storage.ErrWalletReferenced, errorMapping{http.StatusConflict, CodeWalletNotPurgeable}},
+	{storage.ErrWalletsExist, errorMapping{http.StatusConflict, CodeWalletsExist}},
+	{storage.ErrGroupNotFound, errorMapping{http.StatusNotFound, CodeGroupNotFound}},
+	{storage.ErrIdempotencyKeyNotFound, errorMapping{http.StatusNotFound, CodeIdempotencyKeyNotFound}},
+	{storage.ErrIdempotencyKeyReused, errorMapping{http.StatusUnprocessableEntity, CodeIdempotencyKeyReused}},
+	{storage.ErrPeriodClosed, errorMapping{http.StatusLocked, CodePeriodClosed}},
+	{storage.ErrSystemWallet, errorMapping{http.StatusForbidden, CodeSystemWallet}},
+	{storage.ErrPeriodCloseBackward, errorMapping{http.StatusConflict, CodePeriodCloseBackward}},
+	{storage.ErrPaymentRequestNotFound, errorMapping{http.StatusNotFound, CodePaymentRequestNotFound}},
+	{storage.ErrPaymentRequestFulfilled, errorMapping{http.StatusConflict, CodePaymentRequestFulfilled}},
+	{storage.ErrPaymentRequestExpired, errorMapping{http.StatusGone, CodePaymentRequestExpired}},
+	{storage.ErrIncomingCursorNotFound, errorMapping{http.StatusNotFound, CodeIncomingCursorNotFound}},
+	{storage.ErrThresholdNotFound, errorMapping{http.StatusNotFound, CodeThresholdNotFound}},
+	{storage.ErrTooManyThresholds, errorMapping{http.StatusConflict, CodeTooManyThresholds}},
+	{storage.ErrWithdrawalNotFound, errorMapping{http.StatusNotFound, CodeWithdrawalNotFound}},
+	{storage.ErrWithdrawalSettled, errorMapping{http.StatusConflict, CodeWithdrawalSettled}},
+	{storage.ErrCheckpointNotFound, errorMapping{http.StatusNotFound, CodeCheckpointNotFound}},
+}
+
+// requestErrorMappings - коды, которые обработчики и middleware возвращают
+// без участия хранилища.
+var requestErrorMappings = []errorMapping{
+	{http.StatusBadRequest, CodeInvalidRequest},
+	{http.StatusBadRequest, CodeInvalidAddress},
+	{http.StatusBadRequest, CodeInvalidCursor},
+	{http.StatusBadRequest, CodeAmountOutOfRange},
+	{http.StatusRequestEntityTooLarge, CodeRequestTooLarge},
+	{http.StatusNotFound, CodeExampleNotFound},
+	{http.StatusNotImplemented, CodeWithdrawalsDisabled},
+	{http.StatusNotImplemented, CodeCheckpointsDisabled},
+	{http.StatusServiceUnavailable, CodeMaintenance},
+	{http.StatusServiceUnavailable, CodeOverloaded},
+	{http.StatusGatewayTimeout, CodeDeadlineExceeded},
+}
+
+// storageErrorCode возвращает код ответа, которым writeStorageError отдаст err;
+// используется для меток метрик.
+func storageErrorCode(err error) string {
+	return storageErrorMapping(err).Code
+}
+
+// storageErrorMapping возвращает HTTP-статус и код, которыми writeStorageError
+// отдаст err (без учёта истёкшего дедлайна запроса).
+func storageErrorMapping(err error) errorMapping {
+	var txErr *storage.TransactionError
+	if errors.As(err, &txErr) {
+		if mapping, ok := txErrorMappings[txErr.Code]; ok {
+			return mapping
+		}
+		return errorMapping{http.StatusInternalServerError, CodeInternalError}
+	}
+	for _, m := range sentinelErrorMappings {
+		if errors.Is(err, m.Err) {
+			return m.errorMapping
+		}
+	}
+	return errorMapping{http.StatusInternalServerError, CodeInternalError}
+}
+
+// writeError отправляет ошибку в формате JSON с указанным HTTP-статусом и кодом.
+func writeError(w http.ResponseWriter, status int, code, message string, details any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{Code: code, Error: message, Details: details})
+}
+
+// writeRequestError отправляет ошибку так же, как writeError, но суммы в details
+// выводит в формате, выбранном клиентом (amount_format), как writeJSON.
+func writeRequestError(w http.ResponseWriter, r *http.Request, status int, code, message string, details any) {
+	data, err := marshalResponse(r, errorResponse{Code: code, Error: message, Details: details})
+	if err != nil {
+		log.Printf("ошибка сериализации ответа с ошибкой: %v", err)
+		internalError(w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// badRequest отвечает 400 с кодом invalid_request.
+func badRequest(w http.ResponseWriter, message string) {
+	writeError(w, http.StatusBadRequest, CodeInvalidRequest, message, nil)
+}
+
+// internalError отвечает 500, не раскрывая клиенту деталей ошибки.
+func internalError(w http.ResponseWriter) {
+	writeError(w, http.StatusInternalServerError, CodeInternalError, "внутренняя ошибка сервера", nil)
+}
+
+// writeStorageError переводит ошибку хранилища в HTTP-ответ: истечение дедлайна,
+// *storage.TransactionError по таблице txErrorMappings, сигнальные ошибки по
+// таблице sentinelErrorMappings, а всё остальное - в 500.
+func writeStorageError(w http.ResponseWriter, r *http.Request, err error) {
+	if deadlineExceeded(w, r, err) {
+		return
+	}
+
+	var txErr *storage.TransactionError
+	if errors.As(err, &txErr) {
+		mapping, ok := txErrorMappings[txErr.Code]
+		if !ok || mapping.Status == http.StatusInternalServerError {
+			internalError(w)
+			return
+		}
+		var details any
+		switch {
+		case txErr.Attempts > 0:
+			details = map[string]int{"attempts": txErr.Attempts}
+		case txErr.Code == storage.CodeInsufficientFunds:
+			// Баланс уже прочитан при переводе. Детали получает любой клиент: в
+			// сервисе нет ключей API и владельцев кошельков, а тот же баланс и так
+			// доступен любому через /api/wallet/{address}/balance. Если появится
+			// аутентификация, скрывать детали от чужих ключей нужно здесь.
+			details = map[string]money.Amount{"balance": txErr.Balance, "shortfall": txErr.Shortfall}
+		case txErr.Code == storage.CodeRecipientLimitExceeded:
+			details = map[string]money.Amount{"balance": txErr.RecipientBalance, "max_balance": txErr.MaxBalance}
+		}
+		// Детали содержат суммы, поэтому учитывают amount_format.
+		writeRequestError(w, r, mapping.Status, mapping.Code, txErr.Error(), details)
+		return
+	}
+
+	for _, m := range sentinelErrorMappings {
+		if errors.Is(err, m.Err) {
+			writeError(w, m.Status, m.Code, err.Error(), nil)
+			return
+		}
+	}
+
+	internalError(w)
+}
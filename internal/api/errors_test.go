@@ -0,0 +1,57 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"go-payments/internal/storage"
+)
+
+// txErrorGolden - ожидаемые ответы на каждый код TransactionError. Таблица
+// записана вручную: изменение статуса или кода ответа - изменение контракта API,
+// и его нужно отразить здесь явно.
+var txErrorGolden = map[storage.TxErrCode]errorMapping{
+	storage.CodeUnknown:                {http.StatusInternalServerError, CodeInternalError},
+	storage.CodeSenderNotFound:         {http.StatusNotFound, CodeSenderNotFound},
+	storage.CodeRecipientNotFound:      {http.StatusNotFound, CodeRecipientNotFound},
+	storage.CodeInsufficientFunds:      {http.StatusPaymentRequired, CodeInsufficientFunds},
+	storage.CodeInternalError:          {http.StatusInternalServerError, CodeInternalError},
+	storage.CodeRetriesExhausted:       {http.StatusServiceUnavailable, CodeRetriesExhausted},
+	storage.CodeRecipientLimitExceeded: {http.StatusUnprocessableEntity, CodeRecipientLimitExceeded},
+}
+
+func TestTxErrorMappings(t *testing.T) {
+	if len(txErrorGolden) != int(storage.LastTxErrCode)+1 {
+		t.Errorf("в эталонной таблице %d кодов, в storage %d", len(txErrorGolden), storage.LastTxErrCode+1)
+	}
+	for code := storage.CodeUnknown; code <= storage.LastTxErrCode; code++ {
+		want, ok := txErrorGolden[code]
+		if !ok {
+			t.Errorf("код %d отсутствует в эталонной таблице", code)
+			continue
+		}
+		if got, ok := txErrorMappings[code]; !ok || got != want {
+			t.Errorf("txErrorMappings[%d] = %v (есть: %v), want %v", code, got, ok, want)
+		}
+
+		// Ответ writeStorageError и код для метрик совпадают с таблицей, в том числе
+		// для обёрнутой ошибки.
+		err := fmt.Errorf("перевод: %w", &storage.TransactionError{Code: code, OriginalErr: errors.New("исходная ошибка")})
+		if got := storageErrorMapping(err); got != want {
+			t.Errorf("storageErrorMapping(код %d) = %v, want %v", code, got, want)
+		}
+		w := serveAmountFormat(func(w http.ResponseWriter, r *http.Request) { writeStorageError(w, r, err) }, "")
+		if w.Code != want.Status {
+			t.Errorf("writeStorageError(код %d): статус %d, want %d", code, w.Code, want.Status)
+		}
+		checkErrorEnvelope(t, fmt.Sprintf("writeStorageError(код %d)", code), w, want.Code)
+	}
+
+	// Код вне перечисления - внутренняя ошибка, а не пустой ответ.
+	err := &storage.TransactionError{Code: storage.LastTxErrCode + 1}
+	if got := storageErrorMapping(err); got.Status != http.StatusInternalServerError || got.Code != CodeInternalError {
+		t.Errorf("storageErrorMapping(неизвестный код) = %v, want 500 %s", got, CodeInternalError)
+	}
+}
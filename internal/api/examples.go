@@ -0,0 +1,208 @@
+package api
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Примеры запросов и ответов маршрутов: файл examples/<имя>.json на маршрут.
+//
+//go:embed examples/*.json
+var exampleFiles embed.FS
+
+type exampleRequest struct {
+	// Path - путь с query-параметрами.
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+type exampleResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+type example struct {
+	Title    string          `json:"title"`
+	Request  exampleRequest  `json:"request"`
+	Response exampleResponse `json:"response"`
+}
+
+// routeExamples - примеры одного маршрута; Name - имя файла без расширения.
+type routeExamples struct {
+	Name     string    `json:"name"`
+	Method   string    `json:"method"`
+	Pattern  string    `json:"pattern"`
+	Summary  string    `json:"summary"`
+	Examples []example `json:"examples"`
+}
+
+// errorExample - пример ответа с кодом ошибки.
+type errorExample struct {
+	Code       string        `json:"code"`
+	HTTPStatus int           `json:"http_status"`
+	Body       errorResponse `json:"body"`
+}
+
+type examplesInfo struct {
+	Routes []routeExamples `json:"routes"`
+	Errors []errorExample  `json:"errors"`
+}
+
+// loadExamples читает примеры один раз при первом обращении.
+var loadExamples = sync.OnceValues(func() ([]routeExamples, error) {
+	names, err := exampleFiles.ReadDir("examples")
+	if err != nil {
+		return nil, err
+	}
+	var routes []routeExamples
+	for _, entry := range names {
+		data, err := exampleFiles.ReadFile(path.Join("examples", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		route := routeExamples{Name: strings.TrimSuffix(entry.Name(), ".json")}
+		if err := json.Unmarshal(data, &route); err != nil {
+			return nil, fmt.Errorf("примеры %s: %w", entry.Name(), err)
+		}
+		if err := route.validate(); err != nil {
+			return nil, fmt.Errorf("примеры %s: %w", entry.Name(), err)
+		}
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Name < routes[j].Name })
+	return routes, nil
+})
+
+// validate проверяет, что у маршрута есть примеры, а ответы с ошибкой содержат
+// известный код.
+func (r *routeExamples) validate() error {
+	if r.Method == "" || !strings.HasPrefix(r.Pattern, "/") {
+		return fmt.Errorf("не указаны метод или шаблон маршрута")
+	}
+	if len(r.Examples) == 0 {
+		return fmt.Errorf("нет ни одного примера")
+	}
+	for _, e := range r.Examples {
+		if e.Response.Status < 100 {
+			return fmt.Errorf("пример %q: не указан статус ответа", e.Title)
+		}
+		if e.Response.Status < http.StatusBadRequest {
+			continue
+		}
+		var body errorResponse
+		if err := json.Unmarshal(e.Response.Body, &body); err != nil {
+			return fmt.Errorf("пример %q: тело ответа с ошибкой: %w", e.Title, err)
+		}
+		if _, ok := errorDescriptions[body.Code]; !ok {
+			return fmt.Errorf("пример %q: неизвестный код ошибки %q", e.Title, body.Code)
+		}
+	}
+	return nil
+}
+
+// errorExamples строит пример ответа для каждого кода из errorCatalog.
+func errorExamples() []errorExample {
+	catalog := errorCatalog()
+	examples := make([]errorExample, len(catalog))
+	for i, info := range catalog {
+		examples[i] = errorExample{
+			Code:       info.Code,
+			HTTPStatus: info.HTTPStatus,
+			Body:       errorResponse{Code: info.Code, Error: info.Description},
+		}
+	}
+	return examples
+}
+
+// CheckExamples проверяет примеры маршрутов по итоговому дереву маршрутов:
+// у каждого маршрута, кроме внутренних (internalOnlyPrefixes), должен быть
+// пример, и каждый пример должен относиться к зарегистрированному маршруту.
+func CheckExamples(routes chi.Routes) error {
+	examples, err := loadExamples()
+	if err != nil {
+		return err
+	}
+	documented := make(map[string]bool, len(examples))
+	for _, e := range examples {
+		documented[e.Method+" "+e.Pattern] = true
+	}
+
+	registered := make(map[string]bool)
+	var missing []string
+	err = chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		for _, prefix := range internalOnlyPrefixes {
+			if route == prefix || strings.HasPrefix(route, prefix+"/") {
+				return nil
+			}
+		}
+		key := method + " " + route
+		registered[key] = true
+		if !documented[key] {
+			missing = append(missing, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("не удалось обойти маршруты: %w", err)
+	}
+	var stale []string
+	for _, e := range examples {
+		if key := e.Method + " " + e.Pattern; !registered[key] {
+			stale = append(stale, e.Name+" ("+key+")")
+		}
+	}
+
+	var problems []string
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		problems = append(problems, "нет примеров для маршрутов: "+strings.Join(missing, ", "))
+	}
+	if len(stale) > 0 {
+		problems = append(problems, "примеры для незарегистрированных маршрутов: "+strings.Join(stale, ", "))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// GetExamples отдаёт примеры запросов и ответов всех маршрутов и пример ответа
+// для каждого кода ошибки.
+func (a *API) GetExamples(w http.ResponseWriter, r *http.Request) {
+	routes, err := loadExamples()
+	if err != nil {
+		log.Printf("ошибка чтения примеров маршрутов: %v", err)
+		internalError(w)
+		return
+	}
+	writeJSON(w, r, examplesInfo{Routes: routes, Errors: errorExamples()})
+}
+
+// GetRouteExamples отдаёт примеры маршрута {name} или 404 example_not_found.
+func (a *API) GetRouteExamples(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	routes, err := loadExamples()
+	if err != nil {
+		log.Printf("ошибка чтения примеров маршрутов: %v", err)
+		internalError(w)
+		return
+	}
+	for _, route := range routes {
+		if route.Name == name {
+			writeJSON(w, r, route)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, CodeExampleNotFound, fmt.Sprintf("примеры маршрута '%s' не найдены", name), nil)
+}
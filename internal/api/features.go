@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"go-payments/internal/models"
+)
+
+// Драйвер хранилища: PostgreSQL - единственная реализация.
+const storageDriver = "postgres"
+
+type featureReport struct {
+	Features      map[string]bool   `json:"features"`
+	Storage       string            `json:"storage"`
+	SchemaVersion int               `json:"schema_version"`
+	Pool          models.PoolStats  `json:"pool"`
+	Listeners     map[string]string `json:"listeners"`
+}
+
+// features собирает отчёт о включённых функциях. Режим обслуживания отражает
+// действующее состояние, а не только значение MAINTENANCE_MODE.
+func (a *API) features(ctx context.Context) (*featureReport, error) {
+	version, err := a.db.SchemaVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &featureReport{
+		Features:      a.cfg.Features(),
+		Storage:       storageDriver,
+		SchemaVersion: version,
+		Pool:          a.db.PoolStats(),
+		Listeners:     map[string]string{"public": a.cfg.HTTP.PublicAddr},
+	}
+	report.Features["maintenance_mode"] = a.maintenance.Load()
+	if a.cfg.HTTP.SeparateInternal {
+		report.Listeners["internal"] = a.cfg.HTTP.InternalAddr
+	}
+	return report, nil
+}
+
+// LogFeatures пишет отчёт о включённых функциях в лог одной записью JSON.
+func (a *API) LogFeatures(ctx context.Context) error {
+	report, err := a.features(ctx)
+	if err != nil {
+		return fmt.Errorf("не удалось собрать отчёт о функциях: %w", err)
+	}
+	b, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать отчёт о функциях: %w", err)
+	}
+	log.Printf("функции экземпляра: %s", b)
+	return nil
+}
+
+func (a *API) GetFeatures(w http.ResponseWriter, r *http.Request) {
+	report, err := a.features(r.Context())
+	if err != nil {
+		log.Printf("ошибка получения отчёта о функциях: %v", err)
+		writeStorageError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, report)
+}
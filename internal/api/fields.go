@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"go-payments/internal/models"
+)
+
+// Поля транзакции, которые можно запросить параметром fields, - JSON-имена
+// полей models.Transaction.
+var transactionFields = jsonFieldNames(reflect.TypeOf(models.Transaction{}))
+
+// jsonFieldNames возвращает JSON-имена экспортируемых полей структуры t.
+func jsonFieldNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// parseFields разбирает параметр fields - список полей через запятую. Без
+// параметра возвращает nil, что означает все поля.
+func parseFields(r *http.Request, allowed []string) ([]string, error) {
+	v := r.URL.Query().Get("fields")
+	if v == "" {
+		return nil, nil
+	}
+
+	var fields, unknown []string
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(allowed, name) {
+			unknown = append(unknown, name)
+			continue
+		}
+		if !slices.Contains(fields, name) {
+			fields = append(fields, name)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("неизвестные поля в параметре 'fields': %s (допустимы: %s)",
+			strings.Join(unknown, ", "), strings.Join(allowed, ", "))
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("параметр 'fields' не содержит полей")
+	}
+	return fields, nil
+}
+
+// selectFields оставляет в каждом элементе списка items только поля fields.
+// Поля, опущенные при сериализации (omitempty), остаются опущенными. Значения
+// полей сохраняют свои типы, чтобы writeJSON мог найти среди них суммы.
+func selectFields[T any](items []T, fields []string) (any, error) {
+	if fields == nil || items == nil {
+		return items, nil
+	}
+
+	objects := make([]map[string]any, len(items))
+	for i, item := range items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		var present map[string]json.RawMessage
+		if err := json.Unmarshal(data, &present); err != nil {
+			return nil, err
+		}
+		v := reflect.ValueOf(item)
+		for v.Kind() == reflect.Pointer {
+			v = v.Elem()
+		}
+		values := jsonFields(v)
+		obj := make(map[string]any, len(fields))
+		for _, key := range fields {
+			if _, ok := present[key]; !ok {
+				continue
+			}
+			if field, ok := values[key]; ok {
+				obj[key] = field.Interface()
+			} else {
+				obj[key] = present[key]
+			}
+		}
+		objects[i] = obj
+	}
+	return objects, nil
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"go-payments/internal/models"
+)
+
+// Лимиты находок каждой проверки в /api/admin/fsck. Полную проверку без лимита
+// выполняет `payments -fsck`.
+const (
+	defaultFsckLimit = 100
+	maxFsckLimit     = 1000
+)
+
+// fsckResult - итоговая строка потокового ответа Fsck.
+type fsckResult struct {
+	models.FsckSummary
+	Done  bool   `json:"done"`
+	Error string `json:"error,omitempty"`
+}
+
+// Fsck проверяет согласованность данных и передаёт находки потоком NDJSON:
+// строка на каждую находку и итоговая строка с количеством ошибок и предупреждений.
+func (a *API) Fsck(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePagination(r, pagination{Limit: defaultFsckLimit}, maxFsckLimit)
+	if err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+
+	summary, err := a.db.Fsck(r.Context(), page.Limit, func(finding models.FsckFinding) error {
+		if err := enc.Encode(finding); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+
+	result := fsckResult{FsckSummary: summary, Done: err == nil}
+	if err != nil {
+		log.Printf("проверка целостности прервана: %v", err)
+		result.Error = "проверка целостности прервана"
+	} else {
+		log.Printf("проверка целостности: ошибок %d, предупреждений %d", summary.Errors, summary.Warnings)
+	}
+	enc.Encode(result)
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-payments/internal/models"
+	"go-payments/internal/money"
+	"go-payments/internal/storage"
+)
+
+var update = flag.Bool("update", false, "перезаписать эталонные ответы в testdata/golden")
+
+// goldenCase - ответ, формат которого закреплён эталонным файлом
+// testdata/golden/<name>.golden: первая строка - HTTP-статус, дальше тело как есть.
+type goldenCase struct {
+	name string
+	// query - параметры запроса, например amount_format=number.
+	query string
+	write func(a *API, w http.ResponseWriter, r *http.Request)
+}
+
+// goldenWallet и goldenTransaction - канонические экземпляры со всеми полями.
+func goldenWallet() models.Wallet {
+	maxBalance, pendingOut := money.Amount(5000), money.Amount(0.5)
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 600000000, time.UTC)
+	return models.Wallet{
+		Address:       testAddress(1),
+		Balance:       1234.56789012,
+		Frozen:        true,
+		Archived:      true,
+		Label:         "treasury",
+		MaxBalance:    &maxBalance,
+		System:        true,
+		NonReceivable: true,
+		AutoCreated:   true,
+		CreatedAt:     &createdAt,
+		PendingOut:    &pendingOut,
+	}
+}
+
+func goldenTransaction() models.Transaction {
+	acknowledgedAt := time.Date(2026, 1, 2, 3, 5, 0, 0, time.UTC)
+	return models.Transaction{
+		PublicID:       "01ARZ3NDEKTSV4RRFFQ69G5FAV",
+		ID:             42,
+		From:           testAddress(1),
+		To:             testAddress(2),
+		Amount:         0.00000001,
+		Timestamp:      time.Date(2026, 1, 2, 3, 4, 5, 123456000, time.UTC),
+		Status:         models.StatusSuccess,
+		Memo:           "оплата по счёту",
+		Reference:      "INV-1",
+		Metadata:       map[string]string{"order": "7", "channel": "api"},
+		AcknowledgedAt: &acknowledgedAt,
+		GroupID:        "batch-1",
+	}
+}
+
+func writeValue(v any) func(a *API, w http.ResponseWriter, r *http.Request) {
+	return func(a *API, w http.ResponseWriter, r *http.Request) { writeJSON(w, r, v) }
+}
+
+func writeStorage(err error) func(a *API, w http.ResponseWriter, r *http.Request) {
+	return func(a *API, w http.ResponseWriter, r *http.Request) { writeStorageError(w, r, err) }
+}
+
+func goldenCases() []goldenCase {
+	transaction := goldenTransaction()
+	minimal := models.Transaction{PublicID: transaction.PublicID, ID: 1, From: testAddress(1), To: testAddress(2),
+		Amount: 10, Timestamp: transaction.Timestamp, Status: models.StatusFailedInsufficientFunds}
+	page := models.WalletPage{
+		Wallets: []models.Wallet{goldenWallet(), {Address: testAddress(2)}},
+		Total:   12, Limit: 2, Offset: 4, Skipped: 1,
+	}
+
+	cases := []goldenCase{
+		{name: "wallet", write: writeValue(goldenWallet())},
+		{name: "wallet_number", query: "amount_format=number", write: writeValue(goldenWallet())},
+		{name: "wallet_minimal", write: writeValue(models.Wallet{Address: testAddress(1)})},
+		{name: "transaction", write: writeValue(transaction)},
+		{name: "transaction_number", query: "amount_format=number", write: writeValue(transaction)},
+		{name: "transaction_minimal", write: writeValue(minimal)},
+		{name: "send_response", write: writeValue(models.SendResponse{Status: "success", Transaction: &transaction})},
+		{name: "transactions_page", write: writeValue([]models.Transaction{transaction, minimal})},
+		{name: "transactions_page_empty", write: writeValue([]models.Transaction{})},
+		{name: "wallet_page", write: writeValue(page)},
+		{name: "wallet_page_number", query: "amount_format=number", write: writeValue(page)},
+		{name: "wallet_page_empty", write: writeValue(models.WalletPage{Wallets: []models.Wallet{}, Limit: 10})},
+
+		// Ошибки с деталями.
+		{name: "error_insufficient_funds", write: writeStorage(&storage.TransactionError{
+			Code: storage.CodeInsufficientFunds, OriginalErr: storage.ErrInsufficientFunds, Balance: 1.5, Shortfall: 0.25})},
+		{name: "error_insufficient_funds_number", query: "amount_format=number", write: writeStorage(&storage.TransactionError{
+			Code: storage.CodeInsufficientFunds, OriginalErr: storage.ErrInsufficientFunds, Balance: 1.5, Shortfall: 0.25})},
+		{name: "error_recipient_limit_exceeded", write: writeStorage(&storage.TransactionError{
+			Code: storage.CodeRecipientLimitExceeded, OriginalErr: storage.ErrRecipientLimitExceeded, RecipientBalance: 90, MaxBalance: 100})},
+		{name: "error_retries_exhausted", write: writeStorage(&storage.TransactionError{
+			Code: storage.CodeRetriesExhausted, Attempts: 3})},
+		{name: "error_deadline_exceeded", write: writeStorage(fmt.Errorf("ожидание блокировки: %w", context.DeadlineExceeded))},
+		{name: "error_amount_out_of_range", write: func(a *API, w http.ResponseWriter, r *http.Request) {
+			a.amountOutOfRange(w, "amount")
+		}},
+		{name: "error_request_too_large", write: func(a *API, w http.ResponseWriter, r *http.Request) {
+			bodyTooLarge(w, 1<<20)
+		}},
+		{name: "error_invalid_cursor", query: "cursor=garbage", write: func(a *API, w http.ResponseWriter, r *http.Request) {
+			a.parseCursor(w, r)
+		}},
+	}
+
+	// Конверт каждого кода ошибки без деталей.
+	for _, info := range errorCatalog() {
+		cases = append(cases, goldenCase{
+			name: fmt.Sprintf("error_%d_%s", info.HTTPStatus, info.Code),
+			write: func(a *API, w http.ResponseWriter, r *http.Request) {
+				writeError(w, info.HTTPStatus, info.Code, info.Description, nil)
+			},
+		})
+	}
+	return cases
+}
+
+func TestWireFormatGolden(t *testing.T) {
+	a, _ := newTestRouter(&fakeStorage{}, testConfig())
+	seen := map[string]bool{}
+	for _, tt := range goldenCases() {
+		if seen[tt.name] {
+			t.Fatalf("эталон %s повторяется", tt.name)
+		}
+		seen[tt.name] = true
+
+		w := serveAmountFormat(func(w http.ResponseWriter, r *http.Request) { tt.write(a, w, r) }, tt.query)
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s: Content-Type %q, want application/json", tt.name, ct)
+		}
+		got := append([]byte(fmt.Sprintf("%d\n", w.Code)), w.Body.Bytes()...)
+
+		path := filepath.Join("testdata", "golden", tt.name+".golden")
+		if *update {
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, got, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Errorf("%s: %v (эталоны создаёт go test -run TestWireFormatGolden -update)", tt.name, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: ответ отличается от %s (если изменение намеренное, go test -run TestWireFormatGolden -update):\n%s",
+				tt.name, path, goldenDiff(want, got))
+		}
+	}
+
+	// Файл эталона без случая остаётся от удалённого ответа.
+	files, _ := filepath.Glob(filepath.Join("testdata", "golden", "*.golden"))
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".golden")
+		if !seen[name] {
+			if *update {
+				os.Remove(file)
+				continue
+			}
+			t.Errorf("эталон %s без случая в goldenCases", file)
+		}
+	}
+}
+
+// goldenDiff показывает первое расхождение эталона want и ответа got: строку и
+// окрестность первого отличающегося байта.
+func goldenDiff(want, got []byte) string {
+	wantLines, gotLines := strings.Split(string(want), "\n"), strings.Split(string(got), "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w == g {
+			continue
+		}
+		at := 0
+		for at < len(w) && at < len(g) && w[at] == g[at] {
+			at++
+		}
+		from := max(at-40, 0)
+		return fmt.Sprintf("строка %d, байт %d:\n  want: ...%s\n  got:  ...%s", i+1, at+1, w[from:min(at+60, len(w))], g[from:min(at+60, len(g))])
+	}
+	return "отличий в строках нет"
+}
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"go-payments/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (a *API) GetGroupSummary(w http.ResponseWriter, r *http.Request) {
+	groupID := chi.URLParam(r, "group_id")
+
+	summary, err := a.db.GetGroupSummary(r.Context(), groupID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrGroupNotFound) {
+			log.Printf("ошибка получения итогов группы переводов %q: %v", groupID, err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, summary)
+}
@@ -11,122 +11,650 @@ Key components:
     и реализующая методы-обработчики HTTP-запросов.
   - New: Конструктор для создания нового экземпляра API.
   - RegisterRoutes: Метод для регистрации всех маршрутов API с использованием роутера chi.
+  - RegisterPublicRoutes, RegisterInternalRoutes: Регистрируют публичные и внутренние
+    (`/api/admin`, `/readyz`) маршруты на разных роутерах, когда сервис слушает два адреса.
+  - NewRouteRecorder, CheckPublicRoutes: Проверяют маршруты при запуске: RouteRecorder
+    находит повторную регистрацию метода и шаблона, CheckPublicRoutes - внутренние маршруты
+    на публичном слушателе. CheckExamples проверяет, что у каждого публичного маршрута есть
+    примеры и что примеры не ссылаются на незарегистрированные маршруты.
 
 Handlers:
   - Send: Обрабатывает POST-запросы на `/api/send` для перевода средств между кошельками.
-    Принимает JSON-тело с адресами отправителя и получателя и суммой перевода.
+    Принимает JSON-тело с адресами (или метками `from_label`/`to_label`) отправителя и получателя, суммой перевода и
+    необязательными полями memo, reference, metadata (плоский словарь строк) и group_id.
+    Возвращает записанную транзакцию.
     Выполняет валидацию и возвращает соответствующие HTTP-статусы.
+    Заголовки `X-Retry-Attempts` и `X-Storage-Elapsed-Ms` сообщают, сколько раз перевод
+    повторялся из-за конфликтов и сколько времени он занял в хранилище.
+    С заголовком `Idempotency-Key` повтор запроса возвращает уже записанную транзакцию
+    с заголовком `Idempotent-Replayed: true`. При SANDBOX_MODE переводы на магические
+    адреса песочницы завершаются их исходом до обращения к хранилищу.
+    С AUTO_CREATE_RECIPIENTS перевод на неизвестный адрес создаёт кошелёк получателя.
+    Сумма больше MAX_AMOUNT, меньше 0.00000001 или не представимая числом - 400
+    amount_out_of_range.
   - GetLast: Обрабатывает GET-запросы на `/api/transactions` для получения списка
-    последних транзакций. Поддерживает необязательный query-параметр `count` для
-    указания количества запрашиваемых транзакций.
+    последних транзакций. Поддерживает необязательный query-параметр `count` (или `limit`) для
+    указания количества запрашиваемых транзакций, фильтры по метаданным вида
+    `metadata.<ключ>=<значение>`, по паре адресов `between=<адрес1>,<адрес2>` (в обоих
+    направлениях), по статусу `status`, группе переводов `group_id` и периоду
+    `since`/`until` (RFC 3339). Заметки `zero_note` показываются с `include_notes=true`
+    или с `status=zero_note`.
+    Транзакции упорядочены по (timestamp, id) по убыванию; курсор следующей страницы
+    возвращается в заголовке `X-Next-Cursor` и передаётся обратно в параметре `cursor`.
+    Параметр `fields` оставляет в ответе только перечисленные поля транзакций, а
+    `embed=wallets` добавляет раздел `embedded` с меткой и флагами кошельков сторон
+    (одним запросом на страницу); оба поддерживают также GetIncoming и ListTransactions.
+  - GetTransaction: Обрабатывает GET-запросы на `/api/transactions/{id}` и возвращает
+    транзакцию с сильным ETag; при совпадении If-None-Match отвечает 304. Неизменяемые
+    транзакции (см. models.Transaction.Immutable) отдаются с Cache-Control: public, max-age.
+    Здесь и в остальных маршрутах с `{id}` транзакции `{id}` - публичный `public_id` (ULID)
+    или, на переходный период, порядковый номер.
+  - GetGroupSummary: Обрабатывает GET-запросы на `/api/transactions/groups/{group_id}` и
+    возвращает итоги группы переводов: количество, сумму успешных переводов и
+    количество по статусам.
   - GetBalance: Обрабатывает GET-запросы на `/api/wallet/{address}/balance` для
-    получения текущего баланса кошелька по его адресу.
+    получения текущего баланса кошелька по его адресу. Сумма незавершённых выводов
+    средств, уже списанная с баланса, возвращается в `pending_out`.
+  - GetStatement: Обрабатывает GET-запросы на `/api/wallet/{address}/statement` для получения
+    месячной выписки (`month=YYYY-MM`): баланс на начало и конец месяца, транзакции с
+    нарастающим балансом и итоги. При `Accept: text/csv` возвращает те же данные в CSV.
+  - GetWalletActivity: Обрабатывает GET-запросы на `/api/wallet/{address}/activity` и
+    возвращает для панели администратора матрицы 7x24 количества и суммы успешных переводов
+    кошелька по дням недели и часам суток за период `since`/`until` (по умолчанию 30 дней,
+    не больше 366) в часовом поясе `tz` (IANA, по умолчанию UTC). Неизвестный пояс - 400.
+  - GetIncoming: Обрабатывает GET-запросы на `/api/wallet/{address}/incoming` для получения
+    успешных входящих переводов кошелька; при `unacknowledged=true` - только ещё не
+    подтверждённых. Пагинация такая же, как у GetLast (`limit` и курсор `cursor`).
+    С `after_id=N` или `consumer=<имя>` (после сохранённого курсора потребителя) возвращает
+    переводы с id больше N по возрастанию id.
+  - AcknowledgeIncoming: Обрабатывает POST-запросы на `/api/wallet/{address}/incoming/ack`
+    и подтверждает переводы кошелька списком `ids` (результат по каждому) или все до
+    `through_id` включительно. Повтор безопасен.
+  - GetIncomingCursor, SetIncomingCursor: Обрабатывают GET- и PUT-запросы на
+    `/api/wallet/{address}/incoming/cursors/{consumer}` для чтения и сдвига курсора
+    «обработано до транзакции N» потребителя; курсор только растёт.
+  - AcknowledgeTransaction: Обрабатывает POST-запросы на `/api/transactions/{id}/ack` и
+    отмечает перевод обработанным. Тело содержит адрес получателя (`address`); повторное
+    подтверждение отвечает 200, подтверждение чужого перевода - 403.
+  - CreatePaymentRequest: Обрабатывает POST-запросы на `/api/payment-requests` и создаёт запрос
+    на оплату кошельку (`to`, `amount`, необязательные `reference` и `expires_at`, по умолчанию
+    через PAYMENT_REQUEST_TTL). Возвращает 201 с непрозрачным токеном запроса.
+  - GetPaymentRequest: Обрабатывает GET-запросы на `/api/payment-requests/{token}` и возвращает
+    запрос на оплату для страницы плательщика; после срока статус - `expired`.
+  - PayPaymentRequest: Обрабатывает POST-запросы на `/api/payment-requests/{token}/pay` с телом
+    `{"from": ...}`, выполняет перевод тем же путём, что и Send (с ключом идемпотентности
+    `payment-request:<token>`), и отмечает запрос оплаченным со ссылкой на транзакцию.
+    Оплаченный запрос - 409 `payment_request_fulfilled`, истёкший - 410 `payment_request_expired`.
+  - CreateWithdrawal: Обрабатывает POST-запросы на `/api/wallet/{address}/withdraw` (`amount`,
+    необязательный `reference`, заголовок `Idempotency-Key`): списывает сумму переводом на
+    клиринговый кошелёк WITHDRAWAL_WALLET и создаёт вывод средств в состоянии `pending`.
+    Без WITHDRAWAL_WALLET - 501 `withdrawals_disabled`.
+  - GetWithdrawal, GetWalletWithdrawals: Обрабатывают GET-запросы на `/api/withdrawals/{id}` и
+    `/api/wallet/{address}/withdrawals` (фильтр `status`, `limit`) и возвращают выводы средств.
+  - ConfirmWithdrawal, FailWithdrawal: Обрабатывают POST-запросы на
+    `/api/admin/withdrawals/{id}/confirm` и `/api/admin/withdrawals/{id}/fail` (`reason`) и
+    завершают вывод в состоянии `pending` или `review`: подтверждение оставляет сумму на
+    клиринговом кошельке, отказ возвращает её на кошелёк обратной транзакцией. Повтор того
+    же перехода возвращает вывод, противоположный переход - 409 `withdrawal_settled`.
+    Отказ от вывода, списание которого в закрытом учётном периоде, - 423 `period_closed`.
+  - ListWithdrawals: Обрабатывает GET-запросы на `/api/admin/withdrawals` - выводы средств всех
+    кошельков с фильтрами `status` (например, очередь разбора `review`) и `wallet`.
+  - GetCheckpoint: Обрабатывает GET-запросы на `/api/admin/checkpoints/{date}` и возвращает
+    подписанную Ed25519 контрольную точку балансов за дату (корень дерева Меркла над
+    балансами всех кошельков), а с `include_balances=true` - и сами балансы для
+    пересчёта корня аудитором. Без CHECKPOINT_SIGNING_KEY - 501 `checkpoints_disabled`.
+  - GetWalletProof: Обрабатывает GET-запросы на `/api/wallet/{address}/proof` и возвращает
+    доказательство включения баланса кошелька в контрольную точку за `date` (по умолчанию
+    последнюю), которое проверяется без доступа к базе (пакет checkpoint).
+  - RecordZeroNote: Обрабатывает POST-запросы на `/api/admin/zero-notes` (`from`, `to`,
+    `amount` ровно 0, обязательный `memo`, необязательные `reference` и `metadata`) и
+    записывает нулевую транзакцию-заметку `zero_note`: балансы не меняются, в объёме
+    `/api/stats/volume` и итогах групп и выписок заметка не учитывается. Нулевая сумма
+    в `/api/send` по-прежнему - 400.
+  - GetWalletByLabel: Обрабатывает GET-запросы на `/api/wallets/by-label/{label}` для
+    поиска кошелька по уникальной метке.
+  - SetWalletLabel: Обрабатывает PUT-запросы на `/api/admin/wallet/{address}/label` для
+    назначения или снятия метки кошелька. Занятая метка - 409.
+  - SetWalletMaxBalance: Обрабатывает PUT-запросы на `/api/admin/wallet/{address}/max-balance`
+    для назначения или снятия (null) предельного баланса кошелька.
+  - SetWalletSystem: Обрабатывает PUT-запросы на `/api/admin/wallet/{address}/system` и
+    отмечает кошелёк системным: переводы через `/api/send` с него запрещены, а с
+    `non_receivable` - и на него (403 system_wallet); публичный список его не показывает.
   - GetWallets: Обрабатывает GET-запросы на `/api/wallets` для получения списка кошельков с балансом.
-    Поддерживает необязательный query-параметр `count` для указания количества запрашиваемых кошельков.
+    Поддерживает необязательный query-параметр `count` (или `limit`) для указания количества запрашиваемых кошельков.
+  - ListWallets: Обрабатывает GET-запросы на `/api/admin/wallets` для административного списка
+    кошельков. Поддерживает фильтры `frozen`, `include_archived`, `min_balance`, `max_balance`,
+    сортировку `sort` (address, balance) и `order` (asc, desc), а также пагинацию `limit`/`offset`.
+    Возвращает страницу кошельков вместе с общим количеством.
+  - ListTransactions: Обрабатывает GET-запросы на `/api/admin/transactions` - список транзакций
+    с фильтрами GetLast и дополнительным фильтром по тегу `tag`.
+  - AddTransactionTag, RemoveTransactionTag: Обрабатывают POST и DELETE запросы на
+    `/api/admin/transactions/{id}/tags/{tag}` для назначения и снятия тега транзакции.
+    Теги приводятся к нижнему регистру (до 32 символов); обе операции идемпотентны,
+    изменения пишутся в лог с автором из заголовка X-Actor.
+  - ListTransactionTags: Обрабатывает GET-запросы на `/api/admin/tags` и возвращает теги
+    с количеством транзакций для автодополнения.
+  - GetVolume: Обрабатывает GET-запросы на `/api/stats/volume` для получения временного ряда
+    объёма транзакций. Поддерживает параметры `since`, `until` (RFC 3339), `bucket` (hour, day)
+    и `status`. Пустые интервалы возвращаются с нулевыми значениями.
+  - GetErrorCodes, GetTransactionStatuses: Обрабатывают GET-запросы на `/api/meta/error-codes`
+    и `/api/meta/transaction-statuses` и перечисляют все коды ошибок и статусы транзакций
+    с HTTP-статусами и описаниями. Списки строятся из тех же таблиц, по которым отвечают
+    обработчики, поэтому не расходятся с ними.
+  - GetAddressScheme: Обрабатывает GET-запросы на `/api/meta/address-scheme` и сообщает схему
+    адресов новых кошельков (ADDRESS_SCHEME) и схемы, которые принимаются при поиске.
+  - GetSandbox: Обрабатывает GET-запросы на `/api/meta/sandbox` и перечисляет магические адреса
+    песочницы с исходами, HTTP-статусами и кодами ошибок; `enabled` сообщает, включён ли
+    SANDBOX_MODE.
+  - GetExamples, GetRouteExamples: Обрабатывают GET-запросы на `/api/meta/examples` и
+    `/api/meta/examples/{name}` и отдают встроенные примеры запросов и ответов маршрутов
+    (internal/api/examples) и пример ответа для каждого кода ошибки.
+  - GetWalletStats: Обрабатывает GET-запросы на `/api/stats/wallets` и возвращает сводку по
+    неархивным кошелькам (количество, общий баланс, распределение балансов по порядкам
+    величины) из периодически обновляемого представления с временем `stale_as_of`.
+  - RefreshWalletStats: Обрабатывает POST-запросы на `/api/admin/stats/refresh` и
+    пересчитывает сводку по кошелькам немедленно.
+  - GetMaintenance, SetMaintenance: Обрабатывают GET и POST запросы на `/api/admin/maintenance`
+    для чтения и переключения режима обслуживания. В этом режиме изменяющие маршруты
+    отвечают 503 с кодом `maintenance` и заголовком Retry-After до обращения к базе данных.
+  - GetConfig: Обрабатывает GET-запросы на `/api/admin/config` и возвращает действующую
+    конфигурацию, в которой секреты (пароли, DSN, ключи) скрыты по тегам полей.
+  - GetFeatures: Обрабатывает GET-запросы на `/api/admin/features` и возвращает отчёт
+    о включённых функциях (по тегам feature конфигурации), хранилище, версию схемы,
+    пул подключений и адреса слушателей. Тот же отчёт пишется в лог при запуске.
+  - GetPeriodClose, ClosePeriod: Обрабатывают GET- и PUT-запросы на `/api/admin/period-close`
+    для просмотра и переноса закрытия учётного периода. Транзакции до даты закрытия нельзя
+    подтверждать и тегировать (423 `period_closed`); перенос назад требует `force` и пишется
+    в лог с автором из заголовка X-Actor.
+  - GetStorageInfo: Обрабатывает GET-запросы на `/api/admin/storage-info` и возвращает размер
+    базы данных, размеры таблиц и их индексов и оценку количества строк.
+  - GetSchema: Обрабатывает GET-запросы на `/api/admin/schema`, сверяет фактические таблицы,
+    колонки и индексы с ожидаемыми по миграциям и возвращает расхождения.
+  - Fsck: Обрабатывает GET-запросы на `/api/admin/fsck`, проверяет согласованность данных
+    (отрицательные балансы, транзакции с несуществующими кошельками и т. п.) и передаёт
+    находки потоком NDJSON; количество находок каждой проверки ограничено `limit`.
+  - PurgeWallet: Обрабатывает POST-запросы на `/api/admin/wallet/{address}/purge` для
+    безвозвратного удаления архивного кошелька с нулевым балансом. История транзакций
+    сохраняется; если кошелёк не в архиве, его баланс не нулевой или на него ссылаются
+    незавершённые выводы средств и открытые запросы на оплату, возвращает 409.
+  - AddWalletNote, ListWalletNotes: Обрабатывают POST и GET запросы на
+    `/api/admin/wallet/{address}/notes` для служебных заметок поддержки к кошельку.
+    Заметки не редактируются и не удаляются; публичные эндпоинты их не отдают.
+  - RedactWalletNote: Обрабатывает POST-запросы на `/api/admin/wallet/{address}/notes/{id}/redact`
+    и стирает текст заметки, сохраняя саму запись.
+  - ListBalanceThresholds, CreateBalanceThreshold: Обрабатывают GET и POST запросы на
+    `/api/admin/wallet/{address}/thresholds` для правил порога баланса кошелька
+    (`direction` above или below, `amount`, `hysteresis`, `target` - URL вебхука или alerter).
+  - GetBalanceThreshold, UpdateBalanceThreshold, DeleteBalanceThreshold: Обрабатывают GET,
+    PUT и DELETE запросы на `/api/admin/wallet/{address}/thresholds/{id}`. Правило срабатывает
+    один раз при переходе баланса через порог (см. пакет thresholds).
+  - GetAlertRules, SetAlertRules: Обрабатывают GET и PUT запросы на `/api/admin/alert-rules`
+    для чтения и замены правил предупреждений о падении балансов (порог в процентах,
+    окно, переопределения для кошельков, cooldown). Правила хранятся в таблице settings.
+  - Sweep: Обрабатывает POST-запросы на `/api/admin/sweep` для консолидации балансов
+    небольших кошельков на одном целевом кошельке обычными переводами. Возвращает
+    количество перенесённых кошельков, общую сумму и ошибки по отдельным кошелькам.
+  - SeedWallets: Обрабатывает POST-запросы на `/api/admin/seed` для массового создания
+    кошельков (не больше 100000 за запрос). Прогресс передаётся потоком NDJSON после
+    каждой пачки; при отмене запроса последняя строка сообщает, сколько кошельков создано.
+  - GetSnapshot: Обрабатывает GET-запросы на `/api/admin/snapshot` и потоком отдаёт
+    версионированный снимок кошельков (адреса, балансы, метки, флаги) без истории транзакций.
+  - RestoreSnapshot: Обрабатывает POST-запросы на `/api/admin/restore` и восстанавливает
+    кошельки из снимка одной транзакцией. Снимок другой версии отклоняется с 400,
+    непустая база кошельков - с 409, а снимок больше MAX_RESTORE_BYTES - с 413.
+  - GetIdempotencyKey, DeleteIdempotencyKey: Обрабатывают GET и DELETE запросы на
+    `/api/admin/idempotency-keys/{key}` для просмотра ключа идемпотентности и его удаления;
+    удаление пишется в лог с автором из заголовка X-Actor.
+  - Readyz: Обрабатывает GET-запросы на `/readyz` для проверки готовности сервиса.
+    Расхождение схемы с миграциями возвращается предупреждением `schema_drift` в поле `warnings`.
+
+Каждый маршрут объявляет свои query-параметры через params. В строгом режиме
+(заголовок `X-Strict-Params: true` или STRICT_PARAMS=true) неизвестные параметры
+отклоняются с кодом 400 и подсказками по расстоянию редактирования.
+
+Адреса кошельков в пути ({address}), в теле запросов и в параметре `between` проверяются
+и приводятся к каноническому виду (validAddress, normalizeAddress). Принимаются адреса любой
+известной схемы (hex64, uuidv4), поэтому смена ADDRESS_SCHEME не ломает старые кошельки;
+некорректный адрес отклоняется с кодом 400 `invalid_address`.
+
+Переводы (`/api/send`, оплата запросов, консолидация, выводы средств и их отказы) проходят
+через sendLimit: не больше SEND_CONCURRENCY одновременно, а запрос, не дождавшийся места за
+SEND_QUEUE_WAIT, получает 503 `overloaded` с заголовком Retry-After. Чтение не ограничивается.
+
+Параметры пагинации всех списков разбирает parsePagination (parseOffsetPagination у
+списков со смещением), поэтому сообщения об ошибках в них единообразны, а `offset` у
+списков без смещения отклоняется с 400.
+
+Денежные суммы в ответах - строки с фиксированной точкой (money.FormatAmount); параметр
+`amount_format=number` возвращает их числами для старых клиентов.
+
+Ошибки возвращаются в формате JSON с машиночитаемым кодом (`code`) и сообщением (`error`).
+Ошибки хранилища переводятся в HTTP-статусы по таблицам txErrorMappings и sentinelErrorMappings.
+
+Все маршруты API учитывают заголовок `X-Request-Timeout`: контекст запроса получает
+соответствующий дедлайн (не больше серверного максимума), а при его истечении
+обработчик отвечает 504 с кодом `deadline_exceeded` и затраченным временем.
 */
 package api
 
 import (
 	"context"
+	"crypto/ed25519"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"go-payments/internal/address"
+	"go-payments/internal/checkpoint"
+	"go-payments/internal/config"
 	"go-payments/internal/models"
+	"go-payments/internal/redact"
+	"go-payments/internal/sandbox"
 	"go-payments/internal/storage"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type Storage interface {
 	GetWalletBalance(ctx context.Context, address string) (*models.Wallet, error)
-	GetLastTransactions(ctx context.Context, n int) ([]models.Transaction, error)
-	GetWallets(ctx context.Context, n int) ([]models.Wallet, error)
-	SendMoney(ctx context.Context, from string, to string, amount float64) error
+	GetLastTransactions(ctx context.Context, n int, filter models.TransactionFilter) ([]models.Transaction, int, error)
+	GetWallets(ctx context.Context, n int) ([]models.Wallet, int, error)
+	GetWalletsByAddress(ctx context.Context, addresses []string) (map[string]models.Wallet, error)
+	ListWallets(ctx context.Context, filter models.WalletFilter) (*models.WalletPage, error)
+	GetStatement(ctx context.Context, address string, from, to time.Time) (*models.Statement, error)
+	GetWalletActivity(ctx context.Context, address string, since, until time.Time, timezone string) (*models.WalletActivity, error)
+	GetVolumeSeries(ctx context.Context, since, until time.Time, bucket models.BucketSize, status models.TransactionStatus) ([]models.VolumeBucket, error)
+	Execute(ctx context.Context, t models.Transfer) (*models.Transaction, error)
+	RecordZeroNote(ctx context.Context, t models.Transfer) (*models.Transaction, error)
+	Ping(ctx context.Context) error
+	GetSetting(ctx context.Context, key string) (string, bool, error)
+	SetSetting(ctx context.Context, key, value string) error
+	GetWalletByLabel(ctx context.Context, label string) (*models.Wallet, error)
+	SetWalletLabel(ctx context.Context, address, label string) error
+	SetWalletMaxBalance(ctx context.Context, address string, maxBalance *float64) error
+	SetWalletSystem(ctx context.Context, address string, system, nonReceivable bool) error
+	PurgeWallet(ctx context.Context, address string) error
+	CreatePaymentRequest(ctx context.Context, pr models.PaymentRequest) (*models.PaymentRequest, error)
+	GetPaymentRequest(ctx context.Context, token string) (*models.PaymentRequest, error)
+	FulfillPaymentRequest(ctx context.Context, token string, transactionID int) (*models.PaymentRequest, error)
+	AddWalletNote(ctx context.Context, wallet, author, text string) (*models.WalletNote, error)
+	ListWalletNotes(ctx context.Context, wallet string) ([]models.WalletNote, error)
+	RedactWalletNote(ctx context.Context, wallet string, id int) (*models.WalletNote, error)
+	CreateBalanceThreshold(ctx context.Context, th models.BalanceThreshold) (*models.BalanceThreshold, error)
+	ListBalanceThresholds(ctx context.Context, wallet string) ([]models.BalanceThreshold, error)
+	GetBalanceThreshold(ctx context.Context, wallet string, id int) (*models.BalanceThreshold, error)
+	UpdateBalanceThreshold(ctx context.Context, th models.BalanceThreshold) (*models.BalanceThreshold, error)
+	DeleteBalanceThreshold(ctx context.Context, wallet string, id int) error
+	CreateWithdrawal(ctx context.Context, w models.Withdrawal, idempotencyKey string) (*models.Withdrawal, error)
+	GetWithdrawal(ctx context.Context, id int) (*models.Withdrawal, error)
+	ListWithdrawals(ctx context.Context, wallet string, status models.WithdrawalStatus, limit int) ([]models.Withdrawal, error)
+	ConfirmWithdrawal(ctx context.Context, id int) (*models.Withdrawal, error)
+	FailWithdrawal(ctx context.Context, id int, reason string) (*models.Withdrawal, error)
+	ReviewExpiredWithdrawals(ctx context.Context, olderThan time.Duration) ([]models.Withdrawal, error)
+	CreateCheckpoint(ctx context.Context, date time.Time, key ed25519.PrivateKey) (*checkpoint.Checkpoint, bool, error)
+	GetCheckpoint(ctx context.Context, date time.Time, withBalances bool) (*checkpoint.Bundle, error)
+	GetCheckpointProof(ctx context.Context, address string, date time.Time) (*checkpoint.Proof, error)
+	GetTransaction(ctx context.Context, id int) (*models.Transaction, error)
+	TransactionIDByPublicID(ctx context.Context, publicID string) (int, error)
+	GetGroupSummary(ctx context.Context, groupID string) (*models.GroupSummary, error)
+	AcknowledgeTransaction(ctx context.Context, id int, recipient string) (*models.Transaction, error)
+	AcknowledgeTransactions(ctx context.Context, recipient string, ids []int) (map[int]error, error)
+	AcknowledgeTransactionsThrough(ctx context.Context, recipient string, throughID int) (int, error)
+	GetIncomingCursor(ctx context.Context, wallet, consumer string) (*models.IncomingCursor, error)
+	SetIncomingCursor(ctx context.Context, wallet, consumer string, lastTransactionID int) (*models.IncomingCursor, error)
+	SchemaVersion(ctx context.Context) (int, error)
+	CheckSchema(ctx context.Context) (*models.SchemaDiff, error)
+	CreateWallets(ctx context.Context, n int, balance float64, progress func(batch []string)) (created, skipped int, err error)
+	AddTransactionTag(ctx context.Context, id int, tag, addedBy string) error
+	RemoveTransactionTag(ctx context.Context, id int, tag string) error
+	ListTransactionTags(ctx context.Context) ([]models.TagCount, error)
+	PoolStats() models.PoolStats
+	StorageInfo(ctx context.Context) (*models.StorageInfo, error)
+	GetPeriodClose(ctx context.Context) (*models.PeriodClose, error)
+	ClosePeriod(ctx context.Context, through time.Time, closedBy string, force bool) (*models.PeriodClose, error)
+	GetWalletSummary(ctx context.Context) (*models.WalletSummary, error)
+	RefreshWalletSummary(ctx context.Context) error
+	SnapshotWallets(ctx context.Context, fn func(models.Wallet) error) error
+	RestoreWallets(ctx context.Context, wallets []models.Wallet) error
+	GetIdempotencyKey(ctx context.Context, key string) (*models.IdempotencyKey, error)
+	DeleteIdempotencyKey(ctx context.Context, key string) error
+	Fsck(ctx context.Context, limit int, report func(models.FsckFinding) error) (models.FsckSummary, error)
 }
 
+// Расхождение сигнатур хранилища и интерфейса обнаруживается при сборке пакета api.
+var _ Storage = (*storage.Storage)(nil)
+
 type API struct {
-	db Storage
+	db  Storage
+	cfg *config.Config
+
+	// Режим обслуживания: изменяющие запросы отклоняются, чтение продолжает работать.
+	maintenance atomic.Bool
+	// Схема базы данных расходится с миграциями (по последней проверке).
+	schemaDrift atomic.Bool
+	// Места для одновременных переводов (SEND_CONCURRENCY); nil - без ограничения.
+	sendSlots chan struct{}
+	// Подпись курсоров пагинации (CURSOR_SECRET).
+	cursors *cursorCodec
+}
+
+func New(db Storage, cfg *config.Config) *API {
+	a := &API{db: db, cfg: cfg, cursors: newCursorCodec(cfg.CursorSecret, cfg.CursorMaxAge)}
+	if cfg.SendConcurrency > 0 {
+		a.sendSlots = make(chan struct{}, cfg.SendConcurrency)
+	}
+	sendConcurrencyLimit.Set(float64(cfg.SendConcurrency))
+	return a
 }
 
-func New(db Storage) *API {
-	return &API{db: db}
+// RegisterRoutes регистрирует все маршруты на одном роутере (режим одного слушателя).
+func (a *API) RegisterRoutes(r chi.Router) {
+	a.RegisterPublicRoutes(r)
+	a.RegisterInternalRoutes(r)
 }
 
-func (a *API) RegisterRoutes(r *chi.Mux) {
-	r.Post("/api/send", a.Send)
-	r.Get("/api/transactions", a.GetLast)
-	r.Get("/api/wallet/{address}/balance", a.GetBalance)
-	r.Get("/api/wallets", a.GetWallets)
+// RegisterPublicRoutes регистрирует публичные маршруты /api, кроме административных.
+func (a *API) RegisterPublicRoutes(r chi.Router) {
+	r.Group(func(r chi.Router) {
+		r.Use(requestTimeout)
+		r.Use(a.limitBody)
+		r.Use(amountFormat)
+		r.Use(validAddress)
+
+		r.With(a.params("limit", "count", "cursor", "between", "status", "include_notes", "since", "until", "group_id", "fields", "embed", metadataParamPrefix+"*")).Get("/api/transactions", a.GetLast)
+		r.With(a.params()).Get("/api/transactions/groups/{group_id}", a.GetGroupSummary)
+		r.With(a.params()).Get("/api/transactions/{id}", a.GetTransaction)
+		r.With(a.params()).Get("/api/wallet/{address}/balance", a.GetBalance)
+		r.With(a.params("month")).Get("/api/wallet/{address}/statement", a.GetStatement)
+		r.With(a.params("since", "until", "tz")).Get("/api/wallet/{address}/activity", a.GetWalletActivity)
+		r.With(a.params("date")).Get("/api/wallet/{address}/proof", a.GetWalletProof)
+		r.With(a.params("limit", "count", "cursor", "unacknowledged", "after_id", "consumer", "fields", "embed")).Get("/api/wallet/{address}/incoming", a.GetIncoming)
+		r.With(a.params()).Get("/api/wallet/{address}/incoming/cursors/{consumer}", a.GetIncomingCursor)
+		r.With(a.params("limit", "count")).Get("/api/wallets", a.GetWallets)
+		r.With(a.params()).Get("/api/wallets/by-label/{label}", a.GetWalletByLabel)
+		r.With(a.params("since", "until", "bucket", "status")).Get("/api/stats/volume", a.GetVolume)
+		r.With(a.params()).Get("/api/stats/wallets", a.GetWalletStats)
+		r.With(a.params()).Get("/api/meta/error-codes", a.GetErrorCodes)
+		r.With(a.params()).Get("/api/meta/transaction-statuses", a.GetTransactionStatuses)
+		r.With(a.params()).Get("/api/meta/address-scheme", a.GetAddressScheme)
+		r.With(a.params()).Get("/api/meta/sandbox", a.GetSandbox)
+		r.With(a.params()).Get("/api/meta/examples", a.GetExamples)
+		r.With(a.params()).Get("/api/meta/examples/{name}", a.GetRouteExamples)
+		r.With(a.params()).Get("/api/payment-requests/{token}", a.GetPaymentRequest)
+		r.With(a.params("limit", "count", "status")).Get("/api/wallet/{address}/withdrawals", a.GetWalletWithdrawals)
+		r.With(a.params()).Get("/api/withdrawals/{id}", a.GetWithdrawal)
+
+		// Изменяющие маршруты недоступны в режиме обслуживания.
+		r.Group(func(r chi.Router) {
+			r.Use(a.maintenanceGuard)
+
+			r.With(a.params(), a.sendLimit).Post("/api/send", a.Send)
+			r.With(a.params()).Post("/api/transactions/{id}/ack", a.AcknowledgeTransaction)
+			r.With(a.params()).Post("/api/wallet/{address}/incoming/ack", a.AcknowledgeIncoming)
+			r.With(a.params()).Put("/api/wallet/{address}/incoming/cursors/{consumer}", a.SetIncomingCursor)
+			r.With(a.params()).Post("/api/payment-requests", a.CreatePaymentRequest)
+			r.With(a.params(), a.sendLimit).Post("/api/payment-requests/{token}/pay", a.PayPaymentRequest)
+			r.With(a.params(), a.sendLimit).Post("/api/wallet/{address}/withdraw", a.CreateWithdrawal)
+		})
+	})
+}
+
+// RegisterInternalRoutes регистрирует административные маршруты /api/admin и /readyz,
+// которые не должны быть доступны из интернета.
+func (a *API) RegisterInternalRoutes(r chi.Router) {
+	r.Group(func(r chi.Router) {
+		r.Use(requestTimeout)
+		r.Use(amountFormat)
+		r.Use(validAddress)
+
+		// Снимок кошельков ограничен своим пределом MAX_RESTORE_BYTES.
+		r.With(a.maintenanceGuard, a.limitRestoreBody, a.params()).Post("/api/admin/restore", a.RestoreSnapshot)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(requestTimeout)
+		r.Use(a.limitBody)
+		r.Use(amountFormat)
+		r.Use(validAddress)
+
+		// Изменяющие маршруты недоступны в режиме обслуживания.
+		r.Group(func(r chi.Router) {
+			r.Use(a.maintenanceGuard)
+
+			r.With(a.params(), a.sendLimit).Post("/api/admin/sweep", a.Sweep)
+			r.With(a.params()).Post("/api/admin/seed", a.SeedWallets)
+			r.With(a.params()).Post("/api/admin/stats/refresh", a.RefreshWalletStats)
+			r.With(a.params()).Put("/api/admin/period-close", a.ClosePeriod)
+			r.With(a.params()).Post("/api/admin/withdrawals/{id}/confirm", a.ConfirmWithdrawal)
+			r.With(a.params(), a.sendLimit).Post("/api/admin/withdrawals/{id}/fail", a.FailWithdrawal)
+			r.With(a.params()).Post("/api/admin/zero-notes", a.RecordZeroNote)
+			r.With(a.params()).Post("/api/admin/transactions/{id}/tags/{tag}", a.AddTransactionTag)
+			r.With(a.params()).Delete("/api/admin/transactions/{id}/tags/{tag}", a.RemoveTransactionTag)
+			r.With(a.params()).Delete("/api/admin/idempotency-keys/{key}", a.DeleteIdempotencyKey)
+			r.With(a.params()).Post("/api/admin/wallet/{address}/purge", a.PurgeWallet)
+			r.With(a.params()).Put("/api/admin/wallet/{address}/label", a.SetWalletLabel)
+			r.With(a.params()).Put("/api/admin/wallet/{address}/max-balance", a.SetWalletMaxBalance)
+			r.With(a.params()).Put("/api/admin/wallet/{address}/system", a.SetWalletSystem)
+			r.With(a.params()).Post("/api/admin/wallet/{address}/notes", a.AddWalletNote)
+			r.With(a.params()).Post("/api/admin/wallet/{address}/notes/{id}/redact", a.RedactWalletNote)
+			r.With(a.params()).Post("/api/admin/wallet/{address}/thresholds", a.CreateBalanceThreshold)
+			r.With(a.params()).Put("/api/admin/wallet/{address}/thresholds/{id}", a.UpdateBalanceThreshold)
+			r.With(a.params()).Delete("/api/admin/wallet/{address}/thresholds/{id}", a.DeleteBalanceThreshold)
+			r.With(a.params()).Put("/api/admin/alert-rules", a.SetAlertRules)
+		})
+
+		r.With(a.params("limit", "count", "offset", "frozen", "include_archived", "min_balance", "max_balance", "sort", "order")).Get("/api/admin/wallets", a.ListWallets)
+		r.With(a.params("limit", "count", "cursor", "between", "status", "include_notes", "since", "until", "group_id", "tag", "fields", "embed", metadataParamPrefix+"*")).Get("/api/admin/transactions", a.ListTransactions)
+		r.With(a.params()).Get("/api/admin/tags", a.ListTransactionTags)
+		r.With(a.params("limit", "count", "status", "wallet")).Get("/api/admin/withdrawals", a.ListWithdrawals)
+		r.With(a.params("include_balances")).Get("/api/admin/checkpoints/{date}", a.GetCheckpoint)
+		r.With(a.params()).Get("/api/admin/config", a.GetConfig)
+		r.With(a.params()).Get("/api/admin/features", a.GetFeatures)
+		r.With(a.params()).Get("/api/admin/storage-info", a.GetStorageInfo)
+		r.With(a.params()).Get("/api/admin/period-close", a.GetPeriodClose)
+		r.With(a.params()).Get("/api/admin/schema", a.GetSchema)
+		r.With(a.params("limit")).Get("/api/admin/fsck", a.Fsck)
+		r.With(a.params()).Get("/api/admin/snapshot", a.GetSnapshot)
+		r.With(a.params()).Get("/api/admin/idempotency-keys/{key}", a.GetIdempotencyKey)
+		r.With(a.params()).Get("/api/admin/wallet/{address}/notes", a.ListWalletNotes)
+		r.With(a.params()).Get("/api/admin/wallet/{address}/thresholds", a.ListBalanceThresholds)
+		r.With(a.params()).Get("/api/admin/wallet/{address}/thresholds/{id}", a.GetBalanceThreshold)
+		r.With(a.params()).Get("/api/admin/alert-rules", a.GetAlertRules)
+		r.With(a.params()).Get("/api/admin/maintenance", a.GetMaintenance)
+		r.With(a.params()).Post("/api/admin/maintenance", a.SetMaintenance)
+	})
+
+	r.Get("/readyz", a.Readyz)
 }
 
 func (a *API) Send(w http.ResponseWriter, r *http.Request) {
 	var req models.SendRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "неверный формат запроса", http.StatusBadRequest)
+		a.badBody(w, err)
 		return
 	}
 	defer r.Body.Close()
 
+	if !a.resolveSendLabels(w, r, &req) {
+		return
+	}
+	if !normalizeAddress(w, "from", &req.From) || !normalizeAddress(w, "to", &req.To) {
+		return
+	}
+
 	if req.Amount <= 0 {
-		http.Error(w, "сумма перевода должна быть положительной", http.StatusBadRequest)
+		badRequest(w, "сумма перевода должна быть положительной")
+		return
+	}
+	if !a.amountInRange(w, "amount", req.Amount) {
 		return
 	}
 	if req.From == req.To {
-		http.Error(w, "нельзя отправить деньги самому себе", http.StatusBadRequest)
+		badRequest(w, "нельзя отправить деньги самому себе")
+		return
+	}
+	if err := models.ValidateMetadata(req.Metadata); err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+	req.GroupID = strings.TrimSpace(req.GroupID)
+	if len(req.GroupID) > models.MaxGroupIDLength {
+		badRequest(w, fmt.Sprintf("поле 'group_id' длиннее %d символов", models.MaxGroupIDLength))
 		return
 	}
 
-	err := a.db.SendMoney(r.Context(), req.From, req.To, req.Amount)
-	if err != nil {
-		log.Printf("ошибка при переводе средств от %s к %s на сумму %.2f: %v", req.From, req.To, req.Amount, err)
-
-		var txErr *storage.TransactionError
-		if errors.As(err, &txErr) {
-			switch txErr.Code {
-			case storage.CodeSenderNotFound, storage.CodeRecipientNotFound:
-				http.Error(w, txErr.Error(), http.StatusNotFound)
-				return
-			case storage.CodeInsufficientFunds:
-				http.Error(w, txErr.Error(), http.StatusPaymentRequired) // 402 Payment Required - очень подходящий статус
-				return
-			default:
-				http.Error(w, "внутренняя ошибка сервера", http.StatusInternalServerError)
-				return
-			}
+	idempotencyKey := strings.TrimSpace(r.Header.Get(idempotencyKeyHeader))
+	if len(idempotencyKey) > models.MaxIdempotencyKeyLength {
+		badRequest(w, fmt.Sprintf("заголовок %s длиннее %d символов", idempotencyKeyHeader, models.MaxIdempotencyKeyLength))
+		return
+	}
+
+	if a.cfg.SandboxMode {
+		if err := sandbox.Apply(r.Context(), req.To, float64(req.Amount)); err != nil {
+			writeStorageError(w, r, err)
+			return
 		}
+	}
 
-		http.Error(w, "внутренняя ошибка сервера", http.StatusInternalServerError)
+	var stats storage.ExecStats
+	transaction, err := a.db.Execute(storage.WithExecStats(r.Context(), &stats), models.Transfer{
+		From:           req.From,
+		To:             req.To,
+		Amount:         float64(req.Amount),
+		Memo:           req.Memo,
+		Reference:      req.Reference,
+		IdempotencyKey: idempotencyKey,
+		Metadata:       req.Metadata,
+		GroupID:        req.GroupID,
+	})
+	setExecStats(w, stats, err)
+	if err != nil {
+		log.Printf("ошибка при переводе средств от %s к %s на сумму %.2f (попыток: %d, %s): %v",
+			redact.Address(req.From), redact.Address(req.To), req.Amount, stats.Attempts, stats.Elapsed, err)
+		writeStorageError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+	writeJSON(w, r, models.SendResponse{Status: "success", Transaction: transaction})
 }
 
+// Префикс query-параметров фильтра по метаданным: ?metadata.order_id=123.
+const metadataParamPrefix = "metadata."
+
 func (a *API) GetLast(w http.ResponseWriter, r *http.Request) {
-	countStr := r.URL.Query().Get("count")
-	if countStr == "" {
-		countStr = "10"
+	page, err := parsePagination(r, pagination{Limit: defaultListLimit}, maxListLimit)
+	if err != nil {
+		badRequest(w, err.Error())
+		return
 	}
 
-	count, err := strconv.Atoi(countStr)
-	if err != nil || count <= 0 {
-		http.Error(w, "параметр 'count' должен быть положительным числом", http.StatusBadRequest)
+	filter, err := parseTransactionFilter(r)
+	if err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+	var ok bool
+	if filter.After, ok = a.parseCursor(w, r); !ok {
+		return
+	}
+	view, ok := parseTransactionView(w, r, page.Limit)
+	if !ok {
 		return
 	}
 
-	transactions, err := a.db.GetLastTransactions(r.Context(), count)
+	a.writeTransactions(w, r, page.Limit, filter, view)
+}
+
+// parseTransactionFilter разбирает общие фильтры списка транзакций: метаданные,
+// пару адресов, статус и период. Курсор разбирает parseCursor.
+func parseTransactionFilter(r *http.Request) (models.TransactionFilter, error) {
+	var filter models.TransactionFilter
+	for key, values := range r.URL.Query() {
+		name, ok := strings.CutPrefix(key, metadataParamPrefix)
+		if !ok {
+			continue
+		}
+		if filter.Metadata == nil {
+			filter.Metadata = make(map[string]string)
+		}
+		filter.Metadata[name] = values[0]
+	}
+	if err := models.ValidateMetadata(filter.Metadata); err != nil {
+		return filter, err
+	}
+	if v := r.URL.Query().Get("between"); v != "" {
+		a, b, ok := strings.Cut(v, ",")
+		a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+		if !ok || a == "" || b == "" || strings.Contains(b, ",") {
+			return filter, errors.New("параметр 'between' должен содержать два различных адреса через запятую")
+		}
+		var err error
+		if a, err = address.Normalize(a); err == nil {
+			b, err = address.Normalize(b)
+		}
+		if err != nil || a == b {
+			return filter, errors.New("параметр 'between' должен содержать два различных адреса кошельков через запятую")
+		}
+		filter.Between = &models.AddressPair{A: a, B: b}
+	}
+	if v := r.URL.Query().Get("group_id"); v != "" {
+		if len(v) > models.MaxGroupIDLength {
+			return filter, fmt.Errorf("параметр 'group_id' длиннее %d символов", models.MaxGroupIDLength)
+		}
+		filter.GroupID = v
+	}
+	if v := r.URL.Query().Get("status"); v != "" {
+		filter.Status = models.TransactionStatus(v)
+		if !filter.Status.Valid() {
+			return filter, errors.New("неизвестный статус транзакции")
+		}
+	}
+	if v := r.URL.Query().Get("include_notes"); v != "" {
+		includeNotes, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, errors.New("параметр 'include_notes' должен быть true или false")
+		}
+		filter.IncludeNotes = includeNotes
+	}
+	if v := r.URL.Query().Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, errors.New("параметр 'since' должен быть в формате RFC 3339")
+		}
+		filter.Since = &since
+	}
+	if v := r.URL.Query().Get("until"); v != "" {
+		until, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, errors.New("параметр 'until' должен быть в формате RFC 3339")
+		}
+		filter.Until = &until
+	}
+	if filter.Since != nil && filter.Until != nil && !filter.Since.Before(*filter.Until) {
+		return filter, errors.New("параметр 'since' должен быть раньше 'until'")
+	}
+	return filter, nil
+}
+
+// writeTransactions отдаёт страницу транзакций по фильтру с курсором следующей
+// страницы в виде view.
+func (a *API) writeTransactions(w http.ResponseWriter, r *http.Request, count int, filter models.TransactionFilter, view transactionView) {
+	transactions, skipped, err := a.db.GetLastTransactions(r.Context(), count, filter)
 	if err != nil {
 		log.Printf("ошибка получения последних транзакций: %v", err)
-		http.Error(w, "внутренняя ошибка сервера", http.StatusInternalServerError)
+		writeStorageError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(transactions)
+	setSkippedRows(w, skipped)
+	a.setNextCursor(w, r, transactions, skipped, count)
+
+	a.writeTransactionList(w, r, transactions, view)
 }
 
 func (a *API) GetBalance(w http.ResponseWriter, r *http.Request) {
@@ -134,39 +662,30 @@ func (a *API) GetBalance(w http.ResponseWriter, r *http.Request) {
 
 	wallet, err := a.db.GetWalletBalance(r.Context(), address)
 	if err != nil {
-		if errors.Is(err, storage.ErrWalletNotFound) {
-			http.Error(w, err.Error(), http.StatusNotFound)
-			return
+		if !errors.Is(err, storage.ErrWalletNotFound) {
+			log.Printf("ошибка получения баланса для кошелька %s: %v", redact.Address(address), err)
 		}
-
-		log.Printf("ошибка получения баланса для кошелька %s: %v", address, err)
-		http.Error(w, "внутренняя ошибка сервера", http.StatusInternalServerError)
+		writeStorageError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(wallet)
+	writeJSON(w, r, wallet)
 }
 
 func (a *API) GetWallets(w http.ResponseWriter, r *http.Request) {
-	countStr := r.URL.Query().Get("count")
-	if countStr == "" {
-		countStr = "10"
-	}
-
-	count, err := strconv.Atoi(countStr)
-	if err != nil || count <= 0 {
-		http.Error(w, "параметр 'count' должен быть положительным числом", http.StatusBadRequest)
+	page, err := parsePagination(r, pagination{Limit: defaultListLimit}, maxListLimit)
+	if err != nil {
+		badRequest(w, err.Error())
 		return
 	}
 
-	wallets, err := a.db.GetWallets(r.Context(), count)
+	wallets, skipped, err := a.db.GetWallets(r.Context(), page.Limit)
 	if err != nil {
 		log.Printf("ошибка получения wallets: %v", err)
-		http.Error(w, "внутренняя ошибка сервера", http.StatusInternalServerError)
+		writeStorageError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(wallets)
+	setSkippedRows(w, skipped)
+	writeJSON(w, r, wallets)
 }
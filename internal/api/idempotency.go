@@ -0,0 +1,43 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"go-payments/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (a *API) GetIdempotencyKey(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "key")
+
+	k, err := a.db.GetIdempotencyKey(r.Context(), key)
+	if err != nil {
+		if !errors.Is(err, storage.ErrIdempotencyKeyNotFound) {
+			log.Printf("ошибка получения ключа идемпотентности %q: %v", key, err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, k)
+}
+
+// DeleteIdempotencyKey удаляет ключ идемпотентности. Записанная с ним транзакция
+// остаётся, а повтор перевода с этим ключом выполнит перевод заново.
+func (a *API) DeleteIdempotencyKey(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "key")
+
+	if err := a.db.DeleteIdempotencyKey(r.Context(), key); err != nil {
+		if !errors.Is(err, storage.ErrIdempotencyKeyNotFound) {
+			log.Printf("ошибка удаления ключа идемпотентности %q: %v", key, err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+
+	log.Printf("удалён ключ идемпотентности %q (%s)", key, r.Header.Get(actorHeader))
+	w.WriteHeader(http.StatusNoContent)
+}
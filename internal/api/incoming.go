@@ -0,0 +1,300 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go-payments/internal/models"
+	"go-payments/internal/redact"
+	"go-payments/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ackRequest - тело подтверждения перевода: адрес кошелька-получателя.
+type ackRequest struct {
+	Address string `json:"address"`
+}
+
+// bulkAckRequest - тело массового подтверждения: список переводов или граница.
+type bulkAckRequest struct {
+	IDs       []int `json:"ids"`
+	ThroughID *int  `json:"through_id"`
+}
+
+type bulkAckResult struct {
+	ID           int    `json:"id"`
+	Acknowledged bool   `json:"acknowledged"`
+	Code         string `json:"code,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+type bulkAckResponse struct {
+	// Results - результат по каждому идентификатору из ids в порядке запроса.
+	Results []bulkAckResult `json:"results,omitempty"`
+	// ThroughID и Acknowledged - граница и количество подтверждённых сейчас переводов.
+	ThroughID    *int `json:"through_id,omitempty"`
+	Acknowledged int  `json:"acknowledged"`
+}
+
+type incomingCursorRequest struct {
+	LastTransactionID *int `json:"last_transaction_id"`
+}
+
+// Имя потребителя входящих переводов: латиница, цифры, '.', '_' и '-'.
+var consumerNamePattern = regexp.MustCompile(fmt.Sprintf(`^[A-Za-z0-9._-]{1,%d}$`, models.MaxConsumerNameLength))
+
+// validConsumer проверяет имя потребителя и при ошибке отвечает 400.
+func validConsumer(w http.ResponseWriter, consumer string) bool {
+	if !consumerNamePattern.MatchString(consumer) {
+		badRequest(w, fmt.Sprintf("имя потребителя должно состоять из латинских букв, цифр, '.', '_' и '-' и быть не длиннее %d символов",
+			models.MaxConsumerNameLength))
+		return false
+	}
+	return true
+}
+
+func (a *API) GetIncoming(w http.ResponseWriter, r *http.Request) {
+	address := chi.URLParam(r, "address")
+
+	page, err := parsePagination(r, pagination{Limit: defaultListLimit}, maxListLimit)
+	if err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+
+	filter := models.TransactionFilter{To: address, Status: models.StatusSuccess}
+	if v := r.URL.Query().Get("unacknowledged"); v != "" {
+		unacknowledged, err := strconv.ParseBool(v)
+		if err != nil {
+			badRequest(w, "параметр 'unacknowledged' должен быть true или false")
+			return
+		}
+		filter.Unacknowledged = unacknowledged
+	}
+	query := r.URL.Query()
+	positions := 0
+	for _, name := range []string{"cursor", "after_id", "consumer"} {
+		if query.Get(name) != "" {
+			positions++
+		}
+	}
+	if positions > 1 {
+		badRequest(w, "параметры 'cursor', 'after_id' и 'consumer' несовместимы")
+		return
+	}
+	var ok bool
+	if filter.After, ok = a.parseCursor(w, r); !ok {
+		return
+	}
+	if v := query.Get("after_id"); v != "" {
+		afterID, err := strconv.Atoi(v)
+		if err != nil || afterID < 0 {
+			badRequest(w, "параметр 'after_id' должен быть неотрицательным целым числом")
+			return
+		}
+		filter.AfterID = &afterID
+	}
+	if consumer := query.Get("consumer"); consumer != "" {
+		if !validConsumer(w, consumer) {
+			return
+		}
+		// Без сохранённого курсора потребитель читает с начала.
+		afterID := 0
+		cursor, err := a.db.GetIncomingCursor(r.Context(), address, consumer)
+		switch {
+		case err == nil:
+			afterID = cursor.LastTransactionID
+		case !errors.Is(err, storage.ErrIncomingCursorNotFound):
+			log.Printf("ошибка получения курсора %s кошелька %s: %v", consumer, redact.Address(address), err)
+			writeStorageError(w, r, err)
+			return
+		}
+		filter.AfterID = &afterID
+	}
+	view, ok := parseTransactionView(w, r, page.Limit)
+	if !ok {
+		return
+	}
+
+	transactions, skipped, err := a.db.GetLastTransactions(r.Context(), page.Limit, filter)
+	if err != nil {
+		log.Printf("ошибка получения входящих переводов кошелька %s: %v", redact.Address(address), err)
+		writeStorageError(w, r, err)
+		return
+	}
+	if transactions == nil {
+		transactions = []models.Transaction{}
+	}
+
+	setSkippedRows(w, skipped)
+	// По after_id следующая страница запрашивается с id последнего перевода.
+	if filter.AfterID == nil {
+		a.setNextCursor(w, r, transactions, skipped, page.Limit)
+	}
+	a.writeTransactionList(w, r, transactions, view)
+}
+
+func (a *API) AcknowledgeTransaction(w http.ResponseWriter, r *http.Request) {
+	id, ok := a.transactionID(w, r)
+	if !ok {
+		return
+	}
+
+	var req ackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "неверный формат запроса")
+		return
+	}
+	defer r.Body.Close()
+
+	req.Address = strings.TrimSpace(req.Address)
+	if req.Address == "" {
+		badRequest(w, "поле 'address' обязательно")
+		return
+	}
+	if !normalizeAddress(w, "address", &req.Address) {
+		return
+	}
+
+	transaction, err := a.db.AcknowledgeTransaction(r.Context(), id, req.Address)
+	if err != nil {
+		if !errors.Is(err, storage.ErrTxNotFound) && !errors.Is(err, storage.ErrNotRecipient) && !errors.Is(err, storage.ErrPeriodClosed) {
+			log.Printf("ошибка подтверждения транзакции %d: %v", id, err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, transaction)
+}
+
+// AcknowledgeIncoming подтверждает входящие переводы кошелька списком ids или
+// все успешные переводы до through_id включительно.
+func (a *API) AcknowledgeIncoming(w http.ResponseWriter, r *http.Request) {
+	address := chi.URLParam(r, "address")
+
+	var req bulkAckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "неверный формат запроса")
+		return
+	}
+	defer r.Body.Close()
+
+	if (len(req.IDs) > 0) == (req.ThroughID != nil) {
+		badRequest(w, "нужно указать ровно одно из полей 'ids' и 'through_id'")
+		return
+	}
+
+	if req.ThroughID != nil {
+		if *req.ThroughID <= 0 {
+			badRequest(w, "поле 'through_id' должно быть положительным числом")
+			return
+		}
+		n, err := a.db.AcknowledgeTransactionsThrough(r.Context(), address, *req.ThroughID)
+		if err != nil {
+			log.Printf("ошибка подтверждения переводов кошелька %s до %d: %v", redact.Address(address), *req.ThroughID, err)
+			writeStorageError(w, r, err)
+			return
+		}
+		writeJSON(w, r, bulkAckResponse{ThroughID: req.ThroughID, Acknowledged: n})
+		return
+	}
+
+	if len(req.IDs) > models.MaxBulkAckIDs {
+		badRequest(w, fmt.Sprintf("в поле 'ids' больше %d идентификаторов", models.MaxBulkAckIDs))
+		return
+	}
+	ids := make([]int, 0, len(req.IDs))
+	seen := make(map[int]bool, len(req.IDs))
+	for _, id := range req.IDs {
+		if id <= 0 {
+			badRequest(w, "идентификаторы транзакций должны быть положительными числами")
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	results, err := a.db.AcknowledgeTransactions(r.Context(), address, ids)
+	if err != nil {
+		log.Printf("ошибка подтверждения переводов кошелька %s: %v", redact.Address(address), err)
+		writeStorageError(w, r, err)
+		return
+	}
+
+	resp := bulkAckResponse{Results: make([]bulkAckResult, len(ids))}
+	for i, id := range ids {
+		resp.Results[i] = bulkAckResult{ID: id, Acknowledged: results[id] == nil}
+		if err := results[id]; err != nil {
+			resp.Results[i].Code = storageErrorCode(err)
+			resp.Results[i].Error = err.Error()
+		} else {
+			resp.Acknowledged++
+		}
+	}
+	writeJSON(w, r, resp)
+}
+
+// GetIncomingCursor возвращает курсор потребителя входящих переводов кошелька.
+func (a *API) GetIncomingCursor(w http.ResponseWriter, r *http.Request) {
+	address, consumer := chi.URLParam(r, "address"), chi.URLParam(r, "consumer")
+	if !validConsumer(w, consumer) {
+		return
+	}
+
+	cursor, err := a.db.GetIncomingCursor(r.Context(), address, consumer)
+	if err != nil {
+		if !errors.Is(err, storage.ErrIncomingCursorNotFound) {
+			log.Printf("ошибка получения курсора %s кошелька %s: %v", consumer, redact.Address(address), err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+	writeJSON(w, r, cursor)
+}
+
+// SetIncomingCursor сдвигает курсор потребителя; меньшее значение курсор не меняет.
+func (a *API) SetIncomingCursor(w http.ResponseWriter, r *http.Request) {
+	address, consumer := chi.URLParam(r, "address"), chi.URLParam(r, "consumer")
+	if !validConsumer(w, consumer) {
+		return
+	}
+
+	var req incomingCursorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "неверный формат запроса")
+		return
+	}
+	defer r.Body.Close()
+
+	if req.LastTransactionID == nil || *req.LastTransactionID < 0 {
+		badRequest(w, "поле 'last_transaction_id' обязательно и не может быть отрицательным")
+		return
+	}
+
+	// Курсор несуществующего кошелька не создаётся.
+	if _, err := a.db.GetWalletBalance(r.Context(), address); err != nil {
+		if !errors.Is(err, storage.ErrWalletNotFound) {
+			log.Printf("ошибка получения кошелька %s: %v", redact.Address(address), err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+
+	cursor, err := a.db.SetIncomingCursor(r.Context(), address, consumer, *req.LastTransactionID)
+	if err != nil {
+		log.Printf("ошибка сохранения курсора %s кошелька %s: %v", consumer, redact.Address(address), err)
+		writeStorageError(w, r, err)
+		return
+	}
+	writeJSON(w, r, cursor)
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go-payments/internal/models"
+	"go-payments/internal/storage"
+)
+
+// txStorage хранит транзакции в памяти и подтверждает их по правилам настоящего
+// хранилища: только успешный перевод и только его получателем.
+type txStorage struct {
+	*fakeStorage
+
+	mu           sync.Mutex
+	transactions map[int]models.Transaction
+}
+
+func newTxStorage(transactions ...models.Transaction) *txStorage {
+	s := &txStorage{fakeStorage: &fakeStorage{}, transactions: map[int]models.Transaction{}}
+	for _, t := range transactions {
+		s.transactions[t.ID] = t
+	}
+	return s
+}
+
+func (s *txStorage) GetTransaction(ctx context.Context, id int) (*models.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.transactions[id]
+	if !ok {
+		return nil, storage.ErrTxNotFound
+	}
+	return &t, nil
+}
+
+func (s *txStorage) TransactionIDByPublicID(ctx context.Context, publicID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, t := range s.transactions {
+		if t.PublicID == publicID {
+			return id, nil
+		}
+	}
+	return 0, storage.ErrTxNotFound
+}
+
+func (s *txStorage) acknowledge(id int, recipient string) error {
+	t, ok := s.transactions[id]
+	if !ok {
+		return storage.ErrTxNotFound
+	}
+	if t.To != recipient || t.Status != models.StatusSuccess {
+		return storage.ErrNotRecipient
+	}
+	if t.AcknowledgedAt == nil {
+		now := time.Now().UTC()
+		t.AcknowledgedAt = &now
+		s.transactions[id] = t
+	}
+	return nil
+}
+
+func (s *txStorage) AcknowledgeTransaction(ctx context.Context, id int, recipient string) (*models.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.acknowledge(id, recipient); err != nil {
+		return nil, err
+	}
+	t := s.transactions[id]
+	return &t, nil
+}
+
+func (s *txStorage) AcknowledgeTransactions(ctx context.Context, recipient string, ids []int) (map[int]error, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := make(map[int]error, len(ids))
+	for _, id := range ids {
+		results[id] = s.acknowledge(id, recipient)
+	}
+	return results, nil
+}
+
+func TestAcknowledgeTransaction(t *testing.T) {
+	sender, recipient := testAddress(1), testAddress(2)
+	db := newTxStorage(
+		models.Transaction{ID: 1, From: sender, To: recipient, Amount: 10, Status: models.StatusSuccess},
+		models.Transaction{ID: 2, From: sender, To: recipient, Amount: 10, Status: models.StatusFailedInsufficientFunds},
+	)
+	_, router := newTestRouter(db, testConfig())
+	ack := func(id int, address string) *httptest.ResponseRecorder {
+		return serve(router, http.MethodPost, "/api/transactions/"+strconv.Itoa(id)+"/ack", `{"address":"`+address+`"}`, nil)
+	}
+
+	// Получатель подтверждает успешный перевод; повтор отвечает 200 с тем же временем.
+	w := ack(1, recipient)
+	if w.Code != http.StatusOK {
+		t.Fatalf("подтверждение получателем: статус %d, want 200; тело %s", w.Code, truncate(w.Body.String()))
+	}
+	var first models.Transaction
+	json.Unmarshal(w.Body.Bytes(), &first)
+	if first.ID != 1 || first.AcknowledgedAt == nil {
+		t.Fatalf("подтверждённый перевод %+v, want id 1 с acknowledged_at", first)
+	}
+	w = ack(1, recipient)
+	var again models.Transaction
+	json.Unmarshal(w.Body.Bytes(), &again)
+	if w.Code != http.StatusOK || again.AcknowledgedAt == nil || !again.AcknowledgedAt.Equal(*first.AcknowledgedAt) {
+		t.Errorf("повторное подтверждение: статус %d, время %v, want 200 и %v", w.Code, again.AcknowledgedAt, first.AcknowledgedAt)
+	}
+
+	tests := []struct {
+		name    string
+		id      int
+		address string
+		status  int
+		code    string
+	}{
+		{"чужой перевод", 1, sender, http.StatusForbidden, CodeNotRecipient},
+		{"посторонний кошелёк", 1, testAddress(3), http.StatusForbidden, CodeNotRecipient},
+		// Средства по неуспешному переводу не поступили: подтверждать нечего.
+		{"неуспешный перевод", 2, recipient, http.StatusForbidden, CodeNotRecipient},
+		{"неизвестный перевод", 3, recipient, http.StatusNotFound, CodeTxNotFound},
+		{"некорректный адрес", 1, "abc", http.StatusBadRequest, CodeInvalidAddress},
+		{"без адреса", 1, "", http.StatusBadRequest, CodeInvalidRequest},
+	}
+	for _, tt := range tests {
+		w := ack(tt.id, tt.address)
+		if w.Code != tt.status {
+			t.Errorf("%s: статус %d, want %d; тело %s", tt.name, w.Code, tt.status, truncate(w.Body.String()))
+			continue
+		}
+		checkErrorEnvelope(t, tt.name, w, tt.code)
+	}
+	if failed := db.transactions[2]; failed.AcknowledgedAt != nil {
+		t.Errorf("неуспешный перевод отмечен подтверждённым: %v", failed.AcknowledgedAt)
+	}
+}
+
+func TestAcknowledgeIncomingIDs(t *testing.T) {
+	sender, recipient := testAddress(1), testAddress(2)
+	db := newTxStorage(
+		models.Transaction{ID: 1, From: sender, To: recipient, Amount: 10, Status: models.StatusSuccess},
+		models.Transaction{ID: 2, From: sender, To: recipient, Amount: 10, Status: models.StatusFailedInsufficientFunds},
+		models.Transaction{ID: 3, From: recipient, To: sender, Amount: 10, Status: models.StatusSuccess},
+	)
+	_, router := newTestRouter(db, testConfig())
+	w := serve(router, http.MethodPost, "/api/wallet/"+recipient+"/incoming/ack", `{"ids":[1,2,3,4]}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("пакетное подтверждение: статус %d, want 200; тело %s", w.Code, truncate(w.Body.String()))
+	}
+	var resp struct {
+		Results []struct {
+			ID           int    `json:"id"`
+			Acknowledged bool   `json:"acknowledged"`
+			Code         string `json:"code"`
+		} `json:"results"`
+		Acknowledged int `json:"acknowledged"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	want := map[int]string{1: "", 2: CodeNotRecipient, 3: CodeNotRecipient, 4: CodeTxNotFound}
+	if len(resp.Results) != len(want) || resp.Acknowledged != 1 {
+		t.Fatalf("ответ %+v, want %d результата и 1 подтверждение", resp, len(want))
+	}
+	for _, r := range resp.Results {
+		if r.Code != want[r.ID] || r.Acknowledged != (want[r.ID] == "") {
+			t.Errorf("перевод %d: acknowledged %v, code %q, want code %q", r.ID, r.Acknowledged, r.Code, want[r.ID])
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"go-payments/internal/models"
+	"go-payments/internal/redact"
+	"go-payments/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type labelRequest struct {
+	Label string `json:"label"`
+}
+
+func (a *API) GetWalletByLabel(w http.ResponseWriter, r *http.Request) {
+	label := chi.URLParam(r, "label")
+
+	wallet, err := a.db.GetWalletByLabel(r.Context(), label)
+	if err != nil {
+		if !errors.Is(err, storage.ErrLabelNotFound) {
+			log.Printf("ошибка поиска кошелька по метке %s: %v", label, err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, wallet)
+}
+
+// SetWalletLabel назначает кошельку метку; пустая метка снимает её.
+func (a *API) SetWalletLabel(w http.ResponseWriter, r *http.Request) {
+	address := chi.URLParam(r, "address")
+
+	var req labelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "неверный формат запроса")
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Label != "" {
+		if err := models.ValidateLabel(req.Label); err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+	}
+
+	if err := a.db.SetWalletLabel(r.Context(), address, req.Label); err != nil {
+		if !errors.Is(err, storage.ErrWalletNotFound) && !errors.Is(err, storage.ErrLabelTaken) {
+			log.Printf("ошибка назначения метки кошельку %s: %v", redact.Address(address), err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+
+	wallet, err := a.db.GetWalletBalance(r.Context(), address)
+	if err != nil {
+		log.Printf("ошибка получения кошелька %s: %v", redact.Address(address), err)
+		writeStorageError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, wallet)
+}
+
+// resolveSendLabels подставляет адреса вместо меток from_label и to_label.
+// Для каждой стороны должен быть указан ровно один из адреса и метки.
+// При ошибке отвечает клиенту и возвращает false.
+func (a *API) resolveSendLabels(w http.ResponseWriter, r *http.Request, req *models.SendRequest) bool {
+	sides := []struct {
+		field   string
+		address *string
+		label   string
+	}{
+		{"from", &req.From, req.FromLabel},
+		{"to", &req.To, req.ToLabel},
+	}
+
+	for _, side := range sides {
+		if side.label == "" {
+			continue
+		}
+		if *side.address != "" {
+			badRequest(w, fmt.Sprintf("укажите либо '%s', либо '%s_label'", side.field, side.field))
+			return false
+		}
+
+		wallet, err := a.db.GetWalletByLabel(r.Context(), side.label)
+		if errors.Is(err, storage.ErrLabelNotFound) {
+			writeError(w, http.StatusNotFound, CodeLabelNotFound,
+				fmt.Sprintf("метка '%s' из поля '%s_label' не найдена", side.label, side.field),
+				map[string]string{"field": side.field + "_label", "label": side.label})
+			return false
+		}
+		if err != nil {
+			log.Printf("ошибка поиска кошелька по метке %s: %v", side.label, err)
+			writeStorageError(w, r, err)
+			return false
+		}
+		*side.address = wallet.Address
+	}
+	return true
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"go-payments/internal/models"
+	"go-payments/internal/storage"
+)
+
+// labelStorage хранит метки кошельков в памяти с уникальностью метки, как индекс
+// idx_wallets_label, и запоминает выполненные переводы.
+type labelStorage struct {
+	*fakeStorage
+
+	mu        sync.Mutex
+	labels    map[string]string // метка -> адрес
+	transfers []models.Transfer
+}
+
+func (s *labelStorage) GetWalletByLabel(ctx context.Context, label string) (*models.Wallet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	address, ok := s.labels[label]
+	if !ok {
+		return nil, storage.ErrLabelNotFound
+	}
+	return &models.Wallet{Address: address, Label: label}, nil
+}
+
+func (s *labelStorage) SetWalletLabel(ctx context.Context, address, label string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if owner, ok := s.labels[label]; ok && owner != address {
+		return storage.ErrLabelTaken
+	}
+	for l, a := range s.labels {
+		if a == address {
+			delete(s.labels, l)
+		}
+	}
+	if label != "" {
+		s.labels[label] = address
+	}
+	return nil
+}
+
+func (s *labelStorage) GetWalletBalance(ctx context.Context, address string) (*models.Wallet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wallet := models.Wallet{Address: address}
+	for l, a := range s.labels {
+		if a == address {
+			wallet.Label = l
+		}
+	}
+	return &wallet, nil
+}
+
+func (s *labelStorage) Execute(ctx context.Context, t models.Transfer) (*models.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transfers = append(s.transfers, t)
+	return &models.Transaction{ID: len(s.transfers), From: t.From, To: t.To, Status: models.StatusSuccess}, nil
+}
+
+func TestSetWalletLabelDuplicate(t *testing.T) {
+	db := &labelStorage{fakeStorage: &fakeStorage{}, labels: map[string]string{}}
+	_, router := newTestRouter(db, testConfig())
+	first, second := testAddress(1), testAddress(2)
+	setLabel := func(address, label string) (int, models.Wallet) {
+		w := serve(router, http.MethodPut, "/api/admin/wallet/"+address+"/label", `{"label":"`+label+`"}`, nil)
+		var wallet models.Wallet
+		json.Unmarshal(w.Body.Bytes(), &wallet)
+		return w.Code, wallet
+	}
+
+	if code, wallet := setLabel(first, "payroll"); code != http.StatusOK || wallet.Label != "payroll" {
+		t.Fatalf("назначение метки: %d, метка %q", code, wallet.Label)
+	}
+	// Метка другого кошелька - 409, и она остаётся у прежнего владельца.
+	w := serve(router, http.MethodPut, "/api/admin/wallet/"+second+"/label", `{"label":"payroll"}`, nil)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("повторная метка: статус %d, want 409; тело %s", w.Code, truncate(w.Body.String()))
+	}
+	checkErrorEnvelope(t, "повторная метка", w, CodeLabelTaken)
+	if owner := db.labels["payroll"]; owner != first {
+		t.Errorf("метка payroll у %s, want %s", owner, first)
+	}
+	// Повторное назначение своей метки не конфликт.
+	if code, _ := setLabel(first, "payroll"); code != http.StatusOK {
+		t.Errorf("повторное назначение своей метки: %d, want 200", code)
+	}
+	// Снятая метка свободна для другого кошелька.
+	if code, wallet := setLabel(first, ""); code != http.StatusOK || wallet.Label != "" {
+		t.Fatalf("снятие метки: %d, метка %q", code, wallet.Label)
+	}
+	if code, wallet := setLabel(second, "payroll"); code != http.StatusOK || wallet.Label != "payroll" {
+		t.Errorf("метка после снятия: %d, метка %q, want 200 payroll", code, wallet.Label)
+	}
+}
+
+func TestSendLabels(t *testing.T) {
+	payroll, vendor, other := testAddress(1), testAddress(2), testAddress(3)
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+		// from и to - адреса, с которыми выполняется перевод.
+		from, to string
+	}{
+		{name: "обе метки", body: `{"from_label":"payroll","to_label":"vendor","amount":"10"}`,
+			status: http.StatusOK, from: payroll, to: vendor},
+		{name: "метка отправителя и адрес получателя", body: `{"from_label":"payroll","to":"` + other + `","amount":"10"}`,
+			status: http.StatusOK, from: payroll, to: other},
+		{name: "адрес отправителя и метка получателя", body: `{"from":"` + other + `","to_label":"vendor","amount":"10"}`,
+			status: http.StatusOK, from: other, to: vendor},
+		{name: "адрес и метка отправителя", body: `{"from":"` + other + `","from_label":"payroll","to":"` + vendor + `","amount":"10"}`,
+			status: http.StatusBadRequest, code: CodeInvalidRequest},
+		{name: "адрес и метка получателя", body: `{"from":"` + other + `","to":"` + vendor + `","to_label":"vendor","amount":"10"}`,
+			status: http.StatusBadRequest, code: CodeInvalidRequest},
+		{name: "неизвестная метка", body: `{"from_label":"payroll","to_label":"nobody","amount":"10"}`,
+			status: http.StatusNotFound, code: CodeLabelNotFound},
+		{name: "метка указывает на отправителя", body: `{"from_label":"payroll","to":"` + payroll + `","amount":"10"}`,
+			status: http.StatusBadRequest, code: CodeInvalidRequest},
+	}
+	for _, tt := range tests {
+		db := &labelStorage{fakeStorage: &fakeStorage{}, labels: map[string]string{"payroll": payroll, "vendor": vendor}}
+		_, router := newTestRouter(db, testConfig())
+		w := serve(router, http.MethodPost, "/api/send", tt.body, nil)
+		if w.Code != tt.status {
+			t.Errorf("%s: статус %d, want %d; тело %s", tt.name, w.Code, tt.status, truncate(w.Body.String()))
+			continue
+		}
+		if tt.code != "" {
+			checkErrorEnvelope(t, tt.name, w, tt.code)
+			if len(db.transfers) != 0 {
+				t.Errorf("%s: отклонённый запрос выполнил перевод %+v", tt.name, db.transfers)
+			}
+			continue
+		}
+		if len(db.transfers) != 1 || db.transfers[0].From != tt.from || db.transfers[0].To != tt.to {
+			t.Errorf("%s: переводы %+v, want один %s -> %s", tt.name, db.transfers, tt.from, tt.to)
+		}
+	}
+
+	// Ошибка неизвестной метки называет поле и метку.
+	db := &labelStorage{fakeStorage: &fakeStorage{}, labels: map[string]string{}}
+	_, router := newTestRouter(db, testConfig())
+	w := serve(router, http.MethodPost, "/api/send", `{"from":"`+other+`","to_label":"nobody","amount":"10"}`, nil)
+	var body struct {
+		Details map[string]string `json:"details"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if body.Details["field"] != "to_label" || body.Details["label"] != "nobody" {
+		t.Errorf("details неизвестной метки %v, want field to_label и label nobody", body.Details)
+	}
+}
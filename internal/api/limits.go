@@ -0,0 +1,99 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+
+	"go-payments/internal/money"
+)
+
+// Наименьшая положительная сумма, которую хранит база: единица последнего знака
+// DECIMAL(20, 8). Меньшие суммы округлились бы до нуля.
+var minAmount = math.Pow10(-money.Scale)
+
+// limitBody читает тело запроса целиком, но не больше MAX_BODY_BYTES, до
+// обработчика: тело больше предела отклоняется с 413 request_too_large, не
+// доходя до разбора JSON, поэтому огромные числа и глубокая вложенность не
+// тратят память и время декодера.
+func (a *API) limitBody(next http.Handler) http.Handler {
+	return limitBodyTo(next, func() int { return a.cfg.HTTP.MaxBodyBytes })
+}
+
+// limitRestoreBody ограничивает тело так же, как limitBody, но пределом
+// MAX_RESTORE_BYTES: снимок кошельков намного больше обычных тел.
+func (a *API) limitRestoreBody(next http.Handler) http.Handler {
+	return limitBodyTo(next, func() int { return a.cfg.HTTP.MaxRestoreBytes })
+}
+
+// limitBodyTo ограничивает тело запросов к next пределом maxBytes.
+func limitBodyTo(next http.Handler, maxBytes func() int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := int64(maxBytes())
+		if r.ContentLength > limit {
+			bodyTooLarge(w, limit)
+			return
+		}
+		if r.Body != nil && r.Body != http.NoBody {
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+			r.Body.Close()
+			if err != nil {
+				var maxErr *http.MaxBytesError
+				if errors.As(err, &maxErr) {
+					bodyTooLarge(w, limit)
+					return
+				}
+				badRequest(w, "не удалось прочитать тело запроса")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func bodyTooLarge(w http.ResponseWriter, limit int64) {
+	writeError(w, http.StatusRequestEntityTooLarge, CodeRequestTooLarge,
+		fmt.Sprintf("тело запроса больше %d байт", limit), map[string]int64{"max_bytes": limit})
+}
+
+// amountOutOfRange отвечает 400 amount_out_of_range с границами допустимых сумм
+// и полем, если оно известно.
+func (a *API) amountOutOfRange(w http.ResponseWriter, field string) {
+	details := map[string]string{
+		"min": money.FormatAmount(minAmount),
+		"max": strconv.Itoa(a.cfg.MaxAmount),
+	}
+	message := fmt.Sprintf("сумма вне допустимого диапазона: от %s до %d", details["min"], a.cfg.MaxAmount)
+	if field != "" {
+		details["field"] = field
+		message = fmt.Sprintf("поле '%s' вне допустимого диапазона: от %s до %d", field, details["min"], a.cfg.MaxAmount)
+	}
+	writeError(w, http.StatusBadRequest, CodeAmountOutOfRange, message, details)
+}
+
+// amountInRange проверяет, что сумма поля field конечна, не больше MAX_AMOUNT и,
+// если положительна, не меньше minAmount. Знак проверяет вызывающий код. При
+// нарушении отвечает 400 amount_out_of_range и возвращает false.
+func (a *API) amountInRange(w http.ResponseWriter, field string, amount money.Amount) bool {
+	v := float64(amount)
+	if math.IsNaN(v) || math.IsInf(v, 0) || v > float64(a.cfg.MaxAmount) || (v > 0 && v < minAmount) {
+		a.amountOutOfRange(w, field)
+		return false
+	}
+	return true
+}
+
+// badBody отвечает на ошибку разбора JSON-тела: сумма, не представимая числом
+// (например, 1e400), - 400 amount_out_of_range, остальное - 400 invalid_request.
+func (a *API) badBody(w http.ResponseWriter, err error) {
+	if errors.Is(err, money.ErrAmountOutOfRange) {
+		a.amountOutOfRange(w, "")
+		return
+	}
+	badRequest(w, "неверный формат запроса")
+}
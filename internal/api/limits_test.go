@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go-payments/internal/money"
+)
+
+// amountRoutes - маршруты, принимающие суммы, с телом, в которое вместо %s
+// подставляется сумма.
+var amountRoutes = []struct{ method, path, body string }{
+	{"POST", "/api/send", `{"from":"` + testAddress(1) + `","to":"` + testAddress(2) + `","amount":%s}`},
+	{"POST", "/api/payment-requests", `{"to":"` + testAddress(1) + `","amount":%s}`},
+	{"POST", "/api/wallet/" + testAddress(1) + "/withdraw", `{"amount":%s}`},
+	{"POST", "/api/admin/seed", `{"count":1,"balance":%s}`},
+	{"PUT", "/api/admin/wallet/" + testAddress(1) + "/max-balance", `{"max_balance":%s}`},
+	{"POST", "/api/admin/wallet/" + testAddress(1) + "/thresholds", `{"direction":"below","amount":%s}`},
+}
+
+func TestAdversarialAmounts(t *testing.T) {
+	tests := []struct {
+		amount string
+		code   string // пусто - любой отказ 400
+	}{
+		{`1e308`, CodeAmountOutOfRange},
+		{`1.7976931348623157e308`, CodeAmountOutOfRange},
+		{`1e400`, CodeAmountOutOfRange},
+		{`-1e400`, CodeAmountOutOfRange},
+		{`1e999999999`, CodeAmountOutOfRange},
+		{`9e99999999999999999999`, CodeAmountOutOfRange},
+		{`1` + strings.Repeat("0", 400), CodeAmountOutOfRange},
+		{`1000000000.1`, CodeAmountOutOfRange},
+		{`0.000000001`, CodeInvalidRequest},
+		{`1.123456789`, CodeInvalidRequest},
+		{`1.5e-9`, CodeInvalidRequest},
+		{`-1e308`, ""},
+		{`"1e308"`, ""},
+		{`"` + strings.Repeat("9", 10000) + `"`, ""},
+		{`NaN`, ""},
+		{`Infinity`, ""},
+		{`-Infinity`, ""},
+		{`0x10`, ""},
+		{`1e`, ""},
+		{`--1`, ""},
+		{`[1]`, ""},
+		{`{"amount":1}`, ""},
+		{strings.Repeat("[", 10000) + strings.Repeat("]", 10000), ""},
+	}
+	for _, route := range amountRoutes {
+		for _, tt := range tests {
+			fake := &fakeStorage{}
+			_, h := newTestRouter(fake, testConfig())
+			w := serve(h, route.method, route.path, fmt.Sprintf(route.body, tt.amount), nil)
+			name := fmt.Sprintf("%s %s с суммой %s", route.method, route.path, truncate(tt.amount))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("%s: %d %s, want 400", name, w.Code, truncate(w.Body.String()))
+			} else if tt.code != "" {
+				checkErrorEnvelope(t, name, w, tt.code)
+			}
+			if calls := fake.called(); len(calls) > 0 {
+				t.Errorf("%s: запрос дошёл до хранилища: %v", name, calls)
+			}
+		}
+	}
+}
+
+func TestOversizedBodies(t *testing.T) {
+	cfg := testConfig()
+	huge := `{"amount":1` + strings.Repeat("0", cfg.HTTP.MaxBodyBytes) + `}`
+	deep := strings.Repeat(`{"a":`, cfg.HTTP.MaxBodyBytes/5+1) + "1" + strings.Repeat("}", cfg.HTTP.MaxBodyBytes/5+1)
+	for _, route := range append(amountRoutes, struct{ method, path, body string }{"PUT", "/api/admin/period-close", `{"through":%s}`}) {
+		for _, body := range []string{huge, deep, fmt.Sprintf(route.body, "1"+strings.Repeat(" ", cfg.HTTP.MaxBodyBytes))} {
+			fake := &fakeStorage{}
+			_, h := newTestRouter(fake, cfg)
+			w := serve(h, route.method, route.path, body, nil)
+			name := fmt.Sprintf("%s %s с телом %d байт", route.method, route.path, len(body))
+			if w.Code != http.StatusRequestEntityTooLarge {
+				t.Errorf("%s: %d %s, want 413", name, w.Code, truncate(w.Body.String()))
+			} else {
+				checkErrorEnvelope(t, name, w, CodeRequestTooLarge)
+			}
+			if calls := fake.called(); len(calls) > 0 {
+				t.Errorf("%s: запрос дошёл до хранилища: %v", name, calls)
+			}
+		}
+	}
+
+	// Снимок для восстановления ограничен своим, большим пределом.
+	snapshot := `{"version":1,"wallets":[]}` + strings.Repeat(" ", cfg.HTTP.MaxBodyBytes)
+	fake := &fakeStorage{}
+	_, h := newTestRouter(fake, cfg)
+	if w := serve(h, "POST", "/api/admin/restore", snapshot, nil); w.Code == http.StatusRequestEntityTooLarge {
+		t.Errorf("снимок %d байт отклонён: %s", len(snapshot), truncate(w.Body.String()))
+	}
+	snapshot = `{"version":1,"wallets":[]}` + strings.Repeat(" ", cfg.HTTP.MaxRestoreBytes)
+	fake = &fakeStorage{}
+	_, h = newTestRouter(fake, cfg)
+	if w := serve(h, "POST", "/api/admin/restore", snapshot, nil); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("снимок %d байт: %d %s, want 413", len(snapshot), w.Code, truncate(w.Body.String()))
+	}
+	if calls := fake.called(); len(calls) > 0 {
+		t.Errorf("снимок больше MAX_RESTORE_BYTES дошёл до хранилища: %v", calls)
+	}
+}
+
+func FuzzSendAmount(f *testing.F) {
+	for _, seed := range []string{`"10"`, `10`, `1e-8`, `1e308`, `1e400`, `-1e400`, `0.000000001`, `1e999999999`, `"1e5"`, `NaN`, `[[[1]]]`} {
+		f.Add(seed)
+	}
+	cfg := testConfig()
+	body := amountRoutes[0].body
+	f.Fuzz(func(t *testing.T, amount string) {
+		fake := &fakeStorage{}
+		_, h := newTestRouter(fake, cfg)
+		w := serve(h, "POST", "/api/send", fmt.Sprintf(body, amount), nil)
+		if w.Code >= 500 {
+			t.Fatalf("сумма %q: %d %s", amount, w.Code, truncate(w.Body.String()))
+		}
+		// Сумма, которая сама не является значением JSON, может менять другие поля
+		// тела; такие тела проверяются только на отсутствие 5xx.
+		if len(fake.called()) == 0 || !json.Valid([]byte(amount)) {
+			return
+		}
+		// До хранилища доходят только конечные суммы в допустимом диапазоне.
+		var v money.Amount
+		if err := v.UnmarshalJSON([]byte(strings.TrimSpace(amount))); err != nil {
+			t.Fatalf("сумма %q дошла до хранилища, хотя не разбирается: %v", amount, err)
+		}
+		x := float64(v)
+		if math.IsNaN(x) || math.IsInf(x, 0) || x < minAmount || x > float64(cfg.MaxAmount) {
+			t.Fatalf("сумма %q (%v) вне диапазона дошла до хранилища", amount, x)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// Ключ настройки режима обслуживания в таблице settings.
+	settingMaintenance = "maintenance"
+	// Через сколько секунд клиенту предлагается повторить изменяющий запрос.
+	maintenanceRetryAfter = "60"
+)
+
+type maintenanceRequest struct {
+	Enabled bool `json:"enabled"`
+	Persist bool `json:"persist"`
+}
+
+type maintenanceResponse struct {
+	Maintenance bool `json:"maintenance"`
+}
+
+type readyResponse struct {
+	Status      string   `json:"status"`
+	Maintenance bool     `json:"maintenance"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+// LoadMaintenance восстанавливает режим обслуживания при запуске: сначала из
+// сохранённой настройки, затем из конфигурации (MAINTENANCE_MODE), которая,
+// если задана, имеет приоритет.
+func (a *API) LoadMaintenance(ctx context.Context) error {
+	value, ok, err := a.db.GetSetting(ctx, settingMaintenance)
+	if err != nil {
+		return err
+	}
+	if ok {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("некорректное сохранённое значение режима обслуживания %q: %w", value, err)
+		}
+		a.maintenance.Store(enabled)
+	}
+
+	if a.cfg.MaintenanceMode != nil {
+		a.maintenance.Store(*a.cfg.MaintenanceMode)
+	}
+
+	if a.maintenance.Load() {
+		log.Println("сервис запущен в режиме обслуживания: изменяющие запросы отклоняются")
+	}
+	return nil
+}
+
+// maintenanceGuard отклоняет запросы с кодом 503, пока включён режим обслуживания.
+// Применяется ко всем изменяющим маршрутам до обращения к базе данных.
+func (a *API) maintenanceGuard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.maintenance.Load() {
+			w.Header().Set("Retry-After", maintenanceRetryAfter)
+			writeError(w, http.StatusServiceUnavailable, CodeMaintenance, "сервис находится в режиме обслуживания", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *API) GetMaintenance(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(maintenanceResponse{Maintenance: a.maintenance.Load()})
+}
+
+func (a *API) SetMaintenance(w http.ResponseWriter, r *http.Request) {
+	var req maintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "неверный формат запроса")
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Persist {
+		if err := a.db.SetSetting(r.Context(), settingMaintenance, strconv.FormatBool(req.Enabled)); err != nil {
+			log.Printf("ошибка сохранения режима обслуживания: %v", err)
+			writeStorageError(w, r, err)
+			return
+		}
+	}
+
+	a.maintenance.Store(req.Enabled)
+	log.Printf("режим обслуживания: %t", req.Enabled)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(maintenanceResponse{Maintenance: req.Enabled})
+}
+
+// Readyz сообщает о готовности принимать запросы. В режиме обслуживания сервис
+// остаётся готовым для чтения, что отражается в поле maintenance.
+func (a *API) Readyz(w http.ResponseWriter, r *http.Request) {
+	resp := readyResponse{Status: "ready", Maintenance: a.maintenance.Load(), Warnings: a.readyWarnings()}
+	status := http.StatusOK
+
+	if err := a.db.Ping(r.Context()); err != nil {
+		log.Printf("проверка готовности не пройдена: %v", err)
+		resp.Status = "unavailable"
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"go-payments/internal/money"
+	"go-payments/internal/redact"
+	"go-payments/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type maxBalanceRequest struct {
+	// MaxBalance - предельный баланс; null снимает ограничение.
+	MaxBalance *money.Amount `json:"max_balance"`
+}
+
+// SetWalletMaxBalance назначает кошельку предельный баланс или снимает его.
+func (a *API) SetWalletMaxBalance(w http.ResponseWriter, r *http.Request) {
+	address := chi.URLParam(r, "address")
+
+	var req maxBalanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.badBody(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	var maxBalance *float64
+	if req.MaxBalance != nil {
+		if *req.MaxBalance < 0 {
+			badRequest(w, "поле 'max_balance' не может быть отрицательным")
+			return
+		}
+		if !a.amountInRange(w, "max_balance", *req.MaxBalance) {
+			return
+		}
+		v := float64(*req.MaxBalance)
+		maxBalance = &v
+	}
+
+	if err := a.db.SetWalletMaxBalance(r.Context(), address, maxBalance); err != nil {
+		if !errors.Is(err, storage.ErrWalletNotFound) {
+			log.Printf("ошибка назначения предельного баланса кошельку %s: %v", redact.Address(address), err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+	log.Printf("предельный баланс кошелька %s изменён (%s)", redact.Address(address), r.Header.Get(actorHeader))
+
+	wallet, err := a.db.GetWalletBalance(r.Context(), address)
+	if err != nil {
+		log.Printf("ошибка получения кошелька %s: %v", redact.Address(address), err)
+		writeStorageError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, wallet)
+}
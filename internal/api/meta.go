@@ -0,0 +1,143 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"sort"
+
+	"go-payments/internal/models"
+	"go-payments/internal/storage"
+)
+
+// errorDescriptions - краткие описания кодов ошибок для /api/meta/error-codes.
+var errorDescriptions = map[string]string{
+	CodeInvalidRequest:          "Неверный формат запроса или параметров",
+	CodeInvalidAddress:          "Адрес кошелька не соответствует ни одной схеме адресов",
+	CodeInvalidCursor:           "Курсор пагинации изменён, выдан для другого списка или фильтра или устарел",
+	CodeAmountOutOfRange:        "Сумма вне допустимого диапазона (MAX_AMOUNT) или не является конечным числом",
+	CodeRequestTooLarge:         "Тело запроса больше MAX_BODY_BYTES (снимка в /api/admin/restore - MAX_RESTORE_BYTES)",
+	CodeExampleNotFound:         "Примеры маршрута с таким именем не найдены",
+	CodeWalletNotFound:          "Кошелёк не найден",
+	CodeSenderNotFound:          "Кошелёк отправителя не найден",
+	CodeRecipientNotFound:       "Кошелёк получателя не найден",
+	CodeInsufficientFunds:       "Недостаточно средств",
+	CodeRecipientLimitExceeded:  "Перевод превысил бы предельный баланс кошелька получателя",
+	CodeSystemWallet:            "Системный кошелёк недоступен для переводов клиентов",
+	CodeInternalError:           "Внутренняя ошибка сервера",
+	CodeDeadlineExceeded:        "Истёк таймаут запроса",
+	CodeMaintenance:             "Режим обслуживания",
+	CodeOverloaded:              "Слишком много одновременных переводов, повторите позже",
+	CodeWalletNotPurgeable:      "Кошелёк нельзя удалить: он не в архиве или баланс не нулевой",
+	CodeNoteNotFound:            "Заметка к кошельку не найдена",
+	CodeLabelNotFound:           "Метка кошелька не найдена",
+	CodeLabelTaken:              "Метка уже назначена другому кошельку",
+	CodeTxNotFound:              "Транзакция не найдена",
+	CodeNotRecipient:            "Кошелёк не является получателем транзакции",
+	CodeGroupNotFound:           "В группе переводов нет транзакций",
+	CodeIdempotencyKeyNotFound:  "Ключ идемпотентности не найден",
+	CodeIdempotencyKeyReused:    "Ключ идемпотентности уже использован для перевода с другими отправителем, получателем или суммой",
+	CodePeriodClosed:            "Транзакция относится к закрытому учётному периоду и не изменяется",
+	CodePeriodCloseBackward:     "Учётный период уже закрыт по более позднюю дату; перенос назад - только с force",
+	CodeWalletsExist:            "Снимок можно восстановить только в пустую базу кошельков",
+	CodeRetriesExhausted:        "Перевод не выполнен: повторы после конфликтов с параллельными переводами исчерпаны",
+	CodePaymentRequestNotFound:  "Запрос на оплату не найден",
+	CodePaymentRequestFulfilled: "Запрос на оплату уже оплачен",
+	CodePaymentRequestExpired:   "Срок запроса на оплату истёк",
+	CodeIncomingCursorNotFound:  "Курсор потребителя входящих переводов не найден",
+	CodeThresholdNotFound:       "Правило порога баланса не найдено",
+	CodeTooManyThresholds:       "У кошелька слишком много правил порога баланса",
+	CodeWithdrawalNotFound:      "Вывод средств с таким идентификатором не найден",
+	CodeWithdrawalSettled:       "Вывод средств уже подтверждён или отклонён; в details.status - его состояние",
+	CodeWithdrawalsDisabled:     "Выводы средств отключены: не задан клиринговый кошелёк WITHDRAWAL_WALLET",
+	CodeCheckpointNotFound:      "Контрольной точки балансов за эту дату нет",
+	CodeCheckpointsDisabled:     "Контрольные точки балансов отключены: не задан ключ подписи CHECKPOINT_SIGNING_KEY",
+}
+
+// statusTxErrCodes - код TransactionError, с которым Execute записывает неуспешный
+// перевод с данным статусом. Успешному переводу ошибка не соответствует.
+var statusTxErrCodes = map[models.TransactionStatus]storage.TxErrCode{
+	models.StatusFailedInsufficientFunds:      storage.CodeInsufficientFunds,
+	models.StatusFailedRecipientNotFound:      storage.CodeRecipientNotFound,
+	models.StatusFailedSenderNotFound:         storage.CodeSenderNotFound,
+	models.StatusFailedRecipientLimitExceeded: storage.CodeRecipientLimitExceeded,
+	models.StatusUnknownError:                 storage.CodeInternalError,
+}
+
+// statusDescriptions - краткие описания статусов транзакций.
+var statusDescriptions = map[models.TransactionStatus]string{
+	models.StatusSuccess:                      "Перевод выполнен",
+	models.StatusFailedInsufficientFunds:      "Отклонён: недостаточно средств",
+	models.StatusFailedRecipientNotFound:      "Отклонён: кошелёк получателя не найден",
+	models.StatusFailedSenderNotFound:         "Отклонён: кошелёк отправителя не найден",
+	models.StatusFailedRecipientLimitExceeded: "Отклонён: превышен предельный баланс получателя",
+	models.StatusUnknownError:                 "Не выполнен из-за внутренней ошибки",
+	models.StatusZeroNote:                     "Заметка администратора с нулевой суммой: средства не перемещались",
+}
+
+type errorCodeInfo struct {
+	Code        string `json:"code"`
+	HTTPStatus  int    `json:"http_status"`
+	Description string `json:"description"`
+}
+
+type transactionStatusInfo struct {
+	Status      models.TransactionStatus `json:"status"`
+	HTTPStatus  int                      `json:"http_status"`
+	ErrorCode   string                   `json:"error_code,omitempty"`
+	Description string                   `json:"description"`
+}
+
+// errorCatalog собирает коды ошибок из тех же таблиц, по которым отвечают
+// обработчики: txErrorMappings, sentinelErrorMappings и requestErrorMappings.
+func errorCatalog() []errorCodeInfo {
+	mappings := append([]errorMapping(nil), requestErrorMappings...)
+	for _, m := range txErrorMappings {
+		mappings = append(mappings, m)
+	}
+	for _, m := range sentinelErrorMappings {
+		mappings = append(mappings, m.errorMapping)
+	}
+
+	seen := make(map[errorMapping]bool)
+	var catalog []errorCodeInfo
+	for _, m := range mappings {
+		if seen[m] {
+			continue
+		}
+		seen[m] = true
+		description, ok := errorDescriptions[m.Code]
+		if !ok {
+			log.Printf("нет описания кода ошибки %s", m.Code)
+		}
+		catalog = append(catalog, errorCodeInfo{Code: m.Code, HTTPStatus: m.Status, Description: description})
+	}
+	sort.Slice(catalog, func(i, j int) bool { return catalog[i].Code < catalog[j].Code })
+	return catalog
+}
+
+// statusCatalog описывает каждый статус из models.TransactionStatuses вместе с
+// ответом /api/send, который его сопровождает. Заметку zero_note записывает не
+// /api/send, а /api/admin/zero-notes с ответом 201.
+func statusCatalog() []transactionStatusInfo {
+	catalog := make([]transactionStatusInfo, 0, len(models.TransactionStatuses))
+	for _, status := range models.TransactionStatuses {
+		info := transactionStatusInfo{Status: status, HTTPStatus: http.StatusOK, Description: statusDescriptions[status]}
+		if code, ok := statusTxErrCodes[status]; ok {
+			mapping := txErrorMappings[code]
+			info.HTTPStatus, info.ErrorCode = mapping.Status, mapping.Code
+		}
+		if status == models.StatusZeroNote {
+			info.HTTPStatus = http.StatusCreated
+		}
+		catalog = append(catalog, info)
+	}
+	return catalog
+}
+
+func (a *API) GetErrorCodes(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, errorCatalog())
+}
+
+func (a *API) GetTransactionStatuses(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, statusCatalog())
+}
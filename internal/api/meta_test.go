@@ -0,0 +1,89 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"go-payments/internal/models"
+	"go-payments/internal/storage"
+)
+
+func TestErrorCatalogComplete(t *testing.T) {
+	catalog := map[string]errorCodeInfo{}
+	for _, info := range errorCatalog() {
+		if info.Description == "" {
+			t.Errorf("в каталоге нет описания кода %s", info.Code)
+		}
+		catalog[info.Code] = info
+	}
+
+	for code := storage.CodeUnknown; code <= storage.LastTxErrCode; code++ {
+		mapping, ok := txErrorMappings[code]
+		if !ok {
+			t.Errorf("у TxErrCode %d нет ответа в txErrorMappings", code)
+			continue
+		}
+		if errorDescriptions[mapping.Code] == "" {
+			t.Errorf("TxErrCode %d: нет описания кода %s", code, mapping.Code)
+		}
+		if _, ok := catalog[mapping.Code]; !ok {
+			t.Errorf("TxErrCode %d: кода %s нет в /api/meta/error-codes", code, mapping.Code)
+		}
+	}
+	for _, m := range sentinelErrorMappings {
+		if _, ok := catalog[m.Code]; !ok || errorDescriptions[m.Code] == "" {
+			t.Errorf("ошибка %q: код %s без описания в каталоге", m.Err, m.Code)
+		}
+	}
+	for _, m := range requestErrorMappings {
+		if _, ok := catalog[m.Code]; !ok || errorDescriptions[m.Code] == "" {
+			t.Errorf("код запроса %s без описания в каталоге", m.Code)
+		}
+	}
+	// Описание без ответа, который его использует, - устаревшая строка таблицы.
+	for code := range errorDescriptions {
+		if _, ok := catalog[code]; !ok {
+			t.Errorf("описание кода %s, которого нет ни в одной таблице ответов", code)
+		}
+	}
+}
+
+func TestStatusCatalogComplete(t *testing.T) {
+	catalog := map[models.TransactionStatus]transactionStatusInfo{}
+	for _, info := range statusCatalog() {
+		catalog[info.Status] = info
+	}
+	for _, status := range models.TransactionStatuses {
+		info, ok := catalog[status]
+		if !ok {
+			t.Errorf("статуса %s нет в /api/meta/transaction-statuses", status)
+			continue
+		}
+		if info.Description == "" || statusDescriptions[status] == "" {
+			t.Errorf("нет описания статуса %s", status)
+		}
+		if info.HTTPStatus == 0 {
+			t.Errorf("статус %s без HTTP-статуса ответа", status)
+		}
+		if info.ErrorCode != "" && errorDescriptions[info.ErrorCode] == "" {
+			t.Errorf("статус %s: код ошибки %s без описания", status, info.ErrorCode)
+		}
+	}
+	if len(catalog) != len(models.TransactionStatuses) {
+		t.Errorf("в каталоге %d статусов, известно %d", len(catalog), len(models.TransactionStatuses))
+	}
+
+	// Каждый неуспешный статус, который записывает Execute, описан вместе с
+	// ответом /api/send для кода, от которого он получен.
+	for code := storage.CodeUnknown; code <= storage.LastTxErrCode; code++ {
+		status := storage.FailureStatus(&storage.TransactionError{Code: code})
+		info, ok := catalog[status]
+		if !ok {
+			t.Errorf("TxErrCode %d: статус %s не описан", code, status)
+			continue
+		}
+		if info.HTTPStatus == http.StatusOK || info.ErrorCode == "" {
+			t.Errorf("TxErrCode %d: статус %s описан как успешный ответ %d", code, status, info.HTTPStatus)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go-payments/internal/models"
+	"go-payments/internal/redact"
+	"go-payments/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Заголовок, которым административный клиент указывает автора изменения.
+// Аутентификации нет, поэтому значение сохраняется как есть.
+const actorHeader = "X-Actor"
+
+type addNoteRequest struct {
+	Text string `json:"text"`
+}
+
+// Заметки к кошелькам отдаются только административными маршрутами; публичные
+// обработчики кошельков их не читают.
+
+func (a *API) AddWalletNote(w http.ResponseWriter, r *http.Request) {
+	address := chi.URLParam(r, "address")
+
+	var req addNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "неверный формат запроса")
+		return
+	}
+	defer r.Body.Close()
+
+	req.Text = strings.TrimSpace(req.Text)
+	if req.Text == "" {
+		badRequest(w, "текст заметки не может быть пустым")
+		return
+	}
+	if utf8.RuneCountInString(req.Text) > models.MaxWalletNoteLength {
+		badRequest(w, "текст заметки длиннее 2000 символов")
+		return
+	}
+
+	note, err := a.db.AddWalletNote(r.Context(), address, r.Header.Get(actorHeader), req.Text)
+	if err != nil {
+		if !errors.Is(err, storage.ErrWalletNotFound) {
+			log.Printf("ошибка добавления заметки к кошельку %s: %v", redact.Address(address), err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(note)
+}
+
+func (a *API) ListWalletNotes(w http.ResponseWriter, r *http.Request) {
+	address := chi.URLParam(r, "address")
+
+	notes, err := a.db.ListWalletNotes(r.Context(), address)
+	if err != nil {
+		log.Printf("ошибка получения заметок к кошельку %s: %v", redact.Address(address), err)
+		writeStorageError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notes)
+}
+
+func (a *API) RedactWalletNote(w http.ResponseWriter, r *http.Request) {
+	address := chi.URLParam(r, "address")
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		badRequest(w, "идентификатор заметки должен быть положительным числом")
+		return
+	}
+
+	note, err := a.db.RedactWalletNote(r.Context(), address, id)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNoteNotFound) {
+			log.Printf("ошибка скрытия заметки %d: %v", id, err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+	log.Printf("заметка %d к кошельку %s скрыта (%s)", id, redact.Address(address), r.Header.Get(actorHeader))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(note)
+}
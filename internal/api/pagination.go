@@ -0,0 +1,103 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// Количество элементов в публичных списках по умолчанию и максимум.
+	defaultListLimit = 10
+	maxListLimit     = 100
+)
+
+// Заголовок с количеством строк, пропущенных в списке из-за ошибок чтения.
+const skippedRowsHeader = "X-Skipped-Rows"
+
+// setSkippedRows сообщает клиенту, что список может быть неполным.
+func setSkippedRows(w http.ResponseWriter, skipped int) {
+	if skipped > 0 {
+		w.Header().Set(skippedRowsHeader, strconv.Itoa(skipped))
+	}
+}
+
+// pagination - параметры пагинации списка.
+type pagination struct {
+	Limit  int
+	Offset int
+}
+
+// paginationError описывает некорректный параметр пагинации.
+type paginationError struct {
+	Param string
+	Value string
+	Max   int
+	// Unsupported - список не поддерживает параметр (offset у списков без смещения).
+	Unsupported bool
+}
+
+func (e *paginationError) Error() string {
+	if e.Unsupported {
+		return fmt.Sprintf("параметр '%s' не поддерживается этим списком (получено '%s')", e.Param, e.Value)
+	}
+	if e.Param == "offset" {
+		return fmt.Sprintf("параметр 'offset' должен быть неотрицательным целым числом (получено '%s')", e.Value)
+	}
+	return fmt.Sprintf("параметр '%s' должен быть целым числом от 1 до %d (получено '%s')", e.Param, e.Max, e.Value)
+}
+
+// conflictingLimitError возвращается, если count и limit заданы с разными значениями.
+type conflictingLimitError struct {
+	Count, Limit string
+}
+
+func (e *conflictingLimitError) Error() string {
+	return fmt.Sprintf("параметры 'count' и 'limit' - синонимы и не могут различаться (получено '%s' и '%s')", e.Count, e.Limit)
+}
+
+// parsePagination читает размер страницы из `limit` или его синонима `count`;
+// отсутствующий параметр берётся из defaults. Списки без смещения (с курсором или
+// фиксированным порядком) отклоняют `offset`, а не возвращают молча первую страницу.
+func parsePagination(r *http.Request, defaults pagination, max int) (pagination, error) {
+	return parsePage(r, defaults, max, false)
+}
+
+// parseOffsetPagination читает размер страницы так же, как parsePagination, и
+// смещение из `offset`.
+func parseOffsetPagination(r *http.Request, defaults pagination, max int) (pagination, error) {
+	return parsePage(r, defaults, max, true)
+}
+
+func parsePage(r *http.Request, defaults pagination, max int, withOffset bool) (pagination, error) {
+	query := r.URL.Query()
+	p := defaults
+
+	param, value := "limit", query.Get("limit")
+	if count := query.Get("count"); count != "" {
+		if value != "" && value != count {
+			return p, &conflictingLimitError{Count: count, Limit: value}
+		}
+		param, value = "count", count
+	}
+	if value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > max {
+			return p, &paginationError{Param: param, Value: value, Max: max}
+		}
+		p.Limit = limit
+	}
+
+	if value := query.Get("offset"); value != "" {
+		if !withOffset {
+			return p, &paginationError{Param: "offset", Value: value, Unsupported: true}
+		}
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return p, &paginationError{Param: "offset", Value: value}
+		}
+		p.Offset = offset
+	}
+
+	return p, nil
+}
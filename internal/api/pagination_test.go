@@ -0,0 +1,86 @@
+package api
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParsePagination(t *testing.T) {
+	defaults := pagination{Limit: 10}
+	tests := []struct {
+		query      string
+		withOffset bool
+		want       pagination
+		// wantErr - текст ошибки; пустой - ошибки нет.
+		wantErr string
+	}{
+		{"", false, pagination{Limit: 10}, ""},
+		{"count=5", false, pagination{Limit: 5}, ""},
+		{"limit=5", false, pagination{Limit: 5}, ""},
+		{"count=5&limit=5", false, pagination{Limit: 5}, ""},
+		{"limit=1", false, pagination{Limit: 1}, ""},
+		{"limit=100", false, pagination{Limit: 100}, ""},
+		{"count=", false, pagination{Limit: 10}, ""},
+		{"limit=20&offset=40", true, pagination{Limit: 20, Offset: 40}, ""},
+		{"offset=0", true, pagination{Limit: 10}, ""},
+
+		{"count=abc", false, pagination{}, "параметр 'count' должен быть целым числом от 1 до 100 (получено 'abc')"},
+		{"limit=abc", false, pagination{}, "параметр 'limit' должен быть целым числом от 1 до 100 (получено 'abc')"},
+		{"count=0", false, pagination{}, "параметр 'count' должен быть целым числом от 1 до 100 (получено '0')"},
+		{"count=-1", false, pagination{}, "параметр 'count' должен быть целым числом от 1 до 100 (получено '-1')"},
+		{"count=101", false, pagination{}, "параметр 'count' должен быть целым числом от 1 до 100 (получено '101')"},
+		{"limit=1.5", false, pagination{}, "параметр 'limit' должен быть целым числом от 1 до 100 (получено '1.5')"},
+		{"limit=1e2", false, pagination{}, "параметр 'limit' должен быть целым числом от 1 до 100 (получено '1e2')"},
+		{"limit=%2B5", false, pagination{Limit: 5}, ""},
+		{"limit=%205", false, pagination{}, "параметр 'limit' должен быть целым числом от 1 до 100 (получено ' 5')"},
+		{"limit=99999999999999999999", false, pagination{}, "параметр 'limit' должен быть целым числом от 1 до 100 (получено '99999999999999999999')"},
+		{"count=5&limit=6", false, pagination{}, "параметры 'count' и 'limit' - синонимы и не могут различаться (получено '5' и '6')"},
+		{"count=abc&limit=5", false, pagination{}, "параметры 'count' и 'limit' - синонимы и не могут различаться (получено 'abc' и '5')"},
+		{"count=abc&limit=abc", false, pagination{}, "параметр 'count' должен быть целым числом от 1 до 100 (получено 'abc')"},
+
+		{"offset=50", false, pagination{}, "параметр 'offset' не поддерживается этим списком (получено '50')"},
+		{"count=5&offset=0", false, pagination{}, "параметр 'offset' не поддерживается этим списком (получено '0')"},
+		{"offset=-1", true, pagination{}, "параметр 'offset' должен быть неотрицательным целым числом (получено '-1')"},
+		{"offset=abc", true, pagination{}, "параметр 'offset' должен быть неотрицательным целым числом (получено 'abc')"},
+		{"offset=1.5", true, pagination{}, "параметр 'offset' должен быть неотрицательным целым числом (получено '1.5')"},
+		{"limit=0&offset=-1", true, pagination{}, "параметр 'limit' должен быть целым числом от 1 до 100 (получено '0')"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/api/list?"+tt.query, nil)
+		parse := parsePagination
+		if tt.withOffset {
+			parse = parseOffsetPagination
+		}
+		got, err := parse(r, defaults, maxListLimit)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("%q (offset: %v): ошибка %v, want %q", tt.query, tt.withOffset, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q (offset: %v): неожиданная ошибка %v", tt.query, tt.withOffset, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q (offset: %v) = %+v, want %+v", tt.query, tt.withOffset, got, tt.want)
+		}
+	}
+}
+
+func TestParsePaginationErrorTypes(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/list?count=5&limit=6", nil)
+	_, err := parsePagination(r, pagination{Limit: 10}, maxListLimit)
+	var conflict *conflictingLimitError
+	if !errors.As(err, &conflict) {
+		t.Errorf("count и limit с разными значениями: %T, want *conflictingLimitError", err)
+	}
+
+	r = httptest.NewRequest("GET", "/api/list?limit=0", nil)
+	_, err = parsePagination(r, pagination{Limit: 10}, maxListLimit)
+	var pageErr *paginationError
+	if !errors.As(err, &pageErr) || pageErr.Param != "limit" || pageErr.Max != maxListLimit {
+		t.Errorf("limit=0: %#v, want *paginationError для limit с максимумом %d", err, maxListLimit)
+	}
+}
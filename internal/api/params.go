@@ -0,0 +1,127 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Заголовок, включающий строгую проверку query-параметров для одного запроса.
+const strictParamsHeader = "X-Strict-Params"
+
+// Параметры, которые принимает любой маршрут.
+var commonParams = []string{"amount_format"}
+
+// Максимальное расстояние редактирования для подсказки "возможно, имелся в виду".
+const maxSuggestionDistance = 2
+
+// unknownParam - неизвестный параметр и ближайший известный, если он есть.
+type unknownParam struct {
+	Param      string `json:"param"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// params объявляет query-параметры маршрута. В строгом режиме (заголовок
+// X-Strict-Params: true или STRICT_PARAMS=true) запрос с другими параметрами
+// отклоняется с кодом 400 и подсказками; иначе лишние параметры игнорируются.
+// Имя вида "metadata.*" разрешает все параметры с этим префиксом.
+func (a *API) params(known ...string) func(http.Handler) http.Handler {
+	known = append(known, commonParams...)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !a.strictParams(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			unknown := unknownParams(r, known)
+			if len(unknown) > 0 {
+				names := make([]string, len(unknown))
+				for i, u := range unknown {
+					names[i] = u.Param
+					if u.Suggestion != "" {
+						names[i] += fmt.Sprintf(" (возможно, %s)", u.Suggestion)
+					}
+				}
+				writeError(w, http.StatusBadRequest, CodeInvalidRequest,
+					"неизвестные параметры запроса: "+strings.Join(names, ", "), unknown)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// strictParams сообщает, включён ли строгий режим для запроса.
+func (a *API) strictParams(r *http.Request) bool {
+	if a.cfg.StrictParams {
+		return true
+	}
+	strict, _ := strconv.ParseBool(r.Header.Get(strictParamsHeader))
+	return strict
+}
+
+// unknownParams возвращает параметры запроса, которых нет среди known, по алфавиту.
+func unknownParams(r *http.Request, known []string) []unknownParam {
+	var unknown []unknownParam
+	for name := range r.URL.Query() {
+		if paramKnown(name, known) {
+			continue
+		}
+		unknown = append(unknown, unknownParam{Param: name, Suggestion: suggestParam(name, known)})
+	}
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].Param < unknown[j].Param })
+	return unknown
+}
+
+func paramKnown(name string, known []string) bool {
+	for _, k := range known {
+		if prefix, ok := strings.CutSuffix(k, "*"); ok {
+			if strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+				return true
+			}
+		} else if name == k {
+			return true
+		}
+	}
+	return false
+}
+
+// suggestParam возвращает ближайший по расстоянию редактирования известный
+// параметр, если он не дальше maxSuggestionDistance.
+func suggestParam(name string, known []string) string {
+	best, bestDistance := "", maxSuggestionDistance+1
+	for _, k := range known {
+		if strings.HasSuffix(k, "*") {
+			continue
+		}
+		if d := editDistance(strings.ToLower(name), k); d < bestDistance {
+			best, bestDistance = k, d
+		}
+	}
+	return best
+}
+
+// editDistance - расстояние Левенштейна между строками в рунах.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-payments/internal/models"
+	"go-payments/internal/money"
+	"go-payments/internal/redact"
+	"go-payments/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Префикс ключа идемпотентности перевода по запросу на оплату: у запроса может
+// быть только один перевод, даже если два плательщика платят одновременно.
+const paymentRequestKeyPrefix = "payment-request:"
+
+type createPaymentRequestRequest struct {
+	To        string       `json:"to"`
+	Amount    money.Amount `json:"amount"`
+	Reference string       `json:"reference"`
+	// ExpiresAt - срок действия; по умолчанию через PAYMENT_REQUEST_TTL.
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type payPaymentRequestRequest struct {
+	From string `json:"from"`
+}
+
+type payPaymentRequestResponse struct {
+	Status         string                 `json:"status"`
+	Transaction    *models.Transaction    `json:"transaction"`
+	PaymentRequest *models.PaymentRequest `json:"payment_request"`
+}
+
+// CreatePaymentRequest создаёт запрос на оплату кошельку и возвращает его с токеном,
+// которым запросом делятся с плательщиком.
+func (a *API) CreatePaymentRequest(w http.ResponseWriter, r *http.Request) {
+	var req createPaymentRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.badBody(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	if !normalizeAddress(w, "to", &req.To) {
+		return
+	}
+	if req.Amount <= 0 {
+		badRequest(w, "сумма запроса на оплату должна быть положительной")
+		return
+	}
+	if !a.amountInRange(w, "amount", req.Amount) {
+		return
+	}
+	req.Reference = strings.TrimSpace(req.Reference)
+	if len(req.Reference) > models.MaxPaymentReferenceLength {
+		badRequest(w, fmt.Sprintf("поле 'reference' длиннее %d символов", models.MaxPaymentReferenceLength))
+		return
+	}
+	expiresAt := time.Now().Add(a.cfg.PaymentRequestTTL)
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			badRequest(w, "поле 'expires_at' должно быть в будущем")
+			return
+		}
+		expiresAt = *req.ExpiresAt
+	}
+
+	// Запрос на оплату несуществующему кошельку отклоняется сразу, а не при оплате.
+	if _, err := a.db.GetWalletBalance(r.Context(), req.To); err != nil {
+		if !errors.Is(err, storage.ErrWalletNotFound) {
+			log.Printf("ошибка получения кошелька %s: %v", redact.Address(req.To), err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+
+	pr, err := a.db.CreatePaymentRequest(r.Context(), models.PaymentRequest{
+		To:        req.To,
+		Amount:    req.Amount,
+		Reference: req.Reference,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		log.Printf("ошибка создания запроса на оплату кошельку %s: %v", redact.Address(req.To), err)
+		writeStorageError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, r, pr)
+}
+
+// GetPaymentRequest возвращает запрос на оплату по токену для страницы плательщика.
+func (a *API) GetPaymentRequest(w http.ResponseWriter, r *http.Request) {
+	pr, err := a.db.GetPaymentRequest(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		if !errors.Is(err, storage.ErrPaymentRequestNotFound) {
+			log.Printf("ошибка получения запроса на оплату: %v", err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+	writeJSON(w, r, pr)
+}
+
+// PayPaymentRequest оплачивает запрос обычным переводом с кошелька from и отмечает
+// запрос оплаченным. Оплаченный запрос - 409, истёкший - 410.
+func (a *API) PayPaymentRequest(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+
+	var req payPaymentRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "неверный формат запроса")
+		return
+	}
+	defer r.Body.Close()
+
+	if !normalizeAddress(w, "from", &req.From) {
+		return
+	}
+
+	pr, err := a.db.GetPaymentRequest(r.Context(), token)
+	if err != nil {
+		if !errors.Is(err, storage.ErrPaymentRequestNotFound) {
+			log.Printf("ошибка получения запроса на оплату: %v", err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+	switch pr.Status {
+	case models.PaymentRequestFulfilled:
+		writeStorageError(w, r, storage.ErrPaymentRequestFulfilled)
+		return
+	case models.PaymentRequestExpired:
+		writeStorageError(w, r, storage.ErrPaymentRequestExpired)
+		return
+	}
+	if req.From == pr.To {
+		badRequest(w, "нельзя отправить деньги самому себе")
+		return
+	}
+
+	key := paymentRequestKeyPrefix + pr.Token
+	var stats storage.ExecStats
+	transaction, err := a.db.Execute(storage.WithExecStats(r.Context(), &stats), models.Transfer{
+		From:           req.From,
+		To:             pr.To,
+		Amount:         float64(pr.Amount),
+		Reference:      pr.Reference,
+		IdempotencyKey: key,
+	})
+	setExecStats(w, stats, err)
+	if errors.Is(err, storage.ErrIdempotencyKeyReused) {
+		// Запрос уже оплатил другой плательщик. Если его ответ не успел отметить
+		// запрос оплаченным, это делается здесь.
+		if k, kerr := a.db.GetIdempotencyKey(r.Context(), key); kerr == nil {
+			if _, ferr := a.db.FulfillPaymentRequest(r.Context(), pr.Token, k.TransactionID); ferr != nil &&
+				!errors.Is(ferr, storage.ErrPaymentRequestFulfilled) {
+				log.Printf("ошибка отметки оплаты запроса транзакцией %d: %v", k.TransactionID, ferr)
+			}
+		}
+		writeStorageError(w, r, storage.ErrPaymentRequestFulfilled)
+		return
+	}
+	if err != nil {
+		log.Printf("ошибка оплаты запроса с кошелька %s на кошелёк %s на сумму %s (попыток: %d, %s): %v",
+			redact.Address(req.From), redact.Address(pr.To), money.FormatAmount(float64(pr.Amount)), stats.Attempts, stats.Elapsed, err)
+		writeStorageError(w, r, err)
+		return
+	}
+
+	pr, err = a.db.FulfillPaymentRequest(r.Context(), pr.Token, transaction.ID)
+	if err != nil {
+		// Перевод выполнен; повтор оплаты тем же плательщиком вернёт его и отметит запрос.
+		log.Printf("ошибка отметки оплаты запроса транзакцией %d: %v", transaction.ID, err)
+		writeStorageError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, payPaymentRequestResponse{Status: "success", Transaction: transaction, PaymentRequest: pr})
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"go-payments/internal/models"
+	"go-payments/internal/money"
+	"go-payments/internal/storage"
+)
+
+// paymentStorage хранит запросы на оплату и ключи идемпотентности в памяти по
+// правилам настоящего хранилища: повтор перевода с ключом тем же плательщиком
+// возвращает его транзакцию, с ключом другого плательщика - ErrIdempotencyKeyReused.
+type paymentStorage struct {
+	*fakeStorage
+
+	mu       sync.Mutex
+	requests map[string]models.PaymentRequest
+	keys     map[string]models.Transaction
+	executed int
+	// staleReads отдаёт запросы открытыми, как GetPaymentRequest, прочитанный
+	// до оплаты одновременным плательщиком.
+	staleReads bool
+}
+
+func newPaymentStorage(requests ...models.PaymentRequest) *paymentStorage {
+	s := &paymentStorage{fakeStorage: &fakeStorage{}, requests: map[string]models.PaymentRequest{}, keys: map[string]models.Transaction{}}
+	for _, pr := range requests {
+		s.requests[pr.Token] = pr
+	}
+	return s
+}
+
+func (s *paymentStorage) GetPaymentRequest(ctx context.Context, token string) (*models.PaymentRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pr, ok := s.requests[token]
+	if !ok {
+		return nil, storage.ErrPaymentRequestNotFound
+	}
+	if s.staleReads {
+		pr.Status = models.PaymentRequestOpen
+	} else if pr.Status == models.PaymentRequestOpen && !pr.ExpiresAt.After(time.Now()) {
+		pr.Status = models.PaymentRequestExpired
+	}
+	return &pr, nil
+}
+
+func (s *paymentStorage) FulfillPaymentRequest(ctx context.Context, token string, transactionID int) (*models.PaymentRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pr, ok := s.requests[token]
+	if !ok {
+		return nil, storage.ErrPaymentRequestNotFound
+	}
+	if pr.Status != models.PaymentRequestOpen && (pr.TransactionID == nil || *pr.TransactionID != transactionID) {
+		return nil, storage.ErrPaymentRequestFulfilled
+	}
+	if pr.FulfilledAt == nil {
+		now := time.Now().UTC()
+		pr.FulfilledAt = &now
+	}
+	pr.Status, pr.TransactionID = models.PaymentRequestFulfilled, &transactionID
+	s.requests[token] = pr
+	return &pr, nil
+}
+
+func (s *paymentStorage) Execute(ctx context.Context, t models.Transfer) (*models.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if prev, ok := s.keys[t.IdempotencyKey]; ok {
+		if prev.From != t.From || prev.To != t.To || float64(prev.Amount) != t.Amount {
+			return nil, storage.ErrIdempotencyKeyReused
+		}
+		return &prev, nil
+	}
+	s.executed++
+	transaction := models.Transaction{ID: s.executed, From: t.From, To: t.To, Amount: money.Amount(t.Amount),
+		Reference: t.Reference, Status: models.StatusSuccess, Timestamp: time.Now().UTC()}
+	s.keys[t.IdempotencyKey] = transaction
+	return &transaction, nil
+}
+
+func (s *paymentStorage) GetIdempotencyKey(ctx context.Context, key string) (*models.IdempotencyKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	transaction, ok := s.keys[key]
+	if !ok {
+		return nil, storage.ErrIdempotencyKeyNotFound
+	}
+	return &models.IdempotencyKey{Key: key, TransactionID: transaction.ID}, nil
+}
+
+func (s *paymentStorage) request(token string) models.PaymentRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[token]
+}
+
+func (s *paymentStorage) executions() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.executed
+}
+
+func payRequest(t *testing.T, db Storage, token, from string) *payPaymentRequestResponse {
+	t.Helper()
+	_, router := newTestRouter(db, testConfig())
+	w := serve(router, http.MethodPost, "/api/payment-requests/"+token+"/pay", `{"from":"`+from+`"}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("оплата %s с %s: статус %d, want 200; тело %s", token, from, w.Code, truncate(w.Body.String()))
+	}
+	var resp payPaymentRequestResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return &resp
+}
+
+func TestPayPaymentRequest(t *testing.T) {
+	payee, payer, other := testAddress(1), testAddress(2), testAddress(3)
+	open := models.PaymentRequest{Token: "open", To: payee, Amount: 25, Reference: "INV-7",
+		Status: models.PaymentRequestOpen, ExpiresAt: time.Now().Add(time.Hour)}
+	db := newPaymentStorage(open)
+
+	// Оплата - перевод на сумму запроса получателю запроса, после которого запрос оплачен.
+	resp := payRequest(t, db, "open", payer)
+	tx, pr := resp.Transaction, resp.PaymentRequest
+	if resp.Status != "success" || tx == nil || pr == nil {
+		t.Fatalf("ответ %+v, want success с транзакцией и запросом", resp)
+	}
+	if tx.From != payer || tx.To != payee || tx.Amount != 25 || tx.Reference != "INV-7" {
+		t.Errorf("перевод %+v, want %s -> %s на 25 с reference INV-7", tx, payer, payee)
+	}
+	if pr.Status != models.PaymentRequestFulfilled || pr.TransactionID == nil || *pr.TransactionID != tx.ID || pr.FulfilledAt == nil {
+		t.Errorf("запрос после оплаты %+v, want fulfilled транзакцией %d", pr, tx.ID)
+	}
+	if stored := db.request("open"); stored.Status != models.PaymentRequestFulfilled {
+		t.Errorf("сохранённый запрос в состоянии %s, want fulfilled", stored.Status)
+	}
+
+	// Второй платёж по тому же токену - 409 без перевода, от того же
+	// плательщика и от другого.
+	_, router := newTestRouter(db, testConfig())
+	for _, from := range []string{payer, other} {
+		w := serve(router, http.MethodPost, "/api/payment-requests/open/pay", `{"from":"`+from+`"}`, nil)
+		if w.Code != http.StatusConflict {
+			t.Errorf("повторная оплата с %s: статус %d, want 409", from, w.Code)
+			continue
+		}
+		checkErrorEnvelope(t, "повторная оплата", w, CodePaymentRequestFulfilled)
+	}
+	if n := db.executions(); n != 1 {
+		t.Errorf("переводов %d после повторных оплат, want 1", n)
+	}
+}
+
+func TestPayPaymentRequestRejected(t *testing.T) {
+	payee, payer := testAddress(1), testAddress(2)
+	db := newPaymentStorage(
+		models.PaymentRequest{Token: "expired", To: payee, Amount: 5, Status: models.PaymentRequestOpen,
+			ExpiresAt: time.Now().Add(-time.Minute)},
+		models.PaymentRequest{Token: "open", To: payee, Amount: 5, Status: models.PaymentRequestOpen,
+			ExpiresAt: time.Now().Add(time.Hour)},
+	)
+	_, router := newTestRouter(db, testConfig())
+
+	tests := []struct {
+		name, token, from string
+		status            int
+		code              string
+	}{
+		{"истёкший запрос", "expired", payer, http.StatusGone, CodePaymentRequestExpired},
+		{"неизвестный токен", "missing", payer, http.StatusNotFound, CodePaymentRequestNotFound},
+		{"оплата самому себе", "open", payee, http.StatusBadRequest, CodeInvalidRequest},
+		{"некорректный адрес", "open", "abc", http.StatusBadRequest, CodeInvalidAddress},
+	}
+	for _, tt := range tests {
+		w := serve(router, http.MethodPost, "/api/payment-requests/"+tt.token+"/pay", `{"from":"`+tt.from+`"}`, nil)
+		if w.Code != tt.status {
+			t.Errorf("%s: статус %d, want %d; тело %s", tt.name, w.Code, tt.status, truncate(w.Body.String()))
+			continue
+		}
+		checkErrorEnvelope(t, tt.name, w, tt.code)
+	}
+	if n := db.executions(); n != 0 {
+		t.Errorf("отклонённые оплаты выполнили %d переводов, want 0", n)
+	}
+	if stored := db.request("expired"); stored.Status != models.PaymentRequestOpen || stored.TransactionID != nil {
+		t.Errorf("истёкший запрос изменён: %+v", stored)
+	}
+}
+
+func TestPayPaymentRequestConcurrentPayers(t *testing.T) {
+	payee, first, second := testAddress(1), testAddress(2), testAddress(3)
+	db := newPaymentStorage(models.PaymentRequest{Token: "open", To: payee, Amount: 5,
+		Status: models.PaymentRequestOpen, ExpiresAt: time.Now().Add(time.Hour)})
+	// Первый плательщик выполнил перевод, но ответ не успел отметить запрос
+	// оплаченным; второй прочитал запрос открытым.
+	paid, err := db.Execute(context.Background(), models.Transfer{From: first, To: payee, Amount: 5,
+		IdempotencyKey: paymentRequestKeyPrefix + "open"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.staleReads = true
+
+	_, router := newTestRouter(db, testConfig())
+	w := serve(router, http.MethodPost, "/api/payment-requests/open/pay", `{"from":"`+second+`"}`, nil)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("оплата вторым плательщиком: статус %d, want 409; тело %s", w.Code, truncate(w.Body.String()))
+	}
+	checkErrorEnvelope(t, "оплата вторым плательщиком", w, CodePaymentRequestFulfilled)
+	if n := db.executions(); n != 1 {
+		t.Errorf("переводов %d, want 1: второй плательщик не должен платить", n)
+	}
+	// Запрос отмечен оплаченным переводом первого плательщика.
+	if stored := db.request("open"); stored.Status != models.PaymentRequestFulfilled ||
+		stored.TransactionID == nil || *stored.TransactionID != paid.ID {
+		t.Errorf("запрос %+v, want fulfilled транзакцией %d", stored, paid.ID)
+	}
+
+	// Повтор первым плательщиком возвращает его перевод, а не новый.
+	resp := payRequest(t, db, "open", first)
+	if resp.Transaction.ID != paid.ID || db.executions() != 1 {
+		t.Errorf("повтор первым плательщиком: транзакция %d, переводов %d, want %d и 1", resp.Transaction.ID, db.executions(), paid.ID)
+	}
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"go-payments/internal/models"
+	"go-payments/internal/storage"
+)
+
+type periodCloseRequest struct {
+	ClosedThrough time.Time `json:"closed_through"`
+	Force         bool      `json:"force"`
+}
+
+type periodCloseResponse struct {
+	// Close - действующее закрытие; null, если период ещё не закрывался.
+	Close *models.PeriodClose `json:"close"`
+}
+
+func (a *API) GetPeriodClose(w http.ResponseWriter, r *http.Request) {
+	p, err := a.db.GetPeriodClose(r.Context())
+	if err != nil {
+		log.Printf("ошибка получения закрытия периода: %v", err)
+		writeStorageError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, periodCloseResponse{Close: p})
+}
+
+// ClosePeriod закрывает учётный период: транзакции до closed_through больше нельзя
+// подтверждать и тегировать. Дата не может быть в будущем, а перенос назад требует
+// force и пишется в лог как предупреждение вместе с автором из X-Actor.
+func (a *API) ClosePeriod(w http.ResponseWriter, r *http.Request) {
+	var req periodCloseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "неверный формат запроса")
+		return
+	}
+	defer r.Body.Close()
+
+	if req.ClosedThrough.IsZero() {
+		badRequest(w, "поле 'closed_through' обязательно (RFC 3339)")
+		return
+	}
+	if req.ClosedThrough.After(time.Now()) {
+		badRequest(w, "поле 'closed_through' не может быть в будущем")
+		return
+	}
+
+	actor := r.Header.Get(actorHeader)
+	p, err := a.db.ClosePeriod(r.Context(), req.ClosedThrough, actor, req.Force)
+	if err != nil {
+		if !errors.Is(err, storage.ErrPeriodCloseBackward) {
+			log.Printf("ошибка закрытия периода по %s: %v", req.ClosedThrough.UTC().Format(time.RFC3339), err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+
+	if p.Forced {
+		log.Printf("ВНИМАНИЕ: учётный период принудительно открыт заново, закрыт по %s (%s)", p.ClosedThrough.Format(time.RFC3339), actor)
+	} else {
+		log.Printf("учётный период закрыт по %s (%s)", p.ClosedThrough.Format(time.RFC3339), actor)
+	}
+	writeJSON(w, r, periodCloseResponse{Close: p})
+}
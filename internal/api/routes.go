@@ -0,0 +1,162 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Маршруты, которые при отдельном внутреннем слушателе не должны быть доступны
+// на публичном.
+var internalOnlyPrefixes = []string{"/api/admin", "/readyz", "/metrics", "/debug"}
+
+// routeLog - маршруты, зарегистрированные через RouteRecorder, и найденные повторы.
+type routeLog struct {
+	methods    map[string]map[string]bool // шаблон -> методы ("*" - все методы)
+	duplicates []string
+}
+
+func (l *routeLog) record(method, pattern string) {
+	methods := l.methods[pattern]
+	if methods == nil {
+		methods = make(map[string]bool)
+		l.methods[pattern] = methods
+	}
+	// Handle без метода перекрывает любой метод того же шаблона, и наоборот.
+	if methods[method] || methods["*"] || (method == "*" && len(methods) > 0) {
+		l.duplicates = append(l.duplicates, method+" "+pattern)
+	}
+	methods[method] = true
+}
+
+// RouteRecorder - chi.Router, который запоминает регистрируемые маршруты, чтобы
+// найти повторную регистрацию метода и шаблона: chi молча заменяет обработчик.
+// Группы, With и Route возвращают RouteRecorder с тем же журналом.
+type RouteRecorder struct {
+	chi.Router
+	prefix string
+	log    *routeLog
+}
+
+// NewRouteRecorder оборачивает r.
+func NewRouteRecorder(r chi.Router) *RouteRecorder {
+	return &RouteRecorder{Router: r, log: &routeLog{methods: make(map[string]map[string]bool)}}
+}
+
+// Err возвращает ошибку со списком повторно зарегистрированных маршрутов или nil.
+func (r *RouteRecorder) Err() error {
+	if len(r.log.duplicates) == 0 {
+		return nil
+	}
+	return fmt.Errorf("маршруты зарегистрированы повторно: %s", strings.Join(r.log.duplicates, ", "))
+}
+
+func (r *RouteRecorder) wrap(next chi.Router, prefix string) *RouteRecorder {
+	return &RouteRecorder{Router: next, prefix: prefix, log: r.log}
+}
+
+func (r *RouteRecorder) With(middlewares ...func(http.Handler) http.Handler) chi.Router {
+	return r.wrap(r.Router.With(middlewares...), r.prefix)
+}
+
+func (r *RouteRecorder) Group(fn func(r chi.Router)) chi.Router {
+	group := r.With()
+	if fn != nil {
+		fn(group)
+	}
+	return group
+}
+
+func (r *RouteRecorder) Route(pattern string, fn func(r chi.Router)) chi.Router {
+	sub := chi.NewRouter()
+	wrapped := r.wrap(sub, r.prefix+strings.TrimSuffix(pattern, "/"))
+	if fn != nil {
+		fn(wrapped)
+	}
+	r.Router.Mount(pattern, sub)
+	return wrapped
+}
+
+func (r *RouteRecorder) Handle(pattern string, h http.Handler) {
+	r.log.record("*", r.prefix+pattern)
+	r.Router.Handle(pattern, h)
+}
+
+func (r *RouteRecorder) HandleFunc(pattern string, h http.HandlerFunc) {
+	r.log.record("*", r.prefix+pattern)
+	r.Router.HandleFunc(pattern, h)
+}
+
+func (r *RouteRecorder) Method(method, pattern string, h http.Handler) {
+	r.log.record(strings.ToUpper(method), r.prefix+pattern)
+	r.Router.Method(method, pattern, h)
+}
+
+func (r *RouteRecorder) MethodFunc(method, pattern string, h http.HandlerFunc) {
+	r.Method(method, pattern, h)
+}
+
+func (r *RouteRecorder) Connect(pattern string, h http.HandlerFunc) {
+	r.Method(http.MethodConnect, pattern, h)
+}
+
+func (r *RouteRecorder) Delete(pattern string, h http.HandlerFunc) {
+	r.Method(http.MethodDelete, pattern, h)
+}
+
+func (r *RouteRecorder) Get(pattern string, h http.HandlerFunc) {
+	r.Method(http.MethodGet, pattern, h)
+}
+
+func (r *RouteRecorder) Head(pattern string, h http.HandlerFunc) {
+	r.Method(http.MethodHead, pattern, h)
+}
+
+func (r *RouteRecorder) Options(pattern string, h http.HandlerFunc) {
+	r.Method(http.MethodOptions, pattern, h)
+}
+
+func (r *RouteRecorder) Patch(pattern string, h http.HandlerFunc) {
+	r.Method(http.MethodPatch, pattern, h)
+}
+
+func (r *RouteRecorder) Post(pattern string, h http.HandlerFunc) {
+	r.Method(http.MethodPost, pattern, h)
+}
+
+func (r *RouteRecorder) Put(pattern string, h http.HandlerFunc) {
+	r.Method(http.MethodPut, pattern, h)
+}
+
+func (r *RouteRecorder) Trace(pattern string, h http.HandlerFunc) {
+	r.Method(http.MethodTrace, pattern, h)
+}
+
+// CheckPublicRoutes проходит итоговое дерево маршрутов публичного слушателя
+// (включая смонтированные подроутеры) и возвращает ошибку со списком шаблонов,
+// которые должны быть доступны только на внутреннем слушателе.
+func CheckPublicRoutes(public chi.Routes) error {
+	seen := make(map[string]bool)
+	var exposed []string
+	err := chi.Walk(public, func(_, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		for _, prefix := range internalOnlyPrefixes {
+			if (route == prefix || strings.HasPrefix(route, prefix+"/")) && !seen[route] {
+				seen[route] = true
+				exposed = append(exposed, route)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("не удалось обойти маршруты публичного слушателя: %w", err)
+	}
+	if len(exposed) > 0 {
+		sort.Strings(exposed)
+		return fmt.Errorf("внутренние маршруты доступны на публичном слушателе: %s", strings.Join(exposed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+
+	"go-payments/internal/sandbox"
+)
+
+type sandboxAddressInfo struct {
+	Address     string `json:"address"`
+	Outcome     string `json:"outcome"`
+	HTTPStatus  int    `json:"http_status"`
+	ErrorCode   string `json:"error_code,omitempty"`
+	Description string `json:"description"`
+}
+
+type sandboxInfo struct {
+	Enabled   bool                 `json:"enabled"`
+	Addresses []sandboxAddressInfo `json:"addresses"`
+}
+
+// sandboxCatalog описывает магические адреса песочницы. Статус и код ответа
+// вычисляются из ошибки исхода по тем же таблицам, по которым отвечает Send.
+func sandboxCatalog() []sandboxAddressInfo {
+	catalog := make([]sandboxAddressInfo, 0, len(sandbox.Addresses))
+	for _, a := range sandbox.Addresses {
+		info := sandboxAddressInfo{Address: a.Address, Outcome: a.Outcome, HTTPStatus: http.StatusOK, Description: a.Description}
+		if err := a.Err(1); err != nil {
+			mapping := storageErrorMapping(err)
+			info.HTTPStatus, info.ErrorCode = mapping.Status, mapping.Code
+		}
+		catalog = append(catalog, info)
+	}
+	return catalog
+}
+
+// GetSandbox перечисляет магические адреса песочницы; список отдаётся и при
+// выключенном SANDBOX_MODE, чтобы интеграторы видели, чего ожидать.
+func (a *API) GetSandbox(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, sandboxInfo{Enabled: a.cfg.SandboxMode, Addresses: sandboxCatalog()})
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"go-payments/internal/models"
+	"go-payments/internal/storage"
+)
+
+// Предупреждение готовности о расхождении схемы с миграциями.
+const warningSchemaDrift = "schema_drift"
+
+type schemaResponse struct {
+	Version         int               `json:"version"`
+	ExpectedVersion int               `json:"expected_version"`
+	Drift           bool              `json:"drift"`
+	Diff            models.SchemaDiff `json:"diff"`
+}
+
+// CheckSchema сверяет схему базы данных с миграциями при запуске и пишет
+// расхождения в лог. Расхождение отражается в /readyz как предупреждение;
+// при STRICT_SCHEMA=true возвращается ошибка, и сервис не запускается.
+func (a *API) CheckSchema(ctx context.Context) error {
+	diff, err := a.checkSchema(ctx)
+	if err != nil {
+		return err
+	}
+	if !diff.Empty() && a.cfg.StrictSchema {
+		return errors.New("схема базы данных расходится с миграциями (STRICT_SCHEMA=true)")
+	}
+	return nil
+}
+
+// checkSchema получает расхождения схемы, записывает их в лог и обновляет
+// предупреждение готовности.
+func (a *API) checkSchema(ctx context.Context) (*models.SchemaDiff, error) {
+	diff, err := a.db.CheckSchema(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось проверить схему базы данных: %w", err)
+	}
+	a.schemaDrift.Store(!diff.Empty())
+	logSchemaDiff(diff)
+	return diff, nil
+}
+
+// logSchemaDiff пишет в лог по строке на каждое расхождение.
+func logSchemaDiff(d *models.SchemaDiff) {
+	for _, group := range []struct {
+		kind  string
+		items []string
+	}{
+		{"missing_table", d.MissingTables},
+		{"missing_column", d.MissingColumns},
+		{"unexpected_column", d.UnexpectedColumns},
+		{"missing_index", d.MissingIndexes},
+		{"unexpected_index", d.UnexpectedIndexes},
+	} {
+		for _, item := range group.items {
+			log.Printf("расхождение схемы: kind=%s object=%s", group.kind, item)
+		}
+	}
+}
+
+// readyWarnings возвращает предупреждения, которые не делают сервис неготовым.
+func (a *API) readyWarnings() []string {
+	var warnings []string
+	if a.schemaDrift.Load() {
+		warnings = append(warnings, warningSchemaDrift)
+	}
+	return warnings
+}
+
+func (a *API) GetSchema(w http.ResponseWriter, r *http.Request) {
+	diff, err := a.checkSchema(r.Context())
+	if err != nil {
+		log.Println(err)
+		writeStorageError(w, r, err)
+		return
+	}
+
+	version, err := a.db.SchemaVersion(r.Context())
+	if err != nil {
+		log.Printf("ошибка получения версии схемы: %v", err)
+		writeStorageError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, schemaResponse{
+		Version:         version,
+		ExpectedVersion: storage.LatestSchemaVersion(),
+		Drift:           !diff.Empty(),
+		Diff:            *diff,
+	})
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"go-payments/internal/money"
+)
+
+// Максимальное количество кошельков, создаваемых одним запросом.
+const maxSeedWallets = 100000
+
+type seedRequest struct {
+	Count   int          `json:"count"`
+	Balance money.Amount `json:"balance"`
+}
+
+// seedProgress - строка потокового ответа SeedWallets.
+type seedProgress struct {
+	Created int    `json:"created"`
+	Skipped int    `json:"skipped,omitempty"`
+	Done    bool   `json:"done"`
+	Error   string `json:"error,omitempty"`
+}
+
+// SeedWallets создаёт заданное количество кошельков и передаёт прогресс
+// потоком NDJSON: строка после каждой пачки и итоговая строка с done или error.
+// Итоговая строка содержит skipped - сколько сгенерированных адресов оказались
+// заняты и были заменены новыми.
+// Запрос прерывается вместе с его контекстом; созданные до этого кошельки остаются.
+func (a *API) SeedWallets(w http.ResponseWriter, r *http.Request) {
+	var req seedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.badBody(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Count < 1 || req.Count > maxSeedWallets {
+		badRequest(w, fmt.Sprintf("поле 'count' должно быть от 1 до %d", maxSeedWallets))
+		return
+	}
+	if req.Balance < 0 {
+		badRequest(w, "поле 'balance' не может быть отрицательным")
+		return
+	}
+	if !a.amountInRange(w, "balance", req.Balance) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+
+	created := 0
+	_, skipped, err := a.db.CreateWallets(r.Context(), req.Count, float64(req.Balance), func(batch []string) {
+		created += len(batch)
+		enc.Encode(seedProgress{Created: created})
+		if flusher != nil {
+			flusher.Flush()
+		}
+	})
+
+	result := seedProgress{Created: created, Skipped: skipped, Done: err == nil}
+	if err != nil {
+		log.Printf("создание кошельков прервано после %d из %d: %v", created, req.Count, err)
+		result.Error = "создание кошельков прервано"
+	} else {
+		log.Printf("создано кошельков: %d, пропущено занятых адресов: %d", created, skipped)
+	}
+	enc.Encode(result)
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"go-payments/internal/storage"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	// Заголовок с количеством повторов перевода после конфликтов.
+	retryAttemptsHeader = "X-Retry-Attempts"
+	// Заголовок со временем выполнения перевода в хранилище, в миллисекундах.
+	storageElapsedHeader = "X-Storage-Elapsed-Ms"
+	// Заголовок запроса с ключом идемпотентности перевода.
+	idempotencyKeyHeader = "Idempotency-Key"
+	// Заголовок ответа, отмечающий повтор по ключу идемпотентности.
+	idempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+var sendStorageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "payments_send_storage_duration_seconds",
+	Help:    "Время выполнения перевода в хранилище с учётом повторов.",
+	Buckets: prometheus.ExponentialBuckets(0.001, 2, 14),
+}, []string{"outcome"})
+
+// setExecStats сообщает клиенту о повторах и времени перевода в хранилище и
+// записывает время в метрику с итоговым исходом. Повтор по ключу идемпотентности
+// отмечается заголовком Idempotent-Replayed.
+func setExecStats(w http.ResponseWriter, stats storage.ExecStats, err error) {
+	w.Header().Set(retryAttemptsHeader, strconv.Itoa(max(stats.Attempts-1, 0)))
+	w.Header().Set(storageElapsedHeader, strconv.FormatInt(stats.Elapsed.Milliseconds(), 10))
+	if stats.Replayed {
+		w.Header().Set(idempotentReplayedHeader, "true")
+	}
+
+	outcome := "success"
+	if stats.Replayed {
+		outcome = "replayed"
+	}
+	if err != nil {
+		outcome = storageErrorCode(err)
+	}
+	sendStorageDuration.WithLabelValues(outcome).Observe(stats.Elapsed.Seconds())
+}
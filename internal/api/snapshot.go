@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"go-payments/internal/models"
+	"go-payments/internal/storage"
+)
+
+// restoreResponse - результат восстановления снимка.
+type restoreResponse struct {
+	Restored int `json:"restored"`
+}
+
+// GetSnapshot отдаёт снимок кошельков потоком, не собирая его в памяти. Суммы
+// в снимке всегда строки, независимо от amount_format. Если чтение прервалось после
+// начала ответа, JSON остаётся незавершённым, и клиент не примет неполный снимок.
+func (a *API) GetSnapshot(w http.ResponseWriter, r *http.Request) {
+	createdAt, _ := json.Marshal(time.Now().UTC())
+	count := 0
+	writePrefix := func() {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"version":%d,"created_at":%s,"wallets":[`, models.SnapshotVersion, createdAt)
+	}
+
+	err := a.db.SnapshotWallets(r.Context(), func(wallet models.Wallet) error {
+		data, err := json.Marshal(wallet)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			writePrefix()
+		} else {
+			io.WriteString(w, ",")
+		}
+		count++
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		log.Printf("ошибка создания снимка кошельков после %d кошельков: %v", count, err)
+		if count == 0 {
+			writeStorageError(w, r, err)
+		}
+		return
+	}
+
+	if count == 0 {
+		writePrefix()
+	}
+	io.WriteString(w, "]}\n")
+	log.Printf("создан снимок кошельков: %d (%s)", count, r.Header.Get(actorHeader))
+}
+
+// RestoreSnapshot восстанавливает кошельки из снимка. Снимок применяется целиком
+// или не применяется вовсе.
+func (a *API) RestoreSnapshot(w http.ResponseWriter, r *http.Request) {
+	var snapshot models.WalletSnapshot
+	if err := json.NewDecoder(r.Body).Decode(&snapshot); err != nil {
+		badRequest(w, "неверный формат снимка")
+		return
+	}
+	defer r.Body.Close()
+
+	if err := snapshot.Validate(); err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+
+	if err := a.db.RestoreWallets(r.Context(), snapshot.Wallets); err != nil {
+		if !errors.Is(err, storage.ErrWalletsExist) {
+			log.Printf("ошибка восстановления снимка кошельков: %v", err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+
+	log.Printf("восстановлено кошельков из снимка от %s: %d (%s)",
+		snapshot.CreatedAt.Format(time.RFC3339), len(snapshot.Wallets), r.Header.Get(actorHeader))
+	writeJSON(w, r, restoreResponse{Restored: len(snapshot.Wallets)})
+}
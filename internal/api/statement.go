@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/csv"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-payments/internal/models"
+	"go-payments/internal/money"
+	"go-payments/internal/redact"
+	"go-payments/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// Формат параметра month в выписке.
+	statementMonthLayout = "2006-01"
+	// Заголовок CSV-выписки, отмечающий выписку за закрытый период (поле final в JSON).
+	statementFinalHeader = "X-Statement-Final"
+)
+
+func (a *API) GetStatement(w http.ResponseWriter, r *http.Request) {
+	address := chi.URLParam(r, "address")
+
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if v := r.URL.Query().Get("month"); v != "" {
+		month, err := time.Parse(statementMonthLayout, v)
+		if err != nil {
+			badRequest(w, "параметр 'month' должен быть в формате YYYY-MM")
+			return
+		}
+		from = month
+	}
+	to := from.AddDate(0, 1, 0)
+
+	statement, err := a.db.GetStatement(r.Context(), address, from, to)
+	if err != nil {
+		if !errors.Is(err, storage.ErrWalletNotFound) {
+			log.Printf("ошибка получения выписки для кошелька %s: %v", redact.Address(address), err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		writeStatementCSV(w, statement)
+		return
+	}
+
+	writeJSON(w, r, statement)
+}
+
+// writeStatementCSV отдаёт выписку одной таблицей: строка баланса на начало периода,
+// транзакции с нарастающим балансом, итоги и строка баланса на конец периода.
+func writeStatementCSV(w http.ResponseWriter, s *models.Statement) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set(statementFinalHeader, strconv.FormatBool(s.Final))
+
+	formatAmount := func(v money.Amount) string { return money.FormatAmount(float64(v)) }
+	formatTime := func(t time.Time) string { return t.UTC().Format(time.RFC3339) }
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"type", "id", "timestamp", "from", "to", "amount", "running_balance", "memo", "reference", "group_id", "public_id"})
+	cw.Write([]string{"opening_balance", "", formatTime(s.PeriodStart), "", "", "", formatAmount(s.OpeningBalance), "", "", "", ""})
+	for _, e := range s.Transactions {
+		cw.Write([]string{
+			"transaction",
+			strconv.Itoa(e.ID),
+			formatTime(e.Timestamp),
+			e.From,
+			e.To,
+			formatAmount(e.Amount),
+			formatAmount(e.RunningBalance),
+			e.Memo,
+			e.Reference,
+			e.GroupID,
+			e.PublicID,
+		})
+	}
+	cw.Write([]string{"total_in", "", "", "", "", formatAmount(s.TotalIn), "", "", "", "", ""})
+	cw.Write([]string{"total_out", "", "", "", "", formatAmount(s.TotalOut), "", "", "", "", ""})
+	cw.Write([]string{"closing_balance", "", formatTime(s.PeriodEnd), "", "", "", formatAmount(s.ClosingBalance), "", "", "", ""})
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Printf("ошибка записи выписки в CSV: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"go-payments/internal/models"
+)
+
+// Максимальное количество интервалов в одном временном ряду.
+const maxVolumeBuckets = 1000
+
+func (a *API) GetVolume(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	bucket := models.BucketSize(query.Get("bucket"))
+	if bucket == "" {
+		bucket = models.BucketDay
+	}
+	step := bucket.Duration()
+	if step == 0 {
+		badRequest(w, "параметр 'bucket' должен быть hour или day")
+		return
+	}
+
+	until := time.Now().UTC()
+	if v := query.Get("until"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			badRequest(w, "параметр 'until' должен быть в формате RFC 3339")
+			return
+		}
+		until = t
+	}
+
+	since := until.Add(-30 * step)
+	if v := query.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			badRequest(w, "параметр 'since' должен быть в формате RFC 3339")
+			return
+		}
+		since = t
+	}
+
+	if !since.Before(until) {
+		badRequest(w, "параметр 'since' должен быть раньше 'until'")
+		return
+	}
+	if until.Sub(since.Truncate(step)) > maxVolumeBuckets*step {
+		badRequest(w, "слишком много интервалов: уменьшите диапазон или увеличьте 'bucket'")
+		return
+	}
+
+	status := models.TransactionStatus(query.Get("status"))
+	if status != "" && !status.Valid() {
+		badRequest(w, "неизвестный статус транзакции")
+		return
+	}
+
+	series, err := a.db.GetVolumeSeries(r.Context(), since, until, bucket, status)
+	if err != nil {
+		log.Printf("ошибка получения объёма транзакций: %v", err)
+		writeStorageError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, series)
+}
+
+func (a *API) GetWalletStats(w http.ResponseWriter, r *http.Request) {
+	summary, err := a.db.GetWalletSummary(r.Context())
+	if err != nil {
+		log.Printf("ошибка получения сводки по кошелькам: %v", err)
+		writeStorageError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, summary)
+}
+
+// RefreshWalletStats пересчитывает сводку по кошелькам, не дожидаясь планового
+// обновления, и возвращает её.
+func (a *API) RefreshWalletStats(w http.ResponseWriter, r *http.Request) {
+	if err := a.db.RefreshWalletSummary(r.Context()); err != nil {
+		log.Printf("ошибка обновления сводки по кошелькам: %v", err)
+		writeStorageError(w, r, err)
+		return
+	}
+	log.Printf("сводка по кошелькам обновлена вручную (%s)", r.Header.Get(actorHeader))
+
+	a.GetWalletStats(w, r)
+}
@@ -0,0 +1,17 @@
+package api
+
+import (
+	"log"
+	"net/http"
+)
+
+func (a *API) GetStorageInfo(w http.ResponseWriter, r *http.Request) {
+	info, err := a.db.StorageInfo(r.Context())
+	if err != nil {
+		log.Printf("ошибка получения размеров хранилища: %v", err)
+		writeStorageError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, info)
+}
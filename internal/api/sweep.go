@@ -0,0 +1,150 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"go-payments/internal/models"
+	"go-payments/internal/money"
+	"go-payments/internal/redact"
+	"go-payments/internal/storage"
+)
+
+const (
+	// Сколько кошельков консолидируется за один вызов по умолчанию и максимум.
+	defaultSweepWallets = 100
+	maxSweepWallets     = 1000
+	// Минимальный баланс по умолчанию - наименьшая единица суммы в базе (DECIMAL(20, 8)).
+	defaultSweepMinBalance = 0.00000001
+)
+
+type sweepRequest struct {
+	To         string   `json:"to"`
+	MinBalance *float64 `json:"min_balance"`
+	MaxWallets int      `json:"max_wallets"`
+}
+
+type sweepFailure struct {
+	Address string `json:"address"`
+	Code    string `json:"code"`
+	Error   string `json:"error"`
+}
+
+type sweepResponse struct {
+	To         string         `json:"to"`
+	Swept      int            `json:"swept"`
+	TotalMoved money.Amount   `json:"total_moved"`
+	Failures   []sweepFailure `json:"failures"`
+}
+
+// Sweep переводит полные балансы небольших кошельков на один целевой кошелёк.
+// Каждое перемещение - обычный перевод через Execute, поэтому оно попадает в историю.
+// Ошибка на одном кошельке не прерывает остальные; повторный вызов пропускает
+// уже опустошённые кошельки, так как они не проходят по min_balance.
+func (a *API) Sweep(w http.ResponseWriter, r *http.Request) {
+	var req sweepRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "неверный формат запроса")
+		return
+	}
+	defer r.Body.Close()
+
+	if req.To == "" {
+		badRequest(w, "не указан целевой кошелёк 'to'")
+		return
+	}
+	if !normalizeAddress(w, "to", &req.To) {
+		return
+	}
+	minBalance := defaultSweepMinBalance
+	if req.MinBalance != nil {
+		if *req.MinBalance <= 0 {
+			badRequest(w, "параметр 'min_balance' должен быть положительным")
+			return
+		}
+		minBalance = *req.MinBalance
+	}
+	if req.MaxWallets == 0 {
+		req.MaxWallets = defaultSweepWallets
+	}
+	if req.MaxWallets < 1 || req.MaxWallets > maxSweepWallets {
+		badRequest(w, "параметр 'max_wallets' должен быть числом от 1 до 1000")
+		return
+	}
+
+	target, err := a.db.GetWalletBalance(r.Context(), req.To)
+	if err != nil {
+		if !errors.Is(err, storage.ErrWalletNotFound) {
+			log.Printf("ошибка получения целевого кошелька %s: %v", redact.Address(req.To), err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+	// Консолидация - обычные переводы, поэтому закрытый системный кошелёк их не примет.
+	if target.System && target.NonReceivable {
+		writeStorageError(w, r, storage.ErrSystemWallet)
+		return
+	}
+
+	// Целевой кошелёк сам может попасть в выборку, поэтому берём на один больше.
+	// Системные кошельки (например, клиринговый WITHDRAWAL_WALLET) не консолидируются:
+	// их остатки нужны для возврата отклонённых выводов средств.
+	frozen, system := false, false
+	candidates, err := a.db.ListWallets(r.Context(), models.WalletFilter{
+		Frozen:     &frozen,
+		System:     &system,
+		MinBalance: &minBalance,
+		Sort:       models.WalletSortBalance,
+		Order:      models.SortOrderAsc,
+		Limit:      req.MaxWallets + 1,
+	})
+	if err != nil {
+		log.Printf("ошибка выбора кошельков для консолидации: %v", err)
+		writeStorageError(w, r, err)
+		return
+	}
+
+	resp := sweepResponse{To: req.To, Failures: []sweepFailure{}}
+	attempted := 0
+	for _, wallet := range candidates.Wallets {
+		if wallet.Address == req.To {
+			continue
+		}
+		if attempted == req.MaxWallets || r.Context().Err() != nil {
+			break
+		}
+		attempted++
+
+		_, err := a.db.Execute(r.Context(), models.Transfer{
+			From:     wallet.Address,
+			To:       req.To,
+			Amount:   float64(wallet.Balance),
+			Memo:     "sweep",
+			Metadata: map[string]string{"operation": "sweep"},
+		})
+		if err != nil {
+			log.Printf("ошибка консолидации кошелька %s: %v", redact.Address(wallet.Address), err)
+			resp.Failures = append(resp.Failures, sweepFailureFor(wallet.Address, err))
+			continue
+		}
+		resp.Swept++
+		resp.TotalMoved += wallet.Balance
+	}
+
+	log.Printf("консолидация на %s: перенесено %d кошельков, ошибок %d", redact.Address(req.To), resp.Swept, len(resp.Failures))
+
+	writeJSON(w, r, resp)
+}
+
+// sweepFailureFor описывает ошибку перевода тем же кодом, что вернул бы /api/send.
+func sweepFailureFor(address string, err error) sweepFailure {
+	var txErr *storage.TransactionError
+	if errors.As(err, &txErr) {
+		if mapping, ok := txErrorMappings[txErr.Code]; ok && mapping.Status != http.StatusInternalServerError {
+			return sweepFailure{Address: address, Code: mapping.Code, Error: txErr.Error()}
+		}
+	}
+	return sweepFailure{Address: address, Code: CodeInternalError, Error: "внутренняя ошибка сервера"}
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"go-payments/internal/redact"
+	"go-payments/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type systemWalletRequest struct {
+	System        bool `json:"system"`
+	NonReceivable bool `json:"non_receivable"`
+}
+
+// SetWalletSystem отмечает кошелёк системным или снимает отметку.
+func (a *API) SetWalletSystem(w http.ResponseWriter, r *http.Request) {
+	address := chi.URLParam(r, "address")
+
+	var req systemWalletRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "неверный формат запроса")
+		return
+	}
+	defer r.Body.Close()
+
+	if req.NonReceivable && !req.System {
+		badRequest(w, "поле 'non_receivable' допустимо только для системного кошелька")
+		return
+	}
+
+	if err := a.db.SetWalletSystem(r.Context(), address, req.System, req.NonReceivable); err != nil {
+		if !errors.Is(err, storage.ErrWalletNotFound) {
+			log.Printf("ошибка изменения системной отметки кошелька %s: %v", redact.Address(address), err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+	log.Printf("системная отметка кошелька %s: system=%t non_receivable=%t (%s)",
+		redact.Address(address), req.System, req.NonReceivable, r.Header.Get(actorHeader))
+
+	wallet, err := a.db.GetWalletBalance(r.Context(), address)
+	if err != nil {
+		log.Printf("ошибка получения кошелька %s: %v", redact.Address(address), err)
+		writeStorageError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, wallet)
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"go-payments/internal/models"
+	"go-payments/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ListTransactions - административный список транзакций: те же фильтры, что у
+// GetLast, и дополнительно фильтр по тегу `tag`.
+func (a *API) ListTransactions(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePagination(r, pagination{Limit: defaultAdminLimit}, maxAdminLimit)
+	if err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+
+	filter, err := parseTransactionFilter(r)
+	if err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+	var ok bool
+	if filter.After, ok = a.parseCursor(w, r); !ok {
+		return
+	}
+	if v := r.URL.Query().Get("tag"); v != "" {
+		tag, err := models.NormalizeTag(v)
+		if err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+		filter.Tag = tag
+	}
+	view, ok := parseTransactionView(w, r, page.Limit)
+	if !ok {
+		return
+	}
+
+	a.writeTransactions(w, r, page.Limit, filter, view)
+}
+
+func (a *API) AddTransactionTag(w http.ResponseWriter, r *http.Request) {
+	id, tag, ok := a.transactionTagParams(w, r)
+	if !ok {
+		return
+	}
+	actor := r.Header.Get(actorHeader)
+
+	if err := a.db.AddTransactionTag(r.Context(), id, tag, actor); err != nil {
+		if !errors.Is(err, storage.ErrTxNotFound) && !errors.Is(err, storage.ErrPeriodClosed) {
+			log.Printf("ошибка назначения тега %q транзакции %d: %v", tag, id, err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+	log.Printf("транзакции %d назначен тег %q (%s)", id, tag, actor)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) RemoveTransactionTag(w http.ResponseWriter, r *http.Request) {
+	id, tag, ok := a.transactionTagParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := a.db.RemoveTransactionTag(r.Context(), id, tag); err != nil {
+		if !errors.Is(err, storage.ErrTxNotFound) && !errors.Is(err, storage.ErrPeriodClosed) {
+			log.Printf("ошибка снятия тега %q с транзакции %d: %v", tag, id, err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+	log.Printf("с транзакции %d снят тег %q (%s)", id, tag, r.Header.Get(actorHeader))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) ListTransactionTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := a.db.ListTransactionTags(r.Context())
+	if err != nil {
+		log.Printf("ошибка получения тегов: %v", err)
+		writeStorageError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, tags)
+}
+
+// transactionTagParams разбирает идентификатор транзакции и тег из пути; при
+// ошибке отвечает 400 и возвращает false.
+func (a *API) transactionTagParams(w http.ResponseWriter, r *http.Request) (int, string, bool) {
+	id, ok := a.transactionID(w, r)
+	if !ok {
+		return 0, "", false
+	}
+	tag, err := models.NormalizeTag(chi.URLParam(r, "tag"))
+	if err != nil {
+		badRequest(w, err.Error())
+		return 0, "", false
+	}
+	return id, tag, true
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"go-payments/internal/models"
+	"go-payments/internal/money"
+	"go-payments/internal/redact"
+	"go-payments/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type thresholdRequest struct {
+	Direction  models.ThresholdDirection `json:"direction"`
+	Amount     *money.Amount             `json:"amount"`
+	Hysteresis money.Amount              `json:"hysteresis"`
+	// Target - URL вебхука или "alerter" (по умолчанию).
+	Target string `json:"target"`
+}
+
+// decodeThreshold читает и проверяет правило порога из тела запроса. При ошибке
+// отвечает 400 и возвращает false.
+func (a *API) decodeThreshold(w http.ResponseWriter, r *http.Request, th *models.BalanceThreshold) bool {
+	var req thresholdRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.badBody(w, err)
+		return false
+	}
+	defer r.Body.Close()
+
+	if req.Direction != models.ThresholdAbove && req.Direction != models.ThresholdBelow {
+		badRequest(w, "поле 'direction' должно быть 'above' или 'below'")
+		return false
+	}
+	if req.Amount == nil || *req.Amount < 0 {
+		badRequest(w, "поле 'amount' обязательно и не может быть отрицательным")
+		return false
+	}
+	if req.Hysteresis < 0 {
+		badRequest(w, "поле 'hysteresis' не может быть отрицательным")
+		return false
+	}
+	if !a.amountInRange(w, "amount", *req.Amount) || !a.amountInRange(w, "hysteresis", req.Hysteresis) {
+		return false
+	}
+	// Баланс не бывает отрицательным: такое правило above никогда не сработало бы снова.
+	if req.Direction == models.ThresholdAbove && req.Hysteresis > *req.Amount {
+		badRequest(w, "поле 'hysteresis' правила 'above' не может превышать порог")
+		return false
+	}
+	if req.Target == "" {
+		req.Target = models.ThresholdTargetAlerter
+	}
+	if req.Target != models.ThresholdTargetAlerter {
+		u, err := url.Parse(req.Target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			badRequest(w, "поле 'target' должно быть 'alerter' или URL вебхука http(s)")
+			return false
+		}
+	}
+
+	th.Direction = req.Direction
+	th.Amount = *req.Amount
+	th.Hysteresis = req.Hysteresis
+	th.Target = req.Target
+	return true
+}
+
+// thresholdID разбирает параметр пути {id}. При ошибке отвечает 400 и возвращает false.
+func thresholdID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		badRequest(w, "идентификатор правила должен быть положительным числом")
+		return 0, false
+	}
+	return id, true
+}
+
+func (a *API) CreateBalanceThreshold(w http.ResponseWriter, r *http.Request) {
+	th := models.BalanceThreshold{Wallet: chi.URLParam(r, "address")}
+	if !a.decodeThreshold(w, r, &th) {
+		return
+	}
+
+	created, err := a.db.CreateBalanceThreshold(r.Context(), th)
+	if err != nil {
+		if !errors.Is(err, storage.ErrWalletNotFound) && !errors.Is(err, storage.ErrTooManyThresholds) {
+			log.Printf("ошибка создания правила порога кошелька %s: %v", redact.Address(th.Wallet), err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+	log.Printf("правило порога %d кошелька %s создано: %s %s (%s)", created.ID, redact.Address(th.Wallet),
+		created.Direction, money.FormatAmount(float64(created.Amount)), r.Header.Get(actorHeader))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, r, created)
+}
+
+func (a *API) ListBalanceThresholds(w http.ResponseWriter, r *http.Request) {
+	address := chi.URLParam(r, "address")
+
+	thresholds, err := a.db.ListBalanceThresholds(r.Context(), address)
+	if err != nil {
+		log.Printf("ошибка получения правил порога кошелька %s: %v", redact.Address(address), err)
+		writeStorageError(w, r, err)
+		return
+	}
+	writeJSON(w, r, thresholds)
+}
+
+func (a *API) GetBalanceThreshold(w http.ResponseWriter, r *http.Request) {
+	address := chi.URLParam(r, "address")
+	id, ok := thresholdID(w, r)
+	if !ok {
+		return
+	}
+
+	th, err := a.db.GetBalanceThreshold(r.Context(), address, id)
+	if err != nil {
+		if !errors.Is(err, storage.ErrThresholdNotFound) {
+			log.Printf("ошибка получения правила порога %d: %v", id, err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+	writeJSON(w, r, th)
+}
+
+func (a *API) UpdateBalanceThreshold(w http.ResponseWriter, r *http.Request) {
+	th := models.BalanceThreshold{Wallet: chi.URLParam(r, "address")}
+	id, ok := thresholdID(w, r)
+	if !ok {
+		return
+	}
+	th.ID = id
+	if !a.decodeThreshold(w, r, &th) {
+		return
+	}
+
+	updated, err := a.db.UpdateBalanceThreshold(r.Context(), th)
+	if err != nil {
+		if !errors.Is(err, storage.ErrThresholdNotFound) {
+			log.Printf("ошибка изменения правила порога %d: %v", id, err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+	log.Printf("правило порога %d кошелька %s изменено: %s %s (%s)", id, redact.Address(th.Wallet),
+		updated.Direction, money.FormatAmount(float64(updated.Amount)), r.Header.Get(actorHeader))
+
+	writeJSON(w, r, updated)
+}
+
+func (a *API) DeleteBalanceThreshold(w http.ResponseWriter, r *http.Request) {
+	address := chi.URLParam(r, "address")
+	id, ok := thresholdID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := a.db.DeleteBalanceThreshold(r.Context(), address, id); err != nil {
+		if !errors.Is(err, storage.ErrThresholdNotFound) {
+			log.Printf("ошибка удаления правила порога %d: %v", id, err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+	log.Printf("правило порога %d кошелька %s удалено (%s)", id, redact.Address(address), r.Header.Get(actorHeader))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-payments/internal/storage"
+)
+
+const (
+	// Заголовок, которым клиент ограничивает время ожидания ответа.
+	requestTimeoutHeader = "X-Request-Timeout"
+	// Верхняя граница для таймаута, запрошенного клиентом.
+	maxRequestTimeout = 30 * time.Second
+)
+
+type contextKey int
+
+const (
+	requestStartKey contextKey = iota
+	amountFormatKey
+)
+
+// requestTimeout запоминает время начала запроса и, если клиент прислал заголовок
+// X-Request-Timeout, оборачивает контекст запроса соответствующим дедлайном.
+// Значение задаётся в миллисекундах ("500") или как длительность Go ("500ms", "2s")
+// и ограничивается maxRequestTimeout. Некорректные значения игнорируются,
+// о чём клиенту сообщает заголовок Warning.
+func requestTimeout(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), requestStartKey, time.Now())
+
+		if v := r.Header.Get(requestTimeoutHeader); v != "" {
+			timeout, ok := parseRequestTimeout(v)
+			if !ok {
+				w.Header().Add("Warning", `199 go-payments "invalid X-Request-Timeout ignored"`)
+			} else {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, min(timeout, maxRequestTimeout))
+				defer cancel()
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func parseRequestTimeout(v string) (time.Duration, bool) {
+	if ms, err := strconv.Atoi(v); err == nil {
+		if ms <= 0 {
+			return 0, false
+		}
+		return time.Duration(ms) * time.Millisecond, true
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+	return d, true
+}
+
+// deadlineExceeded отвечает 504, если ошибка вызвана истечением дедлайна запроса,
+// и сообщает, была ли ошибка обработана. Другие ошибки, в том числе полученные
+// уже после дедлайна, отдаются как есть: операция завершилась сама по себе.
+// Запрос, который PostgreSQL отменил по истёкшему дедлайну, тоже считается
+// истечением дедлайна.
+func deadlineExceeded(w http.ResponseWriter, r *http.Request, err error) bool {
+	if !errors.Is(err, context.DeadlineExceeded) &&
+		!(storage.QueryCanceled(err) && errors.Is(r.Context().Err(), context.DeadlineExceeded)) {
+		return false
+	}
+
+	var elapsed time.Duration
+	if start, ok := r.Context().Value(requestStartKey).(time.Time); ok {
+		elapsed = time.Since(start)
+	}
+	writeError(w, http.StatusGatewayTimeout, CodeDeadlineExceeded, "превышено время ожидания запроса",
+		map[string]int64{"elapsed_ms": elapsed.Milliseconds()})
+	return true
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-payments/internal/models"
+	"go-payments/internal/money"
+	"go-payments/internal/storage"
+
+	"github.com/lib/pq"
+)
+
+// slowStorage отвечает на GetWalletBalance через delay, если дедлайн запроса не
+// истечёт раньше, и запоминает, сколько времени до дедлайна оставалось при вызове.
+// Execute ведёт себя по execute.
+type slowStorage struct {
+	*fakeStorage
+	delay   time.Duration
+	execute func(ctx context.Context) (*models.Transaction, error)
+
+	mu          sync.Mutex
+	remaining   time.Duration
+	hasDeadline bool
+}
+
+func (s *slowStorage) GetWalletBalance(ctx context.Context, address string) (*models.Wallet, error) {
+	deadline, ok := ctx.Deadline()
+	s.mu.Lock()
+	s.remaining, s.hasDeadline = time.Until(deadline), ok
+	s.mu.Unlock()
+	select {
+	case <-time.After(s.delay):
+		return &models.Wallet{Address: address}, nil
+	case <-ctx.Done():
+		return nil, &storage.TransactionError{Code: storage.CodeInternalError, OriginalErr: ctx.Err()}
+	}
+}
+
+func (s *slowStorage) Execute(ctx context.Context, t models.Transfer) (*models.Transaction, error) {
+	return s.execute(ctx)
+}
+
+func TestRequestTimeoutHeader(t *testing.T) {
+	tests := []struct {
+		header  string
+		delay   time.Duration
+		status  int
+		warning bool
+		// maxDeadline - наибольшее время до дедлайна в хранилище; 0 - без дедлайна.
+		maxDeadline time.Duration
+	}{
+		{"", 10 * time.Millisecond, http.StatusOK, false, 0},
+		{"2000", 10 * time.Millisecond, http.StatusOK, false, 2 * time.Second},
+		{"2s", 10 * time.Millisecond, http.StatusOK, false, 2 * time.Second},
+		{"1h", 10 * time.Millisecond, http.StatusOK, false, maxRequestTimeout},
+		{"20", time.Second, http.StatusGatewayTimeout, false, 20 * time.Millisecond},
+		{"20ms", time.Second, http.StatusGatewayTimeout, false, 20 * time.Millisecond},
+		{"abc", 10 * time.Millisecond, http.StatusOK, true, 0},
+		{"0", 10 * time.Millisecond, http.StatusOK, true, 0},
+		{"-5", 10 * time.Millisecond, http.StatusOK, true, 0},
+		{"-1s", 10 * time.Millisecond, http.StatusOK, true, 0},
+		{"1.5", 10 * time.Millisecond, http.StatusOK, true, 0},
+	}
+	for _, tt := range tests {
+		db := &slowStorage{fakeStorage: &fakeStorage{}, delay: tt.delay}
+		_, router := newTestRouter(db, testConfig())
+		var header http.Header
+		if tt.header != "" {
+			header = http.Header{requestTimeoutHeader: {tt.header}}
+		}
+		w := serve(router, http.MethodGet, "/api/wallet/"+testAddress(1)+"/balance", "", header)
+
+		if w.Code != tt.status {
+			t.Errorf("%q: статус %d, want %d; тело %s", tt.header, w.Code, tt.status, truncate(w.Body.String()))
+			continue
+		}
+		if got := w.Header().Get("Warning") != ""; got != tt.warning {
+			t.Errorf("%q: заголовок Warning %q, want есть: %v", tt.header, w.Header().Get("Warning"), tt.warning)
+		}
+		db.mu.Lock()
+		remaining, hasDeadline := db.remaining, db.hasDeadline
+		db.mu.Unlock()
+		if hasDeadline != (tt.maxDeadline > 0) {
+			t.Errorf("%q: дедлайн хранилища задан: %v, want %v", tt.header, hasDeadline, tt.maxDeadline > 0)
+		} else if hasDeadline && (remaining > tt.maxDeadline || remaining < tt.maxDeadline/2) {
+			t.Errorf("%q: до дедлайна %v, want от %v до %v", tt.header, remaining, tt.maxDeadline/2, tt.maxDeadline)
+		}
+		if tt.status == http.StatusGatewayTimeout {
+			checkErrorEnvelope(t, tt.header, w, CodeDeadlineExceeded)
+			var body struct {
+				Details struct {
+					ElapsedMS int64 `json:"elapsed_ms"`
+				} `json:"details"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &body)
+			if body.Details.ElapsedMS < 20 {
+				t.Errorf("%q: elapsed_ms %d, want не меньше 20", tt.header, body.Details.ElapsedMS)
+			}
+		}
+	}
+}
+
+func TestSendDeadline(t *testing.T) {
+	transfer := `{"from":"` + testAddress(1) + `","to":"` + testAddress(2) + `","amount":"10"}`
+	timeout := http.Header{requestTimeoutHeader: {"30ms"}}
+
+	tests := []struct {
+		name string
+		// execute - перевод в хранилище; committed отмечает фиксацию.
+		execute func(ctx context.Context, committed *atomic.Bool) (*models.Transaction, error)
+		status  int
+		code    string
+	}{
+		{
+			// Хранилище проверяет дедлайн перед фиксацией и откатывает перевод.
+			name: "дедлайн до фиксации",
+			execute: func(ctx context.Context, committed *atomic.Bool) (*models.Transaction, error) {
+				<-ctx.Done()
+				return nil, &storage.TransactionError{Code: storage.CodeInternalError, OriginalErr: ctx.Err()}
+			},
+			status: http.StatusGatewayTimeout, code: CodeDeadlineExceeded,
+		},
+		{
+			name: "PostgreSQL отменил запрос по дедлайну",
+			execute: func(ctx context.Context, committed *atomic.Bool) (*models.Transaction, error) {
+				<-ctx.Done()
+				return nil, &storage.TransactionError{Code: storage.CodeInternalError,
+					OriginalErr: &pq.Error{Code: "57014", Message: "canceling statement due to user request"}}
+			},
+			status: http.StatusGatewayTimeout, code: CodeDeadlineExceeded,
+		},
+		{
+			// Перевод зафиксирован до дедлайна, а ответ готов после него: клиент
+			// получает выполненный перевод, а не 504.
+			name: "поздний ответ после фиксации",
+			execute: func(ctx context.Context, committed *atomic.Bool) (*models.Transaction, error) {
+				committed.Store(true)
+				<-ctx.Done()
+				time.Sleep(10 * time.Millisecond)
+				return &models.Transaction{ID: 1, Amount: money.Amount(10), Status: models.StatusSuccess}, nil
+			},
+			status: http.StatusOK,
+		},
+		{
+			// Отказ, полученный после дедлайна, отдаётся как есть.
+			name: "отказ после дедлайна",
+			execute: func(ctx context.Context, committed *atomic.Bool) (*models.Transaction, error) {
+				<-ctx.Done()
+				return nil, errInsufficient
+			},
+			status: http.StatusPaymentRequired, code: CodeInsufficientFunds,
+		},
+		{
+			name: "внутренняя ошибка после дедлайна",
+			execute: func(ctx context.Context, committed *atomic.Bool) (*models.Transaction, error) {
+				<-ctx.Done()
+				return nil, &storage.TransactionError{Code: storage.CodeInternalError, OriginalErr: errStorageFailure}
+			},
+			status: http.StatusInternalServerError, code: CodeInternalError,
+		},
+	}
+	for _, tt := range tests {
+		var committed atomic.Bool
+		db := &slowStorage{fakeStorage: &fakeStorage{}, execute: func(ctx context.Context) (*models.Transaction, error) {
+			return tt.execute(ctx, &committed)
+		}}
+		_, router := newTestRouter(db, testConfig())
+		w := serve(router, http.MethodPost, "/api/send", transfer, timeout)
+		if w.Code != tt.status {
+			t.Errorf("%s: статус %d, want %d; тело %s", tt.name, w.Code, tt.status, truncate(w.Body.String()))
+			continue
+		}
+		if tt.code != "" {
+			checkErrorEnvelope(t, tt.name, w, tt.code)
+			if committed.Load() {
+				t.Errorf("%s: ответ с ошибкой после фиксации перевода", tt.name)
+			}
+		}
+	}
+
+	// Отмена запроса PostgreSQL без истёкшего дедлайна (например, statement_timeout)
+	// - внутренняя ошибка, а не 504.
+	db := &slowStorage{fakeStorage: &fakeStorage{}, execute: func(ctx context.Context) (*models.Transaction, error) {
+		return nil, &storage.TransactionError{Code: storage.CodeInternalError,
+			OriginalErr: &pq.Error{Code: "57014", Message: "canceling statement due to statement timeout"}}
+	}}
+	_, router := newTestRouter(db, testConfig())
+	if w := serve(router, http.MethodPost, "/api/send", transfer, nil); w.Code != http.StatusInternalServerError {
+		t.Errorf("отмена запроса без дедлайна: статус %d, want 500", w.Code)
+	}
+}
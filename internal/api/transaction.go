@@ -0,0 +1,107 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go-payments/internal/models"
+	"go-payments/internal/storage"
+	"go-payments/internal/ulid"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Cache-Control неизменяемых транзакций; остальные клиент перепроверяет по ETag.
+const (
+	immutableCacheControl = "public, max-age=86400"
+	mutableCacheControl   = "no-cache"
+)
+
+// transactionID разбирает параметр пути {id}: публичный идентификатор транзакции
+// (ULID) или, на переходный период, внутренний порядковый номер. ULID переводится
+// во внутренний номер запросом к хранилищу. При ошибке отвечает клиенту и
+// возвращает false.
+func (a *API) transactionID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	param := chi.URLParam(r, "id")
+	if publicID, ok := ulid.Normalize(param); ok {
+		id, err := a.db.TransactionIDByPublicID(r.Context(), publicID)
+		if err != nil {
+			if !errors.Is(err, storage.ErrTxNotFound) {
+				log.Printf("ошибка поиска транзакции %s: %v", publicID, err)
+			}
+			writeStorageError(w, r, err)
+			return 0, false
+		}
+		return id, true
+	}
+	id, err := strconv.Atoi(param)
+	if err != nil || id <= 0 {
+		badRequest(w, "идентификатор транзакции должен быть ULID или положительным числом")
+		return 0, false
+	}
+	return id, true
+}
+
+func (a *API) GetTransaction(w http.ResponseWriter, r *http.Request) {
+	id, ok := a.transactionID(w, r)
+	if !ok {
+		return
+	}
+
+	transaction, err := a.db.GetTransaction(r.Context(), id)
+	if err != nil {
+		if !errors.Is(err, storage.ErrTxNotFound) {
+			log.Printf("ошибка получения транзакции %d: %v", id, err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+
+	etag, err := transactionETag(r, transaction)
+	if err != nil {
+		log.Printf("ошибка вычисления ETag транзакции %d: %v", id, err)
+		internalError(w)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	if transaction.Immutable() {
+		w.Header().Set("Cache-Control", immutableCacheControl)
+	} else {
+		w.Header().Set("Cache-Control", mutableCacheControl)
+	}
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeJSON(w, r, transaction)
+}
+
+// transactionETag - сильный ETag транзакции: хэш её JSON-представления и формата
+// сумм, поэтому он меняется вместе с телом ответа (например, после подтверждения).
+func transactionETag(r *http.Request, t *models.Transaction) (string, error) {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	format, _ := r.Context().Value(amountFormatKey).(string)
+	sum := sha256.Sum256(append(data, format...))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// etagMatches проверяет заголовок If-None-Match: список ETag через запятую или "*".
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go-payments/internal/models"
+)
+
+func TestTransactionLookupByEitherID(t *testing.T) {
+	const publicID = "01ARZ3NDEKTSV4RRFFQ69G5FAV"
+	sender, recipient := testAddress(1), testAddress(2)
+	db := newTxStorage(
+		models.Transaction{ID: 42, PublicID: publicID, From: sender, To: recipient, Amount: 10, Status: models.StatusSuccess},
+		models.Transaction{ID: 43, PublicID: "01ARZ3NDEKTSV4RRFFQ69G5FAW", From: sender, To: recipient, Amount: 20, Status: models.StatusSuccess},
+	)
+	_, router := newTestRouter(db, testConfig())
+
+	// Публичный идентификатор (в любом регистре) и порядковый номер находят одну
+	// и ту же транзакцию с одним ETag.
+	var etag string
+	for _, id := range []string{publicID, strings.ToLower(publicID), "42"} {
+		w := serve(router, http.MethodGet, "/api/transactions/"+id, "", nil)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: статус %d, want 200; тело %s", id, w.Code, truncate(w.Body.String()))
+			continue
+		}
+		var got models.Transaction
+		json.Unmarshal(w.Body.Bytes(), &got)
+		if got.ID != 42 || got.PublicID != publicID {
+			t.Errorf("%s: транзакция id %d, public_id %s, want 42 и %s", id, got.ID, got.PublicID, publicID)
+		}
+		if etag == "" {
+			etag = w.Header().Get("ETag")
+		} else if w.Header().Get("ETag") != etag {
+			t.Errorf("%s: ETag %s, want %s", id, w.Header().Get("ETag"), etag)
+		}
+	}
+
+	tests := []struct {
+		id     string
+		status int
+		code   string
+	}{
+		{"01ARZ3NDEKTSV4RRFFQ69G5FAX", http.StatusNotFound, CodeTxNotFound},
+		{"44", http.StatusNotFound, CodeTxNotFound},
+		{"0", http.StatusBadRequest, CodeInvalidRequest},
+		{"-42", http.StatusBadRequest, CodeInvalidRequest},
+		{"abc", http.StatusBadRequest, CodeInvalidRequest},
+		// 26 символов, но время первого символа больше 48 бит - не ULID и не число.
+		{"8ZZZZZZZZZZZZZZZZZZZZZZZZZ", http.StatusBadRequest, CodeInvalidRequest},
+	}
+	for _, tt := range tests {
+		w := serve(router, http.MethodGet, "/api/transactions/"+tt.id, "", nil)
+		if w.Code != tt.status {
+			t.Errorf("%s: статус %d, want %d", tt.id, w.Code, tt.status)
+			continue
+		}
+		checkErrorEnvelope(t, tt.id, w, tt.code)
+	}
+
+	// Маршруты с {id}, изменяющие транзакцию, тоже принимают оба идентификатора.
+	w := serve(router, http.MethodPost, "/api/transactions/"+strings.ToLower(publicID)+"/ack", `{"address":"`+recipient+`"}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("подтверждение по public_id: статус %d, want 200; тело %s", w.Code, truncate(w.Body.String()))
+	}
+	if acked := db.transactions[42]; acked.AcknowledgedAt == nil {
+		t.Error("подтверждение по public_id не отметило транзакцию 42")
+	}
+	if other := db.transactions[43]; other.AcknowledgedAt != nil {
+		t.Error("подтверждение по public_id отметило другую транзакцию")
+	}
+	if w := serve(router, http.MethodPost, "/api/transactions/43/ack", `{"address":"`+recipient+`"}`, nil); w.Code != http.StatusOK {
+		t.Errorf("подтверждение по номеру: статус %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,250 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go-payments/internal/models"
+	"go-payments/internal/money"
+	"go-payments/internal/redact"
+	"go-payments/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type createWithdrawalRequest struct {
+	Amount    money.Amount `json:"amount"`
+	Reference string       `json:"reference"`
+}
+
+type failWithdrawalRequest struct {
+	Reason string `json:"reason"`
+}
+
+// withdrawalID разбирает параметр пути {id}. При ошибке отвечает 400 и возвращает false.
+func withdrawalID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		badRequest(w, "идентификатор вывода средств должен быть положительным числом")
+		return 0, false
+	}
+	return id, true
+}
+
+// parseWithdrawalStatus разбирает параметр status списка выводов средств; пустой
+// параметр не ограничивает список. При ошибке отвечает 400 и возвращает false.
+func parseWithdrawalStatus(w http.ResponseWriter, r *http.Request) (models.WithdrawalStatus, bool) {
+	status := models.WithdrawalStatus(r.URL.Query().Get("status"))
+	switch status {
+	case "", models.WithdrawalPending, models.WithdrawalReview, models.WithdrawalConfirmed, models.WithdrawalFailed:
+		return status, true
+	}
+	badRequest(w, "параметр 'status' должен быть pending, review, confirmed или failed")
+	return "", false
+}
+
+// CreateWithdrawal списывает сумму с кошелька на клиринговый кошелёк и создаёт
+// вывод средств в состоянии pending.
+func (a *API) CreateWithdrawal(w http.ResponseWriter, r *http.Request) {
+	if a.cfg.WithdrawalWallet == "" {
+		writeError(w, http.StatusNotImplemented, CodeWithdrawalsDisabled, "выводы средств отключены: не задан WITHDRAWAL_WALLET", nil)
+		return
+	}
+	address := chi.URLParam(r, "address")
+
+	var req createWithdrawalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.badBody(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Amount <= 0 {
+		badRequest(w, "сумма вывода должна быть положительной")
+		return
+	}
+	if !a.amountInRange(w, "amount", req.Amount) {
+		return
+	}
+	req.Reference = strings.TrimSpace(req.Reference)
+	if len(req.Reference) > models.MaxWithdrawalReferenceLength {
+		badRequest(w, fmt.Sprintf("поле 'reference' длиннее %d символов", models.MaxWithdrawalReferenceLength))
+		return
+	}
+	if address == a.cfg.WithdrawalWallet {
+		badRequest(w, "нельзя вывести средства с клирингового кошелька")
+		return
+	}
+	idempotencyKey := strings.TrimSpace(r.Header.Get(idempotencyKeyHeader))
+	if len(idempotencyKey) > models.MaxIdempotencyKeyLength {
+		badRequest(w, fmt.Sprintf("заголовок %s длиннее %d символов", idempotencyKeyHeader, models.MaxIdempotencyKeyLength))
+		return
+	}
+
+	var stats storage.ExecStats
+	withdrawal, err := a.db.CreateWithdrawal(storage.WithExecStats(r.Context(), &stats), models.Withdrawal{
+		Wallet:         address,
+		ClearingWallet: a.cfg.WithdrawalWallet,
+		Amount:         req.Amount,
+		Reference:      req.Reference,
+	}, idempotencyKey)
+	if err != nil {
+		log.Printf("ошибка вывода средств с кошелька %s на сумму %s (попыток: %d, %s): %v",
+			redact.Address(address), money.FormatAmount(float64(req.Amount)), stats.Attempts, stats.Elapsed, err)
+		writeStorageError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if stats.Replayed {
+		w.Header().Set(idempotentReplayedHeader, "true")
+		writeJSON(w, r, withdrawal)
+		return
+	}
+	log.Printf("вывод средств %d с кошелька %s на сумму %s ожидает подтверждения",
+		withdrawal.ID, redact.Address(address), money.FormatAmount(float64(withdrawal.Amount)))
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, r, withdrawal)
+}
+
+// GetWithdrawal возвращает вывод средств по идентификатору.
+func (a *API) GetWithdrawal(w http.ResponseWriter, r *http.Request) {
+	id, ok := withdrawalID(w, r)
+	if !ok {
+		return
+	}
+
+	withdrawal, err := a.db.GetWithdrawal(r.Context(), id)
+	if err != nil {
+		if !errors.Is(err, storage.ErrWithdrawalNotFound) {
+			log.Printf("ошибка получения вывода средств %d: %v", id, err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+	writeJSON(w, r, withdrawal)
+}
+
+// GetWalletWithdrawals возвращает последние выводы средств кошелька.
+func (a *API) GetWalletWithdrawals(w http.ResponseWriter, r *http.Request) {
+	a.writeWithdrawals(w, r, chi.URLParam(r, "address"))
+}
+
+// ListWithdrawals возвращает последние выводы средств всех кошельков, например
+// очередь разбора (status=review).
+func (a *API) ListWithdrawals(w http.ResponseWriter, r *http.Request) {
+	wallet := r.URL.Query().Get("wallet")
+	if wallet != "" && !normalizeAddress(w, "wallet", &wallet) {
+		return
+	}
+	a.writeWithdrawals(w, r, wallet)
+}
+
+// writeWithdrawals отвечает списком выводов средств кошелька wallet (пустой -
+// всех кошельков) с фильтром status и размером страницы limit.
+func (a *API) writeWithdrawals(w http.ResponseWriter, r *http.Request, wallet string) {
+	page, err := parsePagination(r, pagination{Limit: defaultListLimit}, maxListLimit)
+	if err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+	status, ok := parseWithdrawalStatus(w, r)
+	if !ok {
+		return
+	}
+
+	withdrawals, err := a.db.ListWithdrawals(r.Context(), wallet, status, page.Limit)
+	if err != nil {
+		log.Printf("ошибка получения выводов средств: %v", err)
+		writeStorageError(w, r, err)
+		return
+	}
+	writeJSON(w, r, withdrawals)
+}
+
+// settledAs отвечает на повтор перехода вывода id в состояние target: если
+// вывод уже в нём, повтор безопасен и возвращает вывод с 200, иначе 409.
+func (a *API) settledAs(w http.ResponseWriter, r *http.Request, id int, target models.WithdrawalStatus) {
+	withdrawal, err := a.db.GetWithdrawal(r.Context(), id)
+	if err != nil {
+		log.Printf("ошибка получения вывода средств %d: %v", id, err)
+		writeStorageError(w, r, err)
+		return
+	}
+	if withdrawal.Status != target {
+		writeError(w, http.StatusConflict, CodeWithdrawalSettled, storage.ErrWithdrawalSettled.Error(),
+			map[string]models.WithdrawalStatus{"status": withdrawal.Status})
+		return
+	}
+	writeJSON(w, r, withdrawal)
+}
+
+// ConfirmWithdrawal подтверждает расчёт вывода средств в состоянии pending или review.
+func (a *API) ConfirmWithdrawal(w http.ResponseWriter, r *http.Request) {
+	id, ok := withdrawalID(w, r)
+	if !ok {
+		return
+	}
+
+	withdrawal, err := a.db.ConfirmWithdrawal(r.Context(), id)
+	if errors.Is(err, storage.ErrWithdrawalSettled) {
+		a.settledAs(w, r, id, models.WithdrawalConfirmed)
+		return
+	}
+	if err != nil {
+		if !errors.Is(err, storage.ErrWithdrawalNotFound) {
+			log.Printf("ошибка подтверждения вывода средств %d: %v", id, err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+	log.Printf("вывод средств %d кошелька %s подтверждён (%s)", id, redact.Address(withdrawal.Wallet), r.Header.Get(actorHeader))
+	writeJSON(w, r, withdrawal)
+}
+
+// FailWithdrawal отклоняет вывод средств в состоянии pending или review и
+// возвращает сумму на кошелёк.
+func (a *API) FailWithdrawal(w http.ResponseWriter, r *http.Request) {
+	id, ok := withdrawalID(w, r)
+	if !ok {
+		return
+	}
+
+	var req failWithdrawalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.badBody(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		badRequest(w, "поле 'reason' обязательно")
+		return
+	}
+	if len(req.Reason) > models.MaxWithdrawalReasonLength {
+		badRequest(w, fmt.Sprintf("поле 'reason' длиннее %d символов", models.MaxWithdrawalReasonLength))
+		return
+	}
+
+	withdrawal, err := a.db.FailWithdrawal(r.Context(), id, req.Reason)
+	if errors.Is(err, storage.ErrWithdrawalSettled) {
+		a.settledAs(w, r, id, models.WithdrawalFailed)
+		return
+	}
+	if err != nil {
+		if !errors.Is(err, storage.ErrWithdrawalNotFound) && !errors.Is(err, storage.ErrPeriodClosed) {
+			log.Printf("ошибка отказа вывода средств %d: %v", id, err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+	log.Printf("вывод средств %d кошелька %s отклонён, сумма %s возвращена транзакцией %d (%s)", id,
+		redact.Address(withdrawal.Wallet), money.FormatAmount(float64(withdrawal.Amount)), *withdrawal.ReversalTransactionID, r.Header.Get(actorHeader))
+	writeJSON(w, r, withdrawal)
+}
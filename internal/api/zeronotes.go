@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"go-payments/internal/models"
+	"go-payments/internal/money"
+	"go-payments/internal/redact"
+	"go-payments/internal/storage"
+)
+
+type zeroNoteRequest struct {
+	From      string            `json:"from"`
+	To        string            `json:"to"`
+	Amount    *money.Amount     `json:"amount"`
+	Memo      string            `json:"memo"`
+	Reference string            `json:"reference"`
+	Metadata  map[string]string `json:"metadata"`
+}
+
+// RecordZeroNote записывает между кошельками нулевую транзакцию-заметку
+// (статус zero_note), например отметку о сверке с внешней системой. Средства не
+// перемещаются, а в объёмах и итогах переводов заметка не учитывается.
+func (a *API) RecordZeroNote(w http.ResponseWriter, r *http.Request) {
+	var req zeroNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.badBody(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	if !normalizeAddress(w, "from", &req.From) || !normalizeAddress(w, "to", &req.To) {
+		return
+	}
+	// Сумма обязательна и равна нулю, чтобы заметку нельзя было принять за перевод.
+	if req.Amount == nil || *req.Amount != 0 {
+		badRequest(w, "сумма заметки должна быть равна 0: для перевода средств используйте /api/send")
+		return
+	}
+	req.Memo = strings.TrimSpace(req.Memo)
+	if req.Memo == "" {
+		badRequest(w, "поле 'memo' обязательно")
+		return
+	}
+	if req.From == req.To {
+		badRequest(w, "отправитель и получатель заметки должны различаться")
+		return
+	}
+	if err := models.ValidateMetadata(req.Metadata); err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+
+	transaction, err := a.db.RecordZeroNote(r.Context(), models.Transfer{
+		From:      req.From,
+		To:        req.To,
+		Memo:      req.Memo,
+		Reference: req.Reference,
+		Metadata:  req.Metadata,
+	})
+	if err != nil {
+		if storage.FailureStatus(err) == models.StatusUnknownError {
+			log.Printf("ошибка записи заметки от %s к %s: %v", redact.Address(req.From), redact.Address(req.To), err)
+		}
+		writeStorageError(w, r, err)
+		return
+	}
+	log.Printf("заметка %d от %s к %s записана (%s)", transaction.ID,
+		redact.Address(req.From), redact.Address(req.To), r.Header.Get(actorHeader))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, r, transaction)
+}
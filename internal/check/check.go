@@ -0,0 +1,93 @@
+/*
+check выполняет предстартовую проверку окружения для конвейеров развёртывания:
+загружает конфигурацию, подключается к базе данных, сверяет версию схемы
+с ожидаемой сборкой и выполняет простой запрос на чтение.
+
+Run печатает отчёт в формате JSON и сообщает, пройдены ли все проверки.
+Fsck (флаг -fsck) проверяет согласованность данных и печатает находки в формате
+NDJSON. HTTP-сервер при этом не запускается.
+*/
+package check
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"go-payments/internal/config"
+	"go-payments/internal/storage"
+)
+
+// Result - результат одной проверки.
+type Result struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// Report - отчёт предстартовой проверки.
+type Report struct {
+	OK     bool     `json:"ok"`
+	Checks []Result `json:"checks"`
+}
+
+func (r *Report) add(name string, err error) bool {
+	res := Result{Name: name, OK: err == nil}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	r.Checks = append(r.Checks, res)
+	return err == nil
+}
+
+// Run выполняет проверки по порядку, останавливаясь на первой неудачной,
+// записывает отчёт в out и возвращает true, если все проверки пройдены.
+func Run(ctx context.Context, out io.Writer) bool {
+	report := Report{}
+	report.OK = run(ctx, &report)
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+	return report.OK
+}
+
+func run(ctx context.Context, report *Report) bool {
+	cfg, err := config.Load()
+	if !report.add("config", err) {
+		return false
+	}
+
+	db, err := storage.New(cfg.Database)
+	if !report.add("database_connection", describeConnectError(err)) {
+		return false
+	}
+	defer db.Close()
+
+	version, err := db.SchemaVersion(ctx)
+	if err == nil && version != storage.LatestSchemaVersion() {
+		err = fmt.Errorf("версия схемы %d не совпадает с ожидаемой %d", version, storage.LatestSchemaVersion())
+	}
+	if !report.add("schema_version", err) {
+		return false
+	}
+
+	_, _, err = db.GetWallets(ctx, 1)
+	return report.add("read_query", err)
+}
+
+// describeConnectError дополняет ошибку подключения подсказкой о вероятной причине.
+func describeConnectError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, storage.ErrAuthFailed):
+		return fmt.Errorf("проверьте POSTGRES_USER и POSTGRES_PASSWORD: %w", err)
+	case errors.Is(err, storage.ErrDatabaseUnreachable):
+		return fmt.Errorf("проверьте POSTGRES_HOST и POSTGRES_PORT: %w", err)
+	default:
+		return err
+	}
+}
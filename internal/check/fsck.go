@@ -0,0 +1,50 @@
+package check
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"go-payments/internal/config"
+	"go-payments/internal/models"
+	"go-payments/internal/storage"
+)
+
+// fsckResult - итоговая строка вывода Fsck.
+type fsckResult struct {
+	models.FsckSummary
+	Done  bool   `json:"done"`
+	Error string `json:"error,omitempty"`
+}
+
+// Fsck выполняет все проверки целостности данных без лимита находок и пишет
+// в out находки в формате NDJSON, а последней строкой - итог. Возвращает false,
+// если найдена хотя бы одна ошибка или проверку не удалось выполнить;
+// предупреждения результат не меняют.
+func Fsck(ctx context.Context, out io.Writer) bool {
+	enc := json.NewEncoder(out)
+	fail := func(err error) bool {
+		enc.Encode(fsckResult{Error: err.Error()})
+		return false
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fail(err)
+	}
+	db, err := storage.New(cfg.Database)
+	if err != nil {
+		return fail(describeConnectError(err))
+	}
+	defer db.Close()
+
+	summary, err := db.Fsck(ctx, 0, func(finding models.FsckFinding) error {
+		return enc.Encode(finding)
+	})
+	result := fsckResult{FsckSummary: summary, Done: err == nil}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	enc.Encode(result)
+	return err == nil && summary.Errors == 0
+}
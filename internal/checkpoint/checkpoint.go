@@ -0,0 +1,82 @@
+/*
+checkpoint строит и подписывает контрольные точки балансов.
+
+Формат контрольной точки, дерево Меркла и проверка описаны в пакете
+go-payments/pkg/client: проверку выполняет аудитор без остального кода сервиса,
+поэтому она живёт в публичном пакете без зависимостей. Здесь остаются ключ и
+подпись, а типы и функции дерева доступны под прежними именами.
+
+Functions:
+  - ParseSigningKey: Разбирает закрытый ключ Ed25519 из 32-байтового зерна в hex.
+  - LeafHash, InclusionProof: Хеш листа и путь доказательства (см. pkg/client).
+  - Sign: Вычисляет корень по балансам и подписывает контрольную точку.
+*/
+package checkpoint
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"go-payments/pkg/client"
+)
+
+// DateLayout - формат даты контрольной точки.
+const DateLayout = "2006-01-02"
+
+type (
+	// Checkpoint - подписанная контрольная точка (см. client.Checkpoint).
+	Checkpoint = client.Checkpoint
+	// Leaf - баланс одного кошелька в контрольной точке (см. client.Leaf).
+	Leaf = client.Leaf
+	// Bundle - контрольная точка с балансами всех кошельков (см. client.Bundle).
+	Bundle = client.Bundle
+	// Proof - доказательство включения баланса кошелька (см. client.Proof).
+	Proof = client.Proof
+)
+
+var (
+	// ErrBadSignature - подпись не соответствует контрольной точке и ключу.
+	ErrBadSignature = client.ErrBadSignature
+	// ErrRootMismatch - корень, вычисленный по балансам или доказательству, не совпадает с подписанным.
+	ErrRootMismatch = client.ErrRootMismatch
+)
+
+// ParseSigningKey разбирает закрытый ключ Ed25519 из 32-байтового зерна в hex.
+func ParseSigningKey(s string) (ed25519.PrivateKey, error) {
+	seed, err := hex.DecodeString(s)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("ключ подписи контрольных точек должен быть %d байтами в hex", ed25519.SeedSize)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// LeafHash возвращает хеш листа для баланса кошелька.
+func LeafHash(l Leaf) []byte {
+	return client.LeafHash(l)
+}
+
+// InclusionProof возвращает путь доказательства для листа index: хеши соседних
+// поддеревьев от листа к корню.
+func InclusionProof(leaves [][]byte, index int) [][]byte {
+	return client.InclusionProof(leaves, index)
+}
+
+// Sign вычисляет корень дерева над balances (уже упорядоченными по адресу) и
+// подписывает контрольную точку за date на момент asOf ключом key.
+func Sign(key ed25519.PrivateKey, date string, asOf time.Time, balances []Leaf) Checkpoint {
+	leaves := make([][]byte, len(balances))
+	for i, l := range balances {
+		leaves[i] = client.LeafHash(l)
+	}
+	c := Checkpoint{
+		Date:        date,
+		AsOf:        asOf.UTC(),
+		WalletCount: len(balances),
+		Root:        hex.EncodeToString(client.Root(leaves)),
+		PublicKey:   hex.EncodeToString(key.Public().(ed25519.PublicKey)),
+	}
+	c.Signature = hex.EncodeToString(ed25519.Sign(key, client.Message(c.Date, c.AsOf, c.WalletCount, c.Root)))
+	return c
+}
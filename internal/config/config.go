@@ -0,0 +1,401 @@
+/*
+config загружает конфигурацию приложения из переменных окружения
+(и файла `.env`, если он есть).
+
+Поля конфигурации описываются тегами:
+  - env: имя переменной окружения;
+  - required: "true", если переменная обязательна;
+  - default: значение, если переменная не задана;
+  - min, max: допустимый диапазон для целых чисел (min - также для длительностей);
+  - oneof: допустимые значения строки через запятую;
+  - example: пример значения для сообщений об ошибках;
+  - secret: "true" для паролей, DSN и ключей - такие поля скрываются в Redacted;
+  - address: "true" для адресов кошельков - в Redacted они сокращаются через
+    redact.Address;
+  - feature: имя функции, которую включает поле, для отчёта Features.
+
+Load проверяет все переменные сразу и возвращает *ValidationError со списком
+всех проблем, а не только первой найденной.
+*/
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-payments/internal/redact"
+
+	"github.com/joho/godotenv"
+)
+
+// Значение, которым заменяются секреты в Redacted.
+const redactedValue = "[REDACTED]"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Database - параметры подключения к PostgreSQL.
+type Database struct {
+	Host     string `json:"host" env:"POSTGRES_HOST" required:"true" example:"postgres"`
+	Port     int    `json:"port" env:"POSTGRES_PORT" required:"true" min:"1" max:"65535" example:"5432"`
+	User     string `json:"user" env:"POSTGRES_USER" required:"true" example:"postgres"`
+	Password string `json:"password" env:"POSTGRES_PASSWORD" secret:"true" example:"your_password"`
+	Name     string `json:"name" env:"POSTGRES_DB" required:"true" example:"payments"`
+}
+
+// DSN возвращает строку подключения для драйвера lib/pq.
+func (d Database) DSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+		d.Host, d.Port, d.User, d.Password, d.Name)
+}
+
+// HTTP - адреса HTTP-слушателей.
+type HTTP struct {
+	PublicAddr string `json:"public_addr" env:"HTTP_ADDR" default:":8080" example:":8080"`
+	// SeparateInternal включает второй слушатель для /api/admin, /metrics, /debug/pprof и /readyz.
+	SeparateInternal bool   `json:"separate_internal" env:"SEPARATE_INTERNAL_LISTENER" default:"false" example:"true" feature:"separate_internal_listener"`
+	InternalAddr     string `json:"internal_addr" env:"INTERNAL_HTTP_ADDR" default:"127.0.0.1:8081" example:"127.0.0.1:8081"`
+	// MaxBodyBytes - наибольший размер тела запроса к API; тело больше отклоняется до разбора.
+	MaxBodyBytes int `json:"max_body_bytes" env:"MAX_BODY_BYTES" default:"1048576" min:"1024" max:"1073741824" example:"1048576"`
+	// MaxRestoreBytes - наибольший размер снимка кошельков в /api/admin/restore.
+	MaxRestoreBytes int `json:"max_restore_bytes" env:"MAX_RESTORE_BYTES" default:"268435456" min:"1024" max:"1073741824" example:"268435456"`
+}
+
+// Notify - параметры уведомлений о переводах.
+type Notify struct {
+	// WebhookURL, если задан, получает уведомления POST-запросами; иначе они пишутся в лог.
+	WebhookURL   string `json:"webhook_url" env:"NOTIFY_WEBHOOK_URL" secret:"true" example:"https://hooks.example.com/payments" feature:"notify_webhook"`
+	TemplatesDir string `json:"templates_dir" env:"NOTIFY_TEMPLATES_DIR" example:"/etc/payments/templates" feature:"notify_templates"`
+	// EventTypes - типы событий через запятую, о которых отправляются уведомления; пусто - все.
+	EventTypes string `json:"event_types" env:"NOTIFY_EVENT_TYPES" example:"transfer.success,wallet.label_changed" feature:"notify_event_filter"`
+	// DigestWindow, если не 0, объединяет успешные переводы на один кошелёк за это окно в одну сводку.
+	DigestWindow    time.Duration `json:"digest_window" env:"NOTIFY_DIGEST_WINDOW" default:"0s" example:"30s" feature:"notify_digest"`
+	DigestMaxEvents int           `json:"digest_max_events" env:"NOTIFY_DIGEST_MAX_EVENTS" default:"1000" min:"1" example:"1000"`
+}
+
+// Alerts - параметры предупреждений о падении балансов.
+type Alerts struct {
+	// WebhookURL, если задан, получает предупреждения POST-запросами; иначе они пишутся в лог.
+	WebhookURL    string        `json:"webhook_url" env:"ALERT_WEBHOOK_URL" secret:"true" example:"https://hooks.example.com/alerts" feature:"alert_webhook"`
+	CheckInterval time.Duration `json:"check_interval" env:"ALERT_CHECK_INTERVAL" default:"1m" min:"1s" example:"1m"`
+}
+
+// Stats - параметры агрегированной статистики.
+type Stats struct {
+	// RefreshInterval - период обновления сводки по кошелькам (/api/stats/wallets).
+	RefreshInterval time.Duration `json:"refresh_interval" env:"STATS_REFRESH_INTERVAL" default:"1m" min:"1s" example:"1m"`
+}
+
+type Config struct {
+	Database Database `json:"database"`
+	HTTP     HTTP     `json:"http"`
+	Notify   Notify   `json:"notify"`
+	Alerts   Alerts   `json:"alerts"`
+	Stats    Stats    `json:"stats"`
+	// MaintenanceMode, если задан, переопределяет сохранённый режим обслуживания.
+	MaintenanceMode *bool `json:"maintenance_mode" env:"MAINTENANCE_MODE" example:"false" feature:"maintenance_mode"`
+	// SlowQueryThreshold - длительность вызова хранилища, после которой он пишется в лог как медленный.
+	SlowQueryThreshold time.Duration `json:"slow_query_threshold" env:"SLOW_QUERY_THRESHOLD" default:"200ms" example:"200ms"`
+	// StrictSchema запрещает запуск, если схема базы данных расходится с миграциями.
+	StrictSchema bool `json:"strict_schema" env:"STRICT_SCHEMA" default:"false" example:"true" feature:"strict_schema"`
+	// StrictParams отклоняет запросы с неизвестными query-параметрами без заголовка X-Strict-Params.
+	StrictParams bool `json:"strict_params" env:"STRICT_PARAMS" default:"false" example:"true" feature:"strict_params"`
+	// DedupBalanceReads объединяет одинаковые одновременные чтения баланса в один запрос к базе.
+	DedupBalanceReads bool `json:"dedup_balance_reads" env:"DEDUP_BALANCE_READS" default:"true" example:"false" feature:"dedup_balance_reads"`
+	// TransactionCacheSize - количество неизменяемых транзакций в кэше процесса; 0 отключает кэш.
+	TransactionCacheSize int `json:"transaction_cache_size" env:"TRANSACTION_CACHE_SIZE" default:"1000" min:"0" max:"1000000" example:"1000" feature:"transaction_cache"`
+	// SendConcurrency - максимум одновременных запросов на перевод; 0 - без ограничения.
+	SendConcurrency int `json:"send_concurrency" env:"SEND_CONCURRENCY" default:"0" min:"0" max:"10000" example:"20" feature:"send_concurrency_limit"`
+	// SendQueueWait - сколько запрос на перевод ждёт свободного места, прежде чем получить 503.
+	SendQueueWait time.Duration `json:"send_queue_wait" env:"SEND_QUEUE_WAIT" default:"100ms" min:"1ms" example:"100ms"`
+	// PaymentRequestTTL - срок действия запроса на оплату, если клиент не указал expires_at.
+	PaymentRequestTTL time.Duration `json:"payment_request_ttl" env:"PAYMENT_REQUEST_TTL" default:"24h" min:"1m" example:"24h"`
+	// WithdrawalWallet - адрес системного клирингового кошелька, на который списываются
+	// выводы средств; без него выводы средств отключены.
+	WithdrawalWallet string `json:"withdrawal_wallet" env:"WITHDRAWAL_WALLET" address:"true" example:"0000000000000000000000000000000000000000000000000000000000c1ea12" feature:"withdrawals"`
+	// WithdrawalReviewAfter - через сколько неподтверждённый вывод средств передаётся на разбор.
+	WithdrawalReviewAfter time.Duration `json:"withdrawal_review_after" env:"WITHDRAWAL_REVIEW_AFTER" default:"72h" min:"1m" example:"72h"`
+	// CursorSecret - ключ подписи курсоров пагинации; общий для всех экземпляров.
+	// Без него ключ случайный, и курсоры не переживают перезапуск и не переходят между экземплярами.
+	CursorSecret string `json:"cursor_secret" env:"CURSOR_SECRET" secret:"true" example:"long-random-string"`
+	// CursorMaxAge - срок действия курсора пагинации; 0 - без ограничения.
+	CursorMaxAge time.Duration `json:"cursor_max_age" env:"CURSOR_MAX_AGE" default:"0s" example:"24h"`
+	// FaultInjection включает внедрение отказов хранилища (/debug/faults); только в сборке с тегом faults.
+	FaultInjection bool `json:"fault_injection" env:"FAULT_INJECTION" default:"false" example:"true" feature:"fault_injection"`
+	// SandboxMode включает магические адреса песочницы с предопределёнными исходами переводов.
+	SandboxMode bool `json:"sandbox_mode" env:"SANDBOX_MODE" default:"false" example:"true" feature:"sandbox_mode"`
+	// AutoCreateRecipients создаёт кошелёк получателя с нулевым балансом при первом переводе на его адрес.
+	AutoCreateRecipients bool `json:"auto_create_recipients" env:"AUTO_CREATE_RECIPIENTS" default:"false" example:"true" feature:"auto_create_recipients"`
+	// MaxAmount - наибольшая сумма перевода, запроса на оплату, предельного баланса и порога.
+	MaxAmount int `json:"max_amount" env:"MAX_AMOUNT" default:"1000000000" min:"1" max:"999999999999" example:"1000000000"`
+	// CheckpointSigningKey - закрытый ключ Ed25519 (32-байтовое зерно в hex), которым
+	// подписываются ежедневные контрольные точки балансов; без него они не создаются.
+	CheckpointSigningKey string `json:"checkpoint_signing_key" env:"CHECKPOINT_SIGNING_KEY" secret:"true" example:"9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60" feature:"checkpoints"`
+	// AddressScheme - формат адресов новых кошельков; поиск принимает адреса любой схемы.
+	AddressScheme string `json:"address_scheme" env:"ADDRESS_SCHEME" default:"hex64" oneof:"hex64,uuidv4" example:"uuidv4"`
+}
+
+// FieldError описывает проблему с одной переменной окружения.
+type FieldError struct {
+	Variable string `json:"variable"`
+	Problem  string `json:"problem"`
+	Expected string `json:"expected"`
+	Example  string `json:"example,omitempty"`
+}
+
+func (e FieldError) String() string {
+	s := fmt.Sprintf("%s: %s (ожидается %s", e.Variable, e.Problem, e.Expected)
+	if e.Example != "" {
+		s += fmt.Sprintf(", например %s", e.Example)
+	}
+	return s + ")"
+}
+
+// ValidationError содержит все проблемы, найденные при загрузке конфигурации.
+type ValidationError struct {
+	Problems []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		lines[i] = p.String()
+	}
+	return "ошибка конфигурации: " + strings.Join(lines, "; ")
+}
+
+// Load читает конфигурацию из окружения.
+func Load() (*Config, error) {
+	_ = godotenv.Load()
+
+	var cfg Config
+	var problems []FieldError
+	loadStruct(reflect.ValueOf(&cfg).Elem(), &problems)
+
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return &cfg, nil
+}
+
+func loadStruct(v reflect.Value, problems *[]FieldError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		value := v.Field(i)
+
+		name, ok := field.Tag.Lookup("env")
+		if !ok {
+			if field.Type.Kind() == reflect.Struct {
+				loadStruct(value, problems)
+			}
+			continue
+		}
+
+		raw := os.Getenv(name)
+		if raw == "" {
+			raw = field.Tag.Get("default")
+		}
+		if raw == "" {
+			if field.Tag.Get("required") == "true" {
+				*problems = append(*problems, fieldError(field, name, "переменная не задана"))
+			}
+			continue
+		}
+
+		if err := setField(field, value, raw); err != nil {
+			*problems = append(*problems, fieldError(field, name, err.Error()))
+		}
+	}
+}
+
+func setField(field reflect.StructField, value reflect.Value, raw string) error {
+	if value.Kind() == reflect.Pointer {
+		ptr := reflect.New(value.Type().Elem())
+		if err := setField(field, ptr.Elem(), raw); err != nil {
+			return err
+		}
+		value.Set(ptr)
+		return nil
+	}
+
+	if value.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 {
+			return fmt.Errorf("некорректное значение %q", raw)
+		}
+		if minStr, ok := field.Tag.Lookup("min"); ok {
+			if lo, _ := time.ParseDuration(minStr); d < lo {
+				return fmt.Errorf("значение %s меньше %s", d, lo)
+			}
+		}
+		value.SetInt(int64(d))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		if oneof, ok := field.Tag.Lookup("oneof"); ok && !slices.Contains(strings.Split(oneof, ","), raw) {
+			return fmt.Errorf("недопустимое значение %q", raw)
+		}
+		value.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("некорректное значение %q", raw)
+		}
+		if minStr, ok := field.Tag.Lookup("min"); ok {
+			if lo, _ := strconv.Atoi(minStr); n < lo {
+				return fmt.Errorf("значение %d меньше %d", n, lo)
+			}
+		}
+		if maxStr, ok := field.Tag.Lookup("max"); ok {
+			if hi, _ := strconv.Atoi(maxStr); n > hi {
+				return fmt.Errorf("значение %d больше %d", n, hi)
+			}
+		}
+		value.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("некорректное значение %q", raw)
+		}
+		value.SetBool(b)
+	default:
+		return fmt.Errorf("неподдерживаемый тип поля %s", value.Type())
+	}
+	return nil
+}
+
+func fieldError(field reflect.StructField, name, problem string) FieldError {
+	return FieldError{
+		Variable: name,
+		Problem:  problem,
+		Expected: expectedFormat(field),
+		Example:  field.Tag.Get("example"),
+	}
+}
+
+// expectedFormat описывает ожидаемый формат значения по типу поля и тегам.
+func expectedFormat(field reflect.StructField) string {
+	t := field.Type
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == durationType {
+		return "длительность, например 500ms или 2s"
+	}
+
+	switch t.Kind() {
+	case reflect.Int:
+		minStr, hasMin := field.Tag.Lookup("min")
+		maxStr, hasMax := field.Tag.Lookup("max")
+		if hasMin && hasMax {
+			return fmt.Sprintf("целое число от %s до %s", minStr, maxStr)
+		}
+		return "целое число"
+	case reflect.Bool:
+		return "true или false"
+	case reflect.String:
+		if oneof, ok := field.Tag.Lookup("oneof"); ok {
+			return "одно из: " + strings.ReplaceAll(oneof, ",", ", ")
+		}
+		return "непустая строка"
+	default:
+		return "непустая строка"
+	}
+}
+
+// Features возвращает состояние функций, отмеченных тегом feature: булево поле
+// включает функцию своим значением, остальные - тем, что заданы. Значения полей
+// в отчёт не попадают, поэтому секреты (URL вебхуков) не раскрываются.
+func (c *Config) Features() map[string]bool {
+	features := make(map[string]bool)
+	collectFeatures(reflect.ValueOf(c).Elem(), features)
+	return features
+}
+
+func collectFeatures(v reflect.Value, features map[string]bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		value := v.Field(i)
+
+		name, ok := field.Tag.Lookup("feature")
+		if !ok {
+			if field.Type.Kind() == reflect.Struct {
+				collectFeatures(value, features)
+			}
+			continue
+		}
+
+		if value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				features[name] = false
+				continue
+			}
+			value = value.Elem()
+		}
+		if value.Kind() == reflect.Bool {
+			features[name] = value.Bool()
+		} else {
+			features[name] = !value.IsZero()
+		}
+	}
+}
+
+// Redacted возвращает конфигурацию в виде дерева значений, пригодного для JSON,
+// в котором поля с тегом secret заменены на "[REDACTED]", а адреса кошельков
+// (тег address) сокращены. Скрытие структурное: решение принимается по тегу
+// поля, а не по его значению.
+func (c *Config) Redacted() map[string]any {
+	return redactStruct(reflect.ValueOf(c).Elem())
+}
+
+func redactStruct(v reflect.Value) map[string]any {
+	out := make(map[string]any)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" {
+			name = field.Name
+		}
+		if name == "-" {
+			continue
+		}
+
+		value := v.Field(i)
+		switch {
+		case field.Tag.Get("secret") == "true":
+			if value.IsZero() {
+				out[name] = ""
+			} else {
+				out[name] = redactedValue
+			}
+		case field.Tag.Get("address") == "true":
+			out[name] = redact.Address(value.String())
+		case value.Type() == durationType:
+			out[name] = value.Interface().(time.Duration).String()
+		case value.Kind() == reflect.Struct:
+			out[name] = redactStruct(value)
+		case value.Kind() == reflect.Pointer && value.IsNil():
+			out[name] = nil
+		case value.Kind() == reflect.Pointer && value.Elem().Kind() == reflect.Struct:
+			out[name] = redactStruct(value.Elem())
+		case value.Kind() == reflect.Pointer:
+			out[name] = value.Elem().Interface()
+		default:
+			out[name] = value.Interface()
+		}
+	}
+	return out
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setSecrets заполняет каждое поле с тегом secret уникальным значением и
+// возвращает эти значения.
+func setSecrets(t *testing.T, v reflect.Value) []string {
+	t.Helper()
+	var secrets []string
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		switch {
+		case field.Tag.Get("secret") == "true":
+			if value.Kind() != reflect.String {
+				t.Fatalf("секретное поле %s типа %s", field.Name, value.Type())
+			}
+			secret := "secret-" + strings.ToLower(field.Name) + "-7f3a9c"
+			value.SetString(secret)
+			secrets = append(secrets, secret)
+		case value.Kind() == reflect.Struct && value.Type() != durationType:
+			secrets = append(secrets, setSecrets(t, value)...)
+		}
+	}
+	return secrets
+}
+
+func TestRedactedHidesSecrets(t *testing.T) {
+	cfg := &Config{
+		Database:      Database{Host: "db.internal", Port: 5432, User: "payments", Name: "payments"},
+		AddressScheme: "hex64",
+	}
+	secrets := setSecrets(t, reflect.ValueOf(cfg).Elem())
+	if len(secrets) == 0 {
+		t.Fatal("в конфигурации нет полей с тегом secret")
+	}
+
+	dump, err := json.Marshal(cfg.Redacted())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range secrets {
+		if strings.Contains(string(dump), secret) {
+			t.Errorf("секрет %q попал в дамп конфигурации: %s", secret, dump)
+		}
+	}
+	// DSN собирается из пароля и тоже не должен попадать в дамп.
+	if strings.Contains(string(dump), cfg.Database.DSN()) {
+		t.Errorf("DSN попал в дамп конфигурации")
+	}
+
+	redacted := cfg.Redacted()
+	if got := redacted["database"].(map[string]any)["password"]; got != redactedValue {
+		t.Errorf("database.password = %v, want %s", got, redactedValue)
+	}
+	if got := redacted["database"].(map[string]any)["host"]; got != "db.internal" {
+		t.Errorf("database.host = %v, want db.internal", got)
+	}
+	if got := redacted["address_scheme"]; got != "hex64" {
+		t.Errorf("address_scheme = %v, want hex64", got)
+	}
+}
+
+func TestRedactedStructural(t *testing.T) {
+	// Скрытие зависит от тега поля, а не от того, похоже ли значение на секрет.
+	cfg := &Config{CursorSecret: "abc", Notify: Notify{TemplatesDir: "password=hunter2"}}
+	redacted := cfg.Redacted()
+	if got := redacted["cursor_secret"]; got != redactedValue {
+		t.Errorf("cursor_secret = %v, want %s", got, redactedValue)
+	}
+	if got := redacted["notify"].(map[string]any)["templates_dir"]; got != "password=hunter2" {
+		t.Errorf("notify.templates_dir = %v, want значение без изменений", got)
+	}
+	// Незаданный секрет остаётся пустым: по дампу видно, что он не загружен.
+	if got := redacted["checkpoint_signing_key"]; got != "" {
+		t.Errorf("пустой checkpoint_signing_key = %v, want пустую строку", got)
+	}
+}
+
+func TestLoadReportsAllProblems(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "")
+	t.Setenv("POSTGRES_PORT", "70000")
+	t.Setenv("POSTGRES_USER", "")
+	t.Setenv("POSTGRES_DB", "")
+	t.Setenv("SEND_QUEUE_WAIT", "soon")
+	t.Setenv("ADDRESS_SCHEME", "base58")
+
+	_, err := Load()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Load() = %v, want *ValidationError", err)
+	}
+	got := map[string]FieldError{}
+	for _, p := range verr.Problems {
+		got[p.Variable] = p
+	}
+	for _, variable := range []string{"POSTGRES_HOST", "POSTGRES_PORT", "POSTGRES_USER", "POSTGRES_DB", "SEND_QUEUE_WAIT", "ADDRESS_SCHEME"} {
+		p, ok := got[variable]
+		if !ok {
+			t.Errorf("нет проблемы с %s в %v", variable, err)
+			continue
+		}
+		if p.Problem == "" || p.Expected == "" || p.Example == "" {
+			t.Errorf("%s: неполное описание проблемы %+v", variable, p)
+		}
+	}
+	if len(verr.Problems) != len(got) || len(got) != 6 {
+		t.Errorf("проблем %d, want 6: %v", len(verr.Problems), err)
+	}
+}
+
+func TestRedactedShortensAddresses(t *testing.T) {
+	address := strings.Repeat("a1", 31) + "c1ea"
+	cfg := &Config{WithdrawalWallet: address}
+	dump, err := json.Marshal(cfg.Redacted())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(dump), address) {
+		t.Errorf("полный адрес кошелька попал в дамп конфигурации: %s", dump)
+	}
+	if got := cfg.Redacted()["withdrawal_wallet"]; got != "a1a1a1…c1ea" {
+		t.Errorf("withdrawal_wallet = %v, want a1a1a1…c1ea", got)
+	}
+	// Без клирингового кошелька по дампу видно, что выводы средств отключены.
+	if got := (&Config{}).Redacted()["withdrawal_wallet"]; got != "" {
+		t.Errorf("пустой withdrawal_wallet = %v, want пустую строку", got)
+	}
+}
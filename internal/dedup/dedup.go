@@ -0,0 +1,77 @@
+/*
+dedup объединяет одинаковые одновременные чтения баланса кошелька.
+
+Панели мониторинга присылают пачки одинаковых запросов
+GET /api/wallet/{address}/balance. Wrap оборачивает хранилище так, что
+одновременные вызовы GetWalletBalance с одним адресом разделяют один запрос
+к базе данных (golang.org/x/sync/singleflight). Результат используется только
+вызовами, пришедшими, пока запрос выполняется: это не кэш, и следующий вызов
+снова читает базу.
+
+Каждый вызывающий получает собственную копию кошелька. Если общий запрос
+прерван отменой контекста первого вызывающего, остальные повторяют чтение
+со своим контекстом. Объединённые вызовы учитываются в метрике
+`payments_balance_reads_deduplicated_total`.
+*/
+package dedup
+
+import (
+	"context"
+	"errors"
+
+	"go-payments/internal/api"
+	"go-payments/internal/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/singleflight"
+)
+
+var deduplicated = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "payments_balance_reads_deduplicated_total",
+	Help: "Количество чтений баланса, объединённых с уже выполняющимся запросом.",
+})
+
+// dedupStorage объединяет одновременные вызовы GetWalletBalance.
+type dedupStorage struct {
+	api.Storage
+	group singleflight.Group
+}
+
+// Wrap возвращает хранилище с объединением одновременных чтений баланса.
+func Wrap(next api.Storage) api.Storage {
+	return &dedupStorage{Storage: next}
+}
+
+func (s *dedupStorage) GetWalletBalance(ctx context.Context, address string) (*models.Wallet, error) {
+	leader := false
+	ch := s.group.DoChan(address, func() (any, error) {
+		leader = true
+		return s.Storage.GetWalletBalance(ctx, address)
+	})
+
+	var res singleflight.Result
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res = <-ch:
+	}
+
+	if !leader {
+		deduplicated.Inc()
+		// Общий запрос прерван контекстом другого вызова - читаем со своим.
+		if isContextError(res.Err) && ctx.Err() == nil {
+			return s.Storage.GetWalletBalance(ctx, address)
+		}
+	}
+	if res.Err != nil {
+		return nil, res.Err
+	}
+
+	wallet := *res.Val.(*models.Wallet)
+	return &wallet, nil
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
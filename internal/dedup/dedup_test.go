@@ -0,0 +1,171 @@
+package dedup
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go-payments/internal/api"
+	"go-payments/internal/models"
+)
+
+// countingStorage считает вызовы GetWalletBalance по адресам и держит каждый
+// вызов до закрытия release.
+type countingStorage struct {
+	api.Storage
+	release chan struct{}
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func newCountingStorage() *countingStorage {
+	return &countingStorage{release: make(chan struct{}), calls: map[string]int{}}
+}
+
+func (s *countingStorage) GetWalletBalance(ctx context.Context, address string) (*models.Wallet, error) {
+	s.mu.Lock()
+	s.calls[address]++
+	s.mu.Unlock()
+	select {
+	case <-s.release:
+		return &models.Wallet{Address: address, Balance: 100}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *countingStorage) count(address string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[address]
+}
+
+// waitCalls ждёт, пока хранилище получит n вызовов для address.
+func (s *countingStorage) waitCalls(t *testing.T, address string, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); s.count(address) < n; {
+		if time.Now().After(deadline) {
+			t.Fatalf("вызовов хранилища для %s: %d, want %d", address, s.count(address), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConcurrentBalanceReads(t *testing.T) {
+	const readers = 100
+	address := fmt.Sprintf("%064x", 1)
+	db := newCountingStorage()
+	s := Wrap(db)
+
+	var (
+		wg      sync.WaitGroup
+		started sync.WaitGroup
+		mu      sync.Mutex
+		wallets []*models.Wallet
+		errs    []error
+	)
+	started.Add(readers)
+	for range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			started.Done()
+			wallet, err := s.GetWalletBalance(context.Background(), address)
+			mu.Lock()
+			wallets, errs = append(wallets, wallet), append(errs, err)
+			mu.Unlock()
+		}()
+	}
+	// Запрос к базе не завершится, пока не закрыт release: все чтения,
+	// начавшиеся до этого, присоединяются к нему.
+	started.Wait()
+	db.waitCalls(t, address, 1)
+	time.Sleep(50 * time.Millisecond)
+	close(db.release)
+	wg.Wait()
+
+	if n := db.count(address); n != 1 {
+		t.Errorf("вызовов хранилища %d на %d одновременных чтений, want 1", n, readers)
+	}
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("чтение %d: %v", i, err)
+		}
+	}
+	// Каждый вызывающий получает свою копию кошелька.
+	seen := map[*models.Wallet]bool{}
+	for _, wallet := range wallets {
+		if wallet.Address != address || wallet.Balance != 100 {
+			t.Errorf("кошелёк %+v, want адрес %s и баланс 100", wallet, address)
+		}
+		if seen[wallet] {
+			t.Fatal("два вызывающих получили один и тот же *models.Wallet")
+		}
+		seen[wallet] = true
+	}
+
+	// Объединённый запрос не кэшируется: следующее чтение снова идёт в базу.
+	if _, err := s.GetWalletBalance(context.Background(), address); err != nil {
+		t.Fatal(err)
+	}
+	if n := db.count(address); n != 2 {
+		t.Errorf("вызовов хранилища после повторного чтения %d, want 2", n)
+	}
+}
+
+func TestBalanceReadsDifferentAddresses(t *testing.T) {
+	db := newCountingStorage()
+	close(db.release)
+	s := Wrap(db)
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.GetWalletBalance(context.Background(), fmt.Sprintf("%064x", i))
+		}()
+	}
+	wg.Wait()
+	for i := range 10 {
+		if n := db.count(fmt.Sprintf("%064x", i)); n != 1 {
+			t.Errorf("адрес %d: вызовов хранилища %d, want 1", i, n)
+		}
+	}
+}
+
+func TestBalanceReadLeaderCanceled(t *testing.T) {
+	address := fmt.Sprintf("%064x", 1)
+	db := newCountingStorage()
+	s := Wrap(db)
+
+	// Первый вызов начинает общий запрос и отменяется.
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := s.GetWalletBalance(leaderCtx, address)
+		leaderErr <- err
+	}()
+	db.waitCalls(t, address, 1)
+
+	followerDone := make(chan error, 1)
+	go func() {
+		_, err := s.GetWalletBalance(context.Background(), address)
+		followerDone <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	if err := <-leaderErr; err != context.Canceled {
+		t.Errorf("отменённый вызов: %v, want context.Canceled", err)
+	}
+
+	// Присоединившийся вызов не получает чужую отмену, а читает со своим контекстом.
+	db.waitCalls(t, address, 2)
+	close(db.release)
+	if err := <-followerDone; err != nil {
+		t.Errorf("вызов после отмены общего запроса: %v", err)
+	}
+}
@@ -0,0 +1,6 @@
+//go:build !faults
+
+package faults
+
+// Enabled - обычная сборка: внедрение отказов включить нельзя.
+const Enabled = false
@@ -0,0 +1,6 @@
+//go:build faults
+
+package faults
+
+// Enabled - сборка с тегом faults: внедрение отказов можно включить.
+const Enabled = true
@@ -0,0 +1,263 @@
+/*
+faults оборачивает хранилище и внедряет в его вызовы ошибки и задержки, чтобы
+проверять повторы, таймауты и поведение при недоступной базе данных без
+нестабильной настоящей базы.
+
+Правила задаются по методам хранилища: ошибка из предопределённого набора
+(см. Errors), задержка и частота срабатывания (каждый N-й вызов). Задержка
+учитывает контекст вызова, поэтому истечение X-Request-Timeout во время неё
+отдаётся как обычный 504. Правила меняются без перезапуска через Injector.Set
+(в коде) или через Handler (PUT /debug/faults).
+
+Пакет собирается всегда, но включить внедрение можно только в сборке с тегом
+faults (go build -tags faults): в обычной сборке Enabled равно false и main
+отказывается запускаться с FAULT_INJECTION=true.
+
+Внедрение выполняется только для методов из Methods - тех, через которые идут
+переводы, чтение и проверка готовности; остальные вызовы проходят без изменений.
+*/
+package faults
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go-payments/internal/api"
+	"go-payments/internal/models"
+	"go-payments/internal/storage"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var injected = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "payments_faults_injected_total",
+	Help: "Количество вызовов хранилища, в которые внедрена ошибка или задержка.",
+}, []string{"method"})
+
+// ErrInjected - исходная ошибка всех внедрённых отказов.
+var ErrInjected = errors.New("внедрённый отказ хранилища")
+
+// Errors - ошибки, которые можно внедрить, по именам из правил.
+var Errors = map[string]func() error{
+	// internal - непредвиденная ошибка базы данных (500).
+	"internal": func() error {
+		return &storage.TransactionError{Code: storage.CodeInternalError, OriginalErr: ErrInjected}
+	},
+	// retries_exhausted - перевод, исчерпавший повторы после конфликтов (503).
+	"retries_exhausted": func() error {
+		return &storage.TransactionError{Code: storage.CodeRetriesExhausted, OriginalErr: ErrInjected, Attempts: 3}
+	},
+	// deadline - истёкший дедлайн запроса к базе (504).
+	"deadline": func() error { return fmt.Errorf("%w: %w", ErrInjected, context.DeadlineExceeded) },
+	// unreachable - потерянное подключение к базе данных.
+	"unreachable": func() error {
+		return fmt.Errorf("%w: %w: %w", storage.ErrConnectDatabase, storage.ErrDatabaseUnreachable, ErrInjected)
+	},
+}
+
+// Methods - методы хранилища, в которые можно внедрять отказы.
+var Methods = []string{
+	"AcknowledgeTransaction",
+	"Execute",
+	"GetLastTransactions",
+	"GetTransaction",
+	"GetWalletBalance",
+	"GetWallets",
+	"ListWallets",
+	"Ping",
+	"RefreshWalletSummary",
+}
+
+// Rule - правило для одного метода хранилища.
+type Rule struct {
+	Method string `json:"method"`
+	// Error - имя ошибки из Errors; пусто - вызов выполняется после задержки.
+	Error string `json:"error,omitempty"`
+	// Latency - задержка перед вызовом (или перед возвратом ошибки), например 200ms.
+	Latency string `json:"latency,omitempty"`
+	// Every - правило срабатывает на каждом Every-м вызове; 0 и 1 - на каждом.
+	Every int `json:"every,omitempty"`
+}
+
+func (r Rule) latency() time.Duration {
+	d, _ := time.ParseDuration(r.Latency)
+	return d
+}
+
+// validate проверяет метод, ошибку и частоту правила.
+func (r Rule) validate() error {
+	i := sort.SearchStrings(Methods, r.Method)
+	if i == len(Methods) || Methods[i] != r.Method {
+		return fmt.Errorf("метод %q не поддерживает внедрение отказов (допустимые: %v)", r.Method, Methods)
+	}
+	if r.Error != "" {
+		if _, ok := Errors[r.Error]; !ok {
+			return fmt.Errorf("неизвестная ошибка %q для метода %s", r.Error, r.Method)
+		}
+	}
+	if r.Latency != "" {
+		if d, err := time.ParseDuration(r.Latency); err != nil || d < 0 {
+			return fmt.Errorf("latency для метода %s должна быть неотрицательной длительностью, например 200ms", r.Method)
+		}
+	}
+	if r.Every < 0 {
+		return fmt.Errorf("отрицательная частота для метода %s", r.Method)
+	}
+	return nil
+}
+
+// Injector хранит правила и считает вызовы методов.
+type Injector struct {
+	mu    sync.Mutex
+	rules map[string]Rule
+	calls map[string]int
+}
+
+// NewInjector создаёт Injector без правил: все вызовы проходят без изменений.
+func NewInjector() *Injector {
+	return &Injector{rules: make(map[string]Rule), calls: make(map[string]int)}
+}
+
+// Set заменяет все правила и сбрасывает счётчики вызовов. На один метод
+// допускается одно правило.
+func (in *Injector) Set(rules []Rule) error {
+	byMethod := make(map[string]Rule, len(rules))
+	for _, r := range rules {
+		if err := r.validate(); err != nil {
+			return err
+		}
+		if _, ok := byMethod[r.Method]; ok {
+			return fmt.Errorf("повторное правило для метода %s", r.Method)
+		}
+		byMethod[r.Method] = r
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.rules = byMethod
+	in.calls = make(map[string]int)
+	return nil
+}
+
+// Rules возвращает действующие правила, упорядоченные по методу.
+func (in *Injector) Rules() []Rule {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	rules := make([]Rule, 0, len(in.rules))
+	for _, r := range in.rules {
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Method < rules[j].Method })
+	return rules
+}
+
+// inject применяет правило метода к очередному вызову: ждёт задержку и
+// возвращает ошибку, которую нужно вернуть вместо вызова, или nil.
+func (in *Injector) inject(ctx context.Context, method string) error {
+	in.mu.Lock()
+	rule, ok := in.rules[method]
+	if !ok {
+		in.mu.Unlock()
+		return nil
+	}
+	in.calls[method]++
+	n := in.calls[method]
+	in.mu.Unlock()
+
+	if rule.Every > 1 && n%rule.Every != 0 {
+		return nil
+	}
+	injected.WithLabelValues(method).Inc()
+
+	if latency := rule.latency(); latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if rule.Error == "" {
+		return nil
+	}
+	return Errors[rule.Error]()
+}
+
+// faultStorage внедряет отказы в вызовы хранилища по правилам Injector.
+type faultStorage struct {
+	api.Storage
+	in *Injector
+}
+
+// Wrap возвращает хранилище, которое внедряет отказы по правилам in.
+func Wrap(next api.Storage, in *Injector) api.Storage {
+	return &faultStorage{Storage: next, in: in}
+}
+
+func (s *faultStorage) AcknowledgeTransaction(ctx context.Context, id int, recipient string) (*models.Transaction, error) {
+	if err := s.in.inject(ctx, "AcknowledgeTransaction"); err != nil {
+		return nil, err
+	}
+	return s.Storage.AcknowledgeTransaction(ctx, id, recipient)
+}
+
+func (s *faultStorage) Execute(ctx context.Context, t models.Transfer) (*models.Transaction, error) {
+	if err := s.in.inject(ctx, "Execute"); err != nil {
+		return nil, err
+	}
+	return s.Storage.Execute(ctx, t)
+}
+
+func (s *faultStorage) GetLastTransactions(ctx context.Context, n int, filter models.TransactionFilter) ([]models.Transaction, int, error) {
+	if err := s.in.inject(ctx, "GetLastTransactions"); err != nil {
+		return nil, 0, err
+	}
+	return s.Storage.GetLastTransactions(ctx, n, filter)
+}
+
+func (s *faultStorage) GetTransaction(ctx context.Context, id int) (*models.Transaction, error) {
+	if err := s.in.inject(ctx, "GetTransaction"); err != nil {
+		return nil, err
+	}
+	return s.Storage.GetTransaction(ctx, id)
+}
+
+func (s *faultStorage) GetWalletBalance(ctx context.Context, address string) (*models.Wallet, error) {
+	if err := s.in.inject(ctx, "GetWalletBalance"); err != nil {
+		return nil, err
+	}
+	return s.Storage.GetWalletBalance(ctx, address)
+}
+
+func (s *faultStorage) GetWallets(ctx context.Context, n int) ([]models.Wallet, int, error) {
+	if err := s.in.inject(ctx, "GetWallets"); err != nil {
+		return nil, 0, err
+	}
+	return s.Storage.GetWallets(ctx, n)
+}
+
+func (s *faultStorage) ListWallets(ctx context.Context, filter models.WalletFilter) (*models.WalletPage, error) {
+	if err := s.in.inject(ctx, "ListWallets"); err != nil {
+		return nil, err
+	}
+	return s.Storage.ListWallets(ctx, filter)
+}
+
+func (s *faultStorage) Ping(ctx context.Context) error {
+	if err := s.in.inject(ctx, "Ping"); err != nil {
+		return err
+	}
+	return s.Storage.Ping(ctx)
+}
+
+func (s *faultStorage) RefreshWalletSummary(ctx context.Context) error {
+	if err := s.in.inject(ctx, "RefreshWalletSummary"); err != nil {
+		return err
+	}
+	return s.Storage.RefreshWalletSummary(ctx)
+}
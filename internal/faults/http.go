@@ -0,0 +1,48 @@
+package faults
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// errorResponse повторяет формат ошибок API.
+type errorResponse struct {
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
+
+// Handler управляет правилами in: GET возвращает действующие правила, PUT
+// заменяет их списком из тела, DELETE удаляет все. Изменения пишутся в лог.
+// Маршрутизация по методам - на стороне роутера.
+func Handler(in *Injector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var rules []Rule
+			if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+				badRequest(w, "неверный формат запроса: ожидается список правил")
+				return
+			}
+			defer r.Body.Close()
+			if err := in.Set(rules); err != nil {
+				badRequest(w, err.Error())
+				return
+			}
+			log.Printf("правила внедрения отказов заменены (%s): %d", r.Header.Get("X-Actor"), len(rules))
+		case http.MethodDelete:
+			in.Set(nil)
+			log.Printf("правила внедрения отказов удалены (%s)", r.Header.Get("X-Actor"))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(in.Rules())
+	})
+}
+
+func badRequest(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(errorResponse{Code: "invalid_request", Error: message})
+}
@@ -0,0 +1,441 @@
+/*
+instrumented оборачивает хранилище и измеряет длительность каждого вызова.
+
+Длительности записываются в гистограмму Prometheus
+`payments_storage_call_duration_seconds` с меткой method (экспоненциальные интервалы
+от 1 мс). Вызовы дольше порога пишутся в лог как медленные вместе с именем метода
+и параметрами; адреса кошельков в логе сокращаются до 8 символов, а тексты
+(заметки, значения настроек) не выводятся.
+
+Storage реализует api.Storage и подключается в main.go, не затрагивая обработчики.
+*/
+package instrumented
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go-payments/internal/api"
+	"go-payments/internal/checkpoint"
+	"go-payments/internal/models"
+	"go-payments/internal/redact"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var callDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "payments_storage_call_duration_seconds",
+	Help:    "Длительность вызовов методов хранилища.",
+	Buckets: prometheus.ExponentialBuckets(0.001, 2, 14),
+}, []string{"method"})
+
+// Storage - хранилище с измерением длительности вызовов.
+type Storage struct {
+	next      api.Storage
+	threshold time.Duration
+}
+
+var _ api.Storage = (*Storage)(nil)
+
+// New оборачивает next. Вызовы дольше threshold пишутся в лог; нулевой порог
+// отключает запись в лог, но не гистограмму.
+func New(next api.Storage, threshold time.Duration) *Storage {
+	return &Storage{next: next, threshold: threshold}
+}
+
+// observe записывает длительность вызова method, начатого в start.
+// params вычисляются только для медленных вызовов.
+func (s *Storage) observe(method string, start time.Time, params func() string) {
+	elapsed := time.Since(start)
+	callDuration.WithLabelValues(method).Observe(elapsed.Seconds())
+	if s.threshold > 0 && elapsed > s.threshold {
+		log.Printf("медленный вызов хранилища: method=%s duration=%s threshold=%s params=%q",
+			method, elapsed, s.threshold, params())
+	}
+}
+
+func noParams() string { return "" }
+
+func (s *Storage) GetWalletBalance(ctx context.Context, address string) (*models.Wallet, error) {
+	defer s.observe("GetWalletBalance", time.Now(), func() string { return "address=" + redact.Address(address) })
+	return s.next.GetWalletBalance(ctx, address)
+}
+
+func (s *Storage) GetLastTransactions(ctx context.Context, n int, filter models.TransactionFilter) ([]models.Transaction, int, error) {
+	defer s.observe("GetLastTransactions", time.Now(), func() string { return fmt.Sprintf("n=%d %s", n, describeFilter(filter)) })
+	return s.next.GetLastTransactions(ctx, n, filter)
+}
+
+func (s *Storage) GetWallets(ctx context.Context, n int) ([]models.Wallet, int, error) {
+	defer s.observe("GetWallets", time.Now(), func() string { return fmt.Sprintf("n=%d", n) })
+	return s.next.GetWallets(ctx, n)
+}
+
+func (s *Storage) ListWallets(ctx context.Context, filter models.WalletFilter) (*models.WalletPage, error) {
+	defer s.observe("ListWallets", time.Now(), func() string {
+		return fmt.Sprintf("sort=%s order=%s limit=%d offset=%d", filter.Sort, filter.Order, filter.Limit, filter.Offset)
+	})
+	return s.next.ListWallets(ctx, filter)
+}
+
+func (s *Storage) GetStatement(ctx context.Context, address string, from, to time.Time) (*models.Statement, error) {
+	defer s.observe("GetStatement", time.Now(), func() string {
+		return fmt.Sprintf("address=%s from=%s to=%s", redact.Address(address), from.Format(time.RFC3339), to.Format(time.RFC3339))
+	})
+	return s.next.GetStatement(ctx, address, from, to)
+}
+
+func (s *Storage) GetWalletActivity(ctx context.Context, address string, since, until time.Time, timezone string) (*models.WalletActivity, error) {
+	defer s.observe("GetWalletActivity", time.Now(), func() string {
+		return fmt.Sprintf("address=%s tz=%s", redact.Address(address), timezone)
+	})
+	return s.next.GetWalletActivity(ctx, address, since, until, timezone)
+}
+
+func (s *Storage) GetVolumeSeries(ctx context.Context, since, until time.Time, bucket models.BucketSize, status models.TransactionStatus) ([]models.VolumeBucket, error) {
+	defer s.observe("GetVolumeSeries", time.Now(), func() string {
+		return fmt.Sprintf("since=%s until=%s bucket=%s status=%s", since.Format(time.RFC3339), until.Format(time.RFC3339), bucket, status)
+	})
+	return s.next.GetVolumeSeries(ctx, since, until, bucket, status)
+}
+
+func (s *Storage) Execute(ctx context.Context, t models.Transfer) (*models.Transaction, error) {
+	defer s.observe("Execute", time.Now(), func() string { return fmt.Sprintf("from=%s to=%s", redact.Address(t.From), redact.Address(t.To)) })
+	return s.next.Execute(ctx, t)
+}
+
+func (s *Storage) RecordZeroNote(ctx context.Context, t models.Transfer) (*models.Transaction, error) {
+	defer s.observe("RecordZeroNote", time.Now(), func() string { return fmt.Sprintf("from=%s to=%s", redact.Address(t.From), redact.Address(t.To)) })
+	return s.next.RecordZeroNote(ctx, t)
+}
+
+func (s *Storage) Ping(ctx context.Context) error {
+	defer s.observe("Ping", time.Now(), noParams)
+	return s.next.Ping(ctx)
+}
+
+func (s *Storage) GetSetting(ctx context.Context, key string) (string, bool, error) {
+	defer s.observe("GetSetting", time.Now(), func() string { return "key=" + key })
+	return s.next.GetSetting(ctx, key)
+}
+
+func (s *Storage) SetSetting(ctx context.Context, key, value string) error {
+	defer s.observe("SetSetting", time.Now(), func() string { return "key=" + key })
+	return s.next.SetSetting(ctx, key, value)
+}
+
+func (s *Storage) GetWalletsByAddress(ctx context.Context, addresses []string) (map[string]models.Wallet, error) {
+	defer s.observe("GetWalletsByAddress", time.Now(), func() string { return fmt.Sprintf("addresses=%d", len(addresses)) })
+	return s.next.GetWalletsByAddress(ctx, addresses)
+}
+
+func (s *Storage) GetWalletByLabel(ctx context.Context, label string) (*models.Wallet, error) {
+	defer s.observe("GetWalletByLabel", time.Now(), func() string { return "label=" + label })
+	return s.next.GetWalletByLabel(ctx, label)
+}
+
+func (s *Storage) SetWalletLabel(ctx context.Context, address, label string) error {
+	defer s.observe("SetWalletLabel", time.Now(), func() string { return fmt.Sprintf("address=%s label=%s", redact.Address(address), label) })
+	return s.next.SetWalletLabel(ctx, address, label)
+}
+
+func (s *Storage) SetWalletMaxBalance(ctx context.Context, address string, maxBalance *float64) error {
+	defer s.observe("SetWalletMaxBalance", time.Now(), func() string { return "address=" + redact.Address(address) })
+	return s.next.SetWalletMaxBalance(ctx, address, maxBalance)
+}
+
+func (s *Storage) SetWalletSystem(ctx context.Context, address string, system, nonReceivable bool) error {
+	defer s.observe("SetWalletSystem", time.Now(), func() string { return "address=" + redact.Address(address) })
+	return s.next.SetWalletSystem(ctx, address, system, nonReceivable)
+}
+
+func (s *Storage) CreatePaymentRequest(ctx context.Context, pr models.PaymentRequest) (*models.PaymentRequest, error) {
+	defer s.observe("CreatePaymentRequest", time.Now(), func() string { return "to=" + redact.Address(pr.To) })
+	return s.next.CreatePaymentRequest(ctx, pr)
+}
+
+func (s *Storage) GetPaymentRequest(ctx context.Context, token string) (*models.PaymentRequest, error) {
+	defer s.observe("GetPaymentRequest", time.Now(), noParams)
+	return s.next.GetPaymentRequest(ctx, token)
+}
+
+func (s *Storage) FulfillPaymentRequest(ctx context.Context, token string, transactionID int) (*models.PaymentRequest, error) {
+	defer s.observe("FulfillPaymentRequest", time.Now(), func() string { return fmt.Sprintf("transaction_id=%d", transactionID) })
+	return s.next.FulfillPaymentRequest(ctx, token, transactionID)
+}
+
+func (s *Storage) PurgeWallet(ctx context.Context, address string) error {
+	defer s.observe("PurgeWallet", time.Now(), func() string { return "address=" + redact.Address(address) })
+	return s.next.PurgeWallet(ctx, address)
+}
+
+func (s *Storage) AddWalletNote(ctx context.Context, wallet, author, text string) (*models.WalletNote, error) {
+	defer s.observe("AddWalletNote", time.Now(), func() string { return "wallet=" + redact.Address(wallet) })
+	return s.next.AddWalletNote(ctx, wallet, author, text)
+}
+
+func (s *Storage) ListWalletNotes(ctx context.Context, wallet string) ([]models.WalletNote, error) {
+	defer s.observe("ListWalletNotes", time.Now(), func() string { return "wallet=" + redact.Address(wallet) })
+	return s.next.ListWalletNotes(ctx, wallet)
+}
+
+func (s *Storage) RedactWalletNote(ctx context.Context, wallet string, id int) (*models.WalletNote, error) {
+	defer s.observe("RedactWalletNote", time.Now(), func() string { return fmt.Sprintf("wallet=%s id=%d", redact.Address(wallet), id) })
+	return s.next.RedactWalletNote(ctx, wallet, id)
+}
+
+func (s *Storage) CreateBalanceThreshold(ctx context.Context, th models.BalanceThreshold) (*models.BalanceThreshold, error) {
+	defer s.observe("CreateBalanceThreshold", time.Now(), func() string {
+		return fmt.Sprintf("wallet=%s direction=%s", redact.Address(th.Wallet), th.Direction)
+	})
+	return s.next.CreateBalanceThreshold(ctx, th)
+}
+
+func (s *Storage) ListBalanceThresholds(ctx context.Context, wallet string) ([]models.BalanceThreshold, error) {
+	defer s.observe("ListBalanceThresholds", time.Now(), func() string { return "wallet=" + redact.Address(wallet) })
+	return s.next.ListBalanceThresholds(ctx, wallet)
+}
+
+func (s *Storage) GetBalanceThreshold(ctx context.Context, wallet string, id int) (*models.BalanceThreshold, error) {
+	defer s.observe("GetBalanceThreshold", time.Now(), func() string { return fmt.Sprintf("wallet=%s id=%d", redact.Address(wallet), id) })
+	return s.next.GetBalanceThreshold(ctx, wallet, id)
+}
+
+func (s *Storage) UpdateBalanceThreshold(ctx context.Context, th models.BalanceThreshold) (*models.BalanceThreshold, error) {
+	defer s.observe("UpdateBalanceThreshold", time.Now(), func() string {
+		return fmt.Sprintf("wallet=%s id=%d direction=%s", redact.Address(th.Wallet), th.ID, th.Direction)
+	})
+	return s.next.UpdateBalanceThreshold(ctx, th)
+}
+
+func (s *Storage) DeleteBalanceThreshold(ctx context.Context, wallet string, id int) error {
+	defer s.observe("DeleteBalanceThreshold", time.Now(), func() string { return fmt.Sprintf("wallet=%s id=%d", redact.Address(wallet), id) })
+	return s.next.DeleteBalanceThreshold(ctx, wallet, id)
+}
+
+func (s *Storage) CreateWithdrawal(ctx context.Context, w models.Withdrawal, idempotencyKey string) (*models.Withdrawal, error) {
+	defer s.observe("CreateWithdrawal", time.Now(), func() string { return "wallet=" + redact.Address(w.Wallet) })
+	return s.next.CreateWithdrawal(ctx, w, idempotencyKey)
+}
+
+func (s *Storage) GetWithdrawal(ctx context.Context, id int) (*models.Withdrawal, error) {
+	defer s.observe("GetWithdrawal", time.Now(), func() string { return fmt.Sprintf("id=%d", id) })
+	return s.next.GetWithdrawal(ctx, id)
+}
+
+func (s *Storage) ListWithdrawals(ctx context.Context, wallet string, status models.WithdrawalStatus, limit int) ([]models.Withdrawal, error) {
+	defer s.observe("ListWithdrawals", time.Now(), func() string {
+		return fmt.Sprintf("wallet=%s status=%s limit=%d", redact.Address(wallet), status, limit)
+	})
+	return s.next.ListWithdrawals(ctx, wallet, status, limit)
+}
+
+func (s *Storage) ConfirmWithdrawal(ctx context.Context, id int) (*models.Withdrawal, error) {
+	defer s.observe("ConfirmWithdrawal", time.Now(), func() string { return fmt.Sprintf("id=%d", id) })
+	return s.next.ConfirmWithdrawal(ctx, id)
+}
+
+func (s *Storage) FailWithdrawal(ctx context.Context, id int, reason string) (*models.Withdrawal, error) {
+	defer s.observe("FailWithdrawal", time.Now(), func() string { return fmt.Sprintf("id=%d", id) })
+	return s.next.FailWithdrawal(ctx, id, reason)
+}
+
+func (s *Storage) ReviewExpiredWithdrawals(ctx context.Context, olderThan time.Duration) ([]models.Withdrawal, error) {
+	defer s.observe("ReviewExpiredWithdrawals", time.Now(), func() string { return "older_than=" + olderThan.String() })
+	return s.next.ReviewExpiredWithdrawals(ctx, olderThan)
+}
+
+func (s *Storage) CreateCheckpoint(ctx context.Context, date time.Time, key ed25519.PrivateKey) (*checkpoint.Checkpoint, bool, error) {
+	defer s.observe("CreateCheckpoint", time.Now(), func() string { return "date=" + date.Format(checkpoint.DateLayout) })
+	return s.next.CreateCheckpoint(ctx, date, key)
+}
+
+func (s *Storage) GetCheckpoint(ctx context.Context, date time.Time, withBalances bool) (*checkpoint.Bundle, error) {
+	defer s.observe("GetCheckpoint", time.Now(), func() string {
+		return fmt.Sprintf("date=%s with_balances=%t", date.Format(checkpoint.DateLayout), withBalances)
+	})
+	return s.next.GetCheckpoint(ctx, date, withBalances)
+}
+
+func (s *Storage) GetCheckpointProof(ctx context.Context, address string, date time.Time) (*checkpoint.Proof, error) {
+	defer s.observe("GetCheckpointProof", time.Now(), func() string {
+		return fmt.Sprintf("address=%s date=%s", redact.Address(address), date.Format(checkpoint.DateLayout))
+	})
+	return s.next.GetCheckpointProof(ctx, address, date)
+}
+
+func (s *Storage) GetTransaction(ctx context.Context, id int) (*models.Transaction, error) {
+	defer s.observe("GetTransaction", time.Now(), func() string { return fmt.Sprintf("id=%d", id) })
+	return s.next.GetTransaction(ctx, id)
+}
+
+func (s *Storage) TransactionIDByPublicID(ctx context.Context, publicID string) (int, error) {
+	defer s.observe("TransactionIDByPublicID", time.Now(), func() string { return "public_id=" + publicID })
+	return s.next.TransactionIDByPublicID(ctx, publicID)
+}
+
+func (s *Storage) GetGroupSummary(ctx context.Context, groupID string) (*models.GroupSummary, error) {
+	defer s.observe("GetGroupSummary", time.Now(), func() string { return "group_id=" + groupID })
+	return s.next.GetGroupSummary(ctx, groupID)
+}
+
+func (s *Storage) AcknowledgeTransaction(ctx context.Context, id int, recipient string) (*models.Transaction, error) {
+	defer s.observe("AcknowledgeTransaction", time.Now(), func() string { return fmt.Sprintf("id=%d recipient=%s", id, redact.Address(recipient)) })
+	return s.next.AcknowledgeTransaction(ctx, id, recipient)
+}
+
+func (s *Storage) AcknowledgeTransactions(ctx context.Context, recipient string, ids []int) (map[int]error, error) {
+	defer s.observe("AcknowledgeTransactions", time.Now(), func() string { return fmt.Sprintf("ids=%d recipient=%s", len(ids), redact.Address(recipient)) })
+	return s.next.AcknowledgeTransactions(ctx, recipient, ids)
+}
+
+func (s *Storage) AcknowledgeTransactionsThrough(ctx context.Context, recipient string, throughID int) (int, error) {
+	defer s.observe("AcknowledgeTransactionsThrough", time.Now(), func() string {
+		return fmt.Sprintf("through_id=%d recipient=%s", throughID, redact.Address(recipient))
+	})
+	return s.next.AcknowledgeTransactionsThrough(ctx, recipient, throughID)
+}
+
+func (s *Storage) GetIncomingCursor(ctx context.Context, wallet, consumer string) (*models.IncomingCursor, error) {
+	defer s.observe("GetIncomingCursor", time.Now(), func() string { return "wallet=" + redact.Address(wallet) + " consumer=" + consumer })
+	return s.next.GetIncomingCursor(ctx, wallet, consumer)
+}
+
+func (s *Storage) SetIncomingCursor(ctx context.Context, wallet, consumer string, lastTransactionID int) (*models.IncomingCursor, error) {
+	defer s.observe("SetIncomingCursor", time.Now(), func() string { return "wallet=" + redact.Address(wallet) + " consumer=" + consumer })
+	return s.next.SetIncomingCursor(ctx, wallet, consumer, lastTransactionID)
+}
+
+func (s *Storage) SchemaVersion(ctx context.Context) (int, error) {
+	defer s.observe("SchemaVersion", time.Now(), noParams)
+	return s.next.SchemaVersion(ctx)
+}
+
+func (s *Storage) CheckSchema(ctx context.Context) (*models.SchemaDiff, error) {
+	defer s.observe("CheckSchema", time.Now(), noParams)
+	return s.next.CheckSchema(ctx)
+}
+
+func (s *Storage) CreateWallets(ctx context.Context, n int, balance float64, progress func(batch []string)) (int, int, error) {
+	defer s.observe("CreateWallets", time.Now(), func() string { return fmt.Sprintf("n=%d", n) })
+	return s.next.CreateWallets(ctx, n, balance, progress)
+}
+
+func (s *Storage) AddTransactionTag(ctx context.Context, id int, tag, addedBy string) error {
+	defer s.observe("AddTransactionTag", time.Now(), func() string { return fmt.Sprintf("id=%d tag=%s", id, tag) })
+	return s.next.AddTransactionTag(ctx, id, tag, addedBy)
+}
+
+func (s *Storage) RemoveTransactionTag(ctx context.Context, id int, tag string) error {
+	defer s.observe("RemoveTransactionTag", time.Now(), func() string { return fmt.Sprintf("id=%d tag=%s", id, tag) })
+	return s.next.RemoveTransactionTag(ctx, id, tag)
+}
+
+func (s *Storage) ListTransactionTags(ctx context.Context) ([]models.TagCount, error) {
+	defer s.observe("ListTransactionTags", time.Now(), noParams)
+	return s.next.ListTransactionTags(ctx)
+}
+
+func (s *Storage) GetWalletSummary(ctx context.Context) (*models.WalletSummary, error) {
+	defer s.observe("GetWalletSummary", time.Now(), noParams)
+	return s.next.GetWalletSummary(ctx)
+}
+
+func (s *Storage) RefreshWalletSummary(ctx context.Context) error {
+	defer s.observe("RefreshWalletSummary", time.Now(), noParams)
+	return s.next.RefreshWalletSummary(ctx)
+}
+
+func (s *Storage) SnapshotWallets(ctx context.Context, fn func(models.Wallet) error) error {
+	defer s.observe("SnapshotWallets", time.Now(), noParams)
+	return s.next.SnapshotWallets(ctx, fn)
+}
+
+func (s *Storage) RestoreWallets(ctx context.Context, wallets []models.Wallet) error {
+	defer s.observe("RestoreWallets", time.Now(), func() string { return fmt.Sprintf("wallets=%d", len(wallets)) })
+	return s.next.RestoreWallets(ctx, wallets)
+}
+
+func (s *Storage) GetIdempotencyKey(ctx context.Context, key string) (*models.IdempotencyKey, error) {
+	defer s.observe("GetIdempotencyKey", time.Now(), noParams)
+	return s.next.GetIdempotencyKey(ctx, key)
+}
+
+func (s *Storage) DeleteIdempotencyKey(ctx context.Context, key string) error {
+	defer s.observe("DeleteIdempotencyKey", time.Now(), noParams)
+	return s.next.DeleteIdempotencyKey(ctx, key)
+}
+
+func (s *Storage) Fsck(ctx context.Context, limit int, report func(models.FsckFinding) error) (models.FsckSummary, error) {
+	defer s.observe("Fsck", time.Now(), func() string { return fmt.Sprintf("limit=%d", limit) })
+	return s.next.Fsck(ctx, limit, report)
+}
+
+func (s *Storage) StorageInfo(ctx context.Context) (*models.StorageInfo, error) {
+	defer s.observe("StorageInfo", time.Now(), noParams)
+	return s.next.StorageInfo(ctx)
+}
+
+func (s *Storage) GetPeriodClose(ctx context.Context) (*models.PeriodClose, error) {
+	defer s.observe("GetPeriodClose", time.Now(), noParams)
+	return s.next.GetPeriodClose(ctx)
+}
+
+func (s *Storage) ClosePeriod(ctx context.Context, through time.Time, closedBy string, force bool) (*models.PeriodClose, error) {
+	defer s.observe("ClosePeriod", time.Now(), func() string {
+		return fmt.Sprintf("through=%s force=%t", through.UTC().Format(time.RFC3339), force)
+	})
+	return s.next.ClosePeriod(ctx, through, closedBy, force)
+}
+
+// PoolStats не обращается к базе данных и не измеряется.
+func (s *Storage) PoolStats() models.PoolStats {
+	return s.next.PoolStats()
+}
+
+// describeFilter описывает фильтр транзакций без значений метаданных.
+func describeFilter(f models.TransactionFilter) string {
+	var parts []string
+	if len(f.Metadata) > 0 {
+		parts = append(parts, fmt.Sprintf("metadata_keys=%d", len(f.Metadata)))
+	}
+	if f.After != nil {
+		parts = append(parts, "cursor=true")
+	}
+	if f.AfterID != nil {
+		parts = append(parts, fmt.Sprintf("after_id=%d", *f.AfterID))
+	}
+	if f.Between != nil {
+		parts = append(parts, fmt.Sprintf("between=%s,%s", redact.Address(f.Between.A), redact.Address(f.Between.B)))
+	}
+	if f.Address != "" {
+		parts = append(parts, "address="+redact.Address(f.Address))
+	}
+	if f.To != "" {
+		parts = append(parts, "to="+redact.Address(f.To))
+	}
+	if f.Unacknowledged {
+		parts = append(parts, "unacknowledged=true")
+	}
+	if f.Tag != "" {
+		parts = append(parts, "tag="+f.Tag)
+	}
+	if f.GroupID != "" {
+		parts = append(parts, "group_id="+f.GroupID)
+	}
+	if f.Status != "" {
+		parts = append(parts, "status="+string(f.Status))
+	}
+	if f.Since != nil {
+		parts = append(parts, "since="+f.Since.Format(time.RFC3339))
+	}
+	if f.Until != nil {
+		parts = append(parts, "until="+f.Until.Format(time.RFC3339))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,152 @@
+/*
+kpi оборачивает хранилище и ведёт бизнес-метрики движения денег, чтобы панели
+Grafana не обращались к базе данных.
+
+Метрики (общий префикс payments_, как у остальных метрик сервиса):
+  - payments_transfers_total{status}: количество переводов по статусам;
+  - payments_transfer_volume_total{status}: сумма переводов по статусам;
+  - payments_transfer_failure_ratio: доля неуспешных переводов за последние
+    5 минут в этом экземпляре (0, если переводов не было);
+  - payments_wallets: количество кошельков по сводке wallet_summary, обновляется
+    при каждом чтении и пересчёте сводки.
+
+Повтор по ключу идемпотентности и переводы, отклонённые до выполнения
+(storage.Rejected), не учитываются. Сумма хранится как float64 и годится для графиков, но не
+для сверки с базой.
+*/
+package kpi
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go-payments/internal/api"
+	"go-payments/internal/models"
+	"go-payments/internal/storage"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Скользящее окно доли неуспешных переводов: failureBuckets интервалов по failureBucketWidth.
+const (
+	failureBucketWidth = 10 * time.Second
+	failureBuckets     = 30
+)
+
+var (
+	transfers = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "payments_transfers_total",
+		Help: "Количество переводов по статусам.",
+	}, []string{"status"})
+	transferVolume = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "payments_transfer_volume_total",
+		Help: "Сумма переводов по статусам.",
+	}, []string{"status"})
+	wallets = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "payments_wallets",
+		Help: "Количество кошельков по сводке wallet_summary.",
+	})
+)
+
+// failures - скользящее окно переводов этого экземпляра.
+var failures = &failureWindow{}
+
+func init() {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "payments_transfer_failure_ratio",
+		Help: "Доля неуспешных переводов за последние 5 минут.",
+	}, func() float64 { return failures.ratio(time.Now()) })
+}
+
+// failureWindow считает переводы и неуспешные переводы в кольце интервалов.
+// Интервал, начало которого выпало из окна, обнуляется при следующей записи в него
+// и не учитывается при чтении.
+type failureWindow struct {
+	mu      sync.Mutex
+	buckets [failureBuckets]struct {
+		start         int64
+		total, failed int
+	}
+}
+
+func (w *failureWindow) record(now time.Time, failed bool) {
+	start := now.Truncate(failureBucketWidth).Unix()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	b := &w.buckets[(start/int64(failureBucketWidth.Seconds()))%failureBuckets]
+	if b.start != start {
+		b.start, b.total, b.failed = start, 0, 0
+	}
+	b.total++
+	if failed {
+		b.failed++
+	}
+}
+
+func (w *failureWindow) ratio(now time.Time) float64 {
+	oldest := now.Add(-failureBucketWidth * (failureBuckets - 1)).Truncate(failureBucketWidth).Unix()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	total, failed := 0, 0
+	for _, b := range w.buckets {
+		if b.start >= oldest {
+			total += b.total
+			failed += b.failed
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(failed) / float64(total)
+}
+
+// kpiStorage учитывает переводы и количество кошельков в метриках.
+type kpiStorage struct {
+	api.Storage
+}
+
+// Wrap возвращает хранилище, которое ведёт бизнес-метрики.
+func Wrap(next api.Storage) api.Storage {
+	return &kpiStorage{Storage: next}
+}
+
+func (s *kpiStorage) Execute(ctx context.Context, t models.Transfer) (*models.Transaction, error) {
+	stats := storage.ExecStatsFrom(ctx)
+	if stats == nil {
+		stats = &storage.ExecStats{}
+		ctx = storage.WithExecStats(ctx, stats)
+	}
+	transaction, err := s.Storage.Execute(ctx, t)
+	if stats.Replayed || storage.Rejected(err) {
+		return transaction, err
+	}
+
+	status := models.StatusSuccess
+	if err != nil {
+		status = storage.FailureStatus(err)
+	}
+	transfers.WithLabelValues(string(status)).Inc()
+	transferVolume.WithLabelValues(string(status)).Add(t.Amount)
+	failures.record(time.Now(), status != models.StatusSuccess)
+	return transaction, err
+}
+
+func (s *kpiStorage) GetWalletSummary(ctx context.Context) (*models.WalletSummary, error) {
+	summary, err := s.Storage.GetWalletSummary(ctx)
+	if err == nil {
+		wallets.Set(float64(summary.WalletCount))
+	}
+	return summary, err
+}
+
+// RefreshWalletSummary после пересчёта сводки читает её, чтобы обновить
+// payments_wallets и в экземпляре, выполняющем периодический пересчёт.
+func (s *kpiStorage) RefreshWalletSummary(ctx context.Context) error {
+	if err := s.Storage.RefreshWalletSummary(ctx); err != nil {
+		return err
+	}
+	_, err := s.GetWalletSummary(ctx)
+	return err
+}
@@ -0,0 +1,177 @@
+/*
+leader согласует периодические задачи между несколькими экземплярами сервиса.
+
+Каждая задача выполняется под арендой в таблице `job_leases`: экземпляр, захвативший
+аренду, выполняет задачу и продлевает аренду, пока она работает, а по завершении
+освобождает её. Если экземпляр падает, аренда истекает через ttl и достаётся другому.
+
+Функции и методы:
+  - New: Создаёт Leader с уникальным идентификатором экземпляра.
+  - Acquire: Захватывает аренду задачи и запускает её продление.
+  - Run: Периодически выполняет задачу, на каждом тике пытаясь захватить аренду.
+
+Все фоновые задачи сервиса должны запускаться через Run. Экземпляр, выполнивший
+задачу, сообщает задержку её запуска относительно тика (`payments_job_lag_seconds`)
+и время последнего успешного выполнения (`payments_job_last_success_timestamp_seconds`).
+*/
+package leader
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	jobLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "payments_job_lag_seconds",
+		Help: "Задержка запуска периодической задачи относительно её тика при последнем выполнении.",
+	}, []string{"job"})
+	jobLastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "payments_job_last_success_timestamp_seconds",
+		Help: "Время последнего успешного выполнения периодической задачи в этом экземпляре.",
+	}, []string{"job"})
+)
+
+// Store - хранилище аренд задач.
+type Store interface {
+	AcquireLease(ctx context.Context, job, holder string, ttl time.Duration) (bool, error)
+	RenewLease(ctx context.Context, job, holder string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, job, holder string) error
+}
+
+type Leader struct {
+	store  Store
+	holder string
+}
+
+// New создаёт Leader. Идентификатор экземпляра составляется из имени хоста,
+// PID и случайного суффикса, чтобы различать экземпляры на одном хосте.
+func New(store Store) *Leader {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return &Leader{
+		store:  store,
+		holder: fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(suffix)),
+	}
+}
+
+// Lease - захваченная аренда задачи.
+type Lease struct {
+	store  Store
+	job    string
+	holder string
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Acquire пытается захватить аренду задачи job на время ttl. Второй результат
+// равен false, если аренда принадлежит другому экземпляру. Пока аренда захвачена,
+// она продлевается каждые ttl/3; если продлить не удалось, контекст аренды отменяется.
+func (l *Leader) Acquire(ctx context.Context, job string, ttl time.Duration) (*Lease, bool, error) {
+	ok, err := l.store.AcquireLease(ctx, job, l.holder, ttl)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	leaseCtx, cancel := context.WithCancel(ctx)
+	lease := &Lease{
+		store:  l.store,
+		job:    job,
+		holder: l.holder,
+		ctx:    leaseCtx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go lease.renew(ttl)
+	return lease, true, nil
+}
+
+// Context возвращает контекст, который отменяется при потере или освобождении аренды.
+// Задача должна выполняться в этом контексте.
+func (l *Lease) Context() context.Context {
+	return l.ctx
+}
+
+// Release останавливает продление и освобождает аренду.
+func (l *Lease) Release(ctx context.Context) error {
+	l.cancel()
+	<-l.done
+	return l.store.ReleaseLease(ctx, l.job, l.holder)
+}
+
+func (l *Lease) renew(ttl time.Duration) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.ctx.Done():
+			return
+		case <-ticker.C:
+			ok, err := l.store.RenewLease(l.ctx, l.job, l.holder, ttl)
+			if l.ctx.Err() != nil {
+				return
+			}
+			if err != nil || !ok {
+				log.Printf("аренда задачи %s потеряна: %v", l.job, err)
+				l.cancel()
+				return
+			}
+		}
+	}
+}
+
+// Run выполняет fn каждые interval, пока не отменён ctx. На каждом тике Run пытается
+// захватить аренду задачи job; если её держит другой экземпляр, тик пропускается.
+func (l *Leader) Run(ctx context.Context, job string, interval, ttl time.Duration, fn func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case tick := <-ticker.C:
+			l.runOnce(ctx, job, tick, ttl, fn)
+		}
+	}
+}
+
+func (l *Leader) runOnce(ctx context.Context, job string, tick time.Time, ttl time.Duration, fn func(ctx context.Context) error) {
+	lease, ok, err := l.Acquire(ctx, job, ttl)
+	if err != nil {
+		log.Printf("ошибка захвата аренды задачи %s: %v", job, err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	// Задержка включает ожидание в очереди тикера и захват аренды.
+	jobLag.WithLabelValues(job).Set(time.Since(tick).Seconds())
+	if err := fn(lease.Context()); err != nil {
+		log.Printf("ошибка выполнения задачи %s: %v", job, err)
+	} else {
+		jobLastSuccess.WithLabelValues(job).SetToCurrentTime()
+	}
+
+	// Освобождаем аренду даже при отменённом ctx, чтобы другой экземпляр
+	// не ждал её истечения.
+	releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := lease.Release(releaseCtx); err != nil {
+		log.Printf("ошибка освобождения аренды задачи %s: %v", job, err)
+	}
+}
@@ -1,34 +1,714 @@
 // ORM models
 package models
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go-payments/internal/address"
+	"go-payments/internal/money"
+	"go-payments/internal/redact"
+)
 
 type TransactionStatus string
 
 const (
-	StatusSuccess                 TransactionStatus = "success"
-	StatusFailedInsufficientFunds TransactionStatus = "failed_insufficient_funds"
-	StatusFailedRecipientNotFound TransactionStatus = "failed_recipient_not_found"
-	StatusFailedSenderNotFound    TransactionStatus = "failed_sender_not_found"
-	StatusUnknownError            TransactionStatus = "unknown_error"
+	StatusSuccess                      TransactionStatus = "success"
+	StatusFailedInsufficientFunds      TransactionStatus = "failed_insufficient_funds"
+	StatusFailedRecipientNotFound      TransactionStatus = "failed_recipient_not_found"
+	StatusFailedSenderNotFound         TransactionStatus = "failed_sender_not_found"
+	StatusFailedRecipientLimitExceeded TransactionStatus = "failed_recipient_limit_exceeded"
+	StatusUnknownError                 TransactionStatus = "unknown_error"
+	// StatusZeroNote - нулевая транзакция-заметка, записанная администратором:
+	// средства не перемещаются, в объёмах и суммах переводов она не учитывается.
+	StatusZeroNote TransactionStatus = "zero_note"
 )
 
+// TransactionStatuses - все известные статусы транзакций.
+var TransactionStatuses = []TransactionStatus{
+	StatusSuccess,
+	StatusFailedInsufficientFunds,
+	StatusFailedRecipientNotFound,
+	StatusFailedSenderNotFound,
+	StatusFailedRecipientLimitExceeded,
+	StatusUnknownError,
+	StatusZeroNote,
+}
+
+// Valid сообщает, является ли статус одним из известных.
+func (s TransactionStatus) Valid() bool {
+	return slices.Contains(TransactionStatuses, s)
+}
+
 type Wallet struct {
-	Address string  `json:"address"`
-	Balance float64 `json:"balance"`
+	Address  string       `json:"address"`
+	Balance  money.Amount `json:"balance"`
+	Frozen   bool         `json:"frozen,omitempty"`
+	Archived bool         `json:"archived,omitempty"`
+	Label    string       `json:"label,omitempty"`
+	// MaxBalance - предельный баланс, выше которого кошелёк не принимает переводы; nil - без ограничения.
+	MaxBalance *money.Amount `json:"max_balance,omitempty"`
+	// System отмечает служебный кошелёк: переводы клиентов с него запрещены, а с
+	// NonReceivable - и на него. Публичные списки кошельков его не показывают.
+	System        bool `json:"system,omitempty"`
+	NonReceivable bool `json:"non_receivable,omitempty"`
+	// AutoCreated отмечает кошелёк, созданный первым переводом на его адрес
+	// (AUTO_CREATE_RECIPIENTS).
+	AutoCreated bool `json:"auto_created,omitempty"`
+	// CreatedAt - время создания кошелька; nil у кошельков, созданных до миграции 23.
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	// PendingOut - сумма незавершённых выводов средств (pending и review), уже
+	// списанная с баланса. Заполняется только в ответе о балансе кошелька.
+	PendingOut *money.Amount `json:"pending_out,omitempty"`
+}
+
+// Максимальная длина метки кошелька.
+const MaxWalletLabelLength = 64
+
+// ValidateLabel проверяет метку кошелька: от 1 до 64 символов из строчных латинских
+// букв, цифр, '-' и '_'.
+func ValidateLabel(label string) error {
+	if label == "" || len(label) > MaxWalletLabelLength {
+		return fmt.Errorf("метка должна содержать от 1 до %d символов", MaxWalletLabelLength)
+	}
+	for _, r := range label {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return errors.New("метка может содержать только строчные латинские буквы, цифры, '-' и '_'")
+		}
+	}
+	return nil
 }
 
 type Transaction struct {
+	// PublicID - ULID транзакции, основной идентификатор в API. ID - внутренний
+	// порядковый номер; на переходный период API по-прежнему возвращает и принимает его.
+	PublicID  string            `json:"public_id"`
 	ID        int               `json:"id"`
-	From      string            `json:"from`
+	From      string            `json:"from"`
 	To        string            `json:"to"`
-	Amount    float64           `json:"amount"`
+	Amount    money.Amount      `json:"amount"`
 	Timestamp time.Time         `json:"timestamp"`
-	Status    TransactionStatus `json: status`
+	Status    TransactionStatus `json:"status"`
+	Memo      string            `json:"memo,omitempty"`
+	Reference string            `json:"reference,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	// AcknowledgedAt - время, когда получатель отметил перевод обработанным.
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	// GroupID - выбранный клиентом идентификатор группы переводов.
+	GroupID string `json:"group_id,omitempty"`
+}
+
+// EmbeddedWallet - сведения о кошельке стороны перевода в списке транзакций с
+// embed=wallets. Баланс не включается.
+type EmbeddedWallet struct {
+	// Exists ложно, если кошелька нет в базе (например, он удалён).
+	Exists   bool   `json:"exists"`
+	Label    string `json:"label,omitempty"`
+	Frozen   bool   `json:"frozen,omitempty"`
+	Archived bool   `json:"archived,omitempty"`
+	System   bool   `json:"system,omitempty"`
+}
+
+// TransactionEmbed - встроенные сведения о кошельках отправителя и получателя.
+type TransactionEmbed struct {
+	From EmbeddedWallet `json:"from"`
+	To   EmbeddedWallet `json:"to"`
+}
+
+// Immutable сообщает, что транзакция больше не изменится: её статус окончательный
+// (не unknown_error) и получатель уже подтвердил её - подтверждение единственное,
+// что меняется у записанной транзакции.
+func (t *Transaction) Immutable() bool {
+	return t.Status != StatusUnknownError && t.AcknowledgedAt != nil
 }
 
 type SendRequest struct {
-	From   string  `json:"from`
-	To     string  `json:"to"`
-	Amount float64 `json:"amount"`
+	From      string            `json:"from"`
+	To        string            `json:"to"`
+	FromLabel string            `json:"from_label,omitempty"`
+	ToLabel   string            `json:"to_label,omitempty"`
+	Amount    money.Amount      `json:"amount"`
+	Memo      string            `json:"memo,omitempty"`
+	Reference string            `json:"reference,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	GroupID   string            `json:"group_id,omitempty"`
+}
+
+// Максимальная длина идентификатора группы переводов.
+const MaxGroupIDLength = 64
+
+type SendResponse struct {
+	Status      string       `json:"status"`
+	Transaction *Transaction `json:"transaction"`
+}
+
+// Transfer описывает перевод средств между кошельками.
+type Transfer struct {
+	From      string
+	To        string
+	Amount    float64
+	Memo      string
+	Reference string
+	// IdempotencyKey - необязательный ключ идемпотентности: повтор перевода с тем же
+	// ключом возвращает уже записанную транзакцию, а не выполняет перевод снова.
+	IdempotencyKey string
+	Metadata       map[string]string
+	// GroupID объединяет переводы, отправленные по отдельности, в одну группу.
+	GroupID string
+	// AllowSystem разрешает перевод с системного кошелька и на системный кошелёк,
+	// не принимающий переводы. Устанавливается только внутренними операциями.
+	AllowSystem bool
+	// IgnoreMaxBalance зачисляет средства без проверки max_balance получателя.
+	// Устанавливается только для возврата его же средств, например при отказе
+	// от вывода.
+	IgnoreMaxBalance bool
+}
+
+// Ограничения на метаданные транзакции.
+const (
+	MaxMetadataKeys        = 16
+	MaxMetadataKeyLength   = 64
+	MaxMetadataValueLength = 256
+)
+
+// ValidateMetadata проверяет ограничения на метаданные. Ошибка называет ключ,
+// нарушивший ограничение.
+func ValidateMetadata(metadata map[string]string) error {
+	if len(metadata) > MaxMetadataKeys {
+		return fmt.Errorf("metadata содержит %d ключей, допускается не больше %d", len(metadata), MaxMetadataKeys)
+	}
+	for key, value := range metadata {
+		if key == "" {
+			return errors.New("ключ metadata не может быть пустым")
+		}
+		if utf8.RuneCountInString(key) > MaxMetadataKeyLength {
+			return fmt.Errorf("ключ metadata %q длиннее %d символов", key, MaxMetadataKeyLength)
+		}
+		if utf8.RuneCountInString(value) > MaxMetadataValueLength {
+			return fmt.Errorf("значение metadata по ключу %q длиннее %d символов", key, MaxMetadataValueLength)
+		}
+	}
+	return nil
+}
+
+// TransactionCursor - позиция в списке транзакций, упорядоченном по (timestamp, id) по убыванию.
+type TransactionCursor struct {
+	Timestamp time.Time
+	ID        int
+}
+
+// TransactionFilter описывает условия выборки транзакций.
+type TransactionFilter struct {
+	// Metadata отбирает транзакции, метаданные которых содержат все указанные пары.
+	Metadata map[string]string
+	// After возвращает транзакции строго после указанной позиции (keyset-пагинация).
+	After *TransactionCursor
+	// Between отбирает переводы между двумя адресами в любом направлении.
+	Between *AddressPair
+	// Address отбирает транзакции, в которых адрес - отправитель или получатель.
+	Address string
+	// To отбирает входящие транзакции кошелька-получателя.
+	To string
+	// Unacknowledged отбирает транзакции, ещё не подтверждённые получателем.
+	Unacknowledged bool
+	// Tag отбирает транзакции с указанным тегом.
+	Tag string
+	// GroupID отбирает транзакции группы переводов.
+	GroupID string
+	// Status отбирает транзакции с указанным статусом; пустой статус - все,
+	// кроме заметок zero_note, если не задан IncludeNotes.
+	Status TransactionStatus
+	// IncludeNotes добавляет к списку заметки zero_note.
+	IncludeNotes bool
+	// Since и Until ограничивают время транзакции: [Since, Until).
+	Since *time.Time
+	Until *time.Time
+	// AfterID отбирает транзакции с id больше *AfterID и упорядочивает их по id
+	// по возрастанию - так потребитель входящих переводов читает их после своего курсора.
+	// Несовместим с After.
+	AfterID *int
+}
+
+// BalanceChange - текущий баланс кошелька и чистое изменение за период.
+type BalanceChange struct {
+	Address string
+	Balance float64
+	Net     float64
+}
+
+// AddressPair - пара различных адресов кошельков.
+type AddressPair struct {
+	A, B string
+}
+
+// Допустимые значения сортировки для WalletFilter.
+const (
+	WalletSortAddress = "address"
+	WalletSortBalance = "balance"
+
+	SortOrderAsc  = "asc"
+	SortOrderDesc = "desc"
+)
+
+// Максимальная длина текста заметки к кошельку в символах.
+const MaxWalletNoteLength = 2000
+
+// Максимальная длина тега транзакции.
+const MaxTransactionTagLength = 32
+
+// NormalizeTag приводит тег транзакции к нижнему регистру и проверяет его: от 1
+// до 32 символов из латинских букв, цифр, '-' и '_'.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" || len(tag) > MaxTransactionTagLength {
+		return "", fmt.Errorf("тег должен содержать от 1 до %d символов", MaxTransactionTagLength)
+	}
+	for _, r := range tag {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return "", errors.New("тег может содержать только латинские буквы, цифры, '-' и '_'")
+		}
+	}
+	return tag, nil
+}
+
+// TransactionTag - тег, назначенный транзакции.
+type TransactionTag struct {
+	TransactionID int       `json:"transaction_id"`
+	Tag           string    `json:"tag"`
+	AddedBy       string    `json:"added_by,omitempty"`
+	AddedAt       time.Time `json:"added_at"`
+}
+
+// TagCount - тег и количество транзакций с ним.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// WalletNote - служебная заметка поддержки к кошельку. Заметки не отдаются
+// публичными эндпоинтами и не редактируются: их можно только скрыть (RedactedAt).
+type WalletNote struct {
+	ID         int        `json:"id"`
+	Wallet     string     `json:"wallet"`
+	Author     string     `json:"author,omitempty"`
+	Text       string     `json:"text"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RedactedAt *time.Time `json:"redacted_at,omitempty"`
+}
+
+// WalletFilter описывает параметры выборки кошельков для административного списка.
+// Nil-указатели означают отсутствие соответствующего условия.
+type WalletFilter struct {
+	Frozen          *bool
+	System          *bool
+	IncludeArchived bool
+	MinBalance      *float64
+	MaxBalance      *float64
+	Sort            string
+	Order           string
+	Limit           int
+	Offset          int
+}
+
+// Validate проверяет согласованность фильтра и подставляет значения по умолчанию
+// для сортировки.
+func (f *WalletFilter) Validate() error {
+	if f.MinBalance != nil && f.MaxBalance != nil && *f.MinBalance > *f.MaxBalance {
+		return errors.New("min_balance не может быть больше max_balance")
+	}
+	if f.Limit <= 0 {
+		return errors.New("limit должен быть положительным числом")
+	}
+	if f.Offset < 0 {
+		return errors.New("offset не может быть отрицательным")
+	}
+
+	switch f.Sort {
+	case "":
+		f.Sort = WalletSortAddress
+	case WalletSortAddress, WalletSortBalance:
+	default:
+		return fmt.Errorf("неизвестное поле сортировки: %q", f.Sort)
+	}
+
+	switch f.Order {
+	case "":
+		f.Order = SortOrderAsc
+	case SortOrderAsc, SortOrderDesc:
+	default:
+		return fmt.Errorf("неизвестный порядок сортировки: %q", f.Order)
+	}
+	return nil
+}
+
+// WalletPage - страница административного списка кошельков.
+type WalletPage struct {
+	Wallets []Wallet `json:"wallets"`
+	Total   int      `json:"total"`
+	Limit   int      `json:"limit"`
+	Offset  int      `json:"offset"`
+	// Skipped - количество строк, пропущенных из-за ошибок чтения.
+	Skipped int `json:"skipped,omitempty"`
+}
+
+// Размер интервала агрегации для временных рядов.
+type BucketSize string
+
+const (
+	BucketHour BucketSize = "hour"
+	BucketDay  BucketSize = "day"
+)
+
+// Duration возвращает длительность интервала или 0 для неизвестного размера.
+func (b BucketSize) Duration() time.Duration {
+	switch b {
+	case BucketHour:
+		return time.Hour
+	case BucketDay:
+		return 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// VolumeBucket - точка временного ряда объёма транзакций.
+type VolumeBucket struct {
+	BucketStart time.Time    `json:"bucket_start"`
+	Count       int          `json:"count"`
+	TotalAmount money.Amount `json:"total_amount"`
+}
+
+// StatementEntry - транзакция в выписке с балансом кошелька после неё.
+type StatementEntry struct {
+	Transaction
+	RunningBalance money.Amount `json:"running_balance"`
+}
+
+// Statement - выписка по кошельку за период.
+type Statement struct {
+	Address        string           `json:"address"`
+	PeriodStart    time.Time        `json:"period_start"`
+	PeriodEnd      time.Time        `json:"period_end"`
+	OpeningBalance money.Amount     `json:"opening_balance"`
+	ClosingBalance money.Amount     `json:"closing_balance"`
+	TotalIn        money.Amount     `json:"total_in"`
+	TotalOut       money.Amount     `json:"total_out"`
+	Transactions   []StatementEntry `json:"transactions"`
+	// Final - период выписки целиком входит в закрытый учётный период (PeriodClose).
+	Final bool `json:"final"`
+}
+
+// WalletActivity - успешные переводы кошелька (входящие и исходящие) за период
+// [Since, Until), разложенные по дням недели и часам суток в часовом поясе Timezone.
+// Строка матрицы - день недели (0 - воскресенье, 6 - суббота), столбец - час.
+type WalletActivity struct {
+	Address      string              `json:"address"`
+	Since        time.Time           `json:"since"`
+	Until        time.Time           `json:"until"`
+	Timezone     string              `json:"timezone"`
+	Counts       [7][24]int          `json:"counts"`
+	TotalAmounts [7][24]money.Amount `json:"total_amounts"`
+}
+
+// PeriodClose - закрытие учётного периода: транзакции до ClosedThrough
+// (не включительно) больше не изменяются.
+type PeriodClose struct {
+	ClosedThrough time.Time `json:"closed_through"`
+	ClosedBy      string    `json:"closed_by,omitempty"`
+	// Forced - период перенесён назад принудительно.
+	Forced    bool      `json:"forced,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SchemaDiff - расхождения фактической схемы базы данных с ожидаемой по миграциям.
+// Колонки и индексы записываются как "таблица.имя".
+type SchemaDiff struct {
+	MissingTables     []string `json:"missing_tables,omitempty"`
+	MissingColumns    []string `json:"missing_columns,omitempty"`
+	UnexpectedColumns []string `json:"unexpected_columns,omitempty"`
+	MissingIndexes    []string `json:"missing_indexes,omitempty"`
+	UnexpectedIndexes []string `json:"unexpected_indexes,omitempty"`
+}
+
+// Empty сообщает, что расхождений нет.
+func (d SchemaDiff) Empty() bool {
+	return len(d.MissingTables)+len(d.MissingColumns)+len(d.UnexpectedColumns)+
+		len(d.MissingIndexes)+len(d.UnexpectedIndexes) == 0
+}
+
+// PoolStats - состояние пула подключений к базе данных.
+type PoolStats struct {
+	MaxOpen int `json:"max_open"`
+	Open    int `json:"open"`
+	InUse   int `json:"in_use"`
+	Idle    int `json:"idle"`
+}
+
+// StorageInfo - размер базы данных и её таблиц.
+type StorageInfo struct {
+	Driver        string      `json:"driver"`
+	DatabaseBytes int64       `json:"database_bytes"`
+	Tables        []TableSize `json:"tables"`
+}
+
+// TableSize - размер таблицы и её индексов. Rows - оценка планировщика
+// (pg_class.reltuples), а не точное количество строк.
+type TableSize struct {
+	Name       string      `json:"name"`
+	Rows       int64       `json:"rows_estimate"`
+	TableBytes int64       `json:"table_bytes"`
+	IndexBytes int64       `json:"index_bytes"`
+	TotalBytes int64       `json:"total_bytes"`
+	Indexes    []IndexSize `json:"indexes"`
+}
+
+// IndexSize - размер одного индекса.
+type IndexSize struct {
+	Name  string `json:"name"`
+	Bytes int64  `json:"bytes"`
+}
+
+// BalanceBucket - кошельки с балансом в диапазоне [MinBalance, 10*MinBalance);
+// нулевой MinBalance - кошельки с нулевым балансом.
+type BalanceBucket struct {
+	MinBalance   money.Amount `json:"min_balance"`
+	WalletCount  int          `json:"wallet_count"`
+	TotalBalance money.Amount `json:"total_balance"`
+}
+
+// WalletSummary - сводка по неархивным кошелькам на момент StaleAsOf.
+type WalletSummary struct {
+	WalletCount  int             `json:"wallet_count"`
+	TotalBalance money.Amount    `json:"total_balance"`
+	Distribution []BalanceBucket `json:"distribution"`
+	StaleAsOf    time.Time       `json:"stale_as_of"`
+}
+
+// Версия формата снимка кошельков. Восстановление принимает только её.
+const SnapshotVersion = 1
+
+// WalletSnapshot - состояние кошельков (адреса, балансы, метки и флаги) без истории
+// транзакций.
+type WalletSnapshot struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Wallets   []Wallet  `json:"wallets"`
+}
+
+// Validate проверяет версию снимка, адреса (любой известной схемы), балансы и
+// метки кошельков. Адреса и метки должны быть уникальны.
+func (s *WalletSnapshot) Validate() error {
+	if s.Version != SnapshotVersion {
+		return fmt.Errorf("версия снимка %d не поддерживается, ожидается %d", s.Version, SnapshotVersion)
+	}
+	addresses := make(map[string]bool, len(s.Wallets))
+	labels := make(map[string]bool)
+	for i, w := range s.Wallets {
+		if w.Address == "" {
+			return fmt.Errorf("кошелёк %d: адрес обязателен", i)
+		}
+		if normalized, err := address.Normalize(w.Address); err != nil || normalized != w.Address {
+			return fmt.Errorf("кошелёк %d: адрес должен быть в каноническом виде одной из схем: %s", i, strings.Join(address.Names(), ", "))
+		}
+		if addresses[w.Address] {
+			return fmt.Errorf("кошелёк %s встречается в снимке несколько раз", redact.Address(w.Address))
+		}
+		addresses[w.Address] = true
+		if w.Balance < 0 {
+			return fmt.Errorf("кошелёк %s: баланс не может быть отрицательным", redact.Address(w.Address))
+		}
+		if w.MaxBalance != nil && *w.MaxBalance < 0 {
+			return fmt.Errorf("кошелёк %s: предельный баланс не может быть отрицательным", redact.Address(w.Address))
+		}
+		if w.Label == "" {
+			continue
+		}
+		if err := ValidateLabel(w.Label); err != nil {
+			return fmt.Errorf("кошелёк %s: %w", redact.Address(w.Address), err)
+		}
+		if labels[w.Label] {
+			return fmt.Errorf("метка %s назначена в снимке нескольким кошелькам", w.Label)
+		}
+		labels[w.Label] = true
+	}
+	return nil
+}
+
+// Максимальная длина ключа идемпотентности.
+const MaxIdempotencyKeyLength = 255
+
+// IdempotencyKey - ключ идемпотентности и транзакция, записанная с ним.
+type IdempotencyKey struct {
+	Key                 string    `json:"key"`
+	TransactionID       int       `json:"transaction_id"`
+	TransactionPublicID string    `json:"transaction_public_id"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+// Уровни находок проверки целостности.
+const (
+	FsckSeverityError   = "error"
+	FsckSeverityWarning = "warning"
+)
+
+// FsckFinding - нарушение целостности данных, найденное проверкой Check.
+// Object - идентификатор объекта, например "transaction:17" или "wallet:a1b2c3…f9e8".
+type FsckFinding struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Object   string `json:"object"`
+	Message  string `json:"message"`
+}
+
+// FsckSummary - итог проверки целостности. Truncated - хотя бы одна проверка
+// остановилась, достигнув лимита находок.
+type FsckSummary struct {
+	Errors    int  `json:"errors"`
+	Warnings  int  `json:"warnings"`
+	Truncated bool `json:"truncated"`
+}
+
+// GroupSummary - итоги группы переводов. TotalAmount - сумма успешных переводов,
+// Statuses - количество транзакций по статусам.
+type GroupSummary struct {
+	GroupID          string                    `json:"group_id"`
+	TransactionCount int                       `json:"transaction_count"`
+	TotalAmount      money.Amount              `json:"total_amount"`
+	Statuses         map[TransactionStatus]int `json:"statuses"`
+	FirstAt          time.Time                 `json:"first_at"`
+	LastAt           time.Time                 `json:"last_at"`
+}
+
+// PaymentRequestStatus - состояние запроса на оплату.
+type PaymentRequestStatus string
+
+const (
+	PaymentRequestOpen      PaymentRequestStatus = "open"
+	PaymentRequestFulfilled PaymentRequestStatus = "fulfilled"
+	// PaymentRequestExpired не хранится: так отдаётся открытый запрос с истёкшим сроком.
+	PaymentRequestExpired PaymentRequestStatus = "expired"
+)
+
+// Максимальная длина reference запроса на оплату.
+const MaxPaymentReferenceLength = 128
+
+// PaymentRequest - запрос на оплату: «переведите Amount на кошелёк To с Reference».
+// Token - непрозрачный идентификатор, которым запросом делятся с плательщиком.
+type PaymentRequest struct {
+	Token         string               `json:"token"`
+	To            string               `json:"to"`
+	Amount        money.Amount         `json:"amount"`
+	Reference     string               `json:"reference,omitempty"`
+	Status        PaymentRequestStatus `json:"status"`
+	ExpiresAt     time.Time            `json:"expires_at"`
+	CreatedAt     time.Time            `json:"created_at"`
+	TransactionID *int                 `json:"transaction_id,omitempty"`
+	FulfilledAt   *time.Time           `json:"fulfilled_at,omitempty"`
+}
+
+// IncomingCursor - позиция потребителя входящих переводов кошелька: переводы
+// с id не больше LastTransactionID потребитель уже обработал.
+type IncomingCursor struct {
+	Wallet            string    `json:"wallet"`
+	Consumer          string    `json:"consumer"`
+	LastTransactionID int       `json:"last_transaction_id"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// Максимальная длина имени потребителя входящих переводов.
+const MaxConsumerNameLength = 64
+
+// Максимальное количество идентификаторов в одном массовом подтверждении.
+const MaxBulkAckIDs = 1000
+
+// ThresholdDirection - сторона порога баланса, при переходе на которую срабатывает правило.
+type ThresholdDirection string
+
+const (
+	// ThresholdAbove срабатывает, когда баланс становится больше порога.
+	ThresholdAbove ThresholdDirection = "above"
+	// ThresholdBelow срабатывает, когда баланс становится меньше порога.
+	ThresholdBelow ThresholdDirection = "below"
+)
+
+// ThresholdTargetAlerter - получатель предупреждения по умолчанию: канал
+// предупреждений сервиса (ALERT_WEBHOOK_URL или лог).
+const ThresholdTargetAlerter = "alerter"
+
+// Максимальное количество правил порога баланса у одного кошелька.
+const MaxWalletThresholds = 20
+
+// BalanceThreshold - правило порога баланса кошелька. Правило срабатывает один
+// раз при переходе баланса за порог (Triggered становится true) и снова готово
+// к срабатыванию, когда баланс возвращается за порог с запасом Hysteresis.
+type BalanceThreshold struct {
+	ID        int                `json:"id"`
+	Wallet    string             `json:"wallet"`
+	Direction ThresholdDirection `json:"direction"`
+	Amount    money.Amount       `json:"amount"`
+	// Hysteresis - насколько баланс должен вернуться за порог, чтобы правило сработало снова.
+	Hysteresis money.Amount `json:"hysteresis"`
+	// Target - URL вебхука или ThresholdTargetAlerter.
+	Target    string `json:"target"`
+	Triggered bool   `json:"triggered"`
+	// LastTransactionID - перевод, последним переключивший состояние правила.
+	LastTransactionID *int       `json:"last_transaction_id,omitempty"`
+	LastCrossedAt     *time.Time `json:"last_crossed_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// ThresholdCrossing - переключение правила порога переводом: Threshold - правило
+// после переключения, Balance - баланс кошелька после перевода.
+type ThresholdCrossing struct {
+	Threshold BalanceThreshold
+	Balance   money.Amount
+}
+
+// WithdrawalStatus - состояние вывода средств.
+type WithdrawalStatus string
+
+const (
+	// WithdrawalPending - сумма списана с кошелька и ждёт подтверждения расчёта.
+	WithdrawalPending WithdrawalStatus = "pending"
+	// WithdrawalReview - подтверждение не пришло вовремя; вывод ждёт разбора оператором.
+	WithdrawalReview WithdrawalStatus = "review"
+	// WithdrawalConfirmed - расчёт подтверждён, вывод завершён.
+	WithdrawalConfirmed WithdrawalStatus = "confirmed"
+	// WithdrawalFailed - расчёт не состоялся, сумма возвращена на кошелёк.
+	WithdrawalFailed WithdrawalStatus = "failed"
+)
+
+// Settled сообщает, завершён ли вывод: завершённый вывод больше не меняется.
+func (s WithdrawalStatus) Settled() bool {
+	return s == WithdrawalConfirmed || s == WithdrawalFailed
+}
+
+// Максимальная длина reference вывода средств.
+const MaxWithdrawalReferenceLength = 128
+
+// Максимальная длина причины отказа вывода средств.
+const MaxWithdrawalReasonLength = 256
+
+// Withdrawal - вывод средств с кошелька Wallet. Сумма списывается сразу
+// транзакцией TransactionID на клиринговый кошелёк ClearingWallet и до завершения
+// вывода учитывается в балансе кошелька как pending_out. Отказ возвращает сумму
+// транзакцией ReversalTransactionID.
+type Withdrawal struct {
+	ID             int              `json:"id"`
+	Wallet         string           `json:"wallet"`
+	ClearingWallet string           `json:"clearing_wallet"`
+	Amount         money.Amount     `json:"amount"`
+	Status         WithdrawalStatus `json:"status"`
+	Reference      string           `json:"reference,omitempty"`
+	TransactionID  int              `json:"transaction_id"`
+	// ReversalTransactionID - возврат суммы на кошелёк при отказе.
+	ReversalTransactionID *int `json:"reversal_transaction_id,omitempty"`
+	// Reason - причина отказа или перевода на разбор.
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
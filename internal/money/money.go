@@ -0,0 +1,163 @@
+/*
+money форматирует и разбирает денежные суммы.
+
+Суммы хранятся в базе как DECIMAL(20, 8), поэтому в JSON и CSV они выводятся
+строками с фиксированной точкой и ровно Scale знаками после запятой, например
+"100.00000000". Это избавляет клиентов от представлений вроде 99.99999999999999
+или 1e-07, которые даёт прямой вывод float64.
+
+Функции:
+  - FormatAmount: Форматирует сумму с фиксированной точкой.
+  - ParseAmount: Разбирает сумму из строки с фиксированной точкой.
+  - Amount: Тип суммы, который выводится в JSON строкой и читается из строки или числа
+    с не более чем Scale знаками после запятой.
+*/
+package money
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Scale - количество знаков после запятой в суммах.
+const Scale = 8
+
+var ErrInvalidAmount = errors.New("некорректная сумма")
+
+// ErrAmountOutOfRange - сумма записана верно, но не представима числом float64
+// (например, 1e400). Является ErrInvalidAmount.
+var ErrAmountOutOfRange = fmt.Errorf("%w: сумма вне допустимого диапазона", ErrInvalidAmount)
+
+// FormatAmount форматирует сумму с ровно Scale знаками после запятой.
+// Отрицательный ноль и отрицательные суммы, округляющиеся до нуля, выводятся как ноль.
+func FormatAmount(v float64) string {
+	s := strconv.FormatFloat(v, 'f', Scale, 64)
+	if s[0] == '-' && isZero(s[1:]) {
+		return s[1:]
+	}
+	return s
+}
+
+func isZero(s string) bool {
+	for _, c := range s {
+		if c != '0' && c != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseAmount разбирает сумму вида [-]цифры[.цифры] с не более чем Scale знаками
+// после запятой. Экспоненциальная запись, NaN и бесконечности не принимаются.
+func ParseAmount(s string) (float64, error) {
+	digits := s
+	if len(digits) > 0 && digits[0] == '-' {
+		digits = digits[1:]
+	}
+
+	intPart, fracPart := digits, ""
+	for i := 0; i < len(digits); i++ {
+		if digits[i] == '.' {
+			intPart, fracPart = digits[:i], digits[i+1:]
+			if fracPart == "" {
+				return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+			}
+			break
+		}
+	}
+	if intPart == "" || !allDigits(intPart) || !allDigits(fracPart) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if len(fracPart) > Scale {
+		return 0, fmt.Errorf("%w: больше %d знаков после запятой в %q", ErrInvalidAmount, Scale, s)
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("%w: %q", ErrAmountOutOfRange, s)
+	}
+	return v, nil
+}
+
+func allDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Amount - денежная сумма. В JSON выводится строкой FormatAmount, а читается
+// как из строки, так и из числа; в обоих случаях не больше Scale знаков после
+// запятой.
+type Amount float64
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return strconv.AppendQuote(nil, FormatAmount(float64(a))), nil
+}
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	s := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		var err error
+		if s, err = strconv.Unquote(s); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidAmount, data)
+		}
+	} else {
+		// Числа в JSON могут быть в экспоненциальной записи, поэтому разбираются отдельно.
+		v, err := strconv.ParseFloat(s, 64)
+		if errors.Is(err, strconv.ErrRange) {
+			return fmt.Errorf("%w: %s", ErrAmountOutOfRange, data)
+		}
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("%w: %s", ErrInvalidAmount, data)
+		}
+		// Как и в строке, лишние знаки после запятой - ошибка, а не молчаливое округление.
+		if decimalPlaces(s) > Scale {
+			return fmt.Errorf("%w: больше %d знаков после запятой в %s", ErrInvalidAmount, Scale, data)
+		}
+		*a = Amount(v)
+		return nil
+	}
+
+	v, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+	*a = Amount(v)
+	return nil
+}
+
+// decimalPlaces возвращает количество знаков после запятой в записи числа JSON s
+// с учётом экспоненты: у 1.5e-7 их 8, у 1.5e2 - ни одного.
+func decimalPlaces(s string) int {
+	mantissa, exp := s, 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mantissa = s[:i]
+		var err error
+		exp, err = strconv.Atoi(s[i+1:])
+		// Экспонента за пределами int: отрицательная даёт заведомо больше Scale знаков.
+		if err != nil || exp < -maxExponent || exp > maxExponent {
+			if strings.HasPrefix(s[i+1:], "-") {
+				return math.MaxInt
+			}
+			return 0
+		}
+	}
+	places := 0
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		places = len(mantissa) - i - 1
+	}
+	return max(places-exp, 0)
+}
+
+// maxExponent ограничивает экспоненту в decimalPlaces, чтобы разность не переполнялась.
+const maxExponent = 1 << 20
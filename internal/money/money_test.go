@@ -0,0 +1,184 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+// Наибольшая сумма, которую хранит DECIMAL(20, 8).
+const maxStored = "999999999999.99999999"
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want string
+	}{
+		{0, "0.00000000"},
+		{math.Copysign(0, -1), "0.00000000"},
+		{1e-8, "0.00000001"},
+		{-1e-8, "-0.00000001"},
+		{4e-9, "0.00000000"},
+		{-4e-9, "0.00000000"},
+		{6e-9, "0.00000001"},
+		{1e-7, "0.00000010"},
+		{0.1 + 0.2, "0.30000000"},
+		{99.99999999999999, "100.00000000"},
+		{-1, "-1.00000000"},
+		{-100.5, "-100.50000000"},
+		{1e9, "1000000000.00000000"},
+		// Ближайшее к maxStored число float64.
+		{999999999999.99999999, "1000000000000.00000000"},
+		{999999999999.9999, "999999999999.99987793"},
+	}
+	for _, tt := range tests {
+		if got := FormatAmount(tt.in); got != tt.want {
+			t.Errorf("FormatAmount(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"0", 0},
+		{"0.00000000", 0},
+		{"-0", 0},
+		{"0.00000001", 1e-8},
+		{"-0.00000001", -1e-8},
+		{"100", 100},
+		{"-3.25", -3.25},
+		{"007.10000000", 7.1},
+		{maxStored, 999999999999.99999999},
+		{"-" + maxStored, -999999999999.99999999},
+	}
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.in)
+		if err != nil {
+			t.Errorf("ParseAmount(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAmount(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseAmountInvalid(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantErr error
+	}{
+		{"", ErrInvalidAmount},
+		{"-", ErrInvalidAmount},
+		{".5", ErrInvalidAmount},
+		{"5.", ErrInvalidAmount},
+		{"+5", ErrInvalidAmount},
+		{"--5", ErrInvalidAmount},
+		{" 5", ErrInvalidAmount},
+		{"1,5", ErrInvalidAmount},
+		{"1e5", ErrInvalidAmount},
+		{"NaN", ErrInvalidAmount},
+		{"Inf", ErrInvalidAmount},
+		{"0x10", ErrInvalidAmount},
+		// Меньше наименьшей единицы: девятый знак после запятой.
+		{"0.000000001", ErrInvalidAmount},
+		{"1.123456789", ErrInvalidAmount},
+		{"1" + strings.Repeat("0", 400), ErrAmountOutOfRange},
+	}
+	for _, tt := range tests {
+		_, err := ParseAmount(tt.in)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("ParseAmount(%q): %v, want %v", tt.in, err, tt.wantErr)
+		}
+	}
+	// Вне диапазона - частный случай некорректной суммы.
+	if !errors.Is(ErrAmountOutOfRange, ErrInvalidAmount) {
+		t.Error("ErrAmountOutOfRange не является ErrInvalidAmount")
+	}
+}
+
+func TestAmountRoundTrip(t *testing.T) {
+	for _, s := range []string{"0.00000000", "0.00000001", "-0.00000001", "-1.50000000", "123456.78901234"} {
+		v, err := ParseAmount(s)
+		if err != nil {
+			t.Fatalf("ParseAmount(%q): %v", s, err)
+		}
+		if got := FormatAmount(v); got != s {
+			t.Errorf("FormatAmount(ParseAmount(%q)) = %q", s, got)
+		}
+	}
+}
+
+func TestAmountJSON(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Zero     Amount  `json:"zero"`
+		Unit     Amount  `json:"unit"`
+		Negative Amount  `json:"negative"`
+		Pointer  *Amount `json:"pointer"`
+	}{0, 1e-8, -2.5, nil})
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	want := `{"zero":"0.00000000","unit":"0.00000001","negative":"-2.50000000","pointer":null}`
+	if string(data) != want {
+		t.Errorf("json.Marshal = %s, want %s", data, want)
+	}
+
+	tests := []struct {
+		in      string
+		want    Amount
+		wantErr error
+	}{
+		{`"100.5"`, 100.5, nil},
+		{`100.5`, 100.5, nil},
+		{`0`, 0, nil},
+		{`"0"`, 0, nil},
+		{`-0.00000001`, -1e-8, nil},
+		{`"0.00000001"`, 1e-8, nil},
+		{`1e-8`, 1e-8, nil},
+		{`"` + maxStored + `"`, 999999999999.99999999, nil},
+		{`null`, 0, nil},
+		{`1e308`, 1e308, nil},
+		{`1e400`, 0, ErrAmountOutOfRange},
+		{`-1e400`, 0, ErrAmountOutOfRange},
+		{`"1e5"`, 0, ErrInvalidAmount},
+		{`"0.000000001"`, 0, ErrInvalidAmount},
+		{`"abc"`, 0, ErrInvalidAmount},
+		{`true`, 0, ErrInvalidAmount},
+		{`"\x"`, 0, ErrInvalidAmount},
+		// Числа проверяются на точность так же, как строки, с учётом экспоненты.
+		{`1.12345678`, 1.12345678, nil},
+		{`1.5e-7`, 1.5e-7, nil},
+		{`0.00000001e2`, 1e-6, nil},
+		{`12345678.9E-8`, 0, ErrInvalidAmount},
+		{`1.123456789`, 0, ErrInvalidAmount},
+		{`0.000000001`, 0, ErrInvalidAmount},
+		{`100.00000000000000000001`, 0, ErrInvalidAmount},
+		{`1.5e-8`, 0, ErrInvalidAmount},
+		{`1e-9`, 0, ErrInvalidAmount},
+		{`1E-99999999999999999999`, 0, ErrInvalidAmount},
+		{`0.1e99999999999999999999`, 0, ErrAmountOutOfRange},
+	}
+	for _, tt := range tests {
+		var got Amount
+		err := got.UnmarshalJSON([]byte(tt.in))
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("UnmarshalJSON(%s): %v, want %v", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("UnmarshalJSON(%s): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("UnmarshalJSON(%s) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
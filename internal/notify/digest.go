@@ -0,0 +1,147 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go-payments/internal/models"
+	"go-payments/internal/money"
+	"go-payments/internal/redact"
+)
+
+// TypeTransferDigest - тип сводного уведомления о входящих переводах кошелька.
+const TypeTransferDigest = "transfer.digest"
+
+// DigestEvent - сводка успешных переводов на один кошелёк за окно группировки.
+type DigestEvent struct {
+	Type               string       `json:"type"`
+	Wallet             string       `json:"wallet"`
+	Count              int          `json:"count"`
+	TotalAmount        money.Amount `json:"total_amount"`
+	FirstTransactionID int          `json:"first_transaction_id"`
+	LastTransactionID  int          `json:"last_transaction_id"`
+	FirstTimestamp     time.Time    `json:"first_timestamp"`
+	LastTimestamp      time.Time    `json:"last_timestamp"`
+}
+
+// Message возвращает текст сводки с сокращённым адресом кошелька.
+func (e DigestEvent) Message() string {
+	return fmt.Sprintf("Кошелёк %s получил %d переводов на сумму %s (%s - %s)",
+		redact.Address(e.Wallet), e.Count, money.FormatAmount(float64(e.TotalAmount)),
+		e.FirstTimestamp.UTC().Format(time.RFC3339), e.LastTimestamp.UTC().Format(time.RFC3339))
+}
+
+// pendingDigest - накапливаемая сводка и таймер окна, по которому она отправится.
+type pendingDigest struct {
+	event DigestEvent
+	timer *time.Timer
+}
+
+// Digester объединяет успешные переводы на один кошелёк в сводки: вместо
+// уведомления о каждом переводе next получает одну сводку за окно window или
+// по достижении maxEvents переводов. Отклонённые переводы и события кошельков
+// передаются сразу. Close отправляет все накопленные сводки.
+type Digester struct {
+	next      Notifier
+	window    time.Duration
+	maxEvents int
+
+	mu      sync.Mutex
+	pending map[string]*pendingDigest
+	closed  bool
+}
+
+// NewDigester создаёт Digester поверх next.
+func NewDigester(next Notifier, window time.Duration, maxEvents int) *Digester {
+	return &Digester{next: next, window: window, maxEvents: maxEvents, pending: make(map[string]*pendingDigest)}
+}
+
+func (d *Digester) Notify(ctx context.Context, event TransferEvent) error {
+	if event.Status != models.StatusSuccess {
+		return d.next.Notify(ctx, event)
+	}
+
+	d.mu.Lock()
+	if d.closed {
+		// После Close сводку отправить уже некому: уведомление уходит как есть.
+		d.mu.Unlock()
+		return d.next.Notify(ctx, event)
+	}
+	p := d.pending[event.To]
+	if p == nil {
+		p = &pendingDigest{event: DigestEvent{
+			Type:               TypeTransferDigest,
+			Wallet:             event.To,
+			FirstTransactionID: event.TransactionID,
+			FirstTimestamp:     event.Timestamp,
+		}}
+		p.timer = time.AfterFunc(d.window, func() { d.expire(event.To, p) })
+		d.pending[event.To] = p
+	}
+	p.event.Count++
+	p.event.TotalAmount += event.Amount
+	p.event.LastTransactionID = event.TransactionID
+	p.event.LastTimestamp = event.Timestamp
+	if p.event.Count < d.maxEvents {
+		d.mu.Unlock()
+		return nil
+	}
+	p.timer.Stop()
+	delete(d.pending, event.To)
+	d.mu.Unlock()
+
+	return d.next.NotifyDigest(ctx, p.event)
+}
+
+func (d *Digester) NotifyWallet(ctx context.Context, event WalletEvent) error {
+	return d.next.NotifyWallet(ctx, event)
+}
+
+func (d *Digester) NotifyDigest(ctx context.Context, event DigestEvent) error {
+	return d.next.NotifyDigest(ctx, event)
+}
+
+func (d *Digester) NotifyWithdrawal(ctx context.Context, event WithdrawalEvent) error {
+	return d.next.NotifyWithdrawal(ctx, event)
+}
+
+// expire отправляет сводку по истечении окна, если она ещё не отправлена
+// из-за maxEvents или Close.
+func (d *Digester) expire(wallet string, p *pendingDigest) {
+	d.mu.Lock()
+	if d.pending[wallet] != p {
+		d.mu.Unlock()
+		return
+	}
+	delete(d.pending, wallet)
+	d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	if err := d.next.NotifyDigest(ctx, p.event); err != nil {
+		log.Printf("ошибка отправки сводки о переводах на кошелёк %s: %v", redact.Address(wallet), err)
+	}
+}
+
+// Close отправляет все накопленные сводки, не дожидаясь окончания окон.
+// Переводы, о которых сообщат после Close, уведомляются по одному.
+func (d *Digester) Close(ctx context.Context) error {
+	d.mu.Lock()
+	d.closed = true
+	pending := d.pending
+	d.pending = make(map[string]*pendingDigest)
+	d.mu.Unlock()
+
+	var errs []error
+	for wallet, p := range pending {
+		p.timer.Stop()
+		if err := d.next.NotifyDigest(ctx, p.event); err != nil {
+			errs = append(errs, fmt.Errorf("сводка для кошелька %s: %w", redact.Address(wallet), err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,490 @@
+/*
+notify рассылает уведомления о переводах через подключаемый транспорт.
+
+Пакет не знает о конкретных каналах (почта, Slack, SMS): транспорт реализует
+интерфейс Notifier. В комплекте два транспорта:
+  - LogNotifier: пишет отрисованное сообщение в лог (по умолчанию);
+  - HTTPNotifier: отправляет событие и сообщение POST-запросом в формате JSON.
+
+Текст сообщения отрисовывает Renderer по шаблонам text/template, которые можно
+загрузить из каталога. Ошибка отрисовки не прерывает уведомление: она пишется в лог,
+учитывается в метрике `payments_notify_render_errors_total`, а вместо сообщения
+отправляется простой текст.
+
+Wrap оборачивает хранилище так, что после каждого вызова Execute - успешного или
+нет - уведомление отправляется в фоне и не задерживает перевод. Повтор по ключу
+идемпотентности и переводы, отклонённые до выполнения (ключ использован для
+другого перевода, системный кошелёк), уведомлений не порождают.
+
+Заметки администратора с нулевой суммой (RecordZeroNote) порождают событие
+transfer.zero_note, только если заметка записана.
+
+Кроме переводов (тип transfer.<статус>) Wrap сообщает о событиях жизненного
+цикла кошелька: смене метки (wallet.label_changed) и удалении архивного кошелька
+(wallet.purged). Событие кошелька содержит его состояние после изменения, для
+удалённого - последнее состояние. О выводах средств Wrap сообщает при каждом
+переходе состояния (withdrawal.pending, withdrawal.confirmed, withdrawal.failed,
+withdrawal.review) с выводом после перехода. Filter пропускает к транспорту только
+события перечисленных типов (NOTIFY_EVENT_TYPES).
+
+Digester объединяет успешные переводы на один кошелёк за окно
+NOTIFY_DIGEST_WINDOW в сводку transfer.digest: количество, сумма, первый и
+последний идентификаторы транзакций. Сводка уходит по истечении окна, по
+достижении NOTIFY_DIGEST_MAX_EVENTS переводов или при остановке сервиса.
+*/
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-payments/internal/api"
+	"go-payments/internal/models"
+	"go-payments/internal/money"
+	"go-payments/internal/redact"
+	"go-payments/internal/storage"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Время на отправку одного уведомления.
+const notifyTimeout = 10 * time.Second
+
+var webhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "payments_notify_webhook_deliveries_total",
+	Help: "Количество доставок уведомлений на NOTIFY_WEBHOOK_URL по результату (success, failure).",
+}, []string{"result"})
+
+// Типы событий кошелька. Тип события о переводе - transfer.<статус>.
+const (
+	TypeWalletLabelChanged = "wallet.label_changed"
+	TypeWalletPurged       = "wallet.purged"
+)
+
+// Префикс типа события о переводе.
+const transferTypePrefix = "transfer."
+
+// Префикс типа события о выводе средств: withdrawal.<состояние>.
+const withdrawalTypePrefix = "withdrawal."
+
+// Состояния вывода средств, о переходе в которые отправляются события.
+var withdrawalStatuses = []models.WithdrawalStatus{
+	models.WithdrawalPending, models.WithdrawalConfirmed, models.WithdrawalFailed, models.WithdrawalReview,
+}
+
+// TransferEvent - событие о переводе: успешном или отклонённом.
+type TransferEvent struct {
+	// PublicID - публичный идентификатор (ULID) записанной транзакции.
+	PublicID      string                   `json:"public_id,omitempty"`
+	TransactionID int                      `json:"transaction_id,omitempty"`
+	From          string                   `json:"from"`
+	To            string                   `json:"to"`
+	Amount        money.Amount             `json:"amount"`
+	Status        models.TransactionStatus `json:"status"`
+	Timestamp     time.Time                `json:"timestamp"`
+	Memo          string                   `json:"memo,omitempty"`
+	Reference     string                   `json:"reference,omitempty"`
+	GroupID       string                   `json:"group_id,omitempty"`
+}
+
+// Type возвращает тип события о переводе, например transfer.success.
+func (e TransferEvent) Type() string {
+	return transferTypePrefix + string(e.Status)
+}
+
+// WalletEvent - событие жизненного цикла кошелька.
+type WalletEvent struct {
+	Type      string        `json:"type"`
+	Wallet    models.Wallet `json:"wallet"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// Message возвращает текст сообщения о событии кошелька с сокращённым адресом.
+func (e WalletEvent) Message() string {
+	address := redact.Address(e.Wallet.Address)
+	switch e.Type {
+	case TypeWalletLabelChanged:
+		if e.Wallet.Label == "" {
+			return fmt.Sprintf("С кошелька %s снята метка", address)
+		}
+		return fmt.Sprintf("Кошельку %s назначена метка %s", address, e.Wallet.Label)
+	case TypeWalletPurged:
+		return fmt.Sprintf("Кошелёк %s удалён", address)
+	default:
+		return fmt.Sprintf("Событие %s кошелька %s", e.Type, address)
+	}
+}
+
+// WithdrawalEvent - переход вывода средств в состояние Withdrawal.Status.
+type WithdrawalEvent struct {
+	Withdrawal models.Withdrawal `json:"withdrawal"`
+	Timestamp  time.Time         `json:"timestamp"`
+}
+
+// Type возвращает тип события о выводе средств, например withdrawal.confirmed.
+func (e WithdrawalEvent) Type() string {
+	return withdrawalTypePrefix + string(e.Withdrawal.Status)
+}
+
+// Message возвращает текст сообщения о выводе средств с сокращённым адресом.
+func (e WithdrawalEvent) Message() string {
+	w := e.Withdrawal
+	address, amount := redact.Address(w.Wallet), money.FormatAmount(float64(w.Amount))
+	switch w.Status {
+	case models.WithdrawalPending:
+		return fmt.Sprintf("Вывод %d: с кошелька %s списано %s, ожидается подтверждение", w.ID, address, amount)
+	case models.WithdrawalConfirmed:
+		return fmt.Sprintf("Вывод %d: вывод %s с кошелька %s подтверждён", w.ID, amount, address)
+	case models.WithdrawalFailed:
+		return fmt.Sprintf("Вывод %d: вывод с кошелька %s отклонён (%s), %s возвращено", w.ID, address, w.Reason, amount)
+	case models.WithdrawalReview:
+		return fmt.Sprintf("Вывод %d: вывод %s с кошелька %s передан на разбор: %s", w.ID, amount, address, w.Reason)
+	default:
+		return fmt.Sprintf("Вывод %d кошелька %s в состоянии %s", w.ID, address, w.Status)
+	}
+}
+
+// EventTypes перечисляет все типы событий, которые может отправить Wrap.
+func EventTypes() []string {
+	types := make([]string, 0, len(models.TransactionStatuses)+2+len(withdrawalStatuses))
+	for _, status := range models.TransactionStatuses {
+		types = append(types, transferTypePrefix+string(status))
+	}
+	types = append(types, TypeWalletLabelChanged, TypeWalletPurged)
+	for _, status := range withdrawalStatuses {
+		types = append(types, withdrawalTypePrefix+string(status))
+	}
+	return types
+}
+
+// Notifier доставляет события о переводах, кошельках и выводах средств получателю уведомлений.
+type Notifier interface {
+	Notify(ctx context.Context, event TransferEvent) error
+	NotifyWallet(ctx context.Context, event WalletEvent) error
+	NotifyDigest(ctx context.Context, event DigestEvent) error
+	NotifyWithdrawal(ctx context.Context, event WithdrawalEvent) error
+}
+
+// filteringNotifier пропускает только события разрешённых типов.
+type filteringNotifier struct {
+	next    Notifier
+	allowed map[string]bool
+}
+
+// Filter возвращает Notifier, который передаёт next только события типов types.
+// Пустой список пропускает все события. Неизвестный тип - ошибка конфигурации.
+func Filter(next Notifier, types []string) (Notifier, error) {
+	if len(types) == 0 {
+		return next, nil
+	}
+	known := make(map[string]bool)
+	for _, t := range EventTypes() {
+		known[t] = true
+	}
+	allowed := make(map[string]bool, len(types))
+	for _, t := range types {
+		if !known[t] {
+			return nil, fmt.Errorf("неизвестный тип события %q (допустимы: %s)", t, strings.Join(EventTypes(), ", "))
+		}
+		allowed[t] = true
+	}
+	return &filteringNotifier{next: next, allowed: allowed}, nil
+}
+
+func (n *filteringNotifier) Notify(ctx context.Context, event TransferEvent) error {
+	if !n.allowed[event.Type()] {
+		return nil
+	}
+	return n.next.Notify(ctx, event)
+}
+
+func (n *filteringNotifier) NotifyWallet(ctx context.Context, event WalletEvent) error {
+	if !n.allowed[event.Type] {
+		return nil
+	}
+	return n.next.NotifyWallet(ctx, event)
+}
+
+// NotifyDigest пропускает сводку, если разрешены уведомления об успешных
+// переводах, из которых она составлена.
+func (n *filteringNotifier) NotifyDigest(ctx context.Context, event DigestEvent) error {
+	if !n.allowed[transferTypePrefix+string(models.StatusSuccess)] {
+		return nil
+	}
+	return n.next.NotifyDigest(ctx, event)
+}
+
+func (n *filteringNotifier) NotifyWithdrawal(ctx context.Context, event WithdrawalEvent) error {
+	if !n.allowed[event.Type()] {
+		return nil
+	}
+	return n.next.NotifyWithdrawal(ctx, event)
+}
+
+// LogNotifier пишет отрисованное сообщение в лог. Адреса кошельков в сообщении
+// сокращаются через redact.Address.
+type LogNotifier struct {
+	Renderer *Renderer
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, event TransferEvent) error {
+	event.From, event.To = redact.Address(event.From), redact.Address(event.To)
+	log.Printf("уведомление: %s", n.Renderer.Render(event))
+	return nil
+}
+
+func (n *LogNotifier) NotifyWallet(ctx context.Context, event WalletEvent) error {
+	log.Printf("уведомление: %s", event.Message())
+	return nil
+}
+
+func (n *LogNotifier) NotifyDigest(ctx context.Context, event DigestEvent) error {
+	log.Printf("уведомление: %s", event.Message())
+	return nil
+}
+
+func (n *LogNotifier) NotifyWithdrawal(ctx context.Context, event WithdrawalEvent) error {
+	log.Printf("уведомление: %s", event.Message())
+	return nil
+}
+
+// HTTPNotifier отправляет событие и отрисованное сообщение POST-запросом на URL.
+type HTTPNotifier struct {
+	URL      string
+	Client   *http.Client
+	Renderer *Renderer
+}
+
+// httpPayload - тело уведомления. Event - TransferEvent, WalletEvent, DigestEvent
+// или WithdrawalEvent в зависимости от Type.
+type httpPayload struct {
+	Type    string `json:"type"`
+	Event   any    `json:"event"`
+	Message string `json:"message"`
+}
+
+func (n *HTTPNotifier) Notify(ctx context.Context, event TransferEvent) error {
+	return n.post(ctx, httpPayload{Type: event.Type(), Event: event, Message: n.Renderer.Render(event)})
+}
+
+func (n *HTTPNotifier) NotifyWallet(ctx context.Context, event WalletEvent) error {
+	return n.post(ctx, httpPayload{Type: event.Type, Event: event, Message: event.Message()})
+}
+
+func (n *HTTPNotifier) NotifyDigest(ctx context.Context, event DigestEvent) error {
+	return n.post(ctx, httpPayload{Type: event.Type, Event: event, Message: event.Message()})
+}
+
+func (n *HTTPNotifier) NotifyWithdrawal(ctx context.Context, event WithdrawalEvent) error {
+	return n.post(ctx, httpPayload{Type: event.Type(), Event: event, Message: event.Message()})
+}
+
+func (n *HTTPNotifier) post(ctx context.Context, payload httpPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать уведомление: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("не удалось создать запрос уведомления: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		webhookDeliveries.WithLabelValues("failure").Inc()
+		return fmt.Errorf("не удалось отправить уведомление: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		webhookDeliveries.WithLabelValues("failure").Inc()
+		return fmt.Errorf("получатель уведомления ответил %s", resp.Status)
+	}
+	webhookDeliveries.WithLabelValues("success").Inc()
+	return nil
+}
+
+// notifyingStorage отправляет уведомление после каждого вызова Execute, после
+// изменений кошельков и после переходов выводов средств.
+type notifyingStorage struct {
+	api.Storage
+	notifier Notifier
+}
+
+// Wrap возвращает хранилище, которое уведомляет notifier о каждом переводе.
+func Wrap(next api.Storage, notifier Notifier) api.Storage {
+	return &notifyingStorage{Storage: next, notifier: notifier}
+}
+
+func (s *notifyingStorage) Execute(ctx context.Context, t models.Transfer) (*models.Transaction, error) {
+	stats := storage.ExecStatsFrom(ctx)
+	if stats == nil {
+		stats = &storage.ExecStats{}
+		ctx = storage.WithExecStats(ctx, stats)
+	}
+	transaction, err := s.Storage.Execute(ctx, t)
+
+	// Повтор по ключу идемпотентности не выполняет перевод, и уведомление о нём уже
+	// отправлено; перевод с чужим ключом или с участием системного кошелька не
+	// выполнялся вовсе.
+	if stats.Replayed || storage.Rejected(err) {
+		return transaction, err
+	}
+
+	event := TransferEvent{
+		From:      t.From,
+		To:        t.To,
+		Amount:    money.Amount(t.Amount),
+		Memo:      t.Memo,
+		Reference: t.Reference,
+		GroupID:   t.GroupID,
+		Timestamp: time.Now().UTC(),
+		Status:    storage.FailureStatus(err),
+	}
+	if transaction != nil {
+		event.PublicID = transaction.PublicID
+		event.TransactionID = transaction.ID
+		event.Timestamp = transaction.Timestamp
+		event.Status = transaction.Status
+	}
+
+	// Уведомление не должно задерживать ответ и не зависит от отмены запроса.
+	go func() {
+		notifyCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+		defer cancel()
+		if err := s.notifier.Notify(notifyCtx, event); err != nil {
+			log.Printf("ошибка отправки уведомления о переводе от %s к %s: %v", redact.Address(event.From), redact.Address(event.To), err)
+		}
+	}()
+
+	return transaction, err
+}
+
+// RecordZeroNote сообщает о записанной заметке событием transfer.zero_note;
+// отклонённая заметка не записывается, и уведомления о ней нет.
+func (s *notifyingStorage) RecordZeroNote(ctx context.Context, t models.Transfer) (*models.Transaction, error) {
+	transaction, err := s.Storage.RecordZeroNote(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	event := TransferEvent{
+		PublicID:      transaction.PublicID,
+		TransactionID: transaction.ID,
+		From:          transaction.From,
+		To:            transaction.To,
+		Amount:        transaction.Amount,
+		Status:        transaction.Status,
+		Timestamp:     transaction.Timestamp,
+		Memo:          transaction.Memo,
+		Reference:     transaction.Reference,
+	}
+	go func() {
+		notifyCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+		defer cancel()
+		if err := s.notifier.Notify(notifyCtx, event); err != nil {
+			log.Printf("ошибка отправки уведомления о заметке от %s к %s: %v", redact.Address(event.From), redact.Address(event.To), err)
+		}
+	}()
+	return transaction, nil
+}
+
+func (s *notifyingStorage) SetWalletLabel(ctx context.Context, address, label string) error {
+	if err := s.Storage.SetWalletLabel(ctx, address, label); err != nil {
+		return err
+	}
+	wallet, err := s.Storage.GetWalletBalance(ctx, address)
+	if err != nil {
+		log.Printf("ошибка получения кошелька %s для уведомления: %v", redact.Address(address), err)
+		wallet = &models.Wallet{Address: address, Label: label}
+	}
+	s.notifyWallet(ctx, TypeWalletLabelChanged, *wallet)
+	return nil
+}
+
+// PurgeWallet сообщает о последнем состоянии кошелька, прочитанном до удаления.
+func (s *notifyingStorage) PurgeWallet(ctx context.Context, address string) error {
+	wallet, err := s.Storage.GetWalletBalance(ctx, address)
+	if err != nil {
+		wallet = &models.Wallet{Address: address, Archived: true}
+	}
+	if err := s.Storage.PurgeWallet(ctx, address); err != nil {
+		return err
+	}
+	s.notifyWallet(ctx, TypeWalletPurged, *wallet)
+	return nil
+}
+
+// notifyWallet отправляет событие кошелька в фоне, как и уведомление о переводе.
+func (s *notifyingStorage) notifyWallet(ctx context.Context, eventType string, wallet models.Wallet) {
+	event := WalletEvent{Type: eventType, Wallet: wallet, Timestamp: time.Now().UTC()}
+	go func() {
+		notifyCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+		defer cancel()
+		if err := s.notifier.NotifyWallet(notifyCtx, event); err != nil {
+			log.Printf("ошибка отправки уведомления %s о кошельке %s: %v", event.Type, redact.Address(wallet.Address), err)
+		}
+	}()
+}
+
+func (s *notifyingStorage) CreateWithdrawal(ctx context.Context, w models.Withdrawal, idempotencyKey string) (*models.Withdrawal, error) {
+	stats := storage.ExecStatsFrom(ctx)
+	if stats == nil {
+		stats = &storage.ExecStats{}
+		ctx = storage.WithExecStats(ctx, stats)
+	}
+	created, err := s.Storage.CreateWithdrawal(ctx, w, idempotencyKey)
+	// О выводе, возвращённом повтором по ключу идемпотентности, уже сообщено.
+	if err == nil && !stats.Replayed {
+		s.notifyWithdrawal(ctx, *created)
+	}
+	return created, err
+}
+
+func (s *notifyingStorage) ConfirmWithdrawal(ctx context.Context, id int) (*models.Withdrawal, error) {
+	w, err := s.Storage.ConfirmWithdrawal(ctx, id)
+	if err == nil {
+		s.notifyWithdrawal(ctx, *w)
+	}
+	return w, err
+}
+
+func (s *notifyingStorage) FailWithdrawal(ctx context.Context, id int, reason string) (*models.Withdrawal, error) {
+	w, err := s.Storage.FailWithdrawal(ctx, id, reason)
+	if err == nil {
+		s.notifyWithdrawal(ctx, *w)
+	}
+	return w, err
+}
+
+func (s *notifyingStorage) ReviewExpiredWithdrawals(ctx context.Context, olderThan time.Duration) ([]models.Withdrawal, error) {
+	reviewed, err := s.Storage.ReviewExpiredWithdrawals(ctx, olderThan)
+	for _, w := range reviewed {
+		s.notifyWithdrawal(ctx, w)
+	}
+	return reviewed, err
+}
+
+// notifyWithdrawal отправляет событие о переходе вывода средств в фоне.
+func (s *notifyingStorage) notifyWithdrawal(ctx context.Context, w models.Withdrawal) {
+	event := WithdrawalEvent{Withdrawal: w, Timestamp: w.UpdatedAt}
+	go func() {
+		notifyCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+		defer cancel()
+		if err := s.notifier.NotifyWithdrawal(notifyCtx, event); err != nil {
+			log.Printf("ошибка отправки уведомления %s о выводе средств %d: %v", event.Type(), w.ID, err)
+		}
+	}()
+}
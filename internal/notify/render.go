@@ -0,0 +1,119 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"go-payments/internal/money"
+	"go-payments/internal/redact"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Имя шаблона по умолчанию. Шаблон для конкретного статуса называется
+// по статусу, например success.tmpl или failed_insufficient_funds.tmpl.
+const defaultTemplate = "default"
+
+var renderErrors = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "payments_notify_render_errors_total",
+	Help: "Количество ошибок отрисовки шаблонов уведомлений.",
+})
+
+// Встроенные шаблоны, используемые, если каталог шаблонов не задан.
+var builtinTemplates = map[string]string{
+	defaultTemplate: `Перевод {{.Amount}} от {{.From}} к {{.To}}: {{.Status}} ({{.Timestamp}})`,
+	"success":       `Перевод {{.Amount}} от {{.From}} к {{.To}} выполнен ({{.Timestamp}})`,
+	"zero_note":     `Заметка от {{.From}} к {{.To}}: {{.Memo}} ({{.Timestamp}})`,
+}
+
+// MessageData - поля, доступные в шаблоне сообщения.
+type MessageData struct {
+	PublicID      string // ULID транзакции
+	TransactionID int
+	From          string // адрес отправителя, сокращённый через redact.Address
+	To            string // адрес получателя, сокращённый через redact.Address
+	FromAddress   string
+	ToAddress     string
+	Amount        string
+	Status        string
+	Timestamp     string // RFC 3339, UTC
+	Memo          string
+	Reference     string
+	GroupID       string
+}
+
+// Renderer отрисовывает сообщения о переводах по шаблонам.
+type Renderer struct {
+	templates *template.Template
+}
+
+// NewRenderer создаёт Renderer со встроенными шаблонами. Если dir не пуст,
+// из него загружаются файлы *.tmpl; имя файла без расширения - имя шаблона.
+// Загруженные шаблоны заменяют встроенные с тем же именем.
+func NewRenderer(dir string) (*Renderer, error) {
+	root := template.New(defaultTemplate).Option("missingkey=error")
+	for name, text := range builtinTemplates {
+		if _, err := root.New(name).Parse(text); err != nil {
+			return nil, fmt.Errorf("ошибка разбора встроенного шаблона %s: %w", name, err)
+		}
+	}
+
+	if dir != "" {
+		files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения каталога шаблонов %s: %w", dir, err)
+		}
+		for _, file := range files {
+			text, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("ошибка чтения шаблона %s: %w", file, err)
+			}
+			name := filepath.Base(file)
+			name = name[:len(name)-len(filepath.Ext(name))]
+			if _, err := root.New(name).Parse(string(text)); err != nil {
+				return nil, fmt.Errorf("ошибка разбора шаблона %s: %w", file, err)
+			}
+		}
+	}
+
+	return &Renderer{templates: root}, nil
+}
+
+// Render возвращает сообщение о событии. Используется шаблон, названный по статусу
+// события, или шаблон по умолчанию. Ошибка отрисовки пишется в лог и учитывается
+// в метрике, а вместо сообщения возвращается простой текст.
+func (r *Renderer) Render(event TransferEvent) string {
+	data := MessageData{
+		PublicID:      event.PublicID,
+		TransactionID: event.TransactionID,
+		From:          redact.Address(event.From),
+		To:            redact.Address(event.To),
+		FromAddress:   event.From,
+		ToAddress:     event.To,
+		Amount:        money.FormatAmount(float64(event.Amount)),
+		Status:        string(event.Status),
+		Timestamp:     event.Timestamp.UTC().Format(time.RFC3339),
+		Memo:          event.Memo,
+		Reference:     event.Reference,
+		GroupID:       event.GroupID,
+	}
+
+	name := string(event.Status)
+	if r.templates.Lookup(name) == nil {
+		name = defaultTemplate
+	}
+
+	var buf bytes.Buffer
+	if err := r.templates.ExecuteTemplate(&buf, name, data); err != nil {
+		renderErrors.Inc()
+		log.Printf("ошибка отрисовки шаблона уведомления %s: %v", name, err)
+		return fmt.Sprintf("Перевод %s от %s к %s: %s", data.Amount, data.From, data.To, data.Status)
+	}
+	return buf.String()
+}
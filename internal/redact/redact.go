@@ -0,0 +1,42 @@
+/*
+redact сокращает идентификаторы перед записью в логи и сообщения об ошибках.
+
+Адреса кошельков считаются чувствительными идентификаторами: в логи и в тексты
+ошибок попадает только их сокращённая форма, достаточная для поиска кошелька.
+Ответы API владельцу кошелька по-прежнему содержат полные адреса.
+
+Functions:
+  - Address: Оставляет первые 6 и последние 4 символа адреса ("a1b2c3…f9e8").
+  - Text: Сокращает в произвольном тексте (например, URI запроса) все
+    последовательности шестнадцатеричных символов и UUID, похожие на адреса кошельков.
+*/
+package redact
+
+import "regexp"
+
+// Количество символов адреса, которые остаются в начале и в конце.
+const (
+	addressPrefix = 6
+	addressSuffix = 4
+)
+
+// Последовательности, которые Text считает адресами: UUID (схема uuidv4) и
+// шестнадцатеричные строки от 32 символов (схема hex64 создаёт адреса из 64).
+// Более короткие идентификаторы не трогаются.
+var reHexToken = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{32,}`)
+
+// Address сокращает адрес кошелька до первых 6 и последних 4 символов. Адреса,
+// которые не длиннее сокращённой формы, возвращаются без изменений.
+func Address(address string) string {
+	if len(address) <= addressPrefix+addressSuffix+1 {
+		return address
+	}
+	return address[:addressPrefix] + "…" + address[len(address)-addressSuffix:]
+}
+
+// Text сокращает через Address все похожие на адреса кошельков последовательности
+// шестнадцатеричных символов и UUID в s. Используется там, где адрес нельзя выделить
+// заранее, например в URI запроса.
+func Text(s string) string {
+	return reHexToken.ReplaceAllStringFunc(s, Address)
+}
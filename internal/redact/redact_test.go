@@ -0,0 +1,47 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{"a1b2c3" + strings.Repeat("0", 54) + "f9e8", "a1b2c3…f9e8"},
+		{"6f1c2a9e-4b7d-4c1a-9e2f-0d3b5a7c9e1f", "6f1c2a…9e1f"},
+		{"0123456789a", "0123456789a"},
+		{"0123456789ab", "012345…89ab"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Address(tt.address); got != tt.want {
+			t.Errorf("Address(%q) = %q, want %q", tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	hex64 := "a1b2c3" + strings.Repeat("0", 54) + "f9e8"
+	uuid := "6f1c2a9e-4b7d-4c1a-9e2f-0d3b5a7c9e1f"
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"/api/wallet/" + hex64 + "/balance", "/api/wallet/a1b2c3…f9e8/balance"},
+		{"/api/wallet/" + uuid + "/balance", "/api/wallet/6f1c2a…9e1f/balance"},
+		{"/api/transactions?address=" + hex64 + "&between=" + uuid + "," + hex64,
+			"/api/transactions?address=a1b2c3…f9e8&between=6f1c2a…9e1f,a1b2c3…f9e8"},
+		// Короткие идентификаторы - номера транзакций, ULID, ключи - не трогаются.
+		{"/api/transactions/01ARZ3NDEKTSV4RRFFQ69G5FAV", "/api/transactions/01ARZ3NDEKTSV4RRFFQ69G5FAV"},
+		{"/api/transactions/42?limit=10", "/api/transactions/42?limit=10"},
+		{"/api/checkpoints/" + strings.Repeat("f", 31), "/api/checkpoints/" + strings.Repeat("f", 31)},
+	}
+	for _, tt := range tests {
+		if got := Text(tt.text); got != tt.want {
+			t.Errorf("Text(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+/*
+sandbox описывает магические адреса песочницы: при SANDBOX_MODE перевод через
+/api/send на такой адрес детерминированно завершается заданным исходом, чтобы
+интеграторы могли проверить обработку ошибок, не подстраивая данные.
+
+Исход применяется до обращения к хранилищу: отказ возвращается той же ошибкой
+хранилища, что и настоящий (и отдаётся клиенту тем же кодом), но перевод не
+выполняется, не записывается в историю и не порождает уведомлений. Адрес slow
+только задерживает ответ, после чего перевод выполняется как обычно. Переводы на
+остальные адреса песочница не затрагивает.
+
+Магические адреса - корректные адреса схемы hex64, поэтому проходят проверку
+адресов при любой ADDRESS_SCHEME. Список с исходами отдаёт GET /api/meta/sandbox.
+Режим включается только для всего экземпляра: в рабочем развёртывании
+SANDBOX_MODE не задаётся.
+*/
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-payments/internal/money"
+	"go-payments/internal/storage"
+)
+
+// SlowDelay - задержка ответа для адреса с исходом slow.
+const SlowDelay = 3 * time.Second
+
+// ErrSandbox - исходная ошибка отказов, созданных песочницей.
+var ErrSandbox = errors.New("отказ магического адреса песочницы")
+
+// Address - магический адрес и исход перевода на него.
+type Address struct {
+	Address     string `json:"address"`
+	Outcome     string `json:"outcome"`
+	Description string `json:"description"`
+
+	delay time.Duration
+	// fail возвращает ошибку перевода суммы amount; nil - перевод выполняется.
+	fail func(amount float64) error
+}
+
+// Err возвращает ошибку, которой завершается перевод суммы amount на адрес,
+// или nil, если после задержки перевод выполняется как обычно.
+func (a Address) Err(amount float64) error {
+	if a.fail == nil {
+		return nil
+	}
+	return a.fail(amount)
+}
+
+// Addresses - магические адреса в порядке их кодов.
+var Addresses = []Address{
+	{
+		Address:     "0000000000000000000000000000000000000000000000000000000000000402",
+		Outcome:     "insufficient_funds",
+		Description: "Недостаточно средств: баланс отправителя 0, недостаёт всей суммы перевода",
+		fail: func(amount float64) error {
+			return &storage.TransactionError{
+				Code:        storage.CodeInsufficientFunds,
+				OriginalErr: ErrSandbox,
+				Shortfall:   money.Amount(amount),
+			}
+		},
+	},
+	{
+		Address:     "0000000000000000000000000000000000000000000000000000000000000403",
+		Outcome:     "recipient_closed",
+		Description: "Получатель не принимает переводы (системный кошелёк с non_receivable)",
+		fail:        func(float64) error { return storage.ErrSystemWallet },
+	},
+	{
+		Address:     "0000000000000000000000000000000000000000000000000000000000000404",
+		Outcome:     "recipient_not_found",
+		Description: "Кошелёк получателя не найден, даже если он создан",
+		fail: func(float64) error {
+			return &storage.TransactionError{Code: storage.CodeRecipientNotFound, OriginalErr: ErrSandbox}
+		},
+	},
+	{
+		Address:     "0000000000000000000000000000000000000000000000000000000000000422",
+		Outcome:     "recipient_limit_exceeded",
+		Description: "Перевод превысил бы предельный баланс получателя: баланс 0, предел 0",
+		fail: func(float64) error {
+			return &storage.TransactionError{Code: storage.CodeRecipientLimitExceeded, OriginalErr: ErrSandbox}
+		},
+	},
+	{
+		Address:     "0000000000000000000000000000000000000000000000000000000000000500",
+		Outcome:     "internal_error",
+		Description: "Внутренняя ошибка сервера",
+		fail: func(float64) error {
+			return &storage.TransactionError{Code: storage.CodeInternalError, OriginalErr: ErrSandbox}
+		},
+	},
+	{
+		Address:     "0000000000000000000000000000000000000000000000000000000000000503",
+		Outcome:     "retries_exhausted",
+		Description: "Повторы после конфликтов с параллельными переводами исчерпаны (3 попытки)",
+		fail: func(float64) error {
+			return &storage.TransactionError{Code: storage.CodeRetriesExhausted, OriginalErr: ErrSandbox, Attempts: 3}
+		},
+	},
+	{
+		Address:     "0000000000000000000000000000000000000000000000000000000000003000",
+		Outcome:     "slow",
+		Description: "Ответ задерживается на 3 секунды, затем перевод выполняется как обычно (кошелёк получателя должен существовать)",
+		delay:       SlowDelay,
+	},
+}
+
+// Lookup возвращает магический адрес address или false для обычного адреса.
+func Lookup(address string) (Address, bool) {
+	for _, a := range Addresses {
+		if a.Address == address {
+			return a, true
+		}
+	}
+	return Address{}, false
+}
+
+// Apply применяет исход перевода суммы amount на адрес to: ждёт задержку адреса
+// (прерываясь при отмене ctx) и возвращает ошибку исхода. Для обычного адреса
+// сразу возвращает nil.
+func Apply(ctx context.Context, to string, amount float64) error {
+	a, ok := Lookup(to)
+	if !ok {
+		return nil
+	}
+	if a.delay > 0 {
+		timer := time.NewTimer(a.delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return a.Err(amount)
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go-payments/internal/models"
+	"go-payments/internal/redact"
+
+	"github.com/lib/pq"
+)
+
+// AcknowledgeTransaction отмечает успешный перевод id обработанным получателем
+// recipient и возвращает его. Повторное подтверждение не меняет время. Если
+// транзакции нет, возвращает ErrTxNotFound, если она в закрытом периоде,
+// ErrPeriodClosed, если её получатель - другой кошелёк или перевод не выполнен
+// (средства не поступили), ErrNotRecipient.
+func (s *Storage) AcknowledgeTransaction(ctx context.Context, id int, recipient string) (*models.Transaction, error) {
+	row := s.db.QueryRowContext(ctx, `
+    UPDATE transactions SET acknowledged_at = COALESCE(acknowledged_at, CURRENT_TIMESTAMP)
+    WHERE id = $1 AND to_address = $2 AND status = $3 AND `+periodOpenCondition+`
+    RETURNING `+transactionColumns,
+		id, recipient, models.StatusSuccess)
+	t, err := scanTransaction(row)
+	if err == nil {
+		return t, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, internalError(fmt.Errorf("ошибка подтверждения транзакции %d: %w", id, err))
+	}
+
+	if err := s.requireOpenTransaction(ctx, id); err != nil {
+		return nil, err
+	}
+	return nil, ErrNotRecipient
+}
+
+// AcknowledgeTransactions подтверждает переводы ids получателем recipient одним
+// UPDATE и возвращает результат по каждому идентификатору: nil - перевод подтверждён
+// (сейчас или раньше), иначе ErrTxNotFound, ErrPeriodClosed или ErrNotRecipient,
+// как у AcknowledgeTransaction.
+func (s *Storage) AcknowledgeTransactions(ctx context.Context, recipient string, ids []int) (map[int]error, error) {
+	arg := make([]int64, len(ids))
+	for i, id := range ids {
+		arg[i] = int64(id)
+	}
+
+	results := make(map[int]error, len(ids))
+	rows, err := s.db.QueryContext(ctx, `
+    UPDATE transactions SET acknowledged_at = COALESCE(acknowledged_at, CURRENT_TIMESTAMP)
+    WHERE id = ANY($1) AND to_address = $2 AND status = $3 AND `+periodOpenCondition+`
+    RETURNING id`,
+		pq.Array(arg), recipient, models.StatusSuccess)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("ошибка подтверждения переводов кошелька %s: %w", redact.Address(recipient), err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, internalError(fmt.Errorf("ошибка подтверждения переводов кошелька %s: %w", redact.Address(recipient), err))
+		}
+		results[id] = nil
+	}
+	if err := rows.Err(); err != nil {
+		return nil, internalError(fmt.Errorf("ошибка подтверждения переводов кошелька %s: %w", redact.Address(recipient), err))
+	}
+	if len(results) == len(ids) {
+		return results, nil
+	}
+
+	// Причины отказа для остальных - по тем же правилам, что и у AcknowledgeTransaction.
+	check, err := s.db.QueryContext(ctx,
+		"SELECT id, "+periodOpenCondition+" FROM transactions WHERE id = ANY($1)", pq.Array(arg))
+	if err != nil {
+		return nil, internalError(fmt.Errorf("ошибка проверки переводов кошелька %s: %w", redact.Address(recipient), err))
+	}
+	defer check.Close()
+	for check.Next() {
+		var id int
+		var open bool
+		if err := check.Scan(&id, &open); err != nil {
+			return nil, internalError(fmt.Errorf("ошибка проверки переводов кошелька %s: %w", redact.Address(recipient), err))
+		}
+		if _, ok := results[id]; ok {
+			continue
+		}
+		if open {
+			results[id] = ErrNotRecipient
+		} else {
+			results[id] = ErrPeriodClosed
+		}
+	}
+	if err := check.Err(); err != nil {
+		return nil, internalError(fmt.Errorf("ошибка проверки переводов кошелька %s: %w", redact.Address(recipient), err))
+	}
+	for _, id := range ids {
+		if _, ok := results[id]; !ok {
+			results[id] = ErrTxNotFound
+		}
+	}
+	return results, nil
+}
+
+// AcknowledgeTransactionsThrough подтверждает все ещё не подтверждённые успешные
+// переводы получателю recipient с id не больше throughID и возвращает их количество.
+// Переводы закрытого учётного периода пропускаются.
+func (s *Storage) AcknowledgeTransactionsThrough(ctx context.Context, recipient string, throughID int) (int, error) {
+	result, err := s.db.ExecContext(ctx, `
+    UPDATE transactions SET acknowledged_at = CURRENT_TIMESTAMP
+    WHERE to_address = $1 AND id <= $2 AND status = $3 AND acknowledged_at IS NULL AND `+periodOpenCondition,
+		recipient, throughID, models.StatusSuccess)
+	if err != nil {
+		return 0, internalError(fmt.Errorf("ошибка подтверждения переводов кошелька %s: %w", redact.Address(recipient), err))
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, internalError(fmt.Errorf("ошибка подтверждения переводов кошелька %s: %w", redact.Address(recipient), err))
+	}
+	return int(n), nil
+}
+
+// GetTransaction возвращает транзакцию id или ErrTxNotFound.
+func (s *Storage) GetTransaction(ctx context.Context, id int) (*models.Transaction, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+transactionColumns+" FROM transactions WHERE id = $1", id)
+	t, err := scanTransaction(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTxNotFound
+		}
+		return nil, internalError(fmt.Errorf("ошибка получения транзакции %d: %w", id, err))
+	}
+	return t, nil
+}
+
+// TransactionIDByPublicID возвращает внутренний идентификатор транзакции с
+// публичным идентификатором publicID или ErrTxNotFound.
+func (s *Storage) TransactionIDByPublicID(ctx context.Context, publicID string) (int, error) {
+	var id int
+	err := s.db.QueryRowContext(ctx, "SELECT id FROM transactions WHERE public_id = $1", publicID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrTxNotFound
+		}
+		return 0, internalError(fmt.Errorf("ошибка поиска транзакции %s: %w", publicID, err))
+	}
+	return id, nil
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-payments/internal/models"
+)
+
+func TestAcknowledgeTransaction(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	sender, recipient := testAddress(1), testAddress(2)
+	createTestWallet(t, s, sender, 10)
+	createTestWallet(t, s, recipient, 0)
+
+	paid, err := s.Execute(ctx, models.Transfer{From: sender, To: recipient, Amount: 10})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	// Неуспешный перевод записывается, но средства получателю не поступают.
+	if _, err := s.Execute(ctx, models.Transfer{From: sender, To: recipient, Amount: 10}); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("перевод без средств: %v, want ErrInsufficientFunds", err)
+	}
+	var failedID int
+	if err := s.db.QueryRow("SELECT id FROM transactions WHERE status <> $1", models.StatusSuccess).Scan(&failedID); err != nil {
+		t.Fatalf("поиск неуспешного перевода: %v", err)
+	}
+
+	acked, err := s.AcknowledgeTransaction(ctx, paid.ID, recipient)
+	if err != nil {
+		t.Fatalf("AcknowledgeTransaction: %v", err)
+	}
+	if acked.AcknowledgedAt == nil {
+		t.Fatal("подтверждённый перевод без acknowledged_at")
+	}
+	again, err := s.AcknowledgeTransaction(ctx, paid.ID, recipient)
+	if err != nil || !again.AcknowledgedAt.Equal(*acked.AcknowledgedAt) {
+		t.Errorf("повторное подтверждение: %v, %v, want прежнее время %v", again, err, acked.AcknowledgedAt)
+	}
+
+	tests := []struct {
+		name      string
+		id        int
+		recipient string
+		want      error
+	}{
+		{"отправитель", paid.ID, sender, ErrNotRecipient},
+		{"посторонний кошелёк", paid.ID, testAddress(3), ErrNotRecipient},
+		{"неуспешный перевод", failedID, recipient, ErrNotRecipient},
+		{"неизвестный перевод", 1 << 30, recipient, ErrTxNotFound},
+	}
+	for _, tt := range tests {
+		if _, err := s.AcknowledgeTransaction(ctx, tt.id, tt.recipient); !errors.Is(err, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	results, err := s.AcknowledgeTransactions(ctx, recipient, []int{paid.ID, failedID})
+	if err != nil {
+		t.Fatalf("AcknowledgeTransactions: %v", err)
+	}
+	if results[paid.ID] != nil || !errors.Is(results[failedID], ErrNotRecipient) {
+		t.Errorf("пакетное подтверждение: %v, want успех и ErrNotRecipient для неуспешного", results)
+	}
+	failed, err := s.GetTransaction(ctx, failedID)
+	if err != nil {
+		t.Fatalf("GetTransaction: %v", err)
+	}
+	if failed.AcknowledgedAt != nil {
+		t.Errorf("неуспешный перевод подтверждён в %v", failed.AcknowledgedAt)
+	}
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go-payments/internal/models"
+	"go-payments/internal/money"
+	"go-payments/internal/redact"
+)
+
+// GetWalletActivity раскладывает успешные переводы кошелька address за период
+// [since, until) по дням недели и часам суток в часовом поясе timezone (имя из базы
+// часовых поясов IANA). Время транзакций хранится в UTC и переводится в timezone
+// до группировки. Одним запросом проверяется и существование кошелька: для
+// неизвестного возвращается ErrWalletNotFound. Ячейки без переводов остаются нулевыми.
+func (s *Storage) GetWalletActivity(ctx context.Context, address string, since, until time.Time, timezone string) (*models.WalletActivity, error) {
+	rows, err := s.db.QueryContext(ctx, `
+    SELECT EXTRACT(dow FROM t.local)::int, EXTRACT(hour FROM t.local)::int, COUNT(t.local), COALESCE(SUM(t.amount), 0)
+    FROM wallets w
+    LEFT JOIN LATERAL (
+        SELECT (timestamp AT TIME ZONE 'UTC') AT TIME ZONE $4 AS local, amount
+        FROM transactions
+        WHERE status = $5 AND (from_address = w.address OR to_address = w.address)
+          AND timestamp >= $2 AND timestamp < $3
+    ) t ON TRUE
+    WHERE w.address = $1
+    GROUP BY 1, 2`,
+		address, since.UTC(), until.UTC(), timezone, models.StatusSuccess)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("ошибка получения активности кошелька %s: %w", redact.Address(address), err))
+	}
+	defer rows.Close()
+
+	activity := models.WalletActivity{Address: address, Since: since.UTC(), Until: until.UTC(), Timezone: timezone}
+	found := false
+	for rows.Next() {
+		found = true
+		var (
+			dow, hour sql.NullInt64
+			count     int
+			total     money.Amount
+		)
+		if err := rows.Scan(&dow, &hour, &count, &total); err != nil {
+			return nil, internalError(fmt.Errorf("ошибка сканирования строки активности: %w", err))
+		}
+		// Кошелёк без переводов за период даёт одну строку без дня и часа.
+		if !dow.Valid || !hour.Valid {
+			continue
+		}
+		activity.Counts[dow.Int64][hour.Int64] = count
+		activity.TotalAmounts[dow.Int64][hour.Int64] = total
+	}
+	if err := rows.Err(); err != nil {
+		return nil, internalError(fmt.Errorf("ошибка при итерации по активности кошелька: %w", err))
+	}
+	if !found {
+		return nil, ErrWalletNotFound
+	}
+	return &activity, nil
+}
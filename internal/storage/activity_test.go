@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-payments/internal/models"
+	"go-payments/internal/money"
+)
+
+// transferAt выполняет перевод и переносит его на время at (UTC).
+func transferAt(t *testing.T, s *Storage, from, to string, amount float64, at time.Time) {
+	t.Helper()
+	tx, err := s.Execute(context.Background(), models.Transfer{From: from, To: to, Amount: amount})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if _, err := s.db.Exec("UPDATE transactions SET timestamp = $1::timestamp WHERE id = $2",
+		at.UTC().Format("2006-01-02 15:04:05.999999"), tx.ID); err != nil {
+		t.Fatalf("перенос транзакции %d на %s: %v", tx.ID, at, err)
+	}
+}
+
+func TestWalletActivityBuckets(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	wallet, peer, other := testAddress(1), testAddress(2), testAddress(3)
+	createTestWallet(t, s, wallet, 100)
+	createTestWallet(t, s, peer, 100)
+	createTestWallet(t, s, other, 100)
+	createTestWallet(t, s, testAddress(4), 0)
+
+	since := time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC) // воскресенье
+	until := time.Date(2026, 1, 11, 0, 0, 0, 0, time.UTC)
+
+	// Воскресенье 23:30 UTC - исходящий перевод.
+	transferAt(t, s, wallet, peer, 10, time.Date(2026, 1, 4, 23, 30, 0, 0, time.UTC))
+	// Понедельник 09:15 и 09:45 UTC - входящие переводы в одной ячейке.
+	transferAt(t, s, peer, wallet, 5, time.Date(2026, 1, 5, 9, 15, 0, 0, time.UTC))
+	transferAt(t, s, peer, wallet, 2.5, time.Date(2026, 1, 5, 9, 45, 0, 0, time.UTC))
+	// Граница until не входит в период, since - входит.
+	transferAt(t, s, peer, wallet, 1, until)
+	transferAt(t, s, peer, wallet, 1, since)
+	// Переводы других кошельков не учитываются.
+	transferAt(t, s, peer, other, 7, time.Date(2026, 1, 6, 12, 0, 0, 0, time.UTC))
+	// Неуспешный перевод внутри периода не учитывается.
+	if _, err := s.Execute(ctx, models.Transfer{From: testAddress(4), To: wallet, Amount: 1}); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("перевод без средств: %v", err)
+	}
+	if _, err := s.db.Exec("UPDATE transactions SET timestamp = '2026-01-07 15:00:00'::timestamp WHERE status <> $1",
+		models.StatusSuccess); err != nil {
+		t.Fatalf("перенос неуспешного перевода: %v", err)
+	}
+
+	type cell struct {
+		dow, hour, count int
+		total            money.Amount
+	}
+	tests := []struct {
+		timezone string
+		cells    []cell
+	}{
+		{"UTC", []cell{{0, 0, 1, 1}, {0, 23, 1, 10}, {1, 9, 2, 7.5}}},
+		// UTC+3: воскресенье 23:30 - уже понедельник 02:30.
+		{"Europe/Moscow", []cell{{0, 3, 1, 1}, {1, 2, 1, 10}, {1, 12, 2, 7.5}}},
+		// UTC-5: полночь воскресенья - ещё суббота 19:00.
+		{"America/New_York", []cell{{6, 19, 1, 1}, {0, 18, 1, 10}, {1, 4, 2, 7.5}}},
+	}
+	for _, tt := range tests {
+		activity, err := s.GetWalletActivity(ctx, wallet, since, until, tt.timezone)
+		if err != nil {
+			t.Fatalf("%s: GetWalletActivity: %v", tt.timezone, err)
+		}
+		var counts [7][24]int
+		var totals [7][24]money.Amount
+		for _, c := range tt.cells {
+			counts[c.dow][c.hour], totals[c.dow][c.hour] = c.count, c.total
+		}
+		for dow := range 7 {
+			for hour := range 24 {
+				if activity.Counts[dow][hour] != counts[dow][hour] || activity.TotalAmounts[dow][hour] != totals[dow][hour] {
+					t.Errorf("%s: день %d, час %d: %d переводов на %v, want %d на %v", tt.timezone, dow, hour,
+						activity.Counts[dow][hour], activity.TotalAmounts[dow][hour], counts[dow][hour], totals[dow][hour])
+				}
+			}
+		}
+		if activity.Timezone != tt.timezone || !activity.Since.Equal(since) || !activity.Until.Equal(until) {
+			t.Errorf("%s: параметры ответа %s %s %s", tt.timezone, activity.Timezone, activity.Since, activity.Until)
+		}
+	}
+
+	// Кошелёк без переводов за период - нулевая матрица, неизвестный - ErrWalletNotFound.
+	empty, err := s.GetWalletActivity(ctx, other, since, since.Add(time.Hour), "UTC")
+	if err != nil || empty.Counts != ([7][24]int{}) {
+		t.Errorf("кошелёк без переводов: %v, %v, want нулевую матрицу", empty, err)
+	}
+	if _, err := s.GetWalletActivity(ctx, testAddress(5), since, until, "UTC"); !errors.Is(err, ErrWalletNotFound) {
+		t.Errorf("неизвестный кошелёк: %v, want ErrWalletNotFound", err)
+	}
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"go-payments/internal/models"
+)
+
+func TestExecuteAutoCreateConcurrentRecipient(t *testing.T) {
+	s := newTestStorage(t)
+	s.SetAutoCreateRecipients(true)
+	ctx := context.Background()
+	const (
+		senders = 8
+		amount  = 5.0
+	)
+	addresses := make([]string, senders)
+	for i := range addresses {
+		addresses[i] = testAddress(i + 1)
+		createTestWallet(t, s, addresses[i], 100)
+	}
+	to := testAddress(100)
+
+	// Все переводы на ещё не существующий адрес приходят одновременно: кошелёк
+	// создаёт ровно один из них, и зачисления всех переводов применяются.
+	start := make(chan struct{})
+	errs := make([]error, senders)
+	var wg sync.WaitGroup
+	for i, from := range addresses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, errs[i] = s.Execute(ctx, models.Transfer{From: from, To: to, Amount: amount})
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("перевод %d: %v", i, err)
+		}
+	}
+	if n := countRows(t, s, "SELECT COUNT(*) FROM wallets WHERE address = $1 AND auto_created", to); n != 1 {
+		t.Fatalf("созданных кошельков получателя %d, want 1", n)
+	}
+	if got := walletBalance(t, s, to); got != senders*amount {
+		t.Errorf("баланс получателя %v, want %v", got, senders*amount)
+	}
+	for _, from := range addresses {
+		if got := walletBalance(t, s, from); got != 100-amount {
+			t.Errorf("баланс отправителя %s = %v, want %v", from, got, 100-amount)
+		}
+	}
+	if n := countRows(t, s, "SELECT COUNT(*) FROM transactions WHERE to_address = $1 AND status = $2", to, models.StatusSuccess); n != senders {
+		t.Errorf("успешных переводов получателю %d, want %d", n, senders)
+	}
+	// Кошелёк появляется в момент перевода, который его создал.
+	if n := countRows(t, s, `
+    SELECT COUNT(*) FROM wallets w JOIN transactions t ON t.to_address = w.address AND t.timestamp = w.created_at
+    WHERE w.address = $1`, to); n == 0 {
+		t.Error("created_at кошелька не совпадает со временем ни одного перевода")
+	}
+}
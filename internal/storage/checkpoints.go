@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-payments/internal/checkpoint"
+	"go-payments/internal/redact"
+)
+
+const checkpointColumns = "date, as_of, wallet_count, root, signature, public_key"
+
+func scanCheckpoint(row interface{ Scan(...any) error }) (*checkpoint.Checkpoint, error) {
+	var c checkpoint.Checkpoint
+	var date time.Time
+	if err := row.Scan(&date, &c.AsOf, &c.WalletCount, &c.Root, &c.Signature, &c.PublicKey); err != nil {
+		return nil, err
+	}
+	c.Date = date.Format(checkpoint.DateLayout)
+	c.AsOf = c.AsOf.UTC()
+	return &c, nil
+}
+
+// CreateCheckpoint снимает балансы всех кошельков и сохраняет контрольную точку за
+// дату date, подписанную ключом key. Если контрольная точка за эту дату уже есть,
+// возвращает nil и false. Снимок и момент as_of берутся в одной транзакции
+// REPEATABLE READ, поэтому балансы согласованы между собой.
+func (s *Storage) CreateCheckpoint(ctx context.Context, date time.Time, key ed25519.PrivateKey) (*checkpoint.Checkpoint, bool, error) {
+	day := date.UTC().Format(checkpoint.DateLayout)
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return nil, false, internalError(fmt.Errorf("не удалось начать транзакцию: %w", err))
+	}
+	defer tx.Rollback()
+
+	// Строка вставляется первой, чтобы на неё могли ссылаться балансы; корень и
+	// подпись дописываются в той же транзакции, так что пустыми их никто не видит.
+	var asOf time.Time
+	err = tx.QueryRowContext(ctx, `
+    INSERT INTO checkpoints (date, as_of, wallet_count, root, signature, public_key)
+    VALUES ($1, clock_timestamp() AT TIME ZONE 'UTC', 0, '', '', '')
+    ON CONFLICT (date) DO NOTHING
+    RETURNING as_of`, day).Scan(&asOf)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, internalError(fmt.Errorf("ошибка создания контрольной точки за %s: %w", day, err))
+	}
+
+	// COLLATE "C" упорядочивает адреса по байтам, как и сторонняя проверка.
+	rows, err := tx.QueryContext(ctx, `
+    INSERT INTO checkpoint_balances (date, position, address, balance)
+    SELECT $1, row_number() OVER (ORDER BY address COLLATE "C") - 1, address, balance FROM wallets
+    RETURNING position, address, balance::text`, day)
+	if err != nil {
+		return nil, false, internalError(fmt.Errorf("ошибка снимка балансов контрольной точки: %w", err))
+	}
+	var balances []checkpoint.Leaf
+	for rows.Next() {
+		var position int
+		var leaf checkpoint.Leaf
+		if err := rows.Scan(&position, &leaf.Address, &leaf.Balance); err != nil {
+			rows.Close()
+			return nil, false, internalError(fmt.Errorf("ошибка сканирования строки checkpoint_balances: %w", err))
+		}
+		for len(balances) <= position {
+			balances = append(balances, checkpoint.Leaf{})
+		}
+		balances[position] = leaf
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, false, internalError(fmt.Errorf("ошибка при итерации по checkpoint_balances: %w", err))
+	}
+
+	c := checkpoint.Sign(key, day, asOf, balances)
+	_, err = tx.ExecContext(ctx, `
+    UPDATE checkpoints SET wallet_count = $2, root = $3, signature = $4, public_key = $5 WHERE date = $1`,
+		day, c.WalletCount, c.Root, c.Signature, c.PublicKey)
+	if err != nil {
+		return nil, false, internalError(fmt.Errorf("ошибка записи подписи контрольной точки: %w", err))
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, false, internalError(fmt.Errorf("не удалось зафиксировать контрольную точку: %w", err))
+	}
+	return &c, true, nil
+}
+
+// getCheckpoint возвращает контрольную точку за date или, если date нулевая, последнюю.
+func (s *Storage) getCheckpoint(ctx context.Context, date time.Time) (*checkpoint.Checkpoint, error) {
+	var row *sql.Row
+	if date.IsZero() {
+		row = s.db.QueryRowContext(ctx, "SELECT "+checkpointColumns+" FROM checkpoints ORDER BY date DESC LIMIT 1")
+	} else {
+		row = s.db.QueryRowContext(ctx, "SELECT "+checkpointColumns+" FROM checkpoints WHERE date = $1",
+			date.UTC().Format(checkpoint.DateLayout))
+	}
+	c, err := scanCheckpoint(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCheckpointNotFound
+		}
+		return nil, internalError(fmt.Errorf("ошибка получения контрольной точки: %w", err))
+	}
+	return c, nil
+}
+
+// checkpointBalances возвращает балансы контрольной точки за день day в порядке листьев.
+func (s *Storage) checkpointBalances(ctx context.Context, day string) ([]checkpoint.Leaf, error) {
+	rows, err := s.db.QueryContext(ctx, `
+    SELECT address, balance::text FROM checkpoint_balances WHERE date = $1 ORDER BY position`, day)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("ошибка получения балансов контрольной точки за %s: %w", day, err))
+	}
+	defer rows.Close()
+
+	var balances []checkpoint.Leaf
+	for rows.Next() {
+		var leaf checkpoint.Leaf
+		if err := rows.Scan(&leaf.Address, &leaf.Balance); err != nil {
+			return nil, internalError(fmt.Errorf("ошибка сканирования строки checkpoint_balances: %w", err))
+		}
+		balances = append(balances, leaf)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, internalError(fmt.Errorf("ошибка при итерации по checkpoint_balances: %w", err))
+	}
+	return balances, nil
+}
+
+// GetCheckpoint возвращает контрольную точку за date, а с withBalances - и балансы
+// всех кошельков в порядке листьев, по которым аудитор может пересчитать корень.
+// Отсутствующая контрольная точка - ErrCheckpointNotFound.
+func (s *Storage) GetCheckpoint(ctx context.Context, date time.Time, withBalances bool) (*checkpoint.Bundle, error) {
+	c, err := s.getCheckpoint(ctx, date)
+	if err != nil {
+		return nil, err
+	}
+	bundle := checkpoint.Bundle{Checkpoint: *c}
+	if withBalances {
+		if bundle.Balances, err = s.checkpointBalances(ctx, c.Date); err != nil {
+			return nil, err
+		}
+	}
+	return &bundle, nil
+}
+
+// GetCheckpointProof возвращает доказательство включения баланса кошелька address в
+// контрольную точку за date (нулевая date - последняя контрольная точка). Если
+// кошелька не было на момент снимка, возвращает ErrWalletNotFound. Путь
+// пересчитывается по сохранённым балансам при каждом запросе.
+func (s *Storage) GetCheckpointProof(ctx context.Context, address string, date time.Time) (*checkpoint.Proof, error) {
+	c, err := s.getCheckpoint(ctx, date)
+	if err != nil {
+		return nil, err
+	}
+	balances, err := s.checkpointBalances(ctx, c.Date)
+	if err != nil {
+		return nil, err
+	}
+
+	index := -1
+	leaves := make([][]byte, len(balances))
+	for i, l := range balances {
+		leaves[i] = checkpoint.LeafHash(l)
+		if l.Address == address {
+			index = i
+		}
+	}
+	if index < 0 {
+		return nil, ErrWalletNotFound
+	}
+	if len(balances) != c.WalletCount {
+		return nil, internalError(fmt.Errorf("в контрольной точке за %s %d балансов вместо %d (кошелёк %s)",
+			c.Date, len(balances), c.WalletCount, redact.Address(address)))
+	}
+
+	proof := checkpoint.Proof{Checkpoint: *c, Leaf: balances[index], LeafIndex: index, Path: []string{}}
+	for _, h := range checkpoint.InclusionProof(leaves, index) {
+		proof.Path = append(proof.Path, hex.EncodeToString(h))
+	}
+	return &proof, nil
+}
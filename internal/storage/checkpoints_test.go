@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"go-payments/pkg/client"
+)
+
+func TestCheckpointProofs(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	publicKey := hex.EncodeToString(key.Public().(ed25519.PublicKey))
+	const wallets = 11
+	for i := range wallets {
+		createTestWallet(t, s, testAddress(i+1), float64(i)*1.5)
+	}
+
+	date := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	if _, created, err := s.CreateCheckpoint(ctx, date, key); err != nil || !created {
+		t.Fatalf("CreateCheckpoint: %v, создана %v", err, created)
+	}
+	bundle, err := s.GetCheckpoint(ctx, date, true)
+	if err != nil {
+		t.Fatalf("GetCheckpoint: %v", err)
+	}
+	if err := bundle.Verify(publicKey); err != nil {
+		t.Errorf("проверка контрольной точки с балансами: %v", err)
+	}
+	for i := range wallets {
+		proof, err := s.GetCheckpointProof(ctx, testAddress(i+1), date)
+		if err != nil {
+			t.Fatalf("GetCheckpointProof: %v", err)
+		}
+		if err := proof.Verify(publicKey); err != nil {
+			t.Errorf("доказательство кошелька %d: %v", i+1, err)
+		}
+	}
+
+	// Баланс, изменённый в базе после подписи, не проходит проверку.
+	if _, err := s.db.Exec("UPDATE checkpoint_balances SET balance = balance + 1 WHERE address = $1", testAddress(3)); err != nil {
+		t.Fatalf("изменение баланса контрольной точки: %v", err)
+	}
+	proof, err := s.GetCheckpointProof(ctx, testAddress(3), date)
+	if err != nil {
+		t.Fatalf("GetCheckpointProof: %v", err)
+	}
+	if err := proof.Verify(publicKey); !errors.Is(err, client.ErrRootMismatch) {
+		t.Errorf("доказательство изменённого баланса: %v, want %v", err, client.ErrRootMismatch)
+	}
+	if bundle, err = s.GetCheckpoint(ctx, date, true); err != nil {
+		t.Fatalf("GetCheckpoint: %v", err)
+	}
+	if err := bundle.Verify(publicKey); !errors.Is(err, client.ErrRootMismatch) {
+		t.Errorf("контрольная точка с изменённым балансом: %v, want %v", err, client.ErrRootMismatch)
+	}
+}
@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/lib/pq"
+
+	"go-payments/internal/models"
+)
+
+// errCrash - ошибка, которой crashConn обрывает транзакцию.
+var errCrash = errors.New("транзакция оборвана тестом")
+
+// crashPoint считает запросы внутри транзакций и обрывает транзакцию на запросе
+// с номером at, как если бы процесс упал после предыдущего запроса. Фиксация
+// считается последним запросом транзакции.
+type crashPoint struct {
+	mu    sync.Mutex
+	at    int // 0 - не обрывать
+	n     int
+	fired bool
+}
+
+// arm обрывает следующую транзакцию на запросе с номером at.
+func (c *crashPoint) arm(at int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.at, c.n, c.fired = at, 0, false
+}
+
+// disarm отключает обрыв и сообщает, сработал ли он.
+func (c *crashPoint) disarm() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.at = 0
+	return c.fired
+}
+
+// step учитывает очередной запрос транзакции и возвращает errCrash, если на нём
+// транзакция обрывается. После обрыва обрываются и все следующие запросы.
+func (c *crashPoint) step() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.at == 0 {
+		return nil
+	}
+	c.n++
+	if c.fired || c.n >= c.at {
+		c.fired = true
+		return errCrash
+	}
+	return nil
+}
+
+// crashConnector открывает соединения PostgreSQL, транзакции которых обрывает crash.
+type crashConnector struct {
+	driver.Connector
+	crash *crashPoint
+}
+
+func (c crashConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &crashConn{Conn: conn, crash: c.crash}, nil
+}
+
+// crashConn пропускает запросы вне транзакции без изменений, а запросы внутри неё
+// и её фиксацию - через crash.
+type crashConn struct {
+	driver.Conn
+	crash *crashPoint
+	inTx  bool
+}
+
+func (c *crashConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	tx, err := c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	c.inTx = true
+	return &crashTx{Tx: tx, conn: c}, nil
+}
+
+func (c *crashConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if c.inTx {
+		if err := c.crash.step(); err != nil {
+			return nil, err
+		}
+	}
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c *crashConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if c.inTx {
+		if err := c.crash.step(); err != nil {
+			return nil, err
+		}
+	}
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+// crashTx при обрыве на фиксации откатывает транзакцию вместо неё.
+type crashTx struct {
+	driver.Tx
+	conn *crashConn
+}
+
+func (tx *crashTx) Commit() error {
+	tx.conn.inTx = false
+	if err := tx.conn.crash.step(); err != nil {
+		tx.Tx.Rollback()
+		return err
+	}
+	return tx.Tx.Commit()
+}
+
+func (tx *crashTx) Rollback() error {
+	tx.conn.inTx = false
+	return tx.Tx.Rollback()
+}
+
+// newCrashStorage создаёт тестовое хранилище, транзакции которого обрывает
+// возвращаемый crashPoint.
+func newCrashStorage(t *testing.T) (*Storage, *crashPoint) {
+	t.Helper()
+	crash := &crashPoint{}
+	s := newTestStorageWith(t, func(dsn string) (*sql.DB, error) {
+		connector, err := pq.NewConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return sql.OpenDB(crashConnector{Connector: connector, crash: crash}), nil
+	})
+	return s, crash
+}
+
+// checkNoTransfer проверяет, что перевод по ключу key не оставил следов: балансы
+// from и to прежние, успешной транзакции и ключа идемпотентности нет.
+func checkNoTransfer(t *testing.T, s *Storage, point int, from, to, key string, fromBalance, toBalance float64) {
+	t.Helper()
+	if got := walletBalance(t, s, from); got != fromBalance {
+		t.Errorf("обрыв на запросе %d: баланс отправителя %v, want %v", point, got, fromBalance)
+	}
+	if got := walletBalance(t, s, to); got != toBalance {
+		t.Errorf("обрыв на запросе %d: баланс получателя %v, want %v", point, got, toBalance)
+	}
+	if n := countRows(t, s, "SELECT COUNT(*) FROM transactions WHERE status = $1", models.StatusSuccess); n != 0 {
+		t.Errorf("обрыв на запросе %d: успешных транзакций %d, want 0", point, n)
+	}
+	if n := countRows(t, s, "SELECT COUNT(*) FROM idempotency_keys WHERE key = $1", key); n != 0 {
+		t.Errorf("обрыв на запросе %d: ключ идемпотентности записан", point)
+	}
+}
+
+func TestExecuteCrashPoints(t *testing.T) {
+	s, crash := newCrashStorage(t)
+	ctx := context.Background()
+	from, to := testAddress(1), testAddress(2)
+	createTestWallet(t, s, from, 100)
+	createTestWallet(t, s, to, 0)
+	transfer := models.Transfer{From: from, To: to, Amount: 10, IdempotencyKey: "crash"}
+
+	// Каждый повтор с тем же ключом обрывается на следующем запросе, пока перевод
+	// не пройдёт целиком: обрыв в любом месте не оставляет частичного состояния и не
+	// занимает ключ, и перевод выполняется ровно один раз.
+	var transaction *models.Transaction
+	for point := 1; ; point++ {
+		crash.arm(point)
+		got, err := s.Execute(ctx, transfer)
+		if !crash.disarm() {
+			if err != nil {
+				t.Fatalf("перевод без обрыва: %v", err)
+			}
+			transaction = got
+			break
+		}
+		if err == nil {
+			t.Fatalf("обрыв на запросе %d: перевод выполнен", point)
+		}
+		checkNoTransfer(t, s, point, from, to, transfer.IdempotencyKey, 100, 0)
+	}
+
+	replay, err := s.Execute(ctx, transfer)
+	if err != nil {
+		t.Fatalf("повтор по ключу: %v", err)
+	}
+	if replay.ID != transaction.ID {
+		t.Errorf("повтор по ключу вернул транзакцию %d, want %d", replay.ID, transaction.ID)
+	}
+	if got := walletBalance(t, s, from); got != 90 {
+		t.Errorf("баланс отправителя %v, want 90", got)
+	}
+	if got := walletBalance(t, s, to); got != 10 {
+		t.Errorf("баланс получателя %v, want 10", got)
+	}
+	if n := countRows(t, s, "SELECT COUNT(*) FROM transactions WHERE status = $1", models.StatusSuccess); n != 1 {
+		t.Errorf("успешных транзакций %d, want 1", n)
+	}
+}
+
+func TestExecuteInTxStepAbort(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	from, to := testAddress(1), testAddress(2)
+	createTestWallet(t, s, from, 100)
+	createTestWallet(t, s, to, 0)
+	transfer := models.Transfer{From: from, To: to, Amount: 10, IdempotencyKey: "abort"}
+
+	_, err := s.execute(ctx, transfer, func(context.Context, *sql.Tx, *models.Transaction) error { return errCrash })
+	if !errors.Is(err, errCrash) {
+		t.Fatalf("execute с оборванным шагом: %v, want %v", err, errCrash)
+	}
+	checkNoTransfer(t, s, 0, from, to, transfer.IdempotencyKey, 100, 0)
+
+	// Отмена контекста после шага тоже откатывает перевод целиком.
+	cancelCtx, cancel := context.WithCancel(ctx)
+	_, err = s.execute(cancelCtx, transfer, func(context.Context, *sql.Tx, *models.Transaction) error {
+		cancel()
+		return nil
+	})
+	if err == nil {
+		t.Fatal("execute с отменённым контекстом выполнен")
+	}
+	checkNoTransfer(t, s, 0, from, to, transfer.IdempotencyKey, 100, 0)
+
+	first, err := s.Execute(ctx, transfer)
+	if err != nil {
+		t.Fatalf("перевод после обрыва: %v", err)
+	}
+	replay, err := s.Execute(ctx, transfer)
+	if err != nil {
+		t.Fatalf("повтор по ключу: %v", err)
+	}
+	if replay.ID != first.ID {
+		t.Errorf("повтор по ключу вернул транзакцию %d, want %d", replay.ID, first.ID)
+	}
+	if got := walletBalance(t, s, from); got != 90 {
+		t.Errorf("баланс отправителя %v, want 90", got)
+	}
+}
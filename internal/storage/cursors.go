@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go-payments/internal/models"
+	"go-payments/internal/redact"
+)
+
+// GetIncomingCursor возвращает курсор потребителя consumer входящих переводов
+// кошелька wallet или ErrIncomingCursorNotFound.
+func (s *Storage) GetIncomingCursor(ctx context.Context, wallet, consumer string) (*models.IncomingCursor, error) {
+	c := models.IncomingCursor{Wallet: wallet, Consumer: consumer}
+	err := s.db.QueryRowContext(ctx,
+		"SELECT last_transaction_id, updated_at FROM incoming_cursors WHERE wallet = $1 AND consumer = $2",
+		wallet, consumer).Scan(&c.LastTransactionID, &c.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrIncomingCursorNotFound
+		}
+		return nil, internalError(fmt.Errorf("ошибка получения курсора %s кошелька %s: %w", consumer, redact.Address(wallet), err))
+	}
+	c.UpdatedAt = c.UpdatedAt.UTC()
+	return &c, nil
+}
+
+// SetIncomingCursor сдвигает курсор потребителя до lastTransactionID и возвращает
+// его. Курсор только растёт: меньшее значение (повтор или запоздавший запрос)
+// оставляет его на месте, поэтому запись идемпотентна.
+func (s *Storage) SetIncomingCursor(ctx context.Context, wallet, consumer string, lastTransactionID int) (*models.IncomingCursor, error) {
+	c := models.IncomingCursor{Wallet: wallet, Consumer: consumer}
+	err := s.db.QueryRowContext(ctx, `
+    INSERT INTO incoming_cursors (wallet, consumer, last_transaction_id) VALUES ($1, $2, $3)
+    ON CONFLICT (wallet, consumer) DO UPDATE SET
+        last_transaction_id = GREATEST(incoming_cursors.last_transaction_id, EXCLUDED.last_transaction_id),
+        updated_at = CASE WHEN EXCLUDED.last_transaction_id > incoming_cursors.last_transaction_id
+            THEN EXCLUDED.updated_at ELSE incoming_cursors.updated_at END
+    RETURNING last_transaction_id, updated_at`,
+		wallet, consumer, lastTransactionID).Scan(&c.LastTransactionID, &c.UpdatedAt)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("ошибка сохранения курсора %s кошелька %s: %w", consumer, redact.Address(wallet), err))
+	}
+	c.UpdatedAt = c.UpdatedAt.UTC()
+	return &c, nil
+}
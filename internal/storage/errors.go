@@ -3,14 +3,46 @@ package storage
 import (
 	"errors"
 	"fmt"
+
+	"go-payments/internal/models"
+	"go-payments/internal/money"
 )
 
 // Используются для простых, бинарных проверок с помощью errors.Is()
 var (
-	ErrWalletNotFound    = errors.New("кошелёк не найден")
-	ErrInsufficientFunds = errors.New("недостаточно средств на балансе")
-	ErrOpenDatabase      = errors.New("не удалось открыть базу данных")
-	ErrConnectDatabase   = errors.New("не удалось подключиться к базе данных")
+	ErrWalletNotFound          = errors.New("кошелёк не найден")
+	ErrInsufficientFunds       = errors.New("недостаточно средств на балансе")
+	ErrRecipientLimitExceeded  = errors.New("перевод превысил бы предельный баланс кошелька получателя")
+	ErrSystemWallet            = errors.New("системный кошелёк недоступен для этого перевода")
+	ErrWalletNotArchived       = errors.New("кошелёк не находится в архиве")
+	ErrWalletNotEmpty          = errors.New("баланс кошелька не равен нулю")
+	ErrWalletReferenced        = errors.New("на кошелёк ссылаются незавершённые выводы средств или открытые запросы на оплату")
+	ErrNoteNotFound            = errors.New("заметка не найдена")
+	ErrLabelNotFound           = errors.New("метка кошелька не найдена")
+	ErrLabelTaken              = errors.New("метка уже назначена другому кошельку")
+	ErrTxNotFound              = errors.New("транзакция не найдена")
+	ErrNotRecipient            = errors.New("кошелёк не является получателем транзакции")
+	ErrWalletsExist            = errors.New("кошельки уже существуют, восстановление возможно только в пустую базу")
+	ErrGroupNotFound           = errors.New("группа переводов не найдена")
+	ErrIdempotencyKeyNotFound  = errors.New("ключ идемпотентности не найден")
+	ErrIdempotencyKeyReused    = errors.New("ключ идемпотентности уже использован для другого перевода")
+	ErrPeriodClosed            = errors.New("транзакция относится к закрытому учётному периоду")
+	ErrPeriodCloseBackward     = errors.New("учётный период уже закрыт по более позднюю дату")
+	ErrPaymentRequestNotFound  = errors.New("запрос на оплату не найден")
+	ErrPaymentRequestFulfilled = errors.New("запрос на оплату уже оплачен")
+	ErrPaymentRequestExpired   = errors.New("срок запроса на оплату истёк")
+	ErrIncomingCursorNotFound  = errors.New("курсор потребителя входящих переводов не найден")
+	ErrThresholdNotFound       = errors.New("правило порога баланса не найдено")
+	ErrTooManyThresholds       = errors.New("у кошелька слишком много правил порога баланса")
+	ErrWithdrawalNotFound      = errors.New("вывод средств не найден")
+	ErrWithdrawalSettled       = errors.New("вывод средств уже завершён")
+	ErrCheckpointNotFound      = errors.New("контрольная точка не найдена")
+	ErrOpenDatabase            = errors.New("не удалось открыть базу данных")
+	ErrConnectDatabase         = errors.New("не удалось подключиться к базе данных")
+
+	// Уточняют причину ErrConnectDatabase.
+	ErrDatabaseUnreachable = errors.New("хост базы данных недоступен")
+	ErrAuthFailed          = errors.New("неверные учётные данные базы данных")
 )
 
 // Используются для передачи дополнительного контекста об ошибке с помощью errors.As()
@@ -23,12 +55,30 @@ const (
 	CodeRecipientNotFound
 	CodeInsufficientFunds
 	CodeInternalError
+	CodeRetriesExhausted
+	CodeRecipientLimitExceeded
+
+	// LastTxErrCode - наибольший код; новые коды добавляются перед ним, чтобы
+	// таблицы соответствия можно было проверить перебором от CodeUnknown.
+	LastTxErrCode TxErrCode = iota - 1
 )
 
-// TransactionError инкапсулирует любую ошибку, произошедшую во время выполнения SendMoney.
+// TransactionError инкапсулирует любую ошибку, произошедшую во время выполнения перевода,
+// а также внутренние ошибки операций чтения (с кодом CodeInternalError), чтобы
+// вызывающий код видел либо сигнальную ошибку, либо TransactionError с точным кодом.
 type TransactionError struct {
 	Code        TxErrCode
 	OriginalErr error
+	// Attempts - количество сделанных попыток для CodeRetriesExhausted.
+	Attempts int
+	// Balance и Shortfall - баланс отправителя, прочитанный при переводе, и
+	// недостающая сумма для CodeInsufficientFunds.
+	Balance   money.Amount
+	Shortfall money.Amount
+	// RecipientBalance и MaxBalance - баланс получателя и его предел для
+	// CodeRecipientLimitExceeded.
+	RecipientBalance money.Amount
+	MaxBalance       money.Amount
 }
 
 // для совместимости с интерфейсом error.
@@ -42,6 +92,10 @@ func (e *TransactionError) Error() string {
 		return ErrInsufficientFunds.Error() // Используем текст из сигнальной ошибки
 	case CodeInternalError:
 		return fmt.Sprintf("внутренняя ошибка транзакции: %v", e.OriginalErr)
+	case CodeRecipientLimitExceeded:
+		return ErrRecipientLimitExceeded.Error()
+	case CodeRetriesExhausted:
+		return fmt.Sprintf("перевод не выполнен из-за конфликта с параллельными переводами после %d попыток", e.Attempts)
 	default:
 		return fmt.Sprintf("неизвестная ошибка транзакции: %v", e.OriginalErr)
 	}
@@ -51,3 +105,34 @@ func (e *TransactionError) Error() string {
 func (e *TransactionError) Unwrap() error {
 	return e.OriginalErr
 }
+
+// internalError оборачивает неожиданную ошибку базы данных в TransactionError с кодом CodeInternalError.
+func internalError(err error) error {
+	return &TransactionError{Code: CodeInternalError, OriginalErr: err}
+}
+
+// FailureStatus возвращает статус, с которым Execute записывает перевод,
+// завершившийся ошибкой err.
+func FailureStatus(err error) models.TransactionStatus {
+	var txErr *TransactionError
+	if errors.As(err, &txErr) {
+		switch txErr.Code {
+		case CodeSenderNotFound:
+			return models.StatusFailedSenderNotFound
+		case CodeRecipientNotFound:
+			return models.StatusFailedRecipientNotFound
+		case CodeInsufficientFunds:
+			return models.StatusFailedInsufficientFunds
+		case CodeRecipientLimitExceeded:
+			return models.StatusFailedRecipientLimitExceeded
+		}
+	}
+	return models.StatusUnknownError
+}
+
+// Rejected сообщает, что Execute отклонил перевод до выполнения и не записал
+// его: ключ идемпотентности занят другим переводом или перевод затрагивает
+// системный кошелёк. О таких переводах не уведомляют и их не учитывают в метриках.
+func Rejected(err error) bool {
+	return errors.Is(err, ErrIdempotencyKeyReused) || errors.Is(err, ErrSystemWallet)
+}
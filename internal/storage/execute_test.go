@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go-payments/internal/models"
+)
+
+func TestExecuteConcurrentTransfers(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	const (
+		wallets   = 10
+		transfers = 200
+		initial   = 100.0
+	)
+	addresses := make([]string, wallets)
+	for i := range addresses {
+		addresses[i] = testAddress(i + 1)
+		createTestWallet(t, s, addresses[i], initial)
+	}
+
+	type result struct {
+		transaction *models.Transaction
+		err         error
+	}
+	results := make([]result, transfers)
+	var wg sync.WaitGroup
+	for i := range transfers {
+		// Встречные переводы между одними кошельками и суммы, которых не всем хватит.
+		from, to := addresses[i%wallets], addresses[(i*7+3)%wallets]
+		if from == to {
+			to = addresses[(i+1)%wallets]
+		}
+		amount := float64(5 + i%4*10)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			transaction, err := s.Execute(ctx, models.Transfer{From: from, To: to, Amount: amount})
+			results[i] = result{transaction, err}
+		}()
+	}
+	wg.Wait()
+
+	want := make(map[string]float64, wallets)
+	for _, a := range addresses {
+		want[a] = initial
+	}
+	succeeded := 0
+	for i, r := range results {
+		if r.err != nil {
+			var txErr *TransactionError
+			if !errors.As(r.err, &txErr) || txErr.Code != CodeInsufficientFunds {
+				t.Errorf("перевод %d: %v, want успех или CodeInsufficientFunds", i, r.err)
+			}
+			continue
+		}
+		succeeded++
+		want[r.transaction.From] -= float64(r.transaction.Amount)
+		want[r.transaction.To] += float64(r.transaction.Amount)
+	}
+	if succeeded == 0 {
+		t.Fatal("ни один перевод не выполнен")
+	}
+
+	total := 0.0
+	for _, a := range addresses {
+		got := walletBalance(t, s, a)
+		total += got
+		if got != want[a] {
+			t.Errorf("баланс %s = %v, want %v по успешным переводам", a, got, want[a])
+		}
+		if got < 0 {
+			t.Errorf("баланс %s отрицательный: %v", a, got)
+		}
+	}
+	if total != wallets*initial {
+		t.Errorf("сумма балансов = %v, want %v", total, wallets*initial)
+	}
+	if n := countRows(t, s, "SELECT COUNT(*) FROM transactions WHERE status = $1", models.StatusSuccess); n != succeeded {
+		t.Errorf("успешных транзакций в базе %d, want %d", n, succeeded)
+	}
+}
+
+func TestExecuteConcurrentSameIdempotencyKey(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	from, to := testAddress(1), testAddress(2)
+	// Баланса хватает ровно на один перевод: повтор, который не нашёл ключ и
+	// проверил бы баланс заново, получил бы отказ вместо транзакции первого.
+	createTestWallet(t, s, from, 10)
+	createTestWallet(t, s, to, 0)
+	transfer := models.Transfer{From: from, To: to, Amount: 10, IdempotencyKey: "same-key"}
+
+	const callers = 8
+	start := make(chan struct{})
+	results := make([]*models.Transaction, callers)
+	errs := make([]error, callers)
+	var wg sync.WaitGroup
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			results[i], errs[i] = s.Execute(ctx, transfer)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	for i := range callers {
+		if errs[i] != nil {
+			t.Fatalf("запрос %d: %v", i, errs[i])
+		}
+		if results[i].ID != results[0].ID {
+			t.Errorf("запрос %d получил транзакцию %d, want %d", i, results[i].ID, results[0].ID)
+		}
+	}
+	if got := walletBalance(t, s, from); got != 0 {
+		t.Errorf("баланс отправителя %v, want 0", got)
+	}
+	if got := walletBalance(t, s, to); got != 10 {
+		t.Errorf("баланс получателя %v, want 10", got)
+	}
+	if n := countRows(t, s, "SELECT COUNT(*) FROM transactions"); n != 1 {
+		t.Errorf("записано транзакций %d, want 1: повторы не должны записывать отказы", n)
+	}
+}
+
+func TestExecuteDeadlineBeforeCommit(t *testing.T) {
+	s := newTestStorage(t)
+	from, to := testAddress(1), testAddress(2)
+	createTestWallet(t, s, from, 100)
+	createTestWallet(t, s, to, 0)
+
+	// Дедлайн истекает внутри транзакции перевода, после списания и зачисления:
+	// перевод откатывается, а ошибка сообщает об истёкшем дедлайне.
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err := s.execute(ctx, models.Transfer{From: from, To: to, Amount: 10, IdempotencyKey: "late"},
+		func(ctx context.Context, tx *sql.Tx, transaction *models.Transaction) error {
+			<-ctx.Done()
+			return nil
+		})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("перевод после дедлайна: %v, want context.DeadlineExceeded", err)
+	}
+	if b := walletBalance(t, s, from); b != 100 {
+		t.Errorf("баланс отправителя %v, want 100", b)
+	}
+	if b := walletBalance(t, s, to); b != 0 {
+		t.Errorf("баланс получателя %v, want 0", b)
+	}
+	if n := countRows(t, s, "SELECT COUNT(*) FROM transactions WHERE status = $1", models.StatusSuccess); n != 0 {
+		t.Errorf("успешных транзакций %d, want 0", n)
+	}
+	if n := countRows(t, s, "SELECT COUNT(*) FROM idempotency_keys WHERE key = $1", "late"); n != 0 {
+		t.Errorf("ключ идемпотентности записан")
+	}
+}
+
+func TestExecuteInsufficientFundsDetails(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	tests := []struct {
+		balance, amount    float64
+		balanceJSON, short string
+	}{
+		{10, 15.5, `"10.00000000"`, `"5.50000000"`},
+		// 0.7 - 0.3 во float64 - 0.39999999999999997, в ответе - точная разница.
+		{0.3, 0.7, `"0.30000000"`, `"0.40000000"`},
+		{0, 0.00000001, `"0.00000000"`, `"0.00000001"`},
+		{1.23456789, 1.2345679, `"1.23456789"`, `"0.00000001"`},
+	}
+	for i, tt := range tests {
+		sender, recipient := testAddress(2*i+1), testAddress(2*i+2)
+		createTestWallet(t, s, sender, tt.balance)
+		createTestWallet(t, s, recipient, 0)
+
+		_, err := s.Execute(ctx, models.Transfer{From: sender, To: recipient, Amount: tt.amount})
+		var txErr *TransactionError
+		if !errors.As(err, &txErr) || txErr.Code != CodeInsufficientFunds {
+			t.Errorf("%v из %v: %v, want CodeInsufficientFunds", tt.amount, tt.balance, err)
+			continue
+		}
+		balance, _ := txErr.Balance.MarshalJSON()
+		shortfall, _ := txErr.Shortfall.MarshalJSON()
+		if string(balance) != tt.balanceJSON || string(shortfall) != tt.short {
+			t.Errorf("%v из %v: баланс %s, недостача %s, want %s и %s", tt.amount, tt.balance, balance, shortfall, tt.balanceJSON, tt.short)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"go-payments/internal/models"
+	"go-payments/internal/money"
+	"go-payments/internal/redact"
+
+	"github.com/lib/pq"
+)
+
+// Количество строк, которое проверка целостности читает одним запросом. Проверки
+// идут по ключу (id или address) пачками, поэтому память не зависит от размера таблиц.
+const fsckBatchSize = 1000
+
+// fsckCheck - одна проверка целостности. run передаёт находки report и
+// останавливается, когда report возвращает false.
+type fsckCheck struct {
+	name     string
+	severity string
+	run      func(s *Storage, ctx context.Context, report func(object, message string) bool) error
+}
+
+// Проверки целостности в порядке выполнения.
+var fsckChecks = []fsckCheck{
+	{"negative_balance", models.FsckSeverityError, (*Storage).fsckNegativeBalances},
+	{"unknown_wallet", models.FsckSeverityError, (*Storage).fsckTransactionWallets},
+	{"non_positive_amount", models.FsckSeverityError, (*Storage).fsckTransactionAmounts},
+	{"unknown_status", models.FsckSeverityWarning, (*Storage).fsckTransactionStatuses},
+	{"idempotency_key_not_success", models.FsckSeverityError, (*Storage).fsckIdempotencyKeys},
+	{"withdrawal_mismatch", models.FsckSeverityError, (*Storage).fsckWithdrawals},
+}
+
+// Fsck проверяет согласованность данных и передаёт каждую находку report.
+// limit ограничивает количество находок каждой проверки (0 - без ограничения).
+// Ошибка report прерывает проверку и возвращается как есть.
+func (s *Storage) Fsck(ctx context.Context, limit int, report func(models.FsckFinding) error) (models.FsckSummary, error) {
+	var summary models.FsckSummary
+	for _, check := range fsckChecks {
+		found := 0
+		var reportErr error
+		err := check.run(s, ctx, func(object, message string) bool {
+			if limit > 0 && found == limit {
+				summary.Truncated = true
+				return false
+			}
+			found++
+			if check.severity == models.FsckSeverityError {
+				summary.Errors++
+			} else {
+				summary.Warnings++
+			}
+			reportErr = report(models.FsckFinding{Check: check.name, Severity: check.severity, Object: object, Message: message})
+			return reportErr == nil
+		})
+		if reportErr != nil {
+			return summary, reportErr
+		}
+		if err != nil {
+			return summary, fmt.Errorf("проверка %s: %w", check.name, err)
+		}
+	}
+	return summary, nil
+}
+
+// fsckNegativeBalances находит кошельки с отрицательным балансом.
+func (s *Storage) fsckNegativeBalances(ctx context.Context, report func(object, message string) bool) error {
+	last := ""
+	for {
+		rows, err := s.db.QueryContext(ctx, `
+    SELECT address, balance FROM wallets
+    WHERE balance < 0 AND address > $1 ORDER BY address LIMIT $2`, last, fsckBatchSize)
+		if err != nil {
+			return err
+		}
+		n := 0
+		for rows.Next() {
+			var address string
+			var balance money.Amount
+			if err := rows.Scan(&address, &balance); err != nil {
+				rows.Close()
+				return err
+			}
+			n, last = n+1, address
+			if !report("wallet:"+redact.Address(address), "отрицательный баланс "+money.FormatAmount(float64(balance))) {
+				rows.Close()
+				return nil
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if n < fsckBatchSize {
+			return nil
+		}
+	}
+}
+
+// fsckTransactionWallets находит транзакции, отправитель или получатель которых
+// не существует и не был удалён (нет ни кошелька, ни надгробия).
+func (s *Storage) fsckTransactionWallets(ctx context.Context, report func(object, message string) bool) error {
+	return s.fsckTransactions(ctx, `
+    SELECT id, CASE
+        WHEN NOT EXISTS (SELECT 1 FROM wallets w WHERE w.address = t.from_address)
+         AND NOT EXISTS (SELECT 1 FROM wallet_tombstones d WHERE d.address = t.from_address)
+        THEN 'отправитель не существует и не был удалён'
+        ELSE 'получатель не существует и не был удалён' END
+    FROM transactions t
+    WHERE id > $1 AND (
+        (NOT EXISTS (SELECT 1 FROM wallets w WHERE w.address = t.from_address)
+         AND NOT EXISTS (SELECT 1 FROM wallet_tombstones d WHERE d.address = t.from_address))
+     OR (NOT EXISTS (SELECT 1 FROM wallets w WHERE w.address = t.to_address)
+         AND NOT EXISTS (SELECT 1 FROM wallet_tombstones d WHERE d.address = t.to_address)))
+    ORDER BY id LIMIT $2`, nil, report)
+}
+
+// fsckTransactionAmounts находит транзакции с нулевой или отрицательной суммой и
+// заметки zero_note с ненулевой суммой.
+func (s *Storage) fsckTransactionAmounts(ctx context.Context, report func(object, message string) bool) error {
+	return s.fsckTransactions(ctx, `
+    SELECT id, 'сумма ' || amount::text FROM transactions
+    WHERE id > $1 AND CASE WHEN status = 'zero_note' THEN amount <> 0 ELSE amount <= 0 END
+    ORDER BY id LIMIT $2`, nil, report)
+}
+
+// fsckTransactionStatuses находит транзакции с неизвестным сборке статусом.
+func (s *Storage) fsckTransactionStatuses(ctx context.Context, report func(object, message string) bool) error {
+	statuses := make([]string, len(models.TransactionStatuses))
+	for i, status := range models.TransactionStatuses {
+		statuses[i] = string(status)
+	}
+	return s.fsckTransactions(ctx, `
+    SELECT id, 'неизвестный статус ' || status FROM transactions
+    WHERE id > $1 AND status <> ALL($3) ORDER BY id LIMIT $2`, []any{pq.Array(statuses)}, report)
+}
+
+// fsckIdempotencyKeys находит ключи идемпотентности, записанные с неуспешной
+// транзакцией: ключ фиксируется только вместе с успешным переводом.
+func (s *Storage) fsckIdempotencyKeys(ctx context.Context, report func(object, message string) bool) error {
+	return s.fsckTransactions(ctx, `
+    SELECT t.id, 'ключ идемпотентности записан с транзакцией в статусе ' || t.status
+    FROM idempotency_keys k JOIN transactions t ON t.id = k.transaction_id
+    WHERE t.id > $1 AND t.status <> 'success' ORDER BY t.id LIMIT $2`, nil, report)
+}
+
+// fsckWithdrawals сверяет выводы средств с их транзакциями: списание - успешный
+// перевод суммы вывода с кошелька на клиринговый кошелёк, возврат есть ровно у
+// отклонённых выводов и переводит ту же сумму обратно.
+func (s *Storage) fsckWithdrawals(ctx context.Context, report func(object, message string) bool) error {
+	return s.fsckRows(ctx, "withdrawal:", `
+    SELECT w.id, CASE
+        WHEN t.id IS NULL OR t.status <> 'success' OR t.from_address <> w.wallet
+          OR t.to_address <> w.clearing_wallet OR t.amount <> w.amount
+        THEN 'списание не совпадает с транзакцией ' || w.transaction_id
+        WHEN w.status = 'failed' AND w.reversal_transaction_id IS NULL
+        THEN 'отклонённый вывод без возврата'
+        WHEN w.status <> 'failed' AND w.reversal_transaction_id IS NOT NULL
+        THEN 'возврат у вывода в состоянии ' || w.status
+        ELSE 'возврат не совпадает с транзакцией ' || w.reversal_transaction_id END
+    FROM withdrawals w
+    LEFT JOIN transactions t ON t.id = w.transaction_id
+    LEFT JOIN transactions r ON r.id = w.reversal_transaction_id
+    WHERE w.id > $1 AND (
+        t.id IS NULL OR t.status <> 'success' OR t.from_address <> w.wallet
+     OR t.to_address <> w.clearing_wallet OR t.amount <> w.amount
+     OR (w.status = 'failed') <> (w.reversal_transaction_id IS NOT NULL)
+     OR (r.id IS NOT NULL AND (r.status <> 'success' OR r.from_address <> w.clearing_wallet
+         OR r.to_address <> w.wallet OR r.amount <> w.amount)))
+    ORDER BY w.id LIMIT $2`, nil, report)
+}
+
+// fsckTransactions выполняет query пачками по id транзакции. Запрос принимает
+// последний прочитанный id ($1), размер пачки ($2) и extra ($3...) и возвращает
+// id и описание нарушения.
+func (s *Storage) fsckTransactions(ctx context.Context, query string, extra []any, report func(object, message string) bool) error {
+	return s.fsckRows(ctx, "transaction:", query, extra, report)
+}
+
+// fsckRows - fsckTransactions для строк любой таблицы с целочисленным ключом:
+// находки сообщаются об объекте prefix + id.
+func (s *Storage) fsckRows(ctx context.Context, prefix, query string, extra []any, report func(object, message string) bool) error {
+	last := 0
+	for {
+		rows, err := s.db.QueryContext(ctx, query, append([]any{last, fsckBatchSize}, extra...)...)
+		if err != nil {
+			return err
+		}
+		n := 0
+		for rows.Next() {
+			var id int
+			var message string
+			if err := rows.Scan(&id, &message); err != nil {
+				rows.Close()
+				return err
+			}
+			n, last = n+1, id
+			if !report(prefix+strconv.Itoa(id), message) {
+				rows.Close()
+				return nil
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if n < fsckBatchSize {
+			return nil
+		}
+	}
+}
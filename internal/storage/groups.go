@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"go-payments/internal/models"
+)
+
+// GetGroupSummary возвращает итоги группы переводов groupID: количество транзакций,
+// сумму успешных переводов, количество по статусам и время первой и последней
+// транзакции. Итоги считаются одним запросом в SQL по индексу group_id. Если
+// в группе нет транзакций, возвращает ErrGroupNotFound.
+func (s *Storage) GetGroupSummary(ctx context.Context, groupID string) (*models.GroupSummary, error) {
+	summary := models.GroupSummary{GroupID: groupID}
+	var (
+		statuses    []byte
+		first, last sql.NullTime
+	)
+	err := s.db.QueryRowContext(ctx, `
+    SELECT COUNT(*), COALESCE(SUM(amount) FILTER (WHERE status = $2), 0), MIN(timestamp), MAX(timestamp),
+        COALESCE((
+            SELECT json_object_agg(status, n) FROM (
+                SELECT status, COUNT(*) AS n FROM transactions WHERE group_id = $1 GROUP BY status
+            ) s
+        ), '{}')
+    FROM transactions WHERE group_id = $1`, groupID, models.StatusSuccess).
+		Scan(&summary.TransactionCount, &summary.TotalAmount, &first, &last, &statuses)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("ошибка получения итогов группы переводов: %w", err))
+	}
+	if summary.TransactionCount == 0 {
+		return nil, ErrGroupNotFound
+	}
+	if err := json.Unmarshal(statuses, &summary.Statuses); err != nil {
+		return nil, internalError(fmt.Errorf("ошибка разбора статусов группы переводов: %w", err))
+	}
+	summary.FirstAt, summary.LastAt = first.Time, last.Time
+	return &summary, nil
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go-payments/internal/models"
+	"go-payments/internal/money"
+)
+
+// Ключи идемпотентности переводов.
+//
+// Ключ записывается в idempotency_keys в той же транзакции, что и перевод, поэтому
+// возможны только два состояния: ключа нет (перевод не зафиксирован - повтор
+// выполняет его заново) или ключ есть вместе с транзакцией (повтор возвращает её).
+// Сбой между записью ключа и фиксацией откатывает оба. Ключ занимается в начале
+// транзакции перевода, до блокировки кошельков и проверок: если два запроса с одним
+// ключом выполняются одновременно, второй ждёт фиксации или отката первого на
+// advisory-блокировке ключа, после фиксации откатывает свою попытку и при повторе
+// находит транзакцию первого, а не проверяет баланс уже списанного кошелька.
+
+// Класс advisory-блокировок ключей идемпотентности (первый аргумент двухаргументной
+// формы pg_advisory_xact_lock); второй - хеш ключа.
+const idempotencyLockClass = 7262003
+
+// errIdempotencyRace - ключ зафиксировал параллельный запрос; Execute повторяет
+// попытку и возвращает его транзакцию.
+var errIdempotencyRace = errors.New("ключ идемпотентности записан параллельным запросом")
+
+// claimIdempotencyKey занимает ключ key в транзакции перевода до его проверок:
+// ждёт параллельный перевод с тем же ключом и возвращает errIdempotencyRace, если
+// тот зафиксировал ключ. Блокировка снимается с фиксацией или откатом tx.
+func claimIdempotencyKey(ctx context.Context, tx *sql.Tx, key string) error {
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1, hashtext($2))", idempotencyLockClass, key); err != nil {
+		return internalError(fmt.Errorf("ошибка блокировки ключа идемпотентности: %w", err))
+	}
+	var exists bool
+	err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM idempotency_keys WHERE key = $1)", key).Scan(&exists)
+	if err != nil {
+		return internalError(fmt.Errorf("ошибка поиска ключа идемпотентности: %w", err))
+	}
+	if exists {
+		return errIdempotencyRace
+	}
+	return nil
+}
+
+// findIdempotent возвращает транзакцию, записанную с ключом t.IdempotencyKey, или
+// nil, если ключа нет. Если перевод с этим ключом отличается отправителем,
+// получателем или суммой, возвращает ErrIdempotencyKeyReused.
+func (s *Storage) findIdempotent(ctx context.Context, t models.Transfer) (*models.Transaction, error) {
+	row := s.db.QueryRowContext(ctx, `
+    SELECT `+transactionColumns+` FROM transactions
+    WHERE id = (SELECT transaction_id FROM idempotency_keys WHERE key = $1)`,
+		t.IdempotencyKey)
+	transaction, err := scanTransaction(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, internalError(fmt.Errorf("ошибка поиска ключа идемпотентности: %w", err))
+	}
+	if transaction.From != t.From || transaction.To != t.To ||
+		money.FormatAmount(float64(transaction.Amount)) != money.FormatAmount(t.Amount) {
+		return nil, ErrIdempotencyKeyReused
+	}
+	return transaction, nil
+}
+
+// recordIdempotencyKey записывает ключ перевода внутри его транзакции.
+func recordIdempotencyKey(ctx context.Context, tx *sql.Tx, key string, transactionID int) error {
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO idempotency_keys (key, transaction_id) VALUES ($1, $2) ON CONFLICT (key) DO NOTHING",
+		key, transactionID)
+	if err != nil {
+		return internalError(fmt.Errorf("ошибка записи ключа идемпотентности: %w", err))
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return internalError(fmt.Errorf("ошибка записи ключа идемпотентности: %w", err))
+	} else if n == 0 {
+		return errIdempotencyRace
+	}
+	return nil
+}
+
+// GetIdempotencyKey возвращает ключ идемпотентности или ErrIdempotencyKeyNotFound.
+func (s *Storage) GetIdempotencyKey(ctx context.Context, key string) (*models.IdempotencyKey, error) {
+	var k models.IdempotencyKey
+	err := s.db.QueryRowContext(ctx,
+		`SELECT k.key, k.transaction_id, t.public_id, k.created_at
+    FROM idempotency_keys k JOIN transactions t ON t.id = k.transaction_id WHERE k.key = $1`, key).
+		Scan(&k.Key, &k.TransactionID, &k.TransactionPublicID, &k.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrIdempotencyKeyNotFound
+		}
+		return nil, internalError(fmt.Errorf("ошибка получения ключа идемпотентности: %w", err))
+	}
+	return &k, nil
+}
+
+// DeleteIdempotencyKey удаляет ключ идемпотентности; транзакция остаётся.
+// Повтор перевода с этим ключом после удаления выполнит его заново.
+func (s *Storage) DeleteIdempotencyKey(ctx context.Context, key string) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE key = $1", key)
+	if err != nil {
+		return internalError(fmt.Errorf("ошибка удаления ключа идемпотентности: %w", err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return internalError(fmt.Errorf("ошибка удаления ключа идемпотентности: %w", err))
+	}
+	if n == 0 {
+		return ErrIdempotencyKeyNotFound
+	}
+	return nil
+}
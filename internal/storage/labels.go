@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go-payments/internal/models"
+	"go-payments/internal/redact"
+
+	"github.com/lib/pq"
+)
+
+// Уникальный индекс меток кошельков.
+const walletLabelIndex = "idx_wallets_label"
+
+// GetWalletByLabel возвращает кошелёк с меткой label.
+func (s *Storage) GetWalletByLabel(ctx context.Context, label string) (*models.Wallet, error) {
+	query := "SELECT " + walletColumns + " FROM wallets WHERE label = $1"
+	wallet, err := scanWallet(s.db.QueryRowContext(ctx, query, label))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrLabelNotFound
+		}
+		return nil, internalError(fmt.Errorf("ошибка поиска кошелька по метке %s: %w", label, err))
+	}
+	return &wallet, nil
+}
+
+// SetWalletLabel назначает кошельку метку label; пустая метка снимает её.
+// Если метка уже у другого кошелька, возвращает ErrLabelTaken.
+func (s *Storage) SetWalletLabel(ctx context.Context, address, label string) error {
+	result, err := s.db.ExecContext(ctx, "UPDATE wallets SET label = NULLIF($2, '') WHERE address = $1", address, label)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == walletLabelIndex { // unique_violation
+			return ErrLabelTaken
+		}
+		return internalError(fmt.Errorf("ошибка назначения метки кошельку %s: %w", redact.Address(address), err))
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return internalError(fmt.Errorf("ошибка назначения метки кошельку %s: %w", redact.Address(address), err))
+	}
+	if n == 0 {
+		return ErrWalletNotFound
+	}
+	return nil
+}
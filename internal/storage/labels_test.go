@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSetWalletLabelTaken(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	first, second := testAddress(1), testAddress(2)
+	createTestWallet(t, s, first, 0)
+	createTestWallet(t, s, second, 0)
+
+	if err := s.SetWalletLabel(ctx, first, "payroll"); err != nil {
+		t.Fatalf("SetWalletLabel: %v", err)
+	}
+	if err := s.SetWalletLabel(ctx, second, "payroll"); !errors.Is(err, ErrLabelTaken) {
+		t.Fatalf("метка другого кошелька: %v, want ErrLabelTaken", err)
+	}
+	wallet, err := s.GetWalletByLabel(ctx, "payroll")
+	if err != nil || wallet.Address != first {
+		t.Fatalf("GetWalletByLabel: %+v, %v, want %s", wallet, err, first)
+	}
+	if err := s.SetWalletLabel(ctx, first, "payroll"); err != nil {
+		t.Errorf("повторное назначение своей метки: %v", err)
+	}
+
+	// Пустая метка снимает её, и метку может занять другой кошелёк; кошельков
+	// без метки может быть сколько угодно.
+	if err := s.SetWalletLabel(ctx, first, ""); err != nil {
+		t.Fatalf("снятие метки: %v", err)
+	}
+	if _, err := s.GetWalletByLabel(ctx, "payroll"); !errors.Is(err, ErrLabelNotFound) {
+		t.Errorf("поиск снятой метки: %v, want ErrLabelNotFound", err)
+	}
+	if err := s.SetWalletLabel(ctx, second, "payroll"); err != nil {
+		t.Errorf("метка после снятия: %v", err)
+	}
+	if err := s.SetWalletLabel(ctx, testAddress(3), "vendor"); !errors.Is(err, ErrWalletNotFound) {
+		t.Errorf("метка несуществующего кошелька: %v, want ErrWalletNotFound", err)
+	}
+}
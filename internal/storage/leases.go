@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// AcquireLease захватывает аренду задачи job для экземпляра holder на время ttl.
+// Аренда достаётся, если её ещё нет, она истекла или уже принадлежит holder.
+// Захват выполняется одним атомарным UPSERT, поэтому из нескольких экземпляров,
+// пытающихся захватить аренду одновременно, успешен только один.
+func (s *Storage) AcquireLease(ctx context.Context, job, holder string, ttl time.Duration) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `
+    INSERT INTO job_leases (job, holder, expires_at)
+    VALUES ($1, $2, now() + make_interval(secs => $3::double precision))
+    ON CONFLICT (job) DO UPDATE SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+    WHERE job_leases.expires_at < now() OR job_leases.holder = EXCLUDED.holder`,
+		job, holder, ttl.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("ошибка захвата аренды задачи %s: %w", job, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка захвата аренды задачи %s: %w", job, err)
+	}
+	return n == 1, nil
+}
+
+// RenewLease продлевает аренду задачи job на время ttl. Возвращает false, если
+// аренда уже принадлежит другому экземпляру.
+func (s *Storage) RenewLease(ctx context.Context, job, holder string, ttl time.Duration) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `
+    UPDATE job_leases SET expires_at = now() + make_interval(secs => $3::double precision)
+    WHERE job = $1 AND holder = $2`,
+		job, holder, ttl.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("ошибка продления аренды задачи %s: %w", job, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка продления аренды задачи %s: %w", job, err)
+	}
+	return n == 1, nil
+}
+
+// ReleaseLease освобождает аренду задачи job, если она принадлежит holder.
+func (s *Storage) ReleaseLease(ctx context.Context, job, holder string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM job_leases WHERE job = $1 AND holder = $2", job, holder)
+	if err != nil {
+		return fmt.Errorf("ошибка освобождения аренды задачи %s: %w", job, err)
+	}
+	return nil
+}
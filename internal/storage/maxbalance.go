@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"go-payments/internal/redact"
+)
+
+// SetWalletMaxBalance задаёт предельный баланс кошелька; nil снимает ограничение.
+// Предел ниже текущего баланса допустим: кошелёк просто не принимает переводы,
+// пока баланс не опустится ниже предела. Списания предел не ограничивает.
+func (s *Storage) SetWalletMaxBalance(ctx context.Context, address string, maxBalance *float64) error {
+	result, err := s.db.ExecContext(ctx, "UPDATE wallets SET max_balance = $2 WHERE address = $1", address, maxBalance)
+	if err != nil {
+		return internalError(fmt.Errorf("ошибка назначения предельного баланса кошельку %s: %w", redact.Address(address), err))
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return internalError(fmt.Errorf("ошибка назначения предельного баланса кошельку %s: %w", redact.Address(address), err))
+	}
+	if n == 0 {
+		return ErrWalletNotFound
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"go-payments/internal/models"
+)
+
+func TestExecuteMaxBalanceBoundary(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	from, to := testAddress(1), testAddress(2)
+	createTestWallet(t, s, from, 1000)
+	createTestWallet(t, s, to, 90)
+	maxBalance := 100.0
+	if err := s.SetWalletMaxBalance(ctx, to, &maxBalance); err != nil {
+		t.Fatalf("SetWalletMaxBalance: %v", err)
+	}
+
+	// Зачисление ровно до предела проходит.
+	if _, err := s.Execute(ctx, models.Transfer{From: from, To: to, Amount: 10}); err != nil {
+		t.Fatalf("перевод до предела: %v", err)
+	}
+	if got := walletBalance(t, s, to); got != maxBalance {
+		t.Fatalf("баланс получателя %v, want %v", got, maxBalance)
+	}
+
+	// Наименьшая единица сверх предела отклоняется и записывается своим статусом.
+	_, err := s.Execute(ctx, models.Transfer{From: from, To: to, Amount: 1e-8})
+	var txErr *TransactionError
+	if !errors.As(err, &txErr) || txErr.Code != CodeRecipientLimitExceeded {
+		t.Fatalf("перевод сверх предела: %v, want CodeRecipientLimitExceeded", err)
+	}
+	if float64(txErr.RecipientBalance) != maxBalance || float64(txErr.MaxBalance) != maxBalance {
+		t.Errorf("детали ошибки: баланс %v, предел %v, want %v и %v", txErr.RecipientBalance, txErr.MaxBalance, maxBalance, maxBalance)
+	}
+	if got := walletBalance(t, s, to); got != maxBalance {
+		t.Errorf("баланс получателя после отказа %v, want %v", got, maxBalance)
+	}
+	if got := walletBalance(t, s, from); got != 990 {
+		t.Errorf("баланс отправителя после отказа %v, want 990", got)
+	}
+	if n := countRows(t, s, "SELECT COUNT(*) FROM transactions WHERE status = $1", models.StatusFailedRecipientLimitExceeded); n != 1 {
+		t.Errorf("транзакций со статусом %s: %d, want 1", models.StatusFailedRecipientLimitExceeded, n)
+	}
+}
+
+func TestExecuteMaxBalanceConcurrentCredits(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	const (
+		senders   = 5
+		transfers = 40
+		amount    = 7.0
+		limit     = 100.0
+	)
+	to := testAddress(100)
+	createTestWallet(t, s, to, 0)
+	maxBalance := limit
+	if err := s.SetWalletMaxBalance(ctx, to, &maxBalance); err != nil {
+		t.Fatalf("SetWalletMaxBalance: %v", err)
+	}
+	addresses := make([]string, senders)
+	for i := range addresses {
+		addresses[i] = testAddress(i + 1)
+		createTestWallet(t, s, addresses[i], transfers*amount)
+	}
+
+	errs := make([]error, transfers)
+	var wg sync.WaitGroup
+	for i := range transfers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = s.Execute(ctx, models.Transfer{From: addresses[i%senders], To: to, Amount: amount})
+		}()
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for i, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		var txErr *TransactionError
+		if !errors.As(err, &txErr) || txErr.Code != CodeRecipientLimitExceeded {
+			t.Errorf("перевод %d: %v, want успех или CodeRecipientLimitExceeded", i, err)
+		}
+	}
+	// Зачисления по 7 помещаются в предел 100 ровно 14 раз, как бы они ни пересекались.
+	if want := 14; succeeded != want {
+		t.Errorf("успешных зачислений %d, want %d", succeeded, want)
+	}
+	if got, want := walletBalance(t, s, to), float64(succeeded)*amount; got != want {
+		t.Errorf("баланс получателя %v, want %v", got, want)
+	}
+	total := 0.0
+	for _, a := range addresses {
+		total += walletBalance(t, s, a)
+	}
+	if want := senders*transfers*amount - float64(succeeded)*amount; total != want {
+		t.Errorf("сумма балансов отправителей %v, want %v", total, want)
+	}
+}
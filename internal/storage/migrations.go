@@ -0,0 +1,429 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// migration - версионированное изменение схемы базы данных.
+// Запросы написаны идемпотентно, чтобы их можно было применить к базам,
+// созданным до появления таблицы schema_migrations.
+type migration struct {
+	version int
+	name    string
+	query   string
+}
+
+// Миграции применяются строго по возрастанию версии. Новые миграции
+// добавляются только в конец списка.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create_wallets_and_transactions",
+		query: `
+    CREATE TABLE IF NOT EXISTS wallets (
+        address TEXT PRIMARY KEY,
+        balance DECIMAL(20, 8) NOT NULL DEFAULT 0
+    );
+    CREATE TABLE IF NOT EXISTS transactions (
+        id SERIAL PRIMARY KEY,
+        from_address TEXT NOT NULL REFERENCES wallets(address),
+        to_address TEXT NOT NULL REFERENCES wallets(address),
+        amount DECIMAL(20, 8) NOT NULL,
+        timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        status TEXT NOT NULL,
+        CHECK (from_address <> to_address)
+    );`,
+	},
+	{
+		version: 2,
+		name:    "wallets_frozen_archived",
+		query: `
+    ALTER TABLE wallets ADD COLUMN IF NOT EXISTS frozen BOOLEAN NOT NULL DEFAULT FALSE;
+    ALTER TABLE wallets ADD COLUMN IF NOT EXISTS archived BOOLEAN NOT NULL DEFAULT FALSE;`,
+	},
+	{
+		version: 3,
+		name:    "create_settings",
+		query: `
+    CREATE TABLE IF NOT EXISTS settings (
+        key TEXT PRIMARY KEY,
+        value TEXT NOT NULL,
+        updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );`,
+	},
+	{
+		version: 4,
+		name:    "transactions_memo_reference",
+		query: `
+    ALTER TABLE transactions ADD COLUMN IF NOT EXISTS memo TEXT NOT NULL DEFAULT '';
+    ALTER TABLE transactions ADD COLUMN IF NOT EXISTS reference TEXT NOT NULL DEFAULT '';`,
+	},
+	{
+		version: 5,
+		name:    "transactions_metadata",
+		query: `
+    ALTER TABLE transactions ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
+    CREATE INDEX IF NOT EXISTS idx_transactions_metadata ON transactions USING GIN (metadata);`,
+	},
+	{
+		version: 6,
+		name:    "transactions_timestamp_id_index",
+		query: `
+    CREATE INDEX IF NOT EXISTS idx_transactions_timestamp_id ON transactions (timestamp DESC, id DESC);`,
+	},
+	{
+		// История переводов хранит адреса текстом и должна переживать удаление кошелька,
+		// поэтому внешние ключи на wallets заменяются надгробиями удалённых адресов.
+		// Существование кошельков при переводе проверяет Execute внутри транзакции.
+		version: 7,
+		name:    "wallet_tombstones",
+		query: `
+    CREATE TABLE IF NOT EXISTS wallet_tombstones (
+        address TEXT PRIMARY KEY,
+        purged_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
+    ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_from_address_fkey;
+    ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_to_address_fkey;`,
+	},
+	{
+		version: 8,
+		name:    "create_job_leases",
+		query: `
+    CREATE TABLE IF NOT EXISTS job_leases (
+        job TEXT PRIMARY KEY,
+        holder TEXT NOT NULL,
+        expires_at TIMESTAMPTZ NOT NULL
+    );`,
+	},
+	{
+		version: 9,
+		name:    "transactions_from_to_index",
+		query: `
+    CREATE INDEX IF NOT EXISTS idx_transactions_from_to ON transactions (from_address, to_address);`,
+	},
+	{
+		// Заметки не ссылаются на wallets внешним ключом, чтобы переживать удаление кошелька.
+		version: 10,
+		name:    "create_wallet_notes",
+		query: `
+    CREATE TABLE IF NOT EXISTS wallet_notes (
+        id SERIAL PRIMARY KEY,
+        wallet TEXT NOT NULL,
+        author TEXT NOT NULL DEFAULT '',
+        text TEXT NOT NULL,
+        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        redacted_at TIMESTAMP
+    );
+    CREATE INDEX IF NOT EXISTS idx_wallet_notes_wallet ON wallet_notes (wallet, id);`,
+	},
+	{
+		version: 11,
+		name:    "wallets_label",
+		query: `
+    ALTER TABLE wallets ADD COLUMN IF NOT EXISTS label TEXT;
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_wallets_label ON wallets (label) WHERE label IS NOT NULL;`,
+	},
+	{
+		version: 12,
+		name:    "transactions_acknowledged_at",
+		query: `
+    ALTER TABLE transactions ADD COLUMN IF NOT EXISTS acknowledged_at TIMESTAMP;
+    CREATE INDEX IF NOT EXISTS idx_transactions_to_acknowledged ON transactions (to_address, acknowledged_at);`,
+	},
+	{
+		// Время транзакции назначает база данных в UTC, а не часы экземпляра сервиса:
+		// при расхождении часов нескольких экземпляров порядок и курсоры ломались.
+		// clock_timestamp, а не now(), чтобы время не застывало на начале транзакции.
+		version: 13,
+		name:    "transactions_timestamp_db_default",
+		query: `
+    ALTER TABLE transactions ALTER COLUMN timestamp SET DEFAULT (clock_timestamp() AT TIME ZONE 'UTC');`,
+	},
+	{
+		version: 14,
+		name:    "create_transaction_tags",
+		query: `
+    CREATE TABLE IF NOT EXISTS transaction_tags (
+        transaction_id INTEGER NOT NULL REFERENCES transactions(id),
+        tag TEXT NOT NULL,
+        added_by TEXT NOT NULL DEFAULT '',
+        added_at TIMESTAMP NOT NULL DEFAULT (clock_timestamp() AT TIME ZONE 'UTC'),
+        PRIMARY KEY (transaction_id, tag)
+    );
+    CREATE INDEX IF NOT EXISTS idx_transaction_tags_tag ON transaction_tags (tag, transaction_id);`,
+	},
+	{
+		// Сводка по неархивным кошелькам: распределение балансов по порядкам величины.
+		// Каждая строка хранит время обновления; без кошельков остаётся одна строка
+		// с bucket_min IS NULL.
+		version: 15,
+		name:    "create_wallet_summary",
+		query: `
+    CREATE MATERIALIZED VIEW IF NOT EXISTS wallet_summary AS
+    SELECT b.bucket_min, b.wallet_count, b.total_balance, r.refreshed_at
+    FROM (SELECT clock_timestamp() AT TIME ZONE 'UTC' AS refreshed_at) r
+    LEFT JOIN (
+        SELECT CASE WHEN balance <= 0 THEN 0 ELSE POWER(10::numeric, FLOOR(LOG(balance))) END AS bucket_min,
+               COUNT(*) AS wallet_count, SUM(balance) AS total_balance
+        FROM wallets WHERE NOT archived
+        GROUP BY 1
+    ) b ON TRUE;`,
+	},
+	{
+		// Ключ идемпотентности записывается в той же транзакции, что и перевод:
+		// они фиксируются или откатываются вместе, поэтому «зависших» ключей нет.
+		version: 16,
+		name:    "create_idempotency_keys",
+		query: `
+    CREATE TABLE IF NOT EXISTS idempotency_keys (
+        key TEXT PRIMARY KEY,
+        transaction_id INTEGER NOT NULL REFERENCES transactions(id),
+        created_at TIMESTAMP NOT NULL DEFAULT (clock_timestamp() AT TIME ZONE 'UTC')
+    );`,
+	},
+	{
+		version: 17,
+		name:    "transactions_group_id",
+		query: `
+    ALTER TABLE transactions ADD COLUMN IF NOT EXISTS group_id TEXT;
+    CREATE INDEX IF NOT EXISTS idx_transactions_group_id ON transactions (group_id, timestamp, id) WHERE group_id IS NOT NULL;`,
+	},
+	{
+		// Закрытия учётного периода. Строки только добавляются: действует последняя
+		// по id, а предыдущие остаются историей, в том числе принудительных откатов.
+		version: 18,
+		name:    "create_period_closes",
+		query: `
+    CREATE TABLE IF NOT EXISTS period_closes (
+        id SERIAL PRIMARY KEY,
+        closed_through TIMESTAMP NOT NULL,
+        closed_by TEXT NOT NULL DEFAULT '',
+        forced BOOLEAN NOT NULL DEFAULT FALSE,
+        created_at TIMESTAMP NOT NULL DEFAULT (clock_timestamp() AT TIME ZONE 'UTC')
+    );`,
+	},
+	{
+		// Предельный баланс кошелька: NULL - без ограничения.
+		version: 19,
+		name:    "add_wallets_max_balance",
+		query: `
+    ALTER TABLE wallets ADD COLUMN IF NOT EXISTS max_balance DECIMAL(20, 8) CHECK (max_balance >= 0);`,
+	},
+	{
+		// Системные кошельки: недоступны для переводов клиентов как отправители,
+		// а с non_receivable - и как получатели.
+		version: 20,
+		name:    "add_wallets_system",
+		query: `
+    ALTER TABLE wallets ADD COLUMN IF NOT EXISTS system BOOLEAN NOT NULL DEFAULT FALSE;
+    ALTER TABLE wallets ADD COLUMN IF NOT EXISTS non_receivable BOOLEAN NOT NULL DEFAULT FALSE;`,
+	},
+	{
+		// Запросы на оплату. Ссылки на wallets нет, как и у транзакций: удаление
+		// архивного кошелька не должно упираться в старые запросы.
+		version: 21,
+		name:    "create_payment_requests",
+		query: `
+    CREATE TABLE IF NOT EXISTS payment_requests (
+        token TEXT PRIMARY KEY,
+        to_address TEXT NOT NULL,
+        amount DECIMAL(20, 8) NOT NULL CHECK (amount > 0),
+        reference TEXT NOT NULL DEFAULT '',
+        status TEXT NOT NULL DEFAULT 'open',
+        expires_at TIMESTAMP NOT NULL,
+        created_at TIMESTAMP NOT NULL DEFAULT (clock_timestamp() AT TIME ZONE 'UTC'),
+        transaction_id INTEGER REFERENCES transactions(id),
+        fulfilled_at TIMESTAMP
+    );`,
+	},
+	{
+		version: 22,
+		name:    "create_incoming_cursors",
+		query: `
+    CREATE TABLE IF NOT EXISTS incoming_cursors (
+        wallet TEXT NOT NULL,
+        consumer TEXT NOT NULL,
+        last_transaction_id INTEGER NOT NULL CHECK (last_transaction_id >= 0),
+        updated_at TIMESTAMP NOT NULL DEFAULT (clock_timestamp() AT TIME ZONE 'UTC'),
+        PRIMARY KEY (wallet, consumer)
+    );`,
+	},
+	{
+		// Время создания известно только для новых кошельков: у существующих created_at
+		// остаётся NULL, значение по умолчанию задаётся после добавления колонки.
+		version: 23,
+		name:    "wallets_auto_created",
+		query: `
+    ALTER TABLE wallets ADD COLUMN IF NOT EXISTS auto_created BOOLEAN NOT NULL DEFAULT FALSE;
+    ALTER TABLE wallets ADD COLUMN IF NOT EXISTS created_at TIMESTAMP;
+    ALTER TABLE wallets ALTER COLUMN created_at SET DEFAULT (clock_timestamp() AT TIME ZONE 'UTC');`,
+	},
+	{
+		version: 24,
+		name:    "create_balance_thresholds",
+		query: `
+    CREATE TABLE IF NOT EXISTS balance_thresholds (
+        id SERIAL PRIMARY KEY,
+        wallet TEXT NOT NULL,
+        direction TEXT NOT NULL CHECK (direction IN ('above', 'below')),
+        amount DECIMAL(20, 8) NOT NULL CHECK (amount >= 0),
+        hysteresis DECIMAL(20, 8) NOT NULL DEFAULT 0 CHECK (hysteresis >= 0),
+        target TEXT NOT NULL,
+        triggered BOOLEAN NOT NULL DEFAULT FALSE,
+        last_transaction_id INTEGER,
+        last_crossed_at TIMESTAMP,
+        created_at TIMESTAMP NOT NULL DEFAULT (clock_timestamp() AT TIME ZONE 'UTC')
+    );
+    CREATE INDEX IF NOT EXISTS idx_balance_thresholds_wallet ON balance_thresholds (wallet);`,
+	},
+	{
+		// Выводы средств. Сумма уже списана транзакцией transaction_id на клиринговый
+		// кошелёк; отказ возвращает её транзакцией reversal_transaction_id. Частичный
+		// индекс по кошельку обслуживает сумму незавершённых выводов в балансе.
+		version: 25,
+		name:    "create_withdrawals",
+		query: `
+    CREATE TABLE IF NOT EXISTS withdrawals (
+        id SERIAL PRIMARY KEY,
+        wallet TEXT NOT NULL,
+        clearing_wallet TEXT NOT NULL,
+        amount DECIMAL(20, 8) NOT NULL CHECK (amount > 0),
+        status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'review', 'confirmed', 'failed')),
+        reference TEXT NOT NULL DEFAULT '',
+        transaction_id INTEGER NOT NULL REFERENCES transactions(id),
+        reversal_transaction_id INTEGER REFERENCES transactions(id),
+        reason TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMP NOT NULL DEFAULT (clock_timestamp() AT TIME ZONE 'UTC'),
+        updated_at TIMESTAMP NOT NULL DEFAULT (clock_timestamp() AT TIME ZONE 'UTC')
+    );
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_withdrawals_transaction ON withdrawals (transaction_id);
+    CREATE INDEX IF NOT EXISTS idx_withdrawals_status_created ON withdrawals (status, created_at);
+    CREATE INDEX IF NOT EXISTS idx_withdrawals_wallet_open ON withdrawals (wallet) WHERE status IN ('pending', 'review');`,
+	},
+	{
+		// Публичные идентификаторы транзакций (ULID). Существующим строкам ULID
+		// строится по времени транзакции и случайной части из random(); новые
+		// транзакции получают public_id от генератора хранилища.
+		version: 26,
+		name:    "transactions_public_id",
+		query: `
+    ALTER TABLE transactions ADD COLUMN IF NOT EXISTS public_id TEXT;
+    UPDATE transactions t SET public_id = (
+        SELECT string_agg(substr('0123456789ABCDEFGHJKMNPQRSTVWXYZ', CASE
+            WHEN i < 10 THEN (((extract(epoch FROM t.timestamp) * 1000)::bigint >> (45 - 5 * i)) & 31)::int
+            ELSE floor(random() * 32)::int END + 1, 1), '' ORDER BY i)
+        FROM generate_series(0, 25) AS i)
+    WHERE public_id IS NULL;
+    ALTER TABLE transactions ALTER COLUMN public_id SET NOT NULL;
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_public_id ON transactions (public_id);`,
+	},
+	{
+		// Ежедневные подписанные контрольные точки балансов и сами балансы в
+		// порядке листьев дерева, по которым строятся доказательства включения.
+		version: 27,
+		name:    "create_checkpoints",
+		query: `
+    CREATE TABLE IF NOT EXISTS checkpoints (
+        date DATE PRIMARY KEY,
+        as_of TIMESTAMP NOT NULL,
+        wallet_count INTEGER NOT NULL,
+        root TEXT NOT NULL,
+        signature TEXT NOT NULL,
+        public_key TEXT NOT NULL
+    );
+    CREATE TABLE IF NOT EXISTS checkpoint_balances (
+        date DATE NOT NULL REFERENCES checkpoints(date) ON DELETE CASCADE,
+        position INTEGER NOT NULL,
+        address TEXT NOT NULL,
+        balance DECIMAL(20, 8) NOT NULL,
+        PRIMARY KEY (date, position)
+    );
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_checkpoint_balances_address ON checkpoint_balances (date, address);`,
+	},
+}
+
+// Ключ advisory-блокировки, сериализующей применение миграций несколькими экземплярами.
+const migrationLockKey = 7262001
+
+// LatestSchemaVersion возвращает версию схемы, которую ожидает текущая сборка.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// SchemaVersion возвращает версию схемы, применённую к базе данных,
+// или 0, если миграции ещё не применялись.
+func (s *Storage) SchemaVersion(ctx context.Context) (int, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists)
+	if err != nil {
+		return 0, fmt.Errorf("не удалось проверить таблицу schema_migrations: %w", err)
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	var version int
+	err = s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("не удалось получить версию схемы: %w", err)
+	}
+	return version, nil
+}
+
+// migrate применяет все ещё не применённые миграции. Каждая миграция выполняется
+// в отдельной транзакции вместе с записью в schema_migrations.
+func (s *Storage) migrate(ctx context.Context) error {
+	querySchemaMigrations := `
+    CREATE TABLE IF NOT EXISTS schema_migrations (
+        version INTEGER PRIMARY KEY,
+        name TEXT NOT NULL,
+        applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );`
+
+	if _, err := s.db.ExecContext(ctx, querySchemaMigrations); err != nil {
+		return fmt.Errorf("не удалось создать таблицу schema_migrations: %w", err)
+	}
+
+	for _, m := range migrations {
+		if err := s.applyMigration(ctx, m); err != nil {
+			return fmt.Errorf("не удалось применить миграцию %d (%s): %w", m.version, m.name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Storage) applyMigration(ctx context.Context, m migration) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLockKey); err != nil {
+		return err
+	}
+
+	var applied bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", m.version).Scan(&applied)
+	if err != nil {
+		return err
+	}
+	if applied {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, m.query); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.version, m.name); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("применена миграция %d: %s", m.version, m.name)
+	return nil
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-payments/internal/models"
+	"go-payments/internal/redact"
+)
+
+// AddWalletNote добавляет заметку к существующему кошельку.
+func (s *Storage) AddWalletNote(ctx context.Context, wallet, author, text string) (*models.WalletNote, error) {
+	note := models.WalletNote{Wallet: wallet, Author: author, Text: text}
+	err := s.db.QueryRowContext(ctx, `
+    INSERT INTO wallet_notes (wallet, author, text)
+    SELECT $1, $2, $3 WHERE EXISTS (SELECT 1 FROM wallets WHERE address = $1)
+    RETURNING id, created_at`,
+		wallet, author, text).Scan(&note.ID, &note.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWalletNotFound
+		}
+		return nil, fmt.Errorf("ошибка добавления заметки к кошельку %s: %w", redact.Address(wallet), err)
+	}
+	return &note, nil
+}
+
+// ListWalletNotes возвращает заметки к кошельку в порядке добавления. Заметки
+// удалённого кошелька остаются доступными.
+func (s *Storage) ListWalletNotes(ctx context.Context, wallet string) ([]models.WalletNote, error) {
+	rows, err := s.db.QueryContext(ctx, `
+    SELECT id, wallet, author, text, created_at, redacted_at FROM wallet_notes
+    WHERE wallet = $1 ORDER BY id`, wallet)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения заметок к кошельку %s: %w", redact.Address(wallet), err)
+	}
+	defer rows.Close()
+
+	notes := []models.WalletNote{}
+	for rows.Next() {
+		var n models.WalletNote
+		var redactedAt sql.NullTime
+		if err := rows.Scan(&n.ID, &n.Wallet, &n.Author, &n.Text, &n.CreatedAt, &redactedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки wallet_notes: %w", err)
+		}
+		if redactedAt.Valid {
+			n.RedactedAt = &redactedAt.Time
+		}
+		notes = append(notes, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по wallet_notes: %w", err)
+	}
+	return notes, nil
+}
+
+// RedactWalletNote стирает текст заметки, сохраняя саму запись и время скрытия.
+// Это единственное допустимое изменение заметки. Повторное скрытие не меняет время.
+func (s *Storage) RedactWalletNote(ctx context.Context, wallet string, id int) (*models.WalletNote, error) {
+	note := models.WalletNote{ID: id, Wallet: wallet}
+	var redactedAt time.Time
+	err := s.db.QueryRowContext(ctx, `
+    UPDATE wallet_notes SET text = '', redacted_at = COALESCE(redacted_at, CURRENT_TIMESTAMP)
+    WHERE id = $1 AND wallet = $2
+    RETURNING author, text, created_at, redacted_at`,
+		id, wallet).Scan(&note.Author, &note.Text, &note.CreatedAt, &redactedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoteNotFound
+		}
+		return nil, fmt.Errorf("ошибка скрытия заметки %d: %w", id, err)
+	}
+	note.RedactedAt = &redactedAt
+	return &note, nil
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"go-payments/internal/models"
+	"go-payments/internal/redact"
+)
+
+// Колонки запроса на оплату в порядке scanPaymentRequest. Открытый запрос с
+// истёкшим сроком отдаётся со статусом expired по часам базы данных.
+const paymentRequestColumns = `token, to_address, amount, reference,
+    CASE WHEN status = 'open' AND expires_at <= (clock_timestamp() AT TIME ZONE 'UTC') THEN 'expired' ELSE status END,
+    expires_at, created_at, transaction_id, fulfilled_at`
+
+func scanPaymentRequest(row interface{ Scan(...any) error }) (*models.PaymentRequest, error) {
+	var (
+		pr            models.PaymentRequest
+		transactionID sql.NullInt64
+		fulfilledAt   sql.NullTime
+	)
+	if err := row.Scan(&pr.Token, &pr.To, &pr.Amount, &pr.Reference, &pr.Status,
+		&pr.ExpiresAt, &pr.CreatedAt, &transactionID, &fulfilledAt); err != nil {
+		return nil, err
+	}
+	pr.ExpiresAt = pr.ExpiresAt.UTC()
+	pr.CreatedAt = pr.CreatedAt.UTC()
+	if transactionID.Valid {
+		id := int(transactionID.Int64)
+		pr.TransactionID = &id
+	}
+	if fulfilledAt.Valid {
+		t := fulfilledAt.Time.UTC()
+		pr.FulfilledAt = &t
+	}
+	return &pr, nil
+}
+
+// newPaymentRequestToken возвращает случайный непрозрачный токен запроса на оплату.
+func newPaymentRequestToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CreatePaymentRequest записывает открытый запрос на оплату pr (To, Amount,
+// Reference, ExpiresAt) и возвращает его с новым токеном.
+func (s *Storage) CreatePaymentRequest(ctx context.Context, pr models.PaymentRequest) (*models.PaymentRequest, error) {
+	token, err := newPaymentRequestToken()
+	if err != nil {
+		return nil, internalError(fmt.Errorf("не удалось создать токен запроса на оплату: %w", err))
+	}
+	row := s.db.QueryRowContext(ctx, `
+    INSERT INTO payment_requests (token, to_address, amount, reference, expires_at)
+    VALUES ($1, $2, $3, $4, $5)
+    RETURNING `+paymentRequestColumns,
+		token, pr.To, float64(pr.Amount), pr.Reference, pr.ExpiresAt.UTC())
+	created, err := scanPaymentRequest(row)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("ошибка создания запроса на оплату кошельку %s: %w", redact.Address(pr.To), err))
+	}
+	return created, nil
+}
+
+// GetPaymentRequest возвращает запрос на оплату по токену или ErrPaymentRequestNotFound.
+func (s *Storage) GetPaymentRequest(ctx context.Context, token string) (*models.PaymentRequest, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+paymentRequestColumns+" FROM payment_requests WHERE token = $1", token)
+	pr, err := scanPaymentRequest(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPaymentRequestNotFound
+		}
+		return nil, internalError(fmt.Errorf("ошибка получения запроса на оплату: %w", err))
+	}
+	return pr, nil
+}
+
+// FulfillPaymentRequest отмечает запрос оплаченным транзакцией transactionID.
+// Срок не проверяется: перевод уже выполнен, и запрос должен это отражать.
+// Повторная отметка той же транзакцией не меняет запрос; запрос, оплаченный
+// другой транзакцией, - ErrPaymentRequestFulfilled.
+func (s *Storage) FulfillPaymentRequest(ctx context.Context, token string, transactionID int) (*models.PaymentRequest, error) {
+	row := s.db.QueryRowContext(ctx, `
+    UPDATE payment_requests
+    SET status = 'fulfilled', transaction_id = $2,
+        fulfilled_at = COALESCE(fulfilled_at, (clock_timestamp() AT TIME ZONE 'UTC'))
+    WHERE token = $1 AND (status = 'open' OR transaction_id = $2)
+    RETURNING `+paymentRequestColumns,
+		token, transactionID)
+	pr, err := scanPaymentRequest(row)
+	if err == nil {
+		return pr, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, internalError(fmt.Errorf("ошибка отметки оплаты запроса: %w", err))
+	}
+	if _, err := s.GetPaymentRequest(ctx, token); err != nil {
+		return nil, err
+	}
+	return nil, ErrPaymentRequestFulfilled
+}
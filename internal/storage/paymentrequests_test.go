@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-payments/internal/models"
+)
+
+func TestPaymentRequestLifecycle(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	payee, payer := testAddress(1), testAddress(2)
+	createTestWallet(t, s, payee, 0)
+	createTestWallet(t, s, payer, 100)
+
+	pr, err := s.CreatePaymentRequest(ctx, models.PaymentRequest{To: payee, Amount: 10, Reference: "INV-1",
+		ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("CreatePaymentRequest: %v", err)
+	}
+	if pr.Token == "" || pr.Status != models.PaymentRequestOpen {
+		t.Fatalf("новый запрос %+v, want открытый с токеном", pr)
+	}
+
+	first, err := s.Execute(ctx, models.Transfer{From: payer, To: payee, Amount: 10})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	second, err := s.Execute(ctx, models.Transfer{From: payer, To: payee, Amount: 10})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	fulfilled, err := s.FulfillPaymentRequest(ctx, pr.Token, first.ID)
+	if err != nil {
+		t.Fatalf("FulfillPaymentRequest: %v", err)
+	}
+	if fulfilled.Status != models.PaymentRequestFulfilled || fulfilled.TransactionID == nil ||
+		*fulfilled.TransactionID != first.ID || fulfilled.FulfilledAt == nil {
+		t.Fatalf("оплаченный запрос %+v, want fulfilled транзакцией %d", fulfilled, first.ID)
+	}
+
+	// Повторная отметка той же транзакцией не меняет запрос.
+	again, err := s.FulfillPaymentRequest(ctx, pr.Token, first.ID)
+	if err != nil {
+		t.Fatalf("повторная отметка той же транзакцией: %v", err)
+	}
+	if !again.FulfilledAt.Equal(*fulfilled.FulfilledAt) {
+		t.Errorf("время оплаты изменилось: %v, want %v", again.FulfilledAt, fulfilled.FulfilledAt)
+	}
+	// Второй платёж по тому же токену отклоняется и не перезаписывает первый.
+	if _, err := s.FulfillPaymentRequest(ctx, pr.Token, second.ID); !errors.Is(err, ErrPaymentRequestFulfilled) {
+		t.Errorf("отметка другой транзакцией: %v, want ErrPaymentRequestFulfilled", err)
+	}
+	got, err := s.GetPaymentRequest(ctx, pr.Token)
+	if err != nil {
+		t.Fatalf("GetPaymentRequest: %v", err)
+	}
+	if got.TransactionID == nil || *got.TransactionID != first.ID {
+		t.Errorf("запрос оплачен транзакцией %v, want %d", got.TransactionID, first.ID)
+	}
+
+	if _, err := s.FulfillPaymentRequest(ctx, "missing", first.ID); !errors.Is(err, ErrPaymentRequestNotFound) {
+		t.Errorf("отметка неизвестного запроса: %v, want ErrPaymentRequestNotFound", err)
+	}
+}
+
+func TestPaymentRequestExpiry(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	payee, payer := testAddress(1), testAddress(2)
+	createTestWallet(t, s, payee, 0)
+	createTestWallet(t, s, payer, 100)
+
+	pr, err := s.CreatePaymentRequest(ctx, models.PaymentRequest{To: payee, Amount: 10,
+		ExpiresAt: time.Now().Add(time.Second)})
+	if err != nil {
+		t.Fatalf("CreatePaymentRequest: %v", err)
+	}
+	if pr.Status != models.PaymentRequestOpen {
+		t.Fatalf("запрос до срока в состоянии %s, want open", pr.Status)
+	}
+	time.Sleep(1100 * time.Millisecond)
+
+	// Срок проверяется по часам базы при чтении.
+	got, err := s.GetPaymentRequest(ctx, pr.Token)
+	if err != nil {
+		t.Fatalf("GetPaymentRequest: %v", err)
+	}
+	if got.Status != models.PaymentRequestExpired {
+		t.Errorf("запрос после срока в состоянии %s, want expired", got.Status)
+	}
+
+	// Перевод, выполненный до истечения срока, всё равно отмечает запрос оплаченным.
+	paid, err := s.Execute(ctx, models.Transfer{From: payer, To: payee, Amount: 10})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	fulfilled, err := s.FulfillPaymentRequest(ctx, pr.Token, paid.ID)
+	if err != nil {
+		t.Fatalf("FulfillPaymentRequest после срока: %v", err)
+	}
+	if fulfilled.Status != models.PaymentRequestFulfilled {
+		t.Errorf("запрос в состоянии %s, want fulfilled", fulfilled.Status)
+	}
+}
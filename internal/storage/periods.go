@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-payments/internal/models"
+)
+
+// Ограничение транзакций открытым периодом для WHERE по таблице transactions.
+// Действует последнее закрытие (по id), а не самое позднее: принудительный
+// откат назад снова открывает период.
+const periodOpenCondition = `timestamp >= COALESCE(
+        (SELECT closed_through FROM period_closes ORDER BY id DESC LIMIT 1), '-infinity'::timestamp)`
+
+// GetPeriodClose возвращает действующее закрытие учётного периода или nil,
+// если период ещё не закрывался.
+func (s *Storage) GetPeriodClose(ctx context.Context) (*models.PeriodClose, error) {
+	var p models.PeriodClose
+	err := s.db.QueryRowContext(ctx, `
+    SELECT closed_through, closed_by, forced, created_at FROM period_closes
+    ORDER BY id DESC LIMIT 1`).Scan(&p.ClosedThrough, &p.ClosedBy, &p.Forced, &p.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, internalError(fmt.Errorf("ошибка получения закрытия периода: %w", err))
+	}
+	return &p, nil
+}
+
+// ClosePeriod закрывает учётный период по through (не включительно) и возвращает
+// новое закрытие. Перенос закрытия назад возможен только с force, иначе
+// возвращает ErrPeriodCloseBackward. Закрытия сериализуются блокировкой таблицы.
+func (s *Storage) ClosePeriod(ctx context.Context, through time.Time, closedBy string, force bool) (*models.PeriodClose, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("не удалось начать транзакцию: %w", err))
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "LOCK TABLE period_closes IN EXCLUSIVE MODE"); err != nil {
+		return nil, internalError(fmt.Errorf("ошибка блокировки period_closes: %w", err))
+	}
+
+	through = through.UTC()
+	var current sql.NullTime
+	err = tx.QueryRowContext(ctx, "SELECT closed_through FROM period_closes ORDER BY id DESC LIMIT 1").Scan(&current)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, internalError(fmt.Errorf("ошибка получения закрытия периода: %w", err))
+	}
+	backward := current.Valid && through.Before(current.Time)
+	if backward && !force {
+		return nil, ErrPeriodCloseBackward
+	}
+
+	p := models.PeriodClose{ClosedThrough: through, ClosedBy: closedBy, Forced: backward}
+	err = tx.QueryRowContext(ctx, `
+    INSERT INTO period_closes (closed_through, closed_by, forced) VALUES ($1, $2, $3)
+    RETURNING created_at`, through, closedBy, backward).Scan(&p.CreatedAt)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("ошибка записи закрытия периода: %w", err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, internalError(fmt.Errorf("не удалось зафиксировать закрытие периода: %w", err))
+	}
+	return &p, nil
+}
+
+// requireOpenTransaction возвращает ErrTxNotFound, если транзакции id нет, и
+// ErrPeriodClosed, если она относится к закрытому периоду.
+func (s *Storage) requireOpenTransaction(ctx context.Context, id int) error {
+	var open bool
+	err := s.db.QueryRowContext(ctx, "SELECT "+periodOpenCondition+" FROM transactions WHERE id = $1", id).Scan(&open)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTxNotFound
+		}
+		return internalError(fmt.Errorf("ошибка проверки транзакции %d: %w", id, err))
+	}
+	if !open {
+		return ErrPeriodClosed
+	}
+	return nil
+}
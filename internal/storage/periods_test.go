@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-payments/internal/models"
+)
+
+func TestClosePeriod(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	sender, recipient := testAddress(1), testAddress(2)
+	createTestWallet(t, s, sender, 100)
+	createTestWallet(t, s, recipient, 0)
+
+	if p, err := s.GetPeriodClose(ctx); err != nil || p != nil {
+		t.Fatalf("GetPeriodClose до закрытия: %+v, %v, want nil", p, err)
+	}
+
+	closed, err := s.Execute(ctx, models.Transfer{From: sender, To: recipient, Amount: 10})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	through := closed.Timestamp.Add(time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	open, err := s.Execute(ctx, models.Transfer{From: sender, To: recipient, Amount: 5})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	p, err := s.ClosePeriod(ctx, through, "alice", false)
+	if err != nil {
+		t.Fatalf("ClosePeriod: %v", err)
+	}
+	if !p.ClosedThrough.Equal(through) || p.ClosedBy != "alice" || p.Forced {
+		t.Errorf("закрытие %+v, want по %s от alice без force", p, through)
+	}
+	if current, err := s.GetPeriodClose(ctx); err != nil || current == nil || !current.ClosedThrough.Equal(through) {
+		t.Fatalf("GetPeriodClose: %+v, %v, want закрытие по %s", current, err, through)
+	}
+
+	// Транзакции закрытого периода нельзя подтверждать и тегировать.
+	if _, err := s.AcknowledgeTransaction(ctx, closed.ID, recipient); !errors.Is(err, ErrPeriodClosed) {
+		t.Errorf("подтверждение в закрытом периоде: %v, want ErrPeriodClosed", err)
+	}
+	if err := s.AddTransactionTag(ctx, closed.ID, "audit", "alice"); !errors.Is(err, ErrPeriodClosed) {
+		t.Errorf("тег в закрытом периоде: %v, want ErrPeriodClosed", err)
+	}
+	if err := s.RemoveTransactionTag(ctx, closed.ID, "audit"); !errors.Is(err, ErrPeriodClosed) {
+		t.Errorf("снятие тега в закрытом периоде: %v, want ErrPeriodClosed", err)
+	}
+	results, err := s.AcknowledgeTransactions(ctx, recipient, []int{closed.ID, open.ID, 1 << 30})
+	if err != nil {
+		t.Fatalf("AcknowledgeTransactions: %v", err)
+	}
+	if !errors.Is(results[closed.ID], ErrPeriodClosed) || results[open.ID] != nil || !errors.Is(results[1<<30], ErrTxNotFound) {
+		t.Errorf("пакетное подтверждение: %v, want закрытый период, успех и не найдена", results)
+	}
+	if n, err := s.AcknowledgeTransactionsThrough(ctx, recipient, closed.ID); err != nil || n != 0 {
+		t.Errorf("подтверждение до %d: %d, %v, want 0 без ошибки", closed.ID, n, err)
+	}
+	if got, err := s.GetTransaction(ctx, closed.ID); err != nil || got.AcknowledgedAt != nil {
+		t.Errorf("транзакция закрытого периода после отказов: %+v, %v, want без acknowledged_at", got, err)
+	}
+	// Транзакция после closed_through остаётся в открытом периоде.
+	if err := s.AddTransactionTag(ctx, open.ID, "audit", "alice"); err != nil {
+		t.Errorf("тег в открытом периоде: %v", err)
+	}
+
+	// Перенос закрытия назад без force отклоняется и не меняет закрытие.
+	earlier := closed.Timestamp.Add(-time.Hour)
+	if _, err := s.ClosePeriod(ctx, earlier, "bob", false); !errors.Is(err, ErrPeriodCloseBackward) {
+		t.Fatalf("перенос назад без force: %v, want ErrPeriodCloseBackward", err)
+	}
+	if current, _ := s.GetPeriodClose(ctx); current == nil || !current.ClosedThrough.Equal(through) || current.ClosedBy != "alice" {
+		t.Errorf("закрытие после отказа: %+v, want прежнее", current)
+	}
+	// Повторное закрытие по той же дате - не перенос назад.
+	if p, err := s.ClosePeriod(ctx, through, "bob", false); err != nil || p.Forced {
+		t.Errorf("повторное закрытие по той же дате: %+v, %v", p, err)
+	}
+
+	// С force период снова открывается, и закрытие отмечается принудительным.
+	p, err = s.ClosePeriod(ctx, earlier, "bob", true)
+	if err != nil {
+		t.Fatalf("перенос назад с force: %v", err)
+	}
+	if !p.Forced || p.ClosedBy != "bob" || !p.ClosedThrough.Equal(earlier) {
+		t.Errorf("принудительное закрытие %+v, want forced по %s от bob", p, earlier)
+	}
+	if current, _ := s.GetPeriodClose(ctx); current == nil || !current.Forced || !current.ClosedThrough.Equal(earlier) {
+		t.Errorf("действующее закрытие %+v, want принудительное по %s", current, earlier)
+	}
+	if _, err := s.AcknowledgeTransaction(ctx, closed.ID, recipient); err != nil {
+		t.Errorf("подтверждение после открытия периода: %v", err)
+	}
+	if err := s.AddTransactionTag(ctx, closed.ID, "audit", "bob"); err != nil {
+		t.Errorf("тег после открытия периода: %v", err)
+	}
+
+	// force при переносе вперёд не отмечает закрытие принудительным.
+	if p, err := s.ClosePeriod(ctx, through, "carol", true); err != nil || p.Forced {
+		t.Errorf("перенос вперёд с force: %+v, %v, want без отметки forced", p, err)
+	}
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go-payments/internal/models"
+	"go-payments/internal/ulid"
+)
+
+func TestTransactionPublicID(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	sender, recipient := testAddress(1), testAddress(2)
+	createTestWallet(t, s, sender, 100)
+	createTestWallet(t, s, recipient, 0)
+
+	// Генератор с фиксированными часами и нулевой случайной частью делает
+	// public_id предсказуемым.
+	clock := time.UnixMilli(1469918176385)
+	s.SetIDGenerator(ulid.NewGenerator(func() time.Time { return clock }, bytes.NewReader(make([]byte, 100))))
+
+	first, err := s.Execute(ctx, models.Transfer{From: sender, To: recipient, Amount: 10})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	second, err := s.Execute(ctx, models.Transfer{From: sender, To: recipient, Amount: 10})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if want := "01ARYZ6S41" + strings.Repeat("0", 16); first.PublicID != want {
+		t.Errorf("public_id первой транзакции %s, want %s", first.PublicID, want)
+	}
+	if second.PublicID <= first.PublicID {
+		t.Errorf("public_id %s не больше предыдущего %s", second.PublicID, first.PublicID)
+	}
+
+	// Поиск по public_id возвращает порядковый номер, по номеру - тот же public_id.
+	for _, tx := range []*models.Transaction{first, second} {
+		id, err := s.TransactionIDByPublicID(ctx, tx.PublicID)
+		if err != nil || id != tx.ID {
+			t.Errorf("TransactionIDByPublicID(%s) = %d, %v, want %d", tx.PublicID, id, err, tx.ID)
+		}
+		got, err := s.GetTransaction(ctx, tx.ID)
+		if err != nil || got.PublicID != tx.PublicID {
+			t.Errorf("GetTransaction(%d): %+v, %v, want public_id %s", tx.ID, got, err, tx.PublicID)
+		}
+	}
+	if _, err := s.TransactionIDByPublicID(ctx, "01ARYZ6S41ZZZZZZZZZZZZZZZZ"); !errors.Is(err, ErrTxNotFound) {
+		t.Errorf("неизвестный public_id: %v, want ErrTxNotFound", err)
+	}
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go-payments/internal/redact"
+)
+
+// PurgeWallet безвозвратно удаляет архивный кошелёк с нулевым балансом, его правила
+// порога баланса и ключи идемпотентности его переводов, оставляя транзакции в истории.
+// Адрес записывается в wallet_tombstones, чтобы проверки целостности отличали
+// удалённые кошельки от несуществовавших. Если на кошелёк ссылаются незавершённые
+// выводы средств или открытые запросы на оплату, возвращает ErrWalletReferenced:
+// после удаления их нельзя было бы завершить.
+func (s *Storage) PurgeWallet(ctx context.Context, address string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return internalError(fmt.Errorf("не удалось начать транзакцию: %w", err))
+	}
+	defer tx.Rollback()
+
+	var archived, empty bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT archived, balance = 0 FROM wallets WHERE address = $1 FOR UPDATE", address).Scan(&archived, &empty)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrWalletNotFound
+		}
+		return internalError(fmt.Errorf("ошибка получения кошелька %s: %w", redact.Address(address), err))
+	}
+	if !archived {
+		return ErrWalletNotArchived
+	}
+	if !empty {
+		return ErrWalletNotEmpty
+	}
+	// Строка кошелька заблокирована, поэтому новый вывод средств не появится до
+	// фиксации удаления: его перевод ждёт блокировки и затем не найдёт отправителя.
+	var referenced bool
+	err = tx.QueryRowContext(ctx, `
+    SELECT EXISTS (SELECT 1 FROM withdrawals WHERE wallet = $1 AND status IN ('pending', 'review'))
+        OR EXISTS (SELECT 1 FROM payment_requests
+                   WHERE to_address = $1 AND status = 'open' AND expires_at > (clock_timestamp() AT TIME ZONE 'UTC'))`,
+		address).Scan(&referenced)
+	if err != nil {
+		return internalError(fmt.Errorf("ошибка проверки ссылок на кошелёк %s: %w", redact.Address(address), err))
+	}
+	if referenced {
+		return ErrWalletReferenced
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM wallets WHERE address = $1", address); err != nil {
+		return internalError(fmt.Errorf("ошибка удаления кошелька %s: %w", redact.Address(address), err))
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM balance_thresholds WHERE wallet = $1", address); err != nil {
+		return internalError(fmt.Errorf("ошибка удаления правил порога баланса кошелька %s: %w", redact.Address(address), err))
+	}
+	// Повтор перевода по ключу удалённого кошелька выполняет его заново и получает
+	// ошибку отсутствующего кошелька, а не успешный ответ о переводе, которого
+	// кошелёк уже не может подтвердить.
+	_, err = tx.ExecContext(ctx, `
+    DELETE FROM idempotency_keys
+    WHERE transaction_id IN (SELECT id FROM transactions WHERE from_address = $1 OR to_address = $1)`, address)
+	if err != nil {
+		return internalError(fmt.Errorf("ошибка удаления ключей идемпотентности кошелька %s: %w", redact.Address(address), err))
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO wallet_tombstones (address) VALUES ($1) ON CONFLICT (address) DO UPDATE SET purged_at = CURRENT_TIMESTAMP", address)
+	if err != nil {
+		return internalError(fmt.Errorf("ошибка записи надгробия кошелька %s: %w", redact.Address(address), err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return internalError(fmt.Errorf("не удалось зафиксировать транзакцию: %w", err))
+	}
+	return nil
+}
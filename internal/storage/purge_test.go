@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-payments/internal/models"
+)
+
+// archiveTestWallet отмечает кошелёк address архивным.
+func archiveTestWallet(t *testing.T, s *Storage, address string) {
+	t.Helper()
+	if _, err := s.db.Exec("UPDATE wallets SET archived = TRUE WHERE address = $1", address); err != nil {
+		t.Fatalf("архивирование кошелька %s: %v", address, err)
+	}
+}
+
+func TestPurgeWalletKeepsHistory(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	alice, bob := testAddress(1), testAddress(2)
+	createTestWallet(t, s, alice, 100)
+	createTestWallet(t, s, bob, 0)
+
+	if _, err := s.Execute(ctx, models.Transfer{From: alice, To: bob, Amount: 10, IdempotencyKey: "to-bob"}); err != nil {
+		t.Fatalf("перевод alice -> bob: %v", err)
+	}
+	if _, err := s.Execute(ctx, models.Transfer{From: bob, To: alice, Amount: 10, IdempotencyKey: "to-alice"}); err != nil {
+		t.Fatalf("перевод bob -> alice: %v", err)
+	}
+	archiveTestWallet(t, s, bob)
+
+	if err := s.PurgeWallet(ctx, bob); err != nil {
+		t.Fatalf("PurgeWallet: %v", err)
+	}
+
+	if n := countRows(t, s, "SELECT COUNT(*) FROM idempotency_keys"); n != 0 {
+		t.Errorf("после удаления осталось ключей идемпотентности: %d, want 0", n)
+	}
+	wallets, err := s.GetWalletsByAddress(ctx, []string{alice, bob})
+	if err != nil {
+		t.Fatalf("GetWalletsByAddress: %v", err)
+	}
+	if _, ok := wallets[bob]; ok {
+		t.Errorf("удалённый кошелёк всё ещё возвращается")
+	}
+
+	transactions, _, err := s.GetLastTransactions(ctx, 10, models.TransactionFilter{Address: alice})
+	if err != nil {
+		t.Fatalf("GetLastTransactions: %v", err)
+	}
+	if len(transactions) != 2 {
+		t.Fatalf("история alice: %d транзакций, want 2", len(transactions))
+	}
+	for _, tr := range transactions {
+		if tr.From != bob && tr.To != bob {
+			t.Errorf("транзакция %d не связана с удалённым кошельком: %s -> %s", tr.ID, tr.From, tr.To)
+		}
+	}
+
+	now := time.Now().UTC()
+	statement, err := s.GetStatement(ctx, alice, now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetStatement: %v", err)
+	}
+	if len(statement.Transactions) != 2 || statement.ClosingBalance != 100 {
+		t.Errorf("выписка alice: %d транзакций, итоговый баланс %v, want 2 и 100",
+			len(statement.Transactions), statement.ClosingBalance)
+	}
+
+	// Повтор перевода по удалённому ключу выполняется заново и не находит кошелёк.
+	_, err = s.Execute(ctx, models.Transfer{From: bob, To: alice, Amount: 10, IdempotencyKey: "to-alice"})
+	var txErr *TransactionError
+	if !errors.As(err, &txErr) || txErr.Code != CodeSenderNotFound {
+		t.Errorf("повтор перевода удалённого кошелька: %v, want CodeSenderNotFound", err)
+	}
+}
+
+func TestPurgeWalletRefusesOpenWithdrawal(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	wallet, clearing := testAddress(1), testAddress(2)
+	createTestWallet(t, s, wallet, 10)
+	createTestWallet(t, s, clearing, 0)
+	if err := s.SetWalletSystem(ctx, clearing, true, false); err != nil {
+		t.Fatalf("SetWalletSystem: %v", err)
+	}
+
+	w, err := s.CreateWithdrawal(ctx, models.Withdrawal{Wallet: wallet, ClearingWallet: clearing, Amount: 10}, "")
+	if err != nil {
+		t.Fatalf("CreateWithdrawal: %v", err)
+	}
+	archiveTestWallet(t, s, wallet)
+
+	if err := s.PurgeWallet(ctx, wallet); !errors.Is(err, ErrWalletReferenced) {
+		t.Fatalf("удаление кошелька с незавершённым выводом: %v, want ErrWalletReferenced", err)
+	}
+
+	if _, err := s.ConfirmWithdrawal(ctx, w.ID); err != nil {
+		t.Fatalf("ConfirmWithdrawal: %v", err)
+	}
+	if err := s.PurgeWallet(ctx, wallet); err != nil {
+		t.Errorf("удаление кошелька после подтверждения вывода: %v", err)
+	}
+}
+
+func TestPurgeWalletRefusesOpenPaymentRequest(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	wallet := testAddress(1)
+	createTestWallet(t, s, wallet, 0)
+	archiveTestWallet(t, s, wallet)
+
+	pr, err := s.CreatePaymentRequest(ctx, models.PaymentRequest{To: wallet, Amount: 5, ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("CreatePaymentRequest: %v", err)
+	}
+	if err := s.PurgeWallet(ctx, wallet); !errors.Is(err, ErrWalletReferenced) {
+		t.Fatalf("удаление кошелька с открытым запросом на оплату: %v, want ErrWalletReferenced", err)
+	}
+
+	// Истёкший запрос оплатить нельзя, поэтому он удалению не мешает.
+	if _, err := s.db.Exec("UPDATE payment_requests SET expires_at = expires_at - INTERVAL '2 hours' WHERE token = $1", pr.Token); err != nil {
+		t.Fatalf("истечение запроса на оплату: %v", err)
+	}
+	if err := s.PurgeWallet(ctx, wallet); err != nil {
+		t.Errorf("удаление кошелька с истёкшим запросом на оплату: %v", err)
+	}
+}
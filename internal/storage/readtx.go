@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Querier - запросы на чтение, общие для *sql.DB и *sql.Tx.
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// WithReadTx выполняет fn в транзакции только для чтения с уровнем изоляции
+// REPEATABLE READ: все запросы fn видят один снимок базы данных, поэтому ответ,
+// собранный из нескольких запросов, согласован даже при параллельных переводах.
+// Ошибка fn возвращается как есть.
+func (s *Storage) WithReadTx(ctx context.Context, fn func(q Querier) error) error {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return internalError(fmt.Errorf("не удалось начать транзакцию: %w", err))
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return internalError(fmt.Errorf("не удалось завершить транзакцию чтения: %w", err))
+	}
+	return nil
+}
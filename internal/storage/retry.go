@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-payments/internal/models"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// Максимальное количество попыток перевода при конфликтах параллельных переводов.
+	maxExecuteAttempts = 3
+	// Пауза перед повтором; растёт линейно с номером попытки.
+	executeRetryBackoff = 20 * time.Millisecond
+)
+
+// Коды PostgreSQL, при которых транзакцию можно безопасно повторить.
+var retryableCodes = map[pq.ErrorCode]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+}
+
+// ExecStats - сведения о выполнении Execute: количество попыток, затраченное время,
+// был ли перевод повтором по ключу идемпотентности и какие правила порога баланса
+// он переключил.
+type ExecStats struct {
+	Attempts  int
+	Elapsed   time.Duration
+	Replayed  bool
+	Crossings []models.ThresholdCrossing
+}
+
+type execStatsKey struct{}
+
+// ExecStatsFrom возвращает сведения, переданные в контекст через WithExecStats, или nil.
+func ExecStatsFrom(ctx context.Context) *ExecStats {
+	stats, _ := ctx.Value(execStatsKey{}).(*ExecStats)
+	return stats
+}
+
+// WithExecStats возвращает контекст, в котором Execute заполнит stats.
+func WithExecStats(ctx context.Context, stats *ExecStats) context.Context {
+	return context.WithValue(ctx, execStatsKey{}, stats)
+}
+
+// isRetryable сообщает, вызвана ли ошибка конфликтом, который исчезает при повторе.
+func isRetryable(err error) bool {
+	if errors.Is(err, errIdempotencyRace) {
+		return true
+	}
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && retryableCodes[pqErr.Code]
+}
+
+// QueryCanceled сообщает, что PostgreSQL отменил выполняемый запрос
+// (query_canceled). lib/pq так отменяет запрос, контекст которого завершился, и
+// возвращает эту ошибку, а не ошибку контекста.
+func QueryCanceled(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "57014"
+}
+
+// sleepContext ждёт d или отмены ctx.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"database/sql"
+	"log"
+)
+
+// skipRow записывает в лог строку листинга, которую не удалось прочитать, и
+// возвращает 1 для счётчика пропущенных строк. Идентификатор строки (первая
+// колонка) читается повторным Scan текущей строки в нетипизированные значения.
+func skipRow(rows *sql.Rows, table string, err error) int {
+	log.Printf("пропущена строка %s (id=%v): %v", table, rowID(rows), err)
+	return 1
+}
+
+func rowID(rows *sql.Rows) any {
+	cols, err := rows.Columns()
+	if err != nil || len(cols) == 0 {
+		return nil
+	}
+	dest := make([]any, len(cols))
+	for i := range dest {
+		dest[i] = new(any)
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil
+	}
+	return *dest[0].(*any)
+}
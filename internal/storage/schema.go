@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go-payments/internal/models"
+)
+
+// Конструкции миграций, из которых выводится ожидаемая схема. Миграции пишутся
+// в едином стиле (IF NOT EXISTS / IF EXISTS), поэтому разбора регулярными
+// выражениями достаточно.
+var (
+	reCreateTable = regexp.MustCompile(`(?is)CREATE TABLE IF NOT EXISTS (\w+) \((.*?)\n\s*\);`)
+	reAddColumn   = regexp.MustCompile(`(?i)ALTER TABLE (\w+) ADD COLUMN IF NOT EXISTS (\w+)`)
+	reDropColumn  = regexp.MustCompile(`(?i)ALTER TABLE (\w+) DROP COLUMN IF EXISTS (\w+)`)
+	reCreateIndex = regexp.MustCompile(`(?i)CREATE (?:UNIQUE )?INDEX IF NOT EXISTS (\w+) ON (\w+)`)
+	reDropIndex   = regexp.MustCompile(`(?i)DROP INDEX IF EXISTS (\w+)`)
+)
+
+// Строки тела CREATE TABLE, которые описывают ограничения, а не колонки.
+var tableConstraintKeywords = map[string]bool{
+	"CHECK": true, "PRIMARY": true, "UNIQUE": true, "FOREIGN": true, "CONSTRAINT": true,
+}
+
+// schemaShape - таблицы с колонками и индексы с таблицами.
+type schemaShape struct {
+	columns map[string]map[string]bool
+	indexes map[string]string
+}
+
+// expectedSchema выводит схему, которую дают все миграции, из их текста.
+func expectedSchema() schemaShape {
+	shape := schemaShape{columns: make(map[string]map[string]bool), indexes: make(map[string]string)}
+	for _, m := range migrations {
+		for _, match := range reCreateTable.FindAllStringSubmatch(m.query, -1) {
+			table := strings.ToLower(match[1])
+			if shape.columns[table] == nil {
+				shape.columns[table] = make(map[string]bool)
+			}
+			for _, line := range strings.Split(match[2], "\n") {
+				fields := strings.Fields(line)
+				if len(fields) == 0 || tableConstraintKeywords[strings.ToUpper(fields[0])] {
+					continue
+				}
+				shape.columns[table][strings.ToLower(fields[0])] = true
+			}
+		}
+		for _, match := range reAddColumn.FindAllStringSubmatch(m.query, -1) {
+			if cols := shape.columns[strings.ToLower(match[1])]; cols != nil {
+				cols[strings.ToLower(match[2])] = true
+			}
+		}
+		for _, match := range reDropColumn.FindAllStringSubmatch(m.query, -1) {
+			delete(shape.columns[strings.ToLower(match[1])], strings.ToLower(match[2]))
+		}
+		for _, match := range reCreateIndex.FindAllStringSubmatch(m.query, -1) {
+			shape.indexes[strings.ToLower(match[1])] = strings.ToLower(match[2])
+		}
+		for _, match := range reDropIndex.FindAllStringSubmatch(m.query, -1) {
+			delete(shape.indexes, strings.ToLower(match[1]))
+		}
+	}
+	return shape
+}
+
+// CheckSchema сравнивает фактические колонки и индексы таблиц из миграций
+// с ожидаемыми. Индексы первичных ключей (`*_pkey`) не учитываются, а таблицы,
+// не упомянутые в миграциях, не проверяются.
+func (s *Storage) CheckSchema(ctx context.Context) (*models.SchemaDiff, error) {
+	expected := expectedSchema()
+
+	actualColumns := make(map[string]map[string]bool)
+	rows, err := s.db.QueryContext(ctx, `
+    SELECT table_name, column_name FROM information_schema.columns
+    WHERE table_schema = current_schema()`)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать колонки схемы: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования колонки схемы: %w", err)
+		}
+		if actualColumns[table] == nil {
+			actualColumns[table] = make(map[string]bool)
+		}
+		actualColumns[table][column] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по колонкам схемы: %w", err)
+	}
+
+	actualIndexes := make(map[string]string)
+	rows, err = s.db.QueryContext(ctx, "SELECT indexname, tablename FROM pg_indexes WHERE schemaname = current_schema()")
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать индексы схемы: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var index, table string
+		if err := rows.Scan(&index, &table); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования индекса схемы: %w", err)
+		}
+		actualIndexes[index] = table
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по индексам схемы: %w", err)
+	}
+
+	diff := &models.SchemaDiff{}
+	for table, columns := range expected.columns {
+		actual, ok := actualColumns[table]
+		if !ok {
+			diff.MissingTables = append(diff.MissingTables, table)
+			continue
+		}
+		for column := range columns {
+			if !actual[column] {
+				diff.MissingColumns = append(diff.MissingColumns, table+"."+column)
+			}
+		}
+		for column := range actual {
+			if !columns[column] {
+				diff.UnexpectedColumns = append(diff.UnexpectedColumns, table+"."+column)
+			}
+		}
+	}
+	for index, table := range expected.indexes {
+		if _, ok := actualIndexes[index]; !ok {
+			diff.MissingIndexes = append(diff.MissingIndexes, table+"."+index)
+		}
+	}
+	for index, table := range actualIndexes {
+		if _, ok := expected.columns[table]; !ok || strings.HasSuffix(index, "_pkey") {
+			continue
+		}
+		if _, ok := expected.indexes[index]; !ok {
+			diff.UnexpectedIndexes = append(diff.UnexpectedIndexes, table+"."+index)
+		}
+	}
+
+	for _, list := range [][]string{diff.MissingTables, diff.MissingColumns, diff.UnexpectedColumns, diff.MissingIndexes, diff.UnexpectedIndexes} {
+		sort.Strings(list)
+	}
+	return diff, nil
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Ping проверяет доступность базы данных.
+func (s *Storage) Ping(ctx context.Context) error {
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("%w: %v", ErrConnectDatabase, err)
+	}
+	return nil
+}
+
+// GetSetting возвращает значение настройки key. Второй результат равен false,
+// если настройка ещё не сохранялась.
+func (s *Storage) GetSetting(ctx context.Context, key string) (string, bool, error) {
+	var value string
+	err := s.db.QueryRowContext(ctx, "SELECT value FROM settings WHERE key = $1", key).Scan(&value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("ошибка получения настройки %s: %w", key, err)
+	}
+	return value, true, nil
+}
+
+// SetSetting сохраняет значение настройки key, перезаписывая предыдущее.
+func (s *Storage) SetSetting(ctx context.Context, key, value string) error {
+	_, err := s.db.ExecContext(ctx, `
+    INSERT INTO settings (key, value, updated_at) VALUES ($1, $2, CURRENT_TIMESTAMP)
+    ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at`,
+		key, value)
+	if err != nil {
+		return fmt.Errorf("не удалось сохранить настройку %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go-payments/internal/models"
+)
+
+// SnapshotWallets передаёт fn все кошельки в порядке адресов. Кошельки читаются
+// в одной транзакции WithReadTx, поэтому снимок согласован даже при
+// параллельных переводах. Ошибка fn прерывает чтение и возвращается как есть.
+func (s *Storage) SnapshotWallets(ctx context.Context, fn func(models.Wallet) error) error {
+	return s.WithReadTx(ctx, func(q Querier) error {
+		rows, err := q.QueryContext(ctx,
+			"SELECT "+walletColumns+" FROM wallets ORDER BY address")
+		if err != nil {
+			return internalError(fmt.Errorf("не удалось получить кошельки для снимка: %w", err))
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			w, err := scanWallet(rows)
+			if err != nil {
+				return internalError(fmt.Errorf("ошибка сканирования кошелька для снимка: %w", err))
+			}
+			if err := fn(w); err != nil {
+				return err
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return internalError(fmt.Errorf("ошибка при итерации по wallets: %w", err))
+		}
+		return nil
+	})
+}
+
+// RestoreWallets создаёт кошельки снимка одной транзакцией. Восстановление
+// возможно только в пустую таблицу кошельков, иначе возвращается ErrWalletsExist;
+// таблица блокируется до фиксации, чтобы параллельно не появились другие кошельки.
+// Снимок должен быть предварительно проверен через Validate.
+func (s *Storage) RestoreWallets(ctx context.Context, wallets []models.Wallet) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return internalError(fmt.Errorf("не удалось начать транзакцию: %w", err))
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "LOCK TABLE wallets IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		return internalError(fmt.Errorf("не удалось заблокировать таблицу кошельков: %w", err))
+	}
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM wallets)").Scan(&exists); err != nil {
+		return internalError(fmt.Errorf("ошибка проверки таблицы кошельков: %w", err))
+	}
+	if exists {
+		return ErrWalletsExist
+	}
+
+	for start := 0; start < len(wallets); start += walletInsertBatch {
+		batch := wallets[start:min(start+walletInsertBatch, len(wallets))]
+		values := make([]string, len(batch))
+		args := make([]any, 0, len(batch)*10)
+		for i, w := range batch {
+			n := len(args)
+			values[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, NULLIF($%d, ''), $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9, n+10)
+			args = append(args, w.Address, float64(w.Balance), w.Frozen, w.Archived, w.Label, w.MaxBalance, w.System, w.NonReceivable, w.AutoCreated, w.CreatedAt)
+		}
+		query := "INSERT INTO wallets (address, balance, frozen, archived, label, max_balance, system, non_receivable, auto_created, created_at) VALUES " + strings.Join(values, ", ") +
+			" ON CONFLICT (address) DO NOTHING"
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return internalError(fmt.Errorf("ошибка восстановления кошельков: %w", err))
+		}
+		// Снимок применяется целиком: если адрес уже занят, откатывается всё.
+		inserted, err := result.RowsAffected()
+		if err != nil {
+			return internalError(fmt.Errorf("ошибка восстановления кошельков: %w", err))
+		}
+		if int(inserted) != len(batch) {
+			return ErrWalletsExist
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return internalError(fmt.Errorf("не удалось зафиксировать транзакцию: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-payments/internal/models"
+)
+
+// GetStatement возвращает выписку по кошельку за период [from, to): баланс на начало
+// и конец периода, успешные транзакции периода с нарастающим балансом и итоги.
+// Баланс на момент времени восстанавливается из текущего баланса и успешных
+// транзакций после этого момента. Выписка отмечается окончательной (Final), если
+// период целиком входит в закрытый учётный период. Все запросы выполняются в одной
+// транзакции WithReadTx, поэтому выписка согласована даже при параллельных переводах.
+func (s *Storage) GetStatement(ctx context.Context, address string, from, to time.Time) (*models.Statement, error) {
+	var statement *models.Statement
+	err := s.WithReadTx(ctx, func(q Querier) error {
+		var err error
+		statement, err = getStatement(ctx, q, address, from.UTC(), to.UTC())
+		return err
+	})
+	return statement, err
+}
+
+func getStatement(ctx context.Context, q Querier, address string, from, to time.Time) (*models.Statement, error) {
+	// Баланс на начало периода читается как текст, чтобы передать его в следующие
+	// запросы без потери точности DECIMAL.
+	var opening string
+	err := q.QueryRowContext(ctx, `
+    SELECT w.balance - COALESCE((
+        SELECT SUM(CASE WHEN t.to_address = w.address THEN t.amount ELSE -t.amount END)
+        FROM transactions t
+        WHERE t.status = $3 AND (t.from_address = w.address OR t.to_address = w.address) AND t.timestamp >= $2
+    ), 0)
+    FROM wallets w
+    WHERE w.address = $1`, address, from, models.StatusSuccess).Scan(&opening)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWalletNotFound
+		}
+		return nil, internalError(fmt.Errorf("ошибка расчёта баланса на начало периода: %w", err))
+	}
+
+	statement := models.Statement{
+		Address:      address,
+		PeriodStart:  from,
+		PeriodEnd:    to,
+		Transactions: []models.StatementEntry{},
+	}
+
+	err = q.QueryRowContext(ctx, `
+    SELECT $4::numeric,
+        $4::numeric + COALESCE(SUM(CASE WHEN to_address = $1 THEN amount ELSE -amount END), 0),
+        COALESCE(SUM(CASE WHEN to_address = $1 THEN amount ELSE 0 END), 0),
+        COALESCE(SUM(CASE WHEN from_address = $1 THEN amount ELSE 0 END), 0)
+    FROM transactions
+    WHERE status = $5 AND (from_address = $1 OR to_address = $1) AND timestamp >= $2 AND timestamp < $3`,
+		address, from, to, opening, models.StatusSuccess).
+		Scan(&statement.OpeningBalance, &statement.ClosingBalance, &statement.TotalIn, &statement.TotalOut)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("ошибка расчёта итогов выписки: %w", err))
+	}
+
+	err = q.QueryRowContext(ctx, `
+    SELECT COALESCE($1 <= (SELECT closed_through FROM period_closes ORDER BY id DESC LIMIT 1), FALSE)`, to).
+		Scan(&statement.Final)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("ошибка проверки закрытия периода выписки: %w", err))
+	}
+
+	rows, err := q.QueryContext(ctx, `
+    SELECT id, public_id, from_address, to_address, amount, timestamp, status, memo, reference, metadata, COALESCE(group_id, ''),
+        $4::numeric + SUM(CASE WHEN to_address = $1 THEN amount ELSE -amount END) OVER (ORDER BY timestamp, id)
+    FROM transactions
+    WHERE status = $5 AND (from_address = $1 OR to_address = $1) AND timestamp >= $2 AND timestamp < $3
+    ORDER BY timestamp, id`,
+		address, from, to, opening, models.StatusSuccess)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("не удалось получить транзакции выписки: %w", err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.StatementEntry
+		var metadata []byte
+		if err := rows.Scan(&e.ID, &e.PublicID, &e.From, &e.To, &e.Amount, &e.Timestamp, &e.Status, &e.Memo, &e.Reference, &metadata, &e.GroupID, &e.RunningBalance); err != nil {
+			return nil, internalError(fmt.Errorf("ошибка сканирования строки выписки: %w", err))
+		}
+		if err := json.Unmarshal(metadata, &e.Metadata); err != nil {
+			return nil, internalError(fmt.Errorf("ошибка разбора metadata транзакции %d: %w", e.ID, err))
+		}
+		statement.Transactions = append(statement.Transactions, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, internalError(fmt.Errorf("ошибка при итерации по транзакциям выписки: %w", err))
+	}
+
+	return &statement, nil
+}
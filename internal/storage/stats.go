@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-payments/internal/models"
+)
+
+// GetVolumeSeries возвращает количество и сумму транзакций по интервалам bucket
+// в диапазоне [since, until). Интервалы без транзакций заполняются нулями, чтобы
+// на графиках не было разрывов. Пустой status означает транзакции с любым статусом,
+// кроме заметок zero_note: они не перемещают средства и в объём не входят.
+func (s *Storage) GetVolumeSeries(ctx context.Context, since, until time.Time, bucket models.BucketSize, status models.TransactionStatus) ([]models.VolumeBucket, error) {
+	step := bucket.Duration()
+	if step == 0 {
+		return nil, fmt.Errorf("неизвестный размер интервала: %q", bucket)
+	}
+
+	since = since.UTC()
+	until = until.UTC()
+
+	query := `
+    SELECT date_trunc($1, timestamp) AS bucket_start, COUNT(*), COALESCE(SUM(amount), 0)
+    FROM transactions
+    WHERE timestamp >= $2 AND timestamp < $3 AND ($4 = '' OR status = $4) AND status <> 'zero_note'
+    GROUP BY bucket_start
+    ORDER BY bucket_start`
+
+	rows, err := s.db.QueryContext(ctx, query, string(bucket), since, until, string(status))
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить объём транзакций: %w", err)
+	}
+	defer rows.Close()
+
+	found := make(map[time.Time]models.VolumeBucket)
+	for rows.Next() {
+		var b models.VolumeBucket
+		if err := rows.Scan(&b.BucketStart, &b.Count, &b.TotalAmount); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки объёма транзакций: %w", err)
+		}
+		b.BucketStart = b.BucketStart.UTC()
+		found[b.BucketStart] = b
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по объёму транзакций: %w", err)
+	}
+
+	series := []models.VolumeBucket{}
+	for start := since.Truncate(step); start.Before(until); start = start.Add(step) {
+		b, ok := found[start]
+		if !ok {
+			b = models.VolumeBucket{BucketStart: start}
+		}
+		series = append(series, b)
+	}
+	return series, nil
+}
+
+// GetBalanceChanges возвращает для кошельков, участвовавших в успешных переводах
+// начиная с since, текущий баланс и чистое изменение баланса за этот период.
+// Пустой address означает все кошельки.
+func (s *Storage) GetBalanceChanges(ctx context.Context, since time.Time, address string) ([]models.BalanceChange, error) {
+	query := `
+    SELECT w.address, w.balance,
+        COALESCE(SUM(CASE WHEN t.to_address = w.address THEN t.amount ELSE 0 END), 0) -
+        COALESCE(SUM(CASE WHEN t.from_address = w.address THEN t.amount ELSE 0 END), 0)
+    FROM wallets w
+    JOIN transactions t ON t.from_address = w.address OR t.to_address = w.address
+    WHERE t.status = $1 AND t.timestamp >= $2 AND ($3 = '' OR w.address = $3)
+    GROUP BY w.address, w.balance`
+
+	rows, err := s.db.QueryContext(ctx, query, string(models.StatusSuccess), since.UTC(), address)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить изменения балансов: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []models.BalanceChange
+	for rows.Next() {
+		var c models.BalanceChange
+		if err := rows.Scan(&c.Address, &c.Balance, &c.Net); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки изменения баланса: %w", err)
+		}
+		changes = append(changes, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по изменениям балансов: %w", err)
+	}
+	return changes, nil
+}
@@ -4,270 +4,843 @@ storage предоставляет собой слой для взаимодей
 Основной тип в этом пакете - Storage, который содержит в себе пул подключений к базе данных
 и предоставляет методы для работы с ней.
 
+Листинги (GetLastTransactions, GetWallets, ListWallets) не прерываются на строке, которую
+не удалось прочитать: она записывается в лог и пропускается, а количество пропущенных
+строк возвращается вызывающему коду.
+
 Функции и методы:
   - New: Создает новый экземпляр Storage и устанавливает соединение с базой данных.
-  - Init: Инициализирует базу данных, создавая необходимые таблицы (`wallets`, `transactions`).
-    Если кошельки отсутствуют, создает 10 кошельков по умолчанию с начальным балансом.
+    Ошибки подключения различают недоступный хост (ErrDatabaseUnreachable) и неверные
+    учётные данные (ErrAuthFailed).
+  - Init: Инициализирует базу данных, применяя версионированные миграции схемы
+    (таблицы `wallets`, `transactions`, `settings`), учитываемые в `schema_migrations`.
+    Если кошельки отсутствуют, создает 10 кошельков по умолчанию с начальным балансом
+    одной транзакцией под advisory-блокировкой, чтобы одновременно запущенные экземпляры
+    не создали их дважды.
+  - CreateWallets: Создаёт кошельки многострочными INSERT пачками по 500 строк с отчётом
+    о прогрессе; отмена контекста останавливает создание между пачками. Адреса создаются
+    в схеме, заданной SetAddressScheme (по умолчанию hex64). Занятые адреса пропускаются
+    (ON CONFLICT DO NOTHING) и заменяются новыми; их количество возвращается вызывающему.
   - GetWalletBalance: Возвращает информацию о кошельке (адрес и баланс) по его адресу.
-  - GetLastTransactions: Получает N последних транзакций из базы данных.
-  - SendMoney: Осуществляет перевод средств с одного кошелька на другой.
-    Эта операция выполняется в рамках одной транзакции для обеспечения атомарности.
+  - GetLastTransactions: Получает N последних транзакций из базы данных в стабильном порядке
+    (timestamp, id) по убыванию с необязательными фильтрами по метаданным (оператор включения
+    JSONB), паре адресов в любом направлении, получателю, подтверждению, тегу, статусу и периоду,
+    а также keyset-курсором. Заметки zero_note отбираются только по статусу или с IncludeNotes.
+  - AddTransactionTag, RemoveTransactionTag, ListTransactionTags: Назначают и снимают теги
+    транзакций в таблице `transaction_tags` (обе операции идемпотентны) и перечисляют
+    теги с количеством транзакций.
+  - AcknowledgeTransaction: Отмечает успешный входящий перевод обработанным получателем
+    (колонка `acknowledged_at`); повторное подтверждение не меняет время.
+  - AcknowledgeTransactions, AcknowledgeTransactionsThrough: Подтверждают входящие переводы
+    списком идентификаторов (с результатом по каждому) или все успешные переводы до
+    идентификатора включительно одним UPDATE.
+  - GetIncomingCursor, SetIncomingCursor: Читают и сдвигают курсор потребителя входящих
+    переводов (таблица `incoming_cursors`, ключ - кошелёк и имя потребителя). Курсор только
+    растёт, поэтому повтор и запоздавшая запись не откатывают его.
+  - Execute: Осуществляет перевод средств models.Transfer с одного кошелька на другой.
+    Эта операция выполняется в рамках одной транзакции для обеспечения атомарности;
+    строки обоих кошельков блокируются в порядке адресов до проверки баланса, а
+    конфликты параллельных переводов повторяются (см. ExecStats).
     Она включает в себя проверку баланса отправителя, обновление балансов обоих
-    кошельков и запись информации о транзакции.
+    кошельков и запись информации о транзакции, которую возвращает вызывающему коду.
+    Ключ идемпотентности перевода записывается в той же транзакции; повтор с ним
+    возвращает уже записанную транзакцию. С SetAutoCreateRecipients перевод на
+    неизвестный адрес создаёт кошелёк получателя в той же транзакции (`auto_created`).
+  - SetIDGenerator: Задаёт генератор публичных идентификаторов (ULID) транзакций; каждая
+    записанная транзакция, включая отклонённые, получает `public_id`.
+  - TransactionIDByPublicID: Возвращает внутренний идентификатор транзакции по `public_id`.
+  - RecordZeroNote: Записывает нулевую транзакцию-заметку (статус zero_note) между
+    существующими кошельками, не меняя балансов.
+  - GetIdempotencyKey, DeleteIdempotencyKey: Показывают и удаляют ключ идемпотентности.
+  - GetGroupSummary: Считает в SQL итоги группы переводов (group_id) по статусам.
+  - GetPeriodClose, ClosePeriod: Показывают и переносят закрытие учётного периода
+    (таблица `period_closes`); подтверждение и теги транзакций закрытого периода
+    отклоняются с ErrPeriodClosed.
+  - StorageInfo: Возвращает размер базы данных и размеры таблиц и индексов текущей схемы.
+  - Fsck: Проверяет согласованность данных пачками по ключу и передаёт находки вызывающему.
+  - SendMoney: Устаревшая обёртка над Execute с позиционными аргументами.
   - GetWallets: Получает N кошельков с балансом
+  - GetWalletsByAddress: Возвращает кошельки по списку адресов одним запросом.
+  - ListWallets: Возвращает страницу кошельков по фильтру (заморозка, архив, диапазон баланса)
+    с сортировкой и общим количеством для пагинации.
+  - WithReadTx: Выполняет несколько запросов чтения в одной транзакции REPEATABLE READ
+    только для чтения. Через неё работают составные чтения (ListWallets, GetStatement,
+    SnapshotWallets, StorageInfo), поэтому их ответы - согласованный снимок базы.
+  - GetVolumeSeries: Возвращает временной ряд количества и суммы транзакций по часам или дням.
+  - GetBalanceChanges: Возвращает текущие балансы и чистое изменение за период для кошельков
+    с успешными переводами; используется предупреждениями о падении балансов.
+  - GetWalletSummary, RefreshWalletSummary: Читают и пересчитывают материализованное
+    представление `wallet_summary` со сводкой по кошелькам и распределением балансов.
+  - SnapshotWallets, RestoreWallets: Читают все кошельки согласованным снимком и
+    восстанавливают их одной транзакцией в пустую таблицу кошельков.
+  - GetStatement: Возвращает выписку по кошельку за период с балансами на начало и конец,
+    нарастающим балансом по транзакциям и итогами.
+  - GetWalletActivity: Раскладывает успешные переводы кошелька за период по дням недели
+    и часам суток в заданном часовом поясе одним сгруппированным запросом.
+  - CreateCheckpoint: Снимает балансы всех кошельков, подписывает корень дерева Меркла над
+    ними и сохраняет контрольную точку за дату; повторный вызов за ту же дату ничего не делает.
+  - GetCheckpoint, GetCheckpointProof: Возвращают контрольную точку (при необходимости с
+    балансами) и доказательство включения баланса кошелька в неё.
+  - GetWalletByLabel, SetWalletLabel: Ищут кошелёк по уникальной метке и назначают
+    или снимают метку. Транзакции хранят адреса, поэтому смена метки не меняет историю.
+  - SetWalletMaxBalance: Задаёт или снимает предельный баланс кошелька (`max_balance`).
+    Execute отклоняет зачисление сверх предела со статусом `failed_recipient_limit_exceeded`;
+    условие проверяется в UPDATE строки получателя, поэтому параллельные переводы не
+    превышают предел вместе.
+  - SetWalletSystem: Отмечает кошелёк системным. Execute отклоняет перевод с системного
+    кошелька и на системный кошелёк с `non_receivable` ошибкой ErrSystemWallet, не записывая
+    его, если перевод не помечен AllowSystem (внутренние операции, например консолидация).
+    GetWallets системные кошельки не возвращает, ListWallets показывает их с отметкой.
+  - CreatePaymentRequest, GetPaymentRequest, FulfillPaymentRequest: Создают запросы на
+    оплату (таблица `payment_requests`) со случайным токеном, отдают их со статусом
+    `expired` после срока и отмечают оплаченными транзакцией; оплатить запрос можно один раз.
+  - PurgeWallet: Удаляет архивный кошелёк с нулевым балансом и ключи идемпотентности его
+    переводов, сохраняя транзакции, и записывает адрес в `wallet_tombstones`. Кошелёк, на
+    который ссылаются незавершённые выводы или открытые запросы на оплату, не удаляется.
+  - AddWalletNote, ListWalletNotes, RedactWalletNote: Добавляют, перечисляют и скрывают
+    служебные заметки к кошелькам в таблице `wallet_notes`. Заметки только дополняются:
+    единственное изменение - стирание текста при скрытии.
+  - CreateBalanceThreshold, ListBalanceThresholds, GetBalanceThreshold, UpdateBalanceThreshold,
+    DeleteBalanceThreshold: Управляют правилами порога баланса кошельков (`balance_thresholds`).
+    Execute переключает правила отправителя и получателя в транзакции перевода по новым
+    балансам и возвращает переключения в ExecStats.Crossings.
+  - CreateWithdrawal, GetWithdrawal, ListWithdrawals, ConfirmWithdrawal, FailWithdrawal,
+    ReviewExpiredWithdrawals: Управляют выводами средств (`withdrawals`). Вывод списывает
+    сумму переводом на клиринговый кошелёк и записывается в его транзакции; отказ возвращает
+    сумму обратным переводом, в транзакции которого вывод отмечается `failed`. Переходы -
+    условные UPDATE по состоянию, поэтому параллельные переходы выполняются ровно один раз.
+    Сумма незавершённых выводов возвращается GetWalletBalance в PendingOut.
+  - AcquireLease, RenewLease, ReleaseLease: Управляют арендами периодических задач в таблице
+    `job_leases`, чтобы каждую задачу выполнял только один экземпляр сервиса.
+  - Ping: Проверяет доступность базы данных.
+  - PoolStats: Возвращает состояние пула подключений.
+  - GetSetting, SetSetting: Читают и сохраняют служебные настройки в таблице `settings`.
+  - SchemaVersion, LatestSchemaVersion: Возвращают применённую к базе и ожидаемую сборкой
+    версии схемы.
+  - CheckSchema: Сравнивает фактические колонки и индексы (information_schema, pg_indexes)
+    со схемой, выведенной из текста миграций, и возвращает расхождения.
 */
 package storage
 
 import (
 	"context"
-	"crypto/rand"
 	"database/sql"
-	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+	"go-payments/internal/address"
+	"go-payments/internal/config"
 	"go-payments/internal/models"
+	"go-payments/internal/money"
+	"go-payments/internal/redact"
+	"go-payments/internal/ulid"
 	"log"
-	"os"
-	"strconv"
+	"net"
+	"strings"
 	"time"
 )
 
 type Storage struct {
 	db *sql.DB
+	// Схема адресов новых кошельков (CreateWallets).
+	scheme address.Scheme
+	// Создавать ли кошелёк получателя при первом переводе на неизвестный адрес.
+	autoCreateRecipients bool
+	// Генератор публичных идентификаторов транзакций (public_id).
+	ids *ulid.Generator
 }
 
-// Создает новый экземпляр Storage и устанавливает соединение с базой данных.
-func New() (*Storage, error) {
+// SetAddressScheme задаёт схему адресов, в которой CreateWallets создаёт кошельки.
+// По умолчанию используется address.Hex64.
+func (s *Storage) SetAddressScheme(scheme address.Scheme) {
+	s.scheme = scheme
+}
 
-	_ = godotenv.Load()
+// SetAutoCreateRecipients включает создание кошелька получателя при переводе на
+// адрес, которого нет в базе. По умолчанию такой перевод завершается
+// failed_recipient_not_found.
+func (s *Storage) SetAutoCreateRecipients(enabled bool) {
+	s.autoCreateRecipients = enabled
+}
+
+// SetIDGenerator задаёт генератор публичных идентификаторов (ULID) новых
+// транзакций. По умолчанию ULID строятся по системным часам и crypto/rand.
+func (s *Storage) SetIDGenerator(ids *ulid.Generator) {
+	s.ids = ids
+}
 
-	portStr := os.Getenv("POSTGRES_PORT")
-	port, err := strconv.Atoi(portStr)
+// Создает новый экземпляр Storage и устанавливает соединение с базой данных.
+func New(cfg config.Database) (*Storage, error) {
+	db, err := sql.Open("postgres", cfg.DSN())
 	if err != nil {
-		return nil, fmt.Errorf("invalid port value: %s: %w", portStr, err)
+		return nil, fmt.Errorf("%w: %v", ErrOpenDatabase, err)
 	}
-	host := os.Getenv("POSTGRES_HOST")
-	user := os.Getenv("POSTGRES_USER")
-	password := os.Getenv("POSTGRES_PASSWORD")
-	dbname := os.Getenv("POSTGRES_DB")
 
-	if host == "" || user == "" || dbname == "" || portStr == "" {
-		return nil, fmt.Errorf("missing required environment variables")
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, classifyConnectError(err)
 	}
 
-	connectLine := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname)
+	return &Storage{db: db, scheme: address.Hex64, ids: ulid.NewGenerator(nil, nil)}, nil
+}
 
-	db, err := sql.Open("postgres", connectLine)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrOpenDatabase, err)
+// classifyConnectError различает типичные причины неудачного подключения:
+// недоступный хост и неверные учётные данные.
+func classifyConnectError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "28P01", "28000": // invalid_password, invalid_authorization_specification
+			return fmt.Errorf("%w: %w: %v", ErrConnectDatabase, ErrAuthFailed, err)
+		}
 	}
 
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrConnectDatabase, err)
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return fmt.Errorf("%w: %w: %v", ErrConnectDatabase, ErrDatabaseUnreachable, err)
 	}
 
-	return &Storage{db: db}, nil
+	return fmt.Errorf("%w: %v", ErrConnectDatabase, err)
 }
 
-// Инициализирует базу данных, создавая необходимые таблицы (`wallets`, `transactions`).
-// Если кошельки отсутствуют, создает 10 кошельков по умолчанию с начальным балансом.
-func (s *Storage) Init(ctx context.Context) error {
-	queryWallets := `
-    CREATE TABLE IF NOT EXISTS wallets (
-        address TEXT PRIMARY KEY,
-        balance DECIMAL(20, 8) NOT NULL DEFAULT 0
-    );`
+// Close закрывает пул подключений к базе данных.
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
 
-	if _, err := s.db.ExecContext(ctx, queryWallets); err != nil {
-		return fmt.Errorf("не удалось создать таблицу wallets: %w", err)
+// PoolStats возвращает состояние пула подключений; MaxOpen 0 - без ограничения.
+func (s *Storage) PoolStats() models.PoolStats {
+	stats := s.db.Stats()
+	return models.PoolStats{
+		MaxOpen: stats.MaxOpenConnections,
+		Open:    stats.OpenConnections,
+		InUse:   stats.InUse,
+		Idle:    stats.Idle,
 	}
+}
 
-	queryTransaction := `
-    CREATE TABLE IF NOT EXISTS transactions (
-        id SERIAL PRIMARY KEY,
-        from_address TEXT NOT NULL REFERENCES wallets(address),
-        to_address TEXT NOT NULL REFERENCES wallets(address),
-        amount DECIMAL(20, 8) NOT NULL,
-        timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-        status TEXT NOT NULL,
-        CHECK (from_address <> to_address)
-    );`
-
-	if _, err := s.db.ExecContext(ctx, queryTransaction); err != nil {
-		return fmt.Errorf("не удалось создать таблицу transactions: %w", err)
+// Инициализирует базу данных, применяя миграции схемы (таблицы `wallets`, `transactions`, `settings`).
+// Если кошельки отсутствуют, создает 10 кошельков по умолчанию с начальным балансом.
+func (s *Storage) Init(ctx context.Context) error {
+	if err := s.migrate(ctx); err != nil {
+		return err
 	}
 
-	var count int
-	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM wallets;").Scan(&count)
+	created, err := s.seedDefaultWallets(ctx, 10, 100.0)
 	if err != nil {
-		return fmt.Errorf("не удалось прочитать кошельки: %w", err)
+		return err
 	}
-
-	if count == 0 {
-		log.Println("кошельки не найдены, создаём 10 новых кошельков")
-		for i := 0; i < 10; i++ {
-			bytes := make([]byte, 32)
-			if _, err := rand.Read(bytes); err != nil {
-				return fmt.Errorf("не удалось сгенерировать адрес кошелька: %w", err)
-			}
-			address := hex.EncodeToString(bytes)
-
-			_, err := s.db.ExecContext(ctx, "INSERT INTO wallets (address, balance) VALUES ($1, $2)", address, 100.0)
-			if err != nil {
-				return fmt.Errorf("не удалось создать кошелёк: %w", err)
-			}
-			log.Printf("создан кошелёк: %s с балансом 100.0\n", address)
-		}
+	if len(created) > 0 {
+		log.Printf("кошельки не найдены, создано %d новых кошельков", len(created))
+	}
+	for _, address := range created {
+		log.Printf("создан кошелёк: %s с балансом 100.0\n", redact.Address(address))
 	}
 	return nil
 }
 
-// Получает баланс кошелька с адрессом address
+// Колонки кошелька в порядке scanWallet.
+const walletColumns = "address, balance, frozen, archived, COALESCE(label, ''), max_balance, system, non_receivable, auto_created, created_at"
+
+func scanWallet(row interface{ Scan(...any) error }, extra ...any) (models.Wallet, error) {
+	var (
+		w         models.Wallet
+		createdAt sql.NullTime
+	)
+	dest := append([]any{&w.Address, &w.Balance, &w.Frozen, &w.Archived, &w.Label, &w.MaxBalance,
+		&w.System, &w.NonReceivable, &w.AutoCreated, &createdAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return models.Wallet{}, err
+	}
+	if createdAt.Valid {
+		t := createdAt.Time.UTC()
+		w.CreatedAt = &t
+	}
+	return w, nil
+}
+
+// Получает баланс кошелька с адрессом address. Сумма незавершённых выводов
+// средств кошелька возвращается в PendingOut, если она не нулевая.
 func (s *Storage) GetWalletBalance(ctx context.Context, address string) (*models.Wallet, error) {
-	var wallet models.Wallet
-	query := "SELECT address, balance FROM wallets WHERE address = $1"
-	err := s.db.QueryRowContext(ctx, query, address).Scan(&wallet.Address, &wallet.Balance)
+	query := "SELECT " + walletColumns + `,
+        (SELECT COALESCE(SUM(amount), 0) FROM withdrawals WHERE wallet = $1 AND status IN ('pending', 'review'))
+    FROM wallets WHERE address = $1`
+	var pendingOut money.Amount
+	wallet, err := scanWallet(s.db.QueryRowContext(ctx, query, address), &pendingOut)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrWalletNotFound
 		}
-		return nil, fmt.Errorf("ошибка получения баланса кошелька %s: %w", address, err)
+		return nil, internalError(fmt.Errorf("ошибка получения баланса кошелька %s: %w", redact.Address(address), err))
+	}
+	if pendingOut > 0 {
+		wallet.PendingOut = &pendingOut
 	}
 	return &wallet, nil
 }
 
-// Получает N адрессов с балансом
-func (s *Storage) GetWallets(ctx context.Context, n int) ([]models.Wallet, error) {
-	query := "SELECT address, balance FROM wallets LIMIT $1"
+// Получает N адрессов с балансом. Системные кошельки в публичный список не входят.
+func (s *Storage) GetWallets(ctx context.Context, n int) ([]models.Wallet, int, error) {
+	query := "SELECT " + walletColumns + " FROM wallets WHERE NOT system LIMIT $1"
 	rows, err := s.db.QueryContext(ctx, query, n)
 	if err != nil {
-		return nil, fmt.Errorf("не удалось получить транзакции: %w", err)
+		return nil, 0, fmt.Errorf("не удалось получить кошельки: %w", err)
 	}
 	defer rows.Close()
 
 	var wallets []models.Wallet
+	skipped := 0
 	for rows.Next() {
-		var w models.Wallet
-		if err := rows.Scan(&w.Address, &w.Balance); err != nil {
-			return nil, fmt.Errorf("ошибка сканирования строки wallets: %w", err)
+		w, err := scanWallet(rows)
+		if err != nil {
+			skipped += skipRow(rows, "wallets", err)
+			continue
 		}
 		wallets = append(wallets, w)
 	}
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("ошибка при итерации по wallets: %w", err)
+		return nil, 0, fmt.Errorf("ошибка при итерации по wallets: %w", err)
+	}
+	return wallets, skipped, nil
+}
+
+// GetWalletsByAddress возвращает кошельки с адресами addresses одним запросом,
+// по адресу. Адресов, которых нет в базе (в том числе удалённых), в результате нет.
+func (s *Storage) GetWalletsByAddress(ctx context.Context, addresses []string) (map[string]models.Wallet, error) {
+	wallets := make(map[string]models.Wallet, len(addresses))
+	if len(addresses) == 0 {
+		return wallets, nil
+	}
+	rows, err := s.db.QueryContext(ctx, "SELECT "+walletColumns+" FROM wallets WHERE address = ANY($1)", pq.Array(addresses))
+	if err != nil {
+		return nil, internalError(fmt.Errorf("ошибка получения кошельков: %w", err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		w, err := scanWallet(rows)
+		if err != nil {
+			return nil, internalError(fmt.Errorf("ошибка сканирования строки wallets: %w", err))
+		}
+		wallets[w.Address] = w
+	}
+	if err := rows.Err(); err != nil {
+		return nil, internalError(fmt.Errorf("ошибка при итерации по wallets: %w", err))
 	}
 	return wallets, nil
 }
 
-// GetLastTransactions: Получает N последних транзакций из базы данных.
-func (s *Storage) GetLastTransactions(ctx context.Context, n int) ([]models.Transaction, error) {
-	query := "SELECT id, from_address, to_address, amount, timestamp, status FROM transactions ORDER BY timestamp DESC LIMIT $1"
-	rows, err := s.db.QueryContext(ctx, query, n)
+// Колонки, по которым разрешена сортировка в ListWallets.
+// Значения фильтра никогда не подставляются в запрос напрямую.
+var walletSortColumns = map[string]string{
+	models.WalletSortAddress: "address",
+	models.WalletSortBalance: "balance",
+}
+
+// ListWallets возвращает страницу кошельков, удовлетворяющих фильтру, и общее
+// количество таких кошельков. Фильтр должен быть предварительно проверен через Validate.
+// Количество и страница читаются в одной транзакции (WithReadTx) и не расходятся.
+func (s *Storage) ListWallets(ctx context.Context, filter models.WalletFilter) (*models.WalletPage, error) {
+	var page *models.WalletPage
+	err := s.WithReadTx(ctx, func(q Querier) error {
+		var err error
+		page, err = listWallets(ctx, q, filter)
+		return err
+	})
+	return page, err
+}
+
+func listWallets(ctx context.Context, q Querier, filter models.WalletFilter) (*models.WalletPage, error) {
+	var conds []string
+	var args []any
+	addCond := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if filter.Frozen != nil {
+		addCond("frozen = $%d", *filter.Frozen)
+	}
+	if filter.System != nil {
+		addCond("system = $%d", *filter.System)
+	}
+	if !filter.IncludeArchived {
+		conds = append(conds, "archived = FALSE")
+	}
+	if filter.MinBalance != nil {
+		addCond("balance >= $%d", *filter.MinBalance)
+	}
+	if filter.MaxBalance != nil {
+		addCond("balance <= $%d", *filter.MaxBalance)
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	var total int
+	if err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM wallets"+where, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("не удалось посчитать кошельки: %w", err)
+	}
+
+	column, ok := walletSortColumns[filter.Sort]
+	if !ok {
+		return nil, fmt.Errorf("неизвестное поле сортировки: %q", filter.Sort)
+	}
+	order := "ASC"
+	if filter.Order == models.SortOrderDesc {
+		order = "DESC"
+	}
+
+	query := "SELECT " + walletColumns + " FROM wallets" + where +
+		fmt.Sprintf(" ORDER BY %s %s, address %s LIMIT $%d OFFSET $%d", column, order, order, len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := q.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("не удалось получить транзакции: %w", err)
+		return nil, fmt.Errorf("не удалось получить кошельки: %w", err)
+	}
+	defer rows.Close()
+
+	page := &models.WalletPage{Wallets: []models.Wallet{}, Total: total, Limit: filter.Limit, Offset: filter.Offset}
+	for rows.Next() {
+		w, err := scanWallet(rows)
+		if err != nil {
+			page.Skipped += skipRow(rows, "wallets", err)
+			continue
+		}
+		page.Wallets = append(page.Wallets, w)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по wallets: %w", err)
+	}
+	return page, nil
+}
+
+// GetLastTransactions: Получает N последних транзакций из базы данных, удовлетворяющих фильтру.
+// Транзакции упорядочены по (timestamp, id) по убыванию, поэтому порядок стабилен даже
+// для транзакций с одинаковым временем, а keyset-курсор не пропускает и не повторяет строки.
+//
+// Строки, которые не удалось прочитать, пропускаются и записываются в лог; их
+// количество возвращается вторым результатом.
+func (s *Storage) GetLastTransactions(ctx context.Context, n int, filter models.TransactionFilter) ([]models.Transaction, int, error) {
+	var conds []string
+	var args []any
+	if len(filter.Metadata) > 0 {
+		metadata, err := json.Marshal(filter.Metadata)
+		if err != nil {
+			return nil, 0, internalError(fmt.Errorf("не удалось сериализовать фильтр metadata: %w", err))
+		}
+		args = append(args, string(metadata))
+		conds = append(conds, fmt.Sprintf("metadata @> $%d::jsonb", len(args)))
+	}
+	if filter.After != nil {
+		args = append(args, filter.After.Timestamp.UTC(), filter.After.ID)
+		conds = append(conds, fmt.Sprintf("(timestamp, id) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+	order := "timestamp DESC, id DESC"
+	if filter.AfterID != nil {
+		args = append(args, *filter.AfterID)
+		conds = append(conds, fmt.Sprintf("id > $%d", len(args)))
+		order = "id"
+	}
+	if filter.Between != nil {
+		// Оба направления обслуживает индекс (from_address, to_address).
+		args = append(args, filter.Between.A, filter.Between.B)
+		a, b := len(args)-1, len(args)
+		conds = append(conds, fmt.Sprintf("((from_address = $%d AND to_address = $%d) OR (from_address = $%d AND to_address = $%d))", a, b, b, a))
+	}
+	if filter.Address != "" {
+		args = append(args, filter.Address)
+		conds = append(conds, fmt.Sprintf("(from_address = $%d OR to_address = $%d)", len(args), len(args)))
+	}
+	if filter.To != "" {
+		args = append(args, filter.To)
+		conds = append(conds, fmt.Sprintf("to_address = $%d", len(args)))
+	}
+	if filter.Unacknowledged {
+		conds = append(conds, "acknowledged_at IS NULL")
+	}
+	if filter.Tag != "" {
+		args = append(args, filter.Tag)
+		conds = append(conds, fmt.Sprintf("EXISTS (SELECT 1 FROM transaction_tags tt WHERE tt.transaction_id = transactions.id AND tt.tag = $%d)", len(args)))
+	}
+	if filter.GroupID != "" {
+		args = append(args, filter.GroupID)
+		conds = append(conds, fmt.Sprintf("group_id = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conds = append(conds, fmt.Sprintf("status = $%d", len(args)))
+	} else if !filter.IncludeNotes {
+		args = append(args, models.StatusZeroNote)
+		conds = append(conds, fmt.Sprintf("status <> $%d", len(args)))
+	}
+	if filter.Since != nil {
+		args = append(args, filter.Since.UTC())
+		conds = append(conds, fmt.Sprintf("timestamp >= $%d", len(args)))
+	}
+	if filter.Until != nil {
+		args = append(args, filter.Until.UTC())
+		conds = append(conds, fmt.Sprintf("timestamp < $%d", len(args)))
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+	args = append(args, n)
+
+	query := "SELECT " + transactionColumns + " FROM transactions" +
+		where + fmt.Sprintf(" ORDER BY %s LIMIT $%d", order, len(args))
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, internalError(fmt.Errorf("не удалось получить транзакции: %w", err))
 	}
 	defer rows.Close()
 
 	var transactions []models.Transaction
+	skipped := 0
 	for rows.Next() {
-		var t models.Transaction
-		if err := rows.Scan(&t.ID, &t.From, &t.To, &t.Amount, &t.Timestamp, &t.Status); err != nil {
-			return nil, fmt.Errorf("ошибка сканирования строки транзакции: %w", err)
+		t, err := scanTransaction(rows)
+		if err != nil {
+			skipped += skipRow(rows, "transactions", err)
+			continue
 		}
-		transactions = append(transactions, t)
+		transactions = append(transactions, *t)
 	}
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("ошибка при итерации по транзакциям: %w", err)
+		return nil, 0, internalError(fmt.Errorf("ошибка при итерации по транзакциям: %w", err))
 	}
 
-	return transactions, nil
+	return transactions, skipped, nil
 }
 
-// Записывает транзакцию в таблицу transactions в случае ошибки.
-func (s *Storage) logTransaction(ctx context.Context, from, to string, amount float64, status models.TransactionStatus) {
-	_, err := s.db.ExecContext(ctx,
-		"INSERT INTO transactions (from_address, to_address, amount, timestamp, status) VALUES ($1, $2, $3, $4, $5)",
-		from, to, amount, time.Now(), status)
+// Колонки транзакции в порядке, который ожидает scanTransaction.
+const transactionColumns = "id, public_id, from_address, to_address, amount, timestamp, status, memo, reference, metadata, acknowledged_at, COALESCE(group_id, '')"
+
+// scanTransaction читает строку с колонками transactionColumns.
+func scanTransaction(row interface{ Scan(...any) error }) (*models.Transaction, error) {
+	var t models.Transaction
+	var metadata []byte
+	var acknowledgedAt sql.NullTime
+	if err := row.Scan(&t.ID, &t.PublicID, &t.From, &t.To, &t.Amount, &t.Timestamp, &t.Status, &t.Memo, &t.Reference, &metadata, &acknowledgedAt, &t.GroupID); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(metadata, &t.Metadata); err != nil {
+		return nil, fmt.Errorf("ошибка разбора metadata: %w", err)
+	}
+	if acknowledgedAt.Valid {
+		t.AcknowledgedAt = &acknowledgedAt.Time
+	}
+	return &t, nil
+}
+
+// Сериализует метаданные перевода для колонки metadata.
+func marshalMetadata(metadata map[string]string) (string, error) {
+	if metadata == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(metadata)
+	return string(b), err
+}
+
+// Записывает транзакцию в таблицу transactions в случае ошибки. Время транзакции
+// назначает база данных, чтобы порядок не зависел от часов экземпляров сервиса.
+func (s *Storage) logTransaction(ctx context.Context, t models.Transfer, status models.TransactionStatus) {
+	metadata, err := marshalMetadata(t.Metadata)
+	if err != nil {
+		log.Printf("ошибка: не удалось сериализовать metadata транзакции: %v", err)
+		return
+	}
+	publicID, err := s.ids.New()
+	if err != nil {
+		log.Printf("ошибка: не удалось создать public_id транзакции: %v", err)
+		return
+	}
+	_, err = s.db.ExecContext(ctx,
+		"INSERT INTO transactions (public_id, from_address, to_address, amount, status, memo, reference, metadata, group_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''))",
+		publicID, t.From, t.To, t.Amount, status, t.Memo, t.Reference, metadata, t.GroupID)
 	if err != nil {
 		log.Printf("ошибка: не удалось записать лог транзакции: %v", err)
 	}
 }
 
-// Записывает транзакцию в таблицу transactions при успешном выполнении
-func logTransactionInTx(ctx context.Context, tx *sql.Tx, from, to string, amount float64, status models.TransactionStatus) error {
-	_, err := tx.ExecContext(ctx,
-		"INSERT INTO transactions (from_address, to_address, amount, timestamp, status) VALUES ($1, $2, $3, $4, $5)",
-		from, to, amount, time.Now(), status)
+// Записывает транзакцию в таблицу transactions при успешном выполнении и возвращает
+// её с идентификатором и временем, назначенными базой данных.
+func (s *Storage) logTransactionInTx(ctx context.Context, tx *sql.Tx, t models.Transfer, status models.TransactionStatus) (*models.Transaction, error) {
+	publicID, err := s.ids.New()
 	if err != nil {
-		return fmt.Errorf("не удалось записать лог транзакции внутри tx: %w", err)
+		return nil, fmt.Errorf("не удалось создать public_id транзакции: %w", err)
 	}
-	return nil
+	transaction := models.Transaction{
+		PublicID:  publicID,
+		From:      t.From,
+		To:        t.To,
+		Amount:    money.Amount(t.Amount),
+		Status:    status,
+		Memo:      t.Memo,
+		Reference: t.Reference,
+		Metadata:  t.Metadata,
+		GroupID:   t.GroupID,
+	}
+	metadata, err := marshalMetadata(t.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось сериализовать metadata транзакции: %w", err)
+	}
+	err = tx.QueryRowContext(ctx,
+		"INSERT INTO transactions (public_id, from_address, to_address, amount, status, memo, reference, metadata, group_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, '')) RETURNING id, timestamp",
+		publicID, t.From, t.To, t.Amount, status, t.Memo, t.Reference, metadata, t.GroupID).Scan(&transaction.ID, &transaction.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось записать лог транзакции внутри tx: %w", err)
+	}
+	return &transaction, nil
 }
 
+// SendMoney переводит amount с кошелька from на кошелёк to.
+//
+// Deprecated: используйте Execute с models.Transfer. Обёртка сохранена на один
+// релиз для совместимости со сторонними форками.
 func (s *Storage) SendMoney(ctx context.Context, from string, to string, amount float64) error {
+	_, err := s.Execute(ctx, models.Transfer{From: from, To: to, Amount: amount})
+	return err
+}
+
+// Execute выполняет перевод t и возвращает записанную успешную транзакцию.
+// Конфликты параллельных переводов (взаимная блокировка, ошибка сериализации)
+// повторяются до maxExecuteAttempts раз; остальные ошибки возвращаются сразу.
+// Неуспешный перевод записывается в transactions один раз - после последней попытки.
+// Если у перевода есть ключ идемпотентности и с ним уже записана транзакция,
+// возвращается она, а перевод не выполняется (см. idempotency.go).
+func (s *Storage) Execute(ctx context.Context, t models.Transfer) (*models.Transaction, error) {
+	return s.execute(ctx, t, nil)
+}
+
+// inTxStep - дополнительный шаг перевода, который выполняется в его транзакции
+// после записи успешной транзакции transaction. Ошибка шага откатывает перевод,
+// который тогда не записывается; конфликты повторяются вместе с переводом.
+type inTxStep func(ctx context.Context, tx *sql.Tx, transaction *models.Transaction) error
+
+// execute выполняет перевод t так же, как Execute, и шаг inTx (если он задан) в
+// транзакции перевода. При повторе по ключу идемпотентности шаг не выполняется.
+func (s *Storage) execute(ctx context.Context, t models.Transfer, inTx inTxStep) (*models.Transaction, error) {
+	start := time.Now()
+	stats := ExecStatsFrom(ctx)
+
+	var (
+		transaction *models.Transaction
+		status      models.TransactionStatus
+		err         error
+		attempt     int
+		replayed    bool
+	)
+	for attempt = 1; ; attempt++ {
+		if t.IdempotencyKey != "" {
+			transaction, err = s.findIdempotent(ctx, t)
+			if transaction != nil || err != nil {
+				replayed = transaction != nil
+				status = ""
+				break
+			}
+		}
+		transaction, status, err = s.executeOnce(ctx, t, inTx)
+		if err == nil || !isRetryable(err) {
+			break
+		}
+		if attempt == maxExecuteAttempts {
+			err = &TransactionError{Code: CodeRetriesExhausted, OriginalErr: err, Attempts: attempt}
+			break
+		}
+		log.Printf("конфликт при переводе от %s к %s, попытка %d из %d: %v", redact.Address(t.From), redact.Address(t.To), attempt, maxExecuteAttempts, err)
+		if waitErr := sleepContext(ctx, time.Duration(attempt)*executeRetryBackoff); waitErr != nil {
+			err = internalError(waitErr)
+			break
+		}
+	}
+
+	if stats != nil {
+		stats.Attempts = attempt
+		stats.Elapsed = time.Since(start)
+		stats.Replayed = replayed
+	}
+	if status != "" {
+		s.logTransaction(ctx, t, status)
+	}
+	return transaction, err
+}
+
+// executeOnce выполняет одну попытку перевода. При ошибке возвращает статус,
+// с которым перевод нужно записать, если попытка последняя.
+func (s *Storage) executeOnce(ctx context.Context, t models.Transfer, inTx inTxStep) (*models.Transaction, models.TransactionStatus, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		s.logTransaction(ctx, from, to, amount, models.StatusUnknownError)
-		return fmt.Errorf("не удалось начать транзакцию: %w", err)
+		return nil, models.StatusUnknownError, internalError(fmt.Errorf("не удалось начать транзакцию: %w", err))
+	}
+	defer tx.Rollback()
+
+	if t.IdempotencyKey != "" {
+		if err := claimIdempotencyKey(ctx, tx, t.IdempotencyKey); err != nil {
+			return nil, "", err
+		}
+	}
+
+	// Строки обоих кошельков блокируются до проверок, в порядке адресов: баланс
+	// отправителя ниже читается актуальным и не меняется до фиксации, поэтому
+	// параллельные переводы с одного кошелька не уводят его в минус, а встречные
+	// переводы между одними кошельками выполняются по очереди без взаимоблокировки.
+	_, err = tx.ExecContext(ctx, "SELECT 1 FROM wallets WHERE address IN ($1, $2) ORDER BY address FOR UPDATE", t.From, t.To)
+	if err != nil {
+		return nil, models.StatusUnknownError, internalError(fmt.Errorf("ошибка блокировки кошельков: %w", err))
 	}
 
 	// Проверка отправителя
 	var senderBalance float64
-	err = tx.QueryRowContext(ctx, "SELECT balance FROM wallets WHERE address = $1", from).Scan(&senderBalance)
+	var senderSystem bool
+	err = tx.QueryRowContext(ctx, "SELECT balance, system FROM wallets WHERE address = $1", t.From).Scan(&senderBalance, &senderSystem)
 	if err != nil {
-		tx.Rollback()
 		if errors.Is(err, sql.ErrNoRows) {
-			s.logTransaction(ctx, from, to, amount, models.StatusFailedSenderNotFound)
-			return &TransactionError{Code: CodeSenderNotFound, OriginalErr: ErrWalletNotFound}
+			return nil, models.StatusFailedSenderNotFound, &TransactionError{Code: CodeSenderNotFound, OriginalErr: ErrWalletNotFound}
 		}
-		s.logTransaction(ctx, from, to, amount, models.StatusUnknownError)
-		return &TransactionError{Code: CodeInternalError, OriginalErr: fmt.Errorf("ошибка получения баланса отправителя: %w", err)}
+		return nil, models.StatusUnknownError, internalError(fmt.Errorf("ошибка получения баланса отправителя: %w", err))
+	}
+	// Запрещённый перевод с системного кошелька не выполняется и не записывается.
+	if senderSystem && !t.AllowSystem {
+		return nil, "", ErrSystemWallet
 	}
 
 	// Проверка баланса
-	if senderBalance < amount {
-		tx.Rollback()
-		s.logTransaction(ctx, from, to, amount, models.StatusFailedInsufficientFunds)
-		return &TransactionError{Code: CodeInsufficientFunds, OriginalErr: ErrInsufficientFunds}
+	if senderBalance < t.Amount {
+		return nil, models.StatusFailedInsufficientFunds, &TransactionError{
+			Code:        CodeInsufficientFunds,
+			OriginalErr: ErrInsufficientFunds,
+			Balance:     money.Amount(senderBalance),
+			Shortfall:   money.Amount(t.Amount - senderBalance),
+		}
 	}
 
 	// Проверка получателя
-	var recipientExists int
-	err = tx.QueryRowContext(ctx, "SELECT 1 FROM wallets WHERE address = $1", to).Scan(&recipientExists)
+	var recipientClosed bool
+	err = tx.QueryRowContext(ctx, "SELECT system AND non_receivable FROM wallets WHERE address = $1", t.To).Scan(&recipientClosed)
+	autoCreated := false
+	if errors.Is(err, sql.ErrNoRows) && s.autoCreateRecipients {
+		// Созданный кошелёк не системный, поэтому recipientClosed остаётся false.
+		autoCreated, err = s.createRecipientInTx(ctx, tx, t.To)
+	}
 	if err != nil {
-		tx.Rollback()
 		if errors.Is(err, sql.ErrNoRows) {
-			s.logTransaction(ctx, from, to, amount, models.StatusFailedRecipientNotFound)
-			return &TransactionError{Code: CodeRecipientNotFound, OriginalErr: ErrWalletNotFound}
+			return nil, models.StatusFailedRecipientNotFound, &TransactionError{Code: CodeRecipientNotFound, OriginalErr: ErrWalletNotFound}
 		}
-		s.logTransaction(ctx, from, to, amount, models.StatusUnknownError)
-		return &TransactionError{Code: CodeInternalError, OriginalErr: fmt.Errorf("ошибка проверки кошелька получателя: %w", err)}
+		return nil, models.StatusUnknownError, internalError(fmt.Errorf("ошибка проверки кошелька получателя: %w", err))
+	}
+	if recipientClosed && !t.AllowSystem {
+		return nil, "", ErrSystemWallet
 	}
 
 	// Обновление балансов
-	_, err = tx.ExecContext(ctx, "UPDATE wallets SET balance = balance - $1 WHERE address = $2", amount, from)
+	_, err = tx.ExecContext(ctx, "UPDATE wallets SET balance = balance - $1 WHERE address = $2", t.Amount, t.From)
 	if err != nil {
-		tx.Rollback()
-		s.logTransaction(ctx, from, to, amount, models.StatusUnknownError)
-		return &TransactionError{Code: CodeInternalError, OriginalErr: fmt.Errorf("ошибка списания средств: %w", err)}
+		return nil, models.StatusUnknownError, internalError(fmt.Errorf("ошибка списания средств: %w", err))
 	}
 
-	_, err = tx.ExecContext(ctx, "UPDATE wallets SET balance = balance + $1 WHERE address = $2", amount, to)
+	// Предел баланса проверяется в самом UPDATE: строка получателя заблокирована,
+	// и параллельные зачисления не могут вместе превысить max_balance.
+	result, err := tx.ExecContext(ctx,
+		"UPDATE wallets SET balance = balance + $1 WHERE address = $2 AND ($3 OR max_balance IS NULL OR balance + $1 <= max_balance)",
+		t.Amount, t.To, t.IgnoreMaxBalance)
 	if err != nil {
-		tx.Rollback()
-		s.logTransaction(ctx, from, to, amount, models.StatusUnknownError)
-		return &TransactionError{Code: CodeInternalError, OriginalErr: fmt.Errorf("ошибка начисления средств: %w", err)}
+		return nil, models.StatusUnknownError, internalError(fmt.Errorf("ошибка начисления средств: %w", err))
+	}
+	credited, err := result.RowsAffected()
+	if err != nil {
+		return nil, models.StatusUnknownError, internalError(fmt.Errorf("ошибка начисления средств: %w", err))
+	}
+	if credited == 0 {
+		var recipientBalance, maxBalance float64
+		err = tx.QueryRowContext(ctx, "SELECT balance, max_balance FROM wallets WHERE address = $1", t.To).Scan(&recipientBalance, &maxBalance)
+		if err != nil {
+			return nil, models.StatusUnknownError, internalError(fmt.Errorf("ошибка получения предела баланса получателя: %w", err))
+		}
+		return nil, models.StatusFailedRecipientLimitExceeded, &TransactionError{
+			Code:             CodeRecipientLimitExceeded,
+			OriginalErr:      ErrRecipientLimitExceeded,
+			RecipientBalance: money.Amount(recipientBalance),
+			MaxBalance:       money.Amount(maxBalance),
+		}
 	}
 
 	// Запись успешной транзакции
-	err = logTransactionInTx(ctx, tx, from, to, amount, models.StatusSuccess)
+	transaction, err := s.logTransactionInTx(ctx, tx, t, models.StatusSuccess)
 	if err != nil {
-		tx.Rollback()
-		return &TransactionError{Code: CodeInternalError, OriginalErr: err}
+		return nil, "", internalError(err)
 	}
+	if autoCreated {
+		// Кошелёк, созданный переводом, появляется в момент этого перевода.
+		_, err = tx.ExecContext(ctx, "UPDATE wallets SET created_at = $2 WHERE address = $1", t.To, transaction.Timestamp)
+		if err != nil {
+			return nil, "", internalError(fmt.Errorf("ошибка записи времени создания кошелька получателя: %w", err))
+		}
+	}
+	if inTx != nil {
+		if err := inTx(ctx, tx, transaction); err != nil {
+			return nil, "", err
+		}
+	}
+	crossings, err := crossThresholdsInTx(ctx, tx, transaction)
+	if err != nil {
+		return nil, "", internalError(fmt.Errorf("ошибка проверки порогов баланса: %w", err))
+	}
+	if t.IdempotencyKey != "" {
+		if err := recordIdempotencyKey(ctx, tx, t.IdempotencyKey, transaction.ID); err != nil {
+			return nil, "", err
+		}
+	}
+
+	// Если дедлайн запроса истёк до фиксации, транзакция откатывается и перевод
+	// не записывается как успешный. После фиксации результат возвращается как есть.
+	if err := ctx.Err(); err != nil {
+		return nil, "", internalError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, "", internalError(fmt.Errorf("не удалось зафиксировать транзакцию: %w", err))
+	}
+	if autoCreated {
+		log.Printf("создан кошелёк получателя %s переводом %d", redact.Address(t.To), transaction.ID)
+	}
+	if stats := ExecStatsFrom(ctx); stats != nil {
+		stats.Crossings = crossings
+	}
+	return transaction, "", nil
+}
 
-	return tx.Commit()
+// createRecipientInTx создаёт в tx кошелёк получателя address с нулевым балансом и
+// отметкой auto_created. Адрес должен соответствовать схеме адресов сервиса и не
+// принадлежать удалённому кошельку, иначе возвращается sql.ErrNoRows, как для
+// отсутствующего получателя. Если кошелёк одновременно создал другой перевод,
+// вставка ждёт его фиксации и ничего не делает: тогда возвращается false, и перевод
+// зачисляется на уже созданный кошелёк.
+func (s *Storage) createRecipientInTx(ctx context.Context, tx *sql.Tx, address string) (bool, error) {
+	if err := s.scheme.Validate(address); err != nil {
+		return false, sql.ErrNoRows
+	}
+	var purged bool
+	err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM wallet_tombstones WHERE address = $1)", address).Scan(&purged)
+	if err != nil {
+		return false, err
+	}
+	if purged {
+		return false, sql.ErrNoRows
+	}
+	result, err := tx.ExecContext(ctx,
+		"INSERT INTO wallets (address, balance, auto_created) VALUES ($1, 0, TRUE) ON CONFLICT (address) DO NOTHING", address)
+	if err != nil {
+		return false, err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return inserted == 1, nil
 }
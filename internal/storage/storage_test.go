@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"testing"
+
+	"go-payments/internal/address"
+	"go-payments/internal/ulid"
+)
+
+// testDSNEnv - переменная окружения со строкой подключения к PostgreSQL в формате
+// key=value (например, "host=localhost user=postgres dbname=payments_test sslmode=disable").
+// Без неё тесты, которым нужна база, пропускаются.
+const testDSNEnv = "TEST_DATABASE_DSN"
+
+// newTestStorage создаёт хранилище в отдельной схеме базы из TEST_DATABASE_DSN с
+// применёнными миграциями и без кошельков. Схема удаляется после теста.
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+	return newTestStorageWith(t, func(dsn string) (*sql.DB, error) { return sql.Open("postgres", dsn) })
+}
+
+// newTestStorageWith создаёт хранилище так же, как newTestStorage, открывая базу
+// функцией open: тесты подменяют ею драйвер.
+func newTestStorageWith(t *testing.T, open func(dsn string) (*sql.DB, error)) *Storage {
+	t.Helper()
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s не задана, тест с PostgreSQL пропущен", testDSNEnv)
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	suffix := make([]byte, 8)
+	rand.Read(suffix)
+	schema := "test_" + hex.EncodeToString(suffix)
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		admin.Close()
+		t.Fatalf("создание схемы %s: %v", schema, err)
+	}
+
+	db, err := open(dsn + " search_path=" + schema)
+	if err != nil {
+		admin.Close()
+		t.Fatalf("открытие базы: %v", err)
+	}
+	db.SetMaxOpenConns(20)
+	t.Cleanup(func() {
+		db.Close()
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("удаление схемы %s: %v", schema, err)
+		}
+		admin.Close()
+	})
+
+	s := &Storage{db: db, scheme: address.Hex64, ids: ulid.NewGenerator(nil, nil)}
+	if err := s.migrate(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return s
+}
+
+// testAddress возвращает адрес схемы hex64, различный для разных n.
+func testAddress(n int) string {
+	return fmt.Sprintf("%064x", n)
+}
+
+// createTestWallet создаёт кошелёк address с балансом balance.
+func createTestWallet(t *testing.T, s *Storage, address string, balance float64) {
+	t.Helper()
+	if _, err := s.db.Exec("INSERT INTO wallets (address, balance) VALUES ($1, $2)", address, balance); err != nil {
+		t.Fatalf("создание кошелька %s: %v", address, err)
+	}
+}
+
+// walletBalance возвращает баланс кошелька address.
+func walletBalance(t *testing.T, s *Storage, address string) float64 {
+	t.Helper()
+	var balance float64
+	if err := s.db.QueryRow("SELECT balance FROM wallets WHERE address = $1", address).Scan(&balance); err != nil {
+		t.Fatalf("баланс кошелька %s: %v", address, err)
+	}
+	return balance
+}
+
+// countRows возвращает количество строк запроса SELECT COUNT(*) query.
+func countRows(t *testing.T, s *Storage, query string, args ...any) int {
+	t.Helper()
+	var n int
+	if err := s.db.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return n
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"go-payments/internal/models"
+)
+
+// StorageInfo возвращает размер базы данных и размеры таблиц текущей схемы
+// с их индексами (pg_relation_size, pg_indexes_size, pg_total_relation_size).
+// Таблицы упорядочены по полному размеру по убыванию. TOAST входит в TotalBytes,
+// но не в TableBytes. Все размеры читаются в одной транзакции (WithReadTx).
+func (s *Storage) StorageInfo(ctx context.Context) (*models.StorageInfo, error) {
+	var info *models.StorageInfo
+	err := s.WithReadTx(ctx, func(q Querier) error {
+		var err error
+		info, err = storageInfo(ctx, q)
+		return err
+	})
+	return info, err
+}
+
+func storageInfo(ctx context.Context, q Querier) (*models.StorageInfo, error) {
+	info := models.StorageInfo{Driver: "postgres", Tables: []models.TableSize{}}
+	if err := q.QueryRowContext(ctx, `SELECT pg_database_size(current_database())`).Scan(&info.DatabaseBytes); err != nil {
+		return nil, internalError(fmt.Errorf("ошибка получения размера базы данных: %w", err))
+	}
+
+	rows, err := q.QueryContext(ctx, `
+    SELECT c.relname, GREATEST(c.reltuples, 0)::BIGINT,
+        pg_relation_size(c.oid), pg_indexes_size(c.oid), pg_total_relation_size(c.oid)
+    FROM pg_class c
+    JOIN pg_namespace n ON n.oid = c.relnamespace
+    WHERE n.nspname = current_schema() AND c.relkind IN ('r', 'm')
+    ORDER BY pg_total_relation_size(c.oid) DESC, c.relname`)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("ошибка получения размеров таблиц: %w", err))
+	}
+	defer rows.Close()
+
+	byName := make(map[string]int)
+	for rows.Next() {
+		t := models.TableSize{Indexes: []models.IndexSize{}}
+		if err := rows.Scan(&t.Name, &t.Rows, &t.TableBytes, &t.IndexBytes, &t.TotalBytes); err != nil {
+			return nil, internalError(fmt.Errorf("ошибка чтения размера таблицы: %w", err))
+		}
+		byName[t.Name] = len(info.Tables)
+		info.Tables = append(info.Tables, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, internalError(fmt.Errorf("ошибка получения размеров таблиц: %w", err))
+	}
+
+	rows, err = q.QueryContext(ctx, `
+    SELECT t.relname, i.relname, pg_relation_size(i.oid)
+    FROM pg_index x
+    JOIN pg_class i ON i.oid = x.indexrelid
+    JOIN pg_class t ON t.oid = x.indrelid
+    JOIN pg_namespace n ON n.oid = t.relnamespace
+    WHERE n.nspname = current_schema()
+    ORDER BY pg_relation_size(i.oid) DESC, i.relname`)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("ошибка получения размеров индексов: %w", err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var table string
+		var index models.IndexSize
+		if err := rows.Scan(&table, &index.Name, &index.Bytes); err != nil {
+			return nil, internalError(fmt.Errorf("ошибка чтения размера индекса: %w", err))
+		}
+		if i, ok := byName[table]; ok {
+			info.Tables[i].Indexes = append(info.Tables[i].Indexes, index)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, internalError(fmt.Errorf("ошибка получения размеров индексов: %w", err))
+	}
+	return &info, nil
+}
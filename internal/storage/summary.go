@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"go-payments/internal/models"
+	"go-payments/internal/money"
+)
+
+// GetWalletSummary читает сводку по кошелькам из материализованного представления
+// wallet_summary. Данные актуальны на момент последнего обновления (StaleAsOf).
+func (s *Storage) GetWalletSummary(ctx context.Context) (*models.WalletSummary, error) {
+	rows, err := s.db.QueryContext(ctx, `
+    SELECT bucket_min, wallet_count, total_balance, refreshed_at FROM wallet_summary
+    ORDER BY bucket_min`)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("не удалось получить сводку по кошелькам: %w", err))
+	}
+	defer rows.Close()
+
+	summary := &models.WalletSummary{Distribution: []models.BalanceBucket{}}
+	for rows.Next() {
+		var bucketMin, total sql.NullFloat64
+		var count sql.NullInt64
+		if err := rows.Scan(&bucketMin, &count, &total, &summary.StaleAsOf); err != nil {
+			return nil, internalError(fmt.Errorf("ошибка сканирования строки wallet_summary: %w", err))
+		}
+		if !bucketMin.Valid {
+			continue
+		}
+		bucket := models.BalanceBucket{
+			MinBalance:   money.Amount(bucketMin.Float64),
+			WalletCount:  int(count.Int64),
+			TotalBalance: money.Amount(total.Float64),
+		}
+		summary.Distribution = append(summary.Distribution, bucket)
+		summary.WalletCount += bucket.WalletCount
+		summary.TotalBalance += bucket.TotalBalance
+	}
+	if err := rows.Err(); err != nil {
+		return nil, internalError(fmt.Errorf("ошибка при итерации по wallet_summary: %w", err))
+	}
+	summary.StaleAsOf = summary.StaleAsOf.UTC()
+	return summary, nil
+}
+
+// RefreshWalletSummary пересчитывает представление wallet_summary.
+func (s *Storage) RefreshWalletSummary(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, "REFRESH MATERIALIZED VIEW wallet_summary"); err != nil {
+		return internalError(fmt.Errorf("не удалось обновить сводку по кошелькам: %w", err))
+	}
+	return nil
+}
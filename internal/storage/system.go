@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"go-payments/internal/redact"
+)
+
+// SetWalletSystem отмечает кошелёк системным или снимает отметку. nonReceivable
+// запрещает переводы клиентов на системный кошелёк; для обычного кошелька
+// оно сбрасывается.
+func (s *Storage) SetWalletSystem(ctx context.Context, address string, system, nonReceivable bool) error {
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE wallets SET system = $2, non_receivable = $2 AND $3 WHERE address = $1", address, system, nonReceivable)
+	if err != nil {
+		return internalError(fmt.Errorf("ошибка изменения системной отметки кошелька %s: %w", redact.Address(address), err))
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return internalError(fmt.Errorf("ошибка изменения системной отметки кошелька %s: %w", redact.Address(address), err))
+	}
+	if n == 0 {
+		return ErrWalletNotFound
+	}
+	return nil
+}
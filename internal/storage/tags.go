@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"go-payments/internal/models"
+)
+
+// AddTransactionTag назначает тег транзакции id. Повторное назначение не меняет
+// автора и время. Если транзакции нет, возвращает ErrTxNotFound, если она
+// в закрытом периоде, ErrPeriodClosed.
+func (s *Storage) AddTransactionTag(ctx context.Context, id int, tag, addedBy string) error {
+	if err := s.requireOpenTransaction(ctx, id); err != nil {
+		return err
+	}
+	// Условие открытого периода повторяется в самом INSERT на случай закрытия
+	// между проверкой и записью.
+	_, err := s.db.ExecContext(ctx, `
+    INSERT INTO transaction_tags (transaction_id, tag, added_by)
+    SELECT id, $2, $3 FROM transactions WHERE id = $1 AND `+periodOpenCondition+`
+    ON CONFLICT (transaction_id, tag) DO NOTHING`,
+		id, tag, addedBy)
+	if err != nil {
+		return internalError(fmt.Errorf("ошибка назначения тега %q транзакции %d: %w", tag, id, err))
+	}
+	return nil
+}
+
+// RemoveTransactionTag снимает тег с транзакции id; отсутствующий тег не считается
+// ошибкой. Если транзакции нет, возвращает ErrTxNotFound, если она в закрытом
+// периоде, ErrPeriodClosed.
+func (s *Storage) RemoveTransactionTag(ctx context.Context, id int, tag string) error {
+	if err := s.requireOpenTransaction(ctx, id); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, `
+    DELETE FROM transaction_tags
+    WHERE transaction_id = $1 AND tag = $2
+        AND EXISTS (SELECT 1 FROM transactions WHERE id = $1 AND `+periodOpenCondition+`)`, id, tag)
+	if err != nil {
+		return internalError(fmt.Errorf("ошибка снятия тега %q с транзакции %d: %w", tag, id, err))
+	}
+	return nil
+}
+
+// ListTransactionTags возвращает все теги с количеством транзакций, начиная с самых частых.
+func (s *Storage) ListTransactionTags(ctx context.Context) ([]models.TagCount, error) {
+	rows, err := s.db.QueryContext(ctx, `
+    SELECT tag, COUNT(*) FROM transaction_tags
+    GROUP BY tag ORDER BY COUNT(*) DESC, tag`)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("ошибка получения тегов: %w", err))
+	}
+	defer rows.Close()
+
+	tags := []models.TagCount{}
+	for rows.Next() {
+		var t models.TagCount
+		if err := rows.Scan(&t.Tag, &t.Count); err != nil {
+			return nil, internalError(fmt.Errorf("ошибка сканирования строки transaction_tags: %w", err))
+		}
+		tags = append(tags, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, internalError(fmt.Errorf("ошибка при итерации по тегам: %w", err))
+	}
+	return tags, nil
+}
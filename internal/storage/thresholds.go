@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go-payments/internal/models"
+	"go-payments/internal/redact"
+)
+
+// Колонки правила порога в порядке scanThreshold. Таблица всегда под псевдонимом t:
+// в переключении правил она соединяется с wallets, где тоже есть created_at.
+const thresholdColumns = `t.id, t.wallet, t.direction, t.amount, t.hysteresis, t.target, t.triggered,
+    t.last_transaction_id, t.last_crossed_at, t.created_at`
+
+func scanThreshold(row interface{ Scan(...any) error }, extra ...any) (*models.BalanceThreshold, error) {
+	var (
+		th            models.BalanceThreshold
+		transactionID sql.NullInt64
+		crossedAt     sql.NullTime
+	)
+	dest := append([]any{&th.ID, &th.Wallet, &th.Direction, &th.Amount, &th.Hysteresis, &th.Target, &th.Triggered,
+		&transactionID, &crossedAt, &th.CreatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	th.CreatedAt = th.CreatedAt.UTC()
+	if transactionID.Valid {
+		id := int(transactionID.Int64)
+		th.LastTransactionID = &id
+	}
+	if crossedAt.Valid {
+		t := crossedAt.Time.UTC()
+		th.LastCrossedAt = &t
+	}
+	return &th, nil
+}
+
+// thresholdCrossed сообщает, находится ли balance за порогом правила th.
+func thresholdCrossed(th models.BalanceThreshold, balance float64) bool {
+	if th.Direction == models.ThresholdAbove {
+		return balance > float64(th.Amount)
+	}
+	return balance < float64(th.Amount)
+}
+
+// lockThresholdWallet блокирует в tx строку кошелька до фиксации и возвращает его
+// баланс: параллельные переводы и изменения правил кошелька ждут, поэтому
+// начальное состояние правила и предел их количества не устаревают.
+func lockThresholdWallet(ctx context.Context, tx *sql.Tx, wallet string) (float64, error) {
+	var balance float64
+	err := tx.QueryRowContext(ctx, "SELECT balance FROM wallets WHERE address = $1 FOR UPDATE", wallet).Scan(&balance)
+	return balance, err
+}
+
+// CreateBalanceThreshold добавляет кошельку правило порога th (Direction, Amount,
+// Hysteresis, Target). Начальное состояние вычисляется по текущему балансу: если
+// кошелёк уже за порогом, правило сработает только после возврата и нового перехода.
+// Больше models.MaxWalletThresholds правил на кошелёк - ErrTooManyThresholds.
+func (s *Storage) CreateBalanceThreshold(ctx context.Context, th models.BalanceThreshold) (*models.BalanceThreshold, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("не удалось начать транзакцию: %w", err))
+	}
+	defer tx.Rollback()
+
+	balance, err := lockThresholdWallet(ctx, tx, th.Wallet)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWalletNotFound
+		}
+		return nil, internalError(fmt.Errorf("ошибка получения кошелька %s: %w", redact.Address(th.Wallet), err))
+	}
+	var count int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM balance_thresholds WHERE wallet = $1", th.Wallet).Scan(&count); err != nil {
+		return nil, internalError(fmt.Errorf("ошибка подсчёта правил порога кошелька %s: %w", redact.Address(th.Wallet), err))
+	}
+	if count >= models.MaxWalletThresholds {
+		return nil, ErrTooManyThresholds
+	}
+
+	row := tx.QueryRowContext(ctx, `
+    INSERT INTO balance_thresholds AS t (wallet, direction, amount, hysteresis, target, triggered)
+    VALUES ($1, $2, $3, $4, $5, $6)
+    RETURNING `+thresholdColumns,
+		th.Wallet, th.Direction, float64(th.Amount), float64(th.Hysteresis), th.Target, thresholdCrossed(th, balance))
+	created, err := scanThreshold(row)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("ошибка создания правила порога кошелька %s: %w", redact.Address(th.Wallet), err))
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, internalError(fmt.Errorf("не удалось зафиксировать транзакцию: %w", err))
+	}
+	return created, nil
+}
+
+// ListBalanceThresholds возвращает правила порога кошелька в порядке создания.
+func (s *Storage) ListBalanceThresholds(ctx context.Context, wallet string) ([]models.BalanceThreshold, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+thresholdColumns+" FROM balance_thresholds t WHERE t.wallet = $1 ORDER BY t.id", wallet)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("ошибка получения правил порога кошелька %s: %w", redact.Address(wallet), err))
+	}
+	defer rows.Close()
+
+	thresholds := []models.BalanceThreshold{}
+	for rows.Next() {
+		th, err := scanThreshold(rows)
+		if err != nil {
+			return nil, internalError(fmt.Errorf("ошибка сканирования строки balance_thresholds: %w", err))
+		}
+		thresholds = append(thresholds, *th)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, internalError(fmt.Errorf("ошибка при итерации по balance_thresholds: %w", err))
+	}
+	return thresholds, nil
+}
+
+// GetBalanceThreshold возвращает правило id кошелька wallet или ErrThresholdNotFound.
+func (s *Storage) GetBalanceThreshold(ctx context.Context, wallet string, id int) (*models.BalanceThreshold, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+thresholdColumns+" FROM balance_thresholds t WHERE t.id = $1 AND t.wallet = $2", id, wallet)
+	th, err := scanThreshold(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrThresholdNotFound
+		}
+		return nil, internalError(fmt.Errorf("ошибка получения правила порога %d: %w", id, err))
+	}
+	return th, nil
+}
+
+// UpdateBalanceThreshold заменяет направление, порог, гистерезис и получателя
+// правила th.ID кошелька th.Wallet. Состояние вычисляется заново по текущему
+// балансу, как при создании; история последнего переключения сохраняется.
+func (s *Storage) UpdateBalanceThreshold(ctx context.Context, th models.BalanceThreshold) (*models.BalanceThreshold, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("не удалось начать транзакцию: %w", err))
+	}
+	defer tx.Rollback()
+
+	balance, err := lockThresholdWallet(ctx, tx, th.Wallet)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrThresholdNotFound
+		}
+		return nil, internalError(fmt.Errorf("ошибка получения кошелька %s: %w", redact.Address(th.Wallet), err))
+	}
+
+	row := tx.QueryRowContext(ctx, `
+    UPDATE balance_thresholds t
+    SET direction = $3, amount = $4, hysteresis = $5, target = $6, triggered = $7
+    WHERE t.id = $1 AND t.wallet = $2
+    RETURNING `+thresholdColumns,
+		th.ID, th.Wallet, th.Direction, float64(th.Amount), float64(th.Hysteresis), th.Target, thresholdCrossed(th, balance))
+	updated, err := scanThreshold(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrThresholdNotFound
+		}
+		return nil, internalError(fmt.Errorf("ошибка изменения правила порога %d: %w", th.ID, err))
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, internalError(fmt.Errorf("не удалось зафиксировать транзакцию: %w", err))
+	}
+	return updated, nil
+}
+
+// DeleteBalanceThreshold удаляет правило id кошелька wallet.
+func (s *Storage) DeleteBalanceThreshold(ctx context.Context, wallet string, id int) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM balance_thresholds WHERE id = $1 AND wallet = $2", id, wallet)
+	if err != nil {
+		return internalError(fmt.Errorf("ошибка удаления правила порога %d: %w", id, err))
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return internalError(fmt.Errorf("ошибка удаления правила порога %d: %w", id, err))
+	}
+	if deleted == 0 {
+		return ErrThresholdNotFound
+	}
+	return nil
+}
+
+// crossThresholdsInTx переключает в tx правила кошельков перевода transaction,
+// баланс которых после перевода перешёл за порог или вернулся за него с запасом
+// гистерезиса, и возвращает переключения. Строки кошельков уже заблокированы
+// переводом, поэтому параллельные переводы переключают правило по очереди и
+// каждый переход через порог засчитывается ровно одному переводу.
+func crossThresholdsInTx(ctx context.Context, tx *sql.Tx, transaction *models.Transaction) ([]models.ThresholdCrossing, error) {
+	rows, err := tx.QueryContext(ctx, `
+    UPDATE balance_thresholds t
+    SET triggered = NOT t.triggered, last_transaction_id = $3, last_crossed_at = $4
+    FROM wallets w
+    WHERE w.address = t.wallet AND t.wallet IN ($1, $2)
+      AND CASE
+          WHEN NOT t.triggered AND t.direction = 'above' THEN w.balance > t.amount
+          WHEN NOT t.triggered THEN w.balance < t.amount
+          WHEN t.direction = 'above' THEN w.balance <= t.amount - t.hysteresis
+          ELSE w.balance >= t.amount + t.hysteresis
+      END
+    RETURNING `+thresholdColumns+`, w.balance`,
+		transaction.From, transaction.To, transaction.ID, transaction.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var crossings []models.ThresholdCrossing
+	for rows.Next() {
+		var c models.ThresholdCrossing
+		th, err := scanThreshold(rows, &c.Balance)
+		if err != nil {
+			return nil, err
+		}
+		c.Threshold = *th
+		crossings = append(crossings, c)
+	}
+	return crossings, rows.Err()
+}
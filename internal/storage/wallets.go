@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Количество кошельков в одном INSERT при массовом создании.
+const walletInsertBatch = 500
+
+// CreateWallets создаёт n кошельков со случайными адресами в схеме s.scheme и балансом balance
+// многострочными INSERT по walletInsertBatch строк. Каждая пачка фиксируется
+// отдельно, после неё вызывается progress с адресами созданных кошельков пачки
+// (progress может быть nil). Адрес, уже занятый кошельком (совпадение генератора
+// или параллельное создание), пропускается без ошибки: пропуски возвращаются в
+// skipped, а вместо них генерируются новые адреса, пока не будет создано n.
+// При отмене ctx или ошибке уже созданные кошельки остаются, а их количество
+// возвращается вместе с ошибкой.
+func (s *Storage) CreateWallets(ctx context.Context, n int, balance float64, progress func(batch []string)) (created, skipped int, err error) {
+	for created < n {
+		if err := ctx.Err(); err != nil {
+			return created, skipped, err
+		}
+
+		size := min(walletInsertBatch, n-created)
+		values := make([]string, size)
+		args := make([]any, 0, size+1)
+		args = append(args, balance)
+		for i := range values {
+			address, err := s.scheme.Generate()
+			if err != nil {
+				return created, skipped, err
+			}
+			args = append(args, address)
+			values[i] = fmt.Sprintf("($%d, $1)", len(args))
+		}
+
+		inserted, err := insertWallets(ctx, s.db, values, args)
+		if err != nil {
+			return created, skipped, err
+		}
+		if len(inserted) == 0 {
+			// Случайные адреса не повторяются целой пачкой: генератор неисправен.
+			return created, skipped, fmt.Errorf("не удалось создать кошельки: все %d сгенерированных адресов уже заняты", size)
+		}
+		created += len(inserted)
+		skipped += size - len(inserted)
+		if progress != nil {
+			progress(inserted)
+		}
+	}
+	return created, skipped, nil
+}
+
+// insertWallets выполняет INSERT кошельков с пропуском занятых адресов и
+// возвращает адреса фактически вставленных строк.
+func insertWallets(ctx context.Context, q Querier, values []string, args []any) ([]string, error) {
+	query := "INSERT INTO wallets (address, balance) VALUES " + strings.Join(values, ", ") +
+		" ON CONFLICT (address) DO NOTHING RETURNING address"
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать кошельки: %w", err)
+	}
+	defer rows.Close()
+
+	inserted := make([]string, 0, len(values))
+	for rows.Next() {
+		var address string
+		if err := rows.Scan(&address); err != nil {
+			return nil, fmt.Errorf("не удалось прочитать созданные кошельки: %w", err)
+		}
+		inserted = append(inserted, address)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось создать кошельки: %w", err)
+	}
+	return inserted, nil
+}
+
+// Ключ advisory-блокировки, сериализующей создание кошельков по умолчанию.
+const seedLockKey = 7262002
+
+// seedDefaultWallets создаёт count кошельков с балансом balance, если кошельков
+// ещё нет. Проверка и вставка выполняются в одной транзакции под advisory-блокировкой,
+// поэтому экземпляры, запущенные одновременно, не создают кошельки дважды,
+// а ошибка откатывает всю пачку. Возвращает адреса созданных кошельков.
+func (s *Storage) seedDefaultWallets(ctx context.Context, count int, balance float64) ([]string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", seedLockKey); err != nil {
+		return nil, fmt.Errorf("не удалось получить блокировку создания кошельков: %w", err)
+	}
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM wallets)").Scan(&exists); err != nil {
+		return nil, fmt.Errorf("не удалось прочитать кошельки: %w", err)
+	}
+	if exists {
+		return nil, nil
+	}
+
+	values := make([]string, count)
+	args := make([]any, 0, count+1)
+	args = append(args, balance)
+	for i := range values {
+		address, err := s.scheme.Generate()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, address)
+		values[i] = fmt.Sprintf("($%d, $1)", len(args))
+	}
+	inserted, err := insertWallets(ctx, tx, values, args)
+	if err != nil {
+		return nil, err
+	}
+	if skipped := count - len(inserted); skipped > 0 {
+		log.Printf("пропущено занятых адресов при создании кошельков по умолчанию: %d", skipped)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("не удалось зафиксировать транзакцию: %w", err)
+	}
+	return inserted, nil
+}
@@ -0,0 +1,266 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go-payments/internal/models"
+	"go-payments/internal/redact"
+)
+
+// Выводы средств.
+//
+// Вывод списывает сумму с кошелька сразу - обычным переводом на клиринговый
+// (системный) кошелёк, - и строка withdrawals записывается в транзакции этого
+// перевода. Дальше вывод только меняет состояние: подтверждение оставляет сумму на
+// клиринговом кошельке, отказ возвращает её обратным переводом, а строка
+// меняется в транзакции возврата. Все переходы - условные UPDATE по текущему
+// состоянию, поэтому из параллельных подтверждений и отказов выполняется ровно
+// один, а остальные получают ErrWithdrawalSettled.
+
+// Колонки вывода средств в порядке scanWithdrawal.
+const withdrawalColumns = `id, wallet, clearing_wallet, amount, status, reference, transaction_id,
+    reversal_transaction_id, reason, created_at, updated_at`
+
+// Максимальное количество выводов, которые ReviewExpiredWithdrawals переводит на
+// разбор за один вызов; остальные перейдут при следующем запуске задачи.
+const reviewWithdrawalsBatch = 1000
+
+func scanWithdrawal(row interface{ Scan(...any) error }) (*models.Withdrawal, error) {
+	var (
+		w          models.Withdrawal
+		reversalID sql.NullInt64
+	)
+	if err := row.Scan(&w.ID, &w.Wallet, &w.ClearingWallet, &w.Amount, &w.Status, &w.Reference, &w.TransactionID,
+		&reversalID, &w.Reason, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		return nil, err
+	}
+	w.CreatedAt = w.CreatedAt.UTC()
+	w.UpdatedAt = w.UpdatedAt.UTC()
+	if reversalID.Valid {
+		id := int(reversalID.Int64)
+		w.ReversalTransactionID = &id
+	}
+	return &w, nil
+}
+
+// CreateWithdrawal списывает w.Amount с кошелька w.Wallet переводом на клиринговый
+// кошелёк w.ClearingWallet и записывает вывод в состоянии pending в той же
+// транзакции. Ошибки перевода - те же, что у Execute; вывод с системного кошелька -
+// ErrSystemWallet. С ключом идемпотентности idempotencyKey повтор возвращает уже
+// созданный вывод.
+func (s *Storage) CreateWithdrawal(ctx context.Context, w models.Withdrawal, idempotencyKey string) (*models.Withdrawal, error) {
+	var created *models.Withdrawal
+	transaction, err := s.execute(ctx, models.Transfer{
+		From:           w.Wallet,
+		To:             w.ClearingWallet,
+		Amount:         float64(w.Amount),
+		Memo:           "вывод средств",
+		Reference:      w.Reference,
+		IdempotencyKey: idempotencyKey,
+		AllowSystem:    true,
+	}, func(ctx context.Context, tx *sql.Tx, transaction *models.Transaction) error {
+		// AllowSystem нужен только для зачисления на клиринговый кошелёк: системный
+		// кошелёк выводить средства не может.
+		var system bool
+		if err := tx.QueryRowContext(ctx, "SELECT system FROM wallets WHERE address = $1", w.Wallet).Scan(&system); err != nil {
+			return internalError(fmt.Errorf("ошибка проверки кошелька %s: %w", redact.Address(w.Wallet), err))
+		}
+		if system {
+			return ErrSystemWallet
+		}
+		row := tx.QueryRowContext(ctx, `
+    INSERT INTO withdrawals (wallet, clearing_wallet, amount, reference, transaction_id, created_at, updated_at)
+    VALUES ($1, $2, $3, $4, $5, $6, $6)
+    RETURNING `+withdrawalColumns,
+			w.Wallet, w.ClearingWallet, float64(w.Amount), w.Reference, transaction.ID, transaction.Timestamp)
+		var err error
+		if created, err = scanWithdrawal(row); err != nil {
+			return internalError(fmt.Errorf("ошибка записи вывода средств кошелька %s: %w", redact.Address(w.Wallet), err))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if created != nil {
+		return created, nil
+	}
+
+	// Повтор по ключу идемпотентности: перевод уже выполнен вместе с выводом.
+	row := s.db.QueryRowContext(ctx, "SELECT "+withdrawalColumns+" FROM withdrawals WHERE transaction_id = $1", transaction.ID)
+	replayed, err := scanWithdrawal(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// Ключ записан с переводом, который не был выводом средств.
+			return nil, ErrIdempotencyKeyReused
+		}
+		return nil, internalError(fmt.Errorf("ошибка получения вывода средств транзакции %d: %w", transaction.ID, err))
+	}
+	return replayed, nil
+}
+
+// GetWithdrawal возвращает вывод средств id или ErrWithdrawalNotFound.
+func (s *Storage) GetWithdrawal(ctx context.Context, id int) (*models.Withdrawal, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+withdrawalColumns+" FROM withdrawals WHERE id = $1", id)
+	w, err := scanWithdrawal(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWithdrawalNotFound
+		}
+		return nil, internalError(fmt.Errorf("ошибка получения вывода средств %d: %w", id, err))
+	}
+	return w, nil
+}
+
+// ListWithdrawals возвращает до limit последних выводов средств, новые первыми.
+// Пустые wallet и status не ограничивают список.
+func (s *Storage) ListWithdrawals(ctx context.Context, wallet string, status models.WithdrawalStatus, limit int) ([]models.Withdrawal, error) {
+	rows, err := s.db.QueryContext(ctx, `
+    SELECT `+withdrawalColumns+` FROM withdrawals
+    WHERE ($1 = '' OR wallet = $1) AND ($2 = '' OR status = $2)
+    ORDER BY id DESC LIMIT $3`, wallet, string(status), limit)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("ошибка получения выводов средств: %w", err))
+	}
+	defer rows.Close()
+
+	withdrawals := []models.Withdrawal{}
+	for rows.Next() {
+		w, err := scanWithdrawal(rows)
+		if err != nil {
+			return nil, internalError(fmt.Errorf("ошибка сканирования строки withdrawals: %w", err))
+		}
+		withdrawals = append(withdrawals, *w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, internalError(fmt.Errorf("ошибка при итерации по withdrawals: %w", err))
+	}
+	return withdrawals, nil
+}
+
+// settledOrNotFound объясняет, почему условный переход вывода id не изменил
+// строку: вывода нет (ErrWithdrawalNotFound) или он уже завершён (ErrWithdrawalSettled).
+func (s *Storage) settledOrNotFound(ctx context.Context, id int) error {
+	if _, err := s.GetWithdrawal(ctx, id); err != nil {
+		return err
+	}
+	return ErrWithdrawalSettled
+}
+
+// ConfirmWithdrawal подтверждает расчёт вывода id в состоянии pending или review.
+// Сумма остаётся на клиринговом кошельке. Завершённый вывод - ErrWithdrawalSettled.
+func (s *Storage) ConfirmWithdrawal(ctx context.Context, id int) (*models.Withdrawal, error) {
+	row := s.db.QueryRowContext(ctx, `
+    UPDATE withdrawals
+    SET status = 'confirmed', updated_at = (clock_timestamp() AT TIME ZONE 'UTC')
+    WHERE id = $1 AND status IN ('pending', 'review')
+    RETURNING `+withdrawalColumns, id)
+	w, err := scanWithdrawal(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, s.settledOrNotFound(ctx, id)
+		}
+		return nil, internalError(fmt.Errorf("ошибка подтверждения вывода средств %d: %w", id, err))
+	}
+	return w, nil
+}
+
+// FailWithdrawal отклоняет вывод id в состоянии pending или review: возвращает
+// сумму на кошелёк переводом с клирингового кошелька и в транзакции возврата
+// отмечает вывод failed с причиной reason. Если вывод завершил параллельный
+// запрос, возврат откатывается и возвращается ErrWithdrawalSettled. Вывод,
+// списание которого относится к закрытому учётному периоду, отклонить нельзя -
+// ErrPeriodClosed; его можно только подтвердить. Возврат не проверяет max_balance
+// кошелька: средства списаны с него же, и предел, заданный или уменьшенный после
+// списания, не должен оставлять их на клиринговом кошельке.
+func (s *Storage) FailWithdrawal(ctx context.Context, id int, reason string) (*models.Withdrawal, error) {
+	w, err := s.GetWithdrawal(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if w.Status.Settled() {
+		return nil, ErrWithdrawalSettled
+	}
+	if err := s.requireOpenTransaction(ctx, w.TransactionID); err != nil {
+		return nil, err
+	}
+
+	var failed *models.Withdrawal
+	_, err = s.execute(ctx, models.Transfer{
+		From:             w.ClearingWallet,
+		To:               w.Wallet,
+		Amount:           float64(w.Amount),
+		Memo:             "возврат вывода средств " + strconv.Itoa(w.ID),
+		Reference:        w.Reference,
+		AllowSystem:      true,
+		IgnoreMaxBalance: true,
+	}, func(ctx context.Context, tx *sql.Tx, transaction *models.Transaction) error {
+		// Условие открытого периода повторяется в транзакции возврата на случай
+		// закрытия между проверкой и возвратом.
+		var open bool
+		err := tx.QueryRowContext(ctx, "SELECT "+periodOpenCondition+" FROM transactions WHERE id = $1", w.TransactionID).Scan(&open)
+		if err != nil {
+			return internalError(fmt.Errorf("ошибка проверки транзакции %d: %w", w.TransactionID, err))
+		}
+		if !open {
+			return ErrPeriodClosed
+		}
+		row := tx.QueryRowContext(ctx, `
+    UPDATE withdrawals
+    SET status = 'failed', reversal_transaction_id = $2, reason = $3, updated_at = $4
+    WHERE id = $1 AND status IN ('pending', 'review')
+    RETURNING `+withdrawalColumns,
+			id, transaction.ID, reason, transaction.Timestamp)
+		if failed, err = scanWithdrawal(row); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrWithdrawalSettled
+			}
+			return internalError(fmt.Errorf("ошибка отказа вывода средств %d: %w", id, err))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return failed, nil
+}
+
+// ReviewExpiredWithdrawals переводит на разбор (review) выводы, которые остаются
+// в состоянии pending дольше olderThan, и возвращает их. Вывод на разборе можно
+// подтвердить или отклонить как обычно; баланс он не меняет.
+func (s *Storage) ReviewExpiredWithdrawals(ctx context.Context, olderThan time.Duration) ([]models.Withdrawal, error) {
+	rows, err := s.db.QueryContext(ctx, `
+    UPDATE withdrawals
+    SET status = 'review', reason = $2, updated_at = (clock_timestamp() AT TIME ZONE 'UTC')
+    WHERE id IN (
+        SELECT id FROM withdrawals
+        WHERE status = 'pending'
+          AND created_at <= (clock_timestamp() AT TIME ZONE 'UTC') - $1 * INTERVAL '1 second'
+        ORDER BY id LIMIT $3
+        FOR UPDATE SKIP LOCKED)
+      AND status = 'pending'
+    RETURNING `+withdrawalColumns,
+		olderThan.Seconds(), "подтверждение расчёта не получено за "+olderThan.String(), reviewWithdrawalsBatch)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("ошибка перевода выводов средств на разбор: %w", err))
+	}
+	defer rows.Close()
+
+	var reviewed []models.Withdrawal
+	for rows.Next() {
+		w, err := scanWithdrawal(rows)
+		if err != nil {
+			return nil, internalError(fmt.Errorf("ошибка сканирования строки withdrawals: %w", err))
+		}
+		reviewed = append(reviewed, *w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, internalError(fmt.Errorf("ошибка при итерации по withdrawals: %w", err))
+	}
+	return reviewed, nil
+}
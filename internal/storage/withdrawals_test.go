@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-payments/internal/models"
+)
+
+func TestFailWithdrawalClosedPeriod(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	wallet, clearing := testAddress(1), testAddress(2)
+	createTestWallet(t, s, wallet, 10)
+	createTestWallet(t, s, clearing, 0)
+	if err := s.SetWalletSystem(ctx, clearing, true, false); err != nil {
+		t.Fatalf("SetWalletSystem: %v", err)
+	}
+	w, err := s.CreateWithdrawal(ctx, models.Withdrawal{Wallet: wallet, ClearingWallet: clearing, Amount: 10}, "")
+	if err != nil {
+		t.Fatalf("CreateWithdrawal: %v", err)
+	}
+
+	// Списание вывода попадает в закрытый период: возврат изменил бы его.
+	if _, err := s.ClosePeriod(ctx, w.CreatedAt.Add(time.Second), "test", false); err != nil {
+		t.Fatalf("ClosePeriod: %v", err)
+	}
+	if _, err := s.FailWithdrawal(ctx, w.ID, "банк отклонил"); !errors.Is(err, ErrPeriodClosed) {
+		t.Fatalf("отказ от вывода в закрытом периоде: %v, want ErrPeriodClosed", err)
+	}
+	got, err := s.GetWithdrawal(ctx, w.ID)
+	if err != nil {
+		t.Fatalf("GetWithdrawal: %v", err)
+	}
+	if got.Status != models.WithdrawalPending || got.ReversalTransactionID != nil {
+		t.Errorf("вывод после отказа: состояние %s, возврат %v, want pending без возврата", got.Status, got.ReversalTransactionID)
+	}
+	if b := walletBalance(t, s, wallet); b != 0 {
+		t.Errorf("баланс кошелька %v, want 0", b)
+	}
+	if b := walletBalance(t, s, clearing); b != 10 {
+		t.Errorf("баланс клирингового кошелька %v, want 10", b)
+	}
+
+	// Принудительный откат закрытия снова открывает период.
+	if _, err := s.ClosePeriod(ctx, w.CreatedAt.Add(-time.Hour), "test", true); err != nil {
+		t.Fatalf("ClosePeriod с force: %v", err)
+	}
+	failed, err := s.FailWithdrawal(ctx, w.ID, "банк отклонил")
+	if err != nil {
+		t.Fatalf("отказ от вывода в открытом периоде: %v", err)
+	}
+	if failed.Status != models.WithdrawalFailed || failed.ReversalTransactionID == nil {
+		t.Errorf("вывод после отказа: состояние %s, возврат %v, want failed с возвратом", failed.Status, failed.ReversalTransactionID)
+	}
+	if b := walletBalance(t, s, wallet); b != 10 {
+		t.Errorf("баланс кошелька после возврата %v, want 10", b)
+	}
+}
+
+func TestWithdrawalThresholdCrossings(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	wallet, clearing := testAddress(1), testAddress(2)
+	createTestWallet(t, s, wallet, 100)
+	createTestWallet(t, s, clearing, 0)
+	if err := s.SetWalletSystem(ctx, clearing, true, false); err != nil {
+		t.Fatalf("SetWalletSystem: %v", err)
+	}
+	th, err := s.CreateBalanceThreshold(ctx, models.BalanceThreshold{
+		Wallet: wallet, Direction: models.ThresholdBelow, Amount: 50, Hysteresis: 10, Target: models.ThresholdTargetAlerter,
+	})
+	if err != nil {
+		t.Fatalf("CreateBalanceThreshold: %v", err)
+	}
+
+	// Списание вывода переключает правило, как и перевод.
+	var stats ExecStats
+	w, err := s.CreateWithdrawal(WithExecStats(ctx, &stats), models.Withdrawal{Wallet: wallet, ClearingWallet: clearing, Amount: 70}, "")
+	if err != nil {
+		t.Fatalf("CreateWithdrawal: %v", err)
+	}
+	if len(stats.Crossings) != 1 || stats.Crossings[0].Threshold.ID != th.ID || !stats.Crossings[0].Threshold.Triggered ||
+		stats.Crossings[0].Balance != 30 {
+		t.Fatalf("переключения после списания %+v, want срабатывание правила %d при балансе 30", stats.Crossings, th.ID)
+	}
+
+	// Возврат поднимает баланс выше порога с гистерезисом и снова активирует правило.
+	stats = ExecStats{}
+	failed, err := s.FailWithdrawal(WithExecStats(ctx, &stats), w.ID, "банк отклонил")
+	if err != nil {
+		t.Fatalf("FailWithdrawal: %v", err)
+	}
+	if len(stats.Crossings) != 1 || stats.Crossings[0].Threshold.Triggered ||
+		*stats.Crossings[0].Threshold.LastTransactionID != *failed.ReversalTransactionID {
+		t.Errorf("переключения после возврата %+v, want активацию правила переводом %d", stats.Crossings, *failed.ReversalTransactionID)
+	}
+}
+
+func TestFailWithdrawalIgnoresMaxBalance(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	wallet, clearing, sender := testAddress(1), testAddress(2), testAddress(3)
+	createTestWallet(t, s, wallet, 100)
+	createTestWallet(t, s, clearing, 0)
+	createTestWallet(t, s, sender, 100)
+	if err := s.SetWalletSystem(ctx, clearing, true, false); err != nil {
+		t.Fatalf("SetWalletSystem: %v", err)
+	}
+	w, err := s.CreateWithdrawal(ctx, models.Withdrawal{Wallet: wallet, ClearingWallet: clearing, Amount: 60}, "")
+	if err != nil {
+		t.Fatalf("CreateWithdrawal: %v", err)
+	}
+
+	// Предел задан после списания и ниже баланса, который даст возврат.
+	maxBalance := 50.0
+	if err := s.SetWalletMaxBalance(ctx, wallet, &maxBalance); err != nil {
+		t.Fatalf("SetWalletMaxBalance: %v", err)
+	}
+	failed, err := s.FailWithdrawal(ctx, w.ID, "банк отклонил")
+	if err != nil {
+		t.Fatalf("отказ от вывода сверх max_balance: %v", err)
+	}
+	if failed.Status != models.WithdrawalFailed || failed.ReversalTransactionID == nil {
+		t.Errorf("вывод после отказа: состояние %s, возврат %v, want failed с возвратом", failed.Status, failed.ReversalTransactionID)
+	}
+	if b := walletBalance(t, s, wallet); b != 100 {
+		t.Errorf("баланс кошелька после возврата %v, want 100 сверх предела", b)
+	}
+	if b := walletBalance(t, s, clearing); b != 0 {
+		t.Errorf("баланс клирингового кошелька %v, want 0", b)
+	}
+
+	// Обычные переводы на этот кошелёк предел по-прежнему ограничивает.
+	if _, err := s.Execute(ctx, models.Transfer{From: sender, To: wallet, Amount: 1}); !errors.Is(err, ErrRecipientLimitExceeded) {
+		t.Errorf("перевод на кошелёк сверх предела: %v, want ErrRecipientLimitExceeded", err)
+	}
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"go-payments/internal/models"
+)
+
+// RecordZeroNote записывает нулевую транзакцию-заметку t (статус zero_note) между
+// существующими кошельками t.From и t.To. Балансы не меняются, пороги, пределы
+// баланса и ключи идемпотентности не проверяются; отсутствующий кошелёк -
+// TransactionError с кодом CodeSenderNotFound или CodeRecipientNotFound, причём
+// неудачная заметка, в отличие от перевода, не записывается.
+func (s *Storage) RecordZeroNote(ctx context.Context, t models.Transfer) (*models.Transaction, error) {
+	t.Amount = 0
+	t.GroupID = ""
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("не удалось начать транзакцию: %w", err))
+	}
+	defer tx.Rollback()
+
+	// FOR SHARE не даёт удалить кошельки, пока заметка не записана.
+	rows, err := tx.QueryContext(ctx, "SELECT address FROM wallets WHERE address IN ($1, $2) FOR SHARE", t.From, t.To)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("ошибка проверки кошельков заметки: %w", err))
+	}
+	found := make(map[string]bool, 2)
+	for rows.Next() {
+		var address string
+		if err := rows.Scan(&address); err != nil {
+			rows.Close()
+			return nil, internalError(fmt.Errorf("ошибка сканирования строки wallets: %w", err))
+		}
+		found[address] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, internalError(fmt.Errorf("ошибка при итерации по wallets: %w", err))
+	}
+	if !found[t.From] {
+		return nil, &TransactionError{Code: CodeSenderNotFound, OriginalErr: ErrWalletNotFound}
+	}
+	if !found[t.To] {
+		return nil, &TransactionError{Code: CodeRecipientNotFound, OriginalErr: ErrWalletNotFound}
+	}
+
+	transaction, err := s.logTransactionInTx(ctx, tx, t, models.StatusZeroNote)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, internalError(fmt.Errorf("ошибка фиксации заметки: %w", err))
+	}
+	return transaction, nil
+}
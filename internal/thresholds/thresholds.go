@@ -0,0 +1,159 @@
+/*
+thresholds отправляет предупреждения о переходе балансов кошельков через пороги.
+
+Правила порога (таблица balance_thresholds) задаются для отдельных кошельков:
+направление (above - баланс стал больше порога, below - меньше), сумма порога,
+гистерезис и получатель предупреждения - URL вебхука или канал предупреждений
+сервиса (alerter: ALERT_WEBHOOK_URL или лог).
+
+Правила проверяет само хранилище в транзакции перевода по новым балансам
+отправителя и получателя и возвращает переключения в storage.ExecStats. Правило
+срабатывает один раз при переходе через порог и снова готово к срабатыванию,
+только когда баланс вернётся за порог с запасом гистерезиса, поэтому переводы,
+пока баланс остаётся за порогом, предупреждений не порождают.
+
+Wrap оборачивает хранилище так, что после каждого изменения балансов - Execute,
+CreateWithdrawal (списание на клиринговый кошелёк) и FailWithdrawal (возврат на
+кошелёк) - предупреждения по сработавшим правилам отправляются в фоне и не
+задерживают ответ. Предупреждение содержит правило, баланс после перевода и сам
+перевод. Результаты доставки
+учитываются в метрике payments_threshold_alerts_total{direction, result}.
+*/
+package thresholds
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go-payments/internal/alerts"
+	"go-payments/internal/api"
+	"go-payments/internal/models"
+	"go-payments/internal/redact"
+	"go-payments/internal/storage"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Время на доставку одного предупреждения.
+const alertTimeout = 10 * time.Second
+
+var alertsSent = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "payments_threshold_alerts_total",
+	Help: "Количество предупреждений о порогах баланса по направлению и результату доставки (success, failure).",
+}, []string{"direction", "result"})
+
+// thresholdStorage отправляет предупреждения по правилам, которые переключил перевод.
+type thresholdStorage struct {
+	api.Storage
+	alerter alerts.ThresholdAlerter
+}
+
+// Wrap возвращает хранилище, которое после каждого перевода отправляет
+// предупреждения по сработавшим правилам порога. alerter получает предупреждения
+// правил с получателем models.ThresholdTargetAlerter.
+func Wrap(next api.Storage, alerter alerts.ThresholdAlerter) api.Storage {
+	return &thresholdStorage{Storage: next, alerter: alerter}
+}
+
+func (s *thresholdStorage) Execute(ctx context.Context, t models.Transfer) (*models.Transaction, error) {
+	ctx, stats := withExecStats(ctx)
+	transaction, err := s.Storage.Execute(ctx, t)
+	if err != nil {
+		return transaction, err
+	}
+	s.dispatch(ctx, stats.Crossings, *transaction)
+	return transaction, nil
+}
+
+// CreateWithdrawal и FailWithdrawal переводят средства тем же путём, что и Execute,
+// и тоже переключают правила порога: списание - кошелька вывода и клирингового
+// кошелька, возврат - их же в обратную сторону.
+func (s *thresholdStorage) CreateWithdrawal(ctx context.Context, w models.Withdrawal, idempotencyKey string) (*models.Withdrawal, error) {
+	ctx, stats := withExecStats(ctx)
+	created, err := s.Storage.CreateWithdrawal(ctx, w, idempotencyKey)
+	if err != nil {
+		return created, err
+	}
+	if len(stats.Crossings) > 0 {
+		s.dispatch(ctx, stats.Crossings, s.transaction(ctx, created.TransactionID))
+	}
+	return created, nil
+}
+
+func (s *thresholdStorage) FailWithdrawal(ctx context.Context, id int, reason string) (*models.Withdrawal, error) {
+	ctx, stats := withExecStats(ctx)
+	failed, err := s.Storage.FailWithdrawal(ctx, id, reason)
+	if err != nil {
+		return failed, err
+	}
+	if len(stats.Crossings) > 0 && failed.ReversalTransactionID != nil {
+		s.dispatch(ctx, stats.Crossings, s.transaction(ctx, *failed.ReversalTransactionID))
+	}
+	return failed, nil
+}
+
+// withExecStats возвращает контекст, в котором хранилище заполнит ExecStats:
+// переданные вызывающим или новые.
+func withExecStats(ctx context.Context) (context.Context, *storage.ExecStats) {
+	stats := storage.ExecStatsFrom(ctx)
+	if stats == nil {
+		stats = &storage.ExecStats{}
+		ctx = storage.WithExecStats(ctx, stats)
+	}
+	return ctx, stats
+}
+
+// transaction возвращает перевод id для предупреждения. Перевод уже
+// зафиксирован; если прочитать его не удалось, предупреждение всё равно
+// отправляется с одним идентификатором перевода.
+func (s *thresholdStorage) transaction(ctx context.Context, id int) models.Transaction {
+	transaction, err := s.Storage.GetTransaction(ctx, id)
+	if err != nil {
+		log.Printf("ошибка получения перевода %d для предупреждения о пороге: %v", id, err)
+		return models.Transaction{ID: id}
+	}
+	return *transaction
+}
+
+// dispatch отправляет предупреждения по правилам, которые сработали после
+// перевода transaction, и записывает в лог правила, снова готовые к срабатыванию.
+func (s *thresholdStorage) dispatch(ctx context.Context, crossings []models.ThresholdCrossing, transaction models.Transaction) {
+	for _, c := range crossings {
+		th := c.Threshold
+		if !th.Triggered {
+			log.Printf("правило порога %d кошелька %s снова активно после перевода %d",
+				th.ID, redact.Address(th.Wallet), transaction.ID)
+			continue
+		}
+		s.send(ctx, th.Target, alerts.ThresholdAlert{
+			ThresholdID: th.ID,
+			Address:     th.Wallet,
+			Direction:   th.Direction,
+			Threshold:   th.Amount,
+			Balance:     c.Balance,
+			Transaction: transaction,
+		})
+	}
+}
+
+// send доставляет предупреждение получателю target в фоне: предупреждение не
+// должно задерживать ответ и не зависит от отмены запроса.
+func (s *thresholdStorage) send(ctx context.Context, target string, alert alerts.ThresholdAlert) {
+	alerter := s.alerter
+	if target != models.ThresholdTargetAlerter {
+		alerter = &alerts.WebhookAlerter{URL: target}
+	}
+	go func() {
+		alertCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), alertTimeout)
+		defer cancel()
+		if err := alerter.AlertThreshold(alertCtx, alert); err != nil {
+			alertsSent.WithLabelValues(string(alert.Direction), "failure").Inc()
+			log.Printf("ошибка отправки предупреждения по правилу порога %d кошелька %s: %v",
+				alert.ThresholdID, redact.Address(alert.Address), err)
+			return
+		}
+		alertsSent.WithLabelValues(string(alert.Direction), "success").Inc()
+	}()
+}
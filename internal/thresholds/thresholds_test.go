@@ -0,0 +1,261 @@
+package thresholds
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-payments/internal/alerts"
+	"go-payments/internal/api"
+	"go-payments/internal/models"
+	"go-payments/internal/money"
+	"go-payments/internal/storage"
+)
+
+const (
+	wallet   = "wallet"
+	clearing = "clearing"
+)
+
+// ledgerStorage - хранилище с балансами в памяти, которое переключает правила
+// порога по тем же условиям, что и crossThresholdsInTx, и ставит переводам время
+// по искусственным часам: каждый перевод на минуту позже предыдущего.
+type ledgerStorage struct {
+	api.Storage
+
+	now          time.Time
+	balances     map[string]float64
+	rules        []*models.BalanceThreshold
+	transactions map[int]models.Transaction
+	withdrawals  map[int]models.Withdrawal
+}
+
+func newLedgerStorage(balances map[string]float64, rules ...models.BalanceThreshold) *ledgerStorage {
+	s := &ledgerStorage{
+		now:          time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		balances:     balances,
+		transactions: map[int]models.Transaction{},
+		withdrawals:  map[int]models.Withdrawal{},
+	}
+	for i := range rules {
+		s.rules = append(s.rules, &rules[i])
+	}
+	return s
+}
+
+func (s *ledgerStorage) transfer(ctx context.Context, from, to string, amount float64) models.Transaction {
+	s.now = s.now.Add(time.Minute)
+	s.balances[from] -= amount
+	s.balances[to] += amount
+	transaction := models.Transaction{
+		ID:        len(s.transactions) + 1,
+		From:      from,
+		To:        to,
+		Amount:    money.Amount(amount),
+		Timestamp: s.now,
+		Status:    models.StatusSuccess,
+	}
+	s.transactions[transaction.ID] = transaction
+
+	var crossings []models.ThresholdCrossing
+	for _, th := range s.rules {
+		if th.Wallet != from && th.Wallet != to {
+			continue
+		}
+		balance := s.balances[th.Wallet]
+		var flip bool
+		switch {
+		case !th.Triggered && th.Direction == models.ThresholdAbove:
+			flip = balance > float64(th.Amount)
+		case !th.Triggered:
+			flip = balance < float64(th.Amount)
+		case th.Direction == models.ThresholdAbove:
+			flip = balance <= float64(th.Amount-th.Hysteresis)
+		default:
+			flip = balance >= float64(th.Amount+th.Hysteresis)
+		}
+		if flip {
+			th.Triggered = !th.Triggered
+			th.LastTransactionID = &transaction.ID
+			th.LastCrossedAt = &transaction.Timestamp
+			crossings = append(crossings, models.ThresholdCrossing{Threshold: *th, Balance: money.Amount(balance)})
+		}
+	}
+	if stats := storage.ExecStatsFrom(ctx); stats != nil {
+		stats.Crossings = crossings
+	}
+	return transaction
+}
+
+func (s *ledgerStorage) Execute(ctx context.Context, t models.Transfer) (*models.Transaction, error) {
+	transaction := s.transfer(ctx, t.From, t.To, t.Amount)
+	return &transaction, nil
+}
+
+func (s *ledgerStorage) CreateWithdrawal(ctx context.Context, w models.Withdrawal, idempotencyKey string) (*models.Withdrawal, error) {
+	transaction := s.transfer(ctx, w.Wallet, w.ClearingWallet, float64(w.Amount))
+	w.ID = len(s.withdrawals) + 1
+	w.Status = models.WithdrawalPending
+	w.TransactionID = transaction.ID
+	s.withdrawals[w.ID] = w
+	return &w, nil
+}
+
+func (s *ledgerStorage) FailWithdrawal(ctx context.Context, id int, reason string) (*models.Withdrawal, error) {
+	w := s.withdrawals[id]
+	transaction := s.transfer(ctx, w.ClearingWallet, w.Wallet, float64(w.Amount))
+	w.Status = models.WithdrawalFailed
+	w.ReversalTransactionID = &transaction.ID
+	s.withdrawals[id] = w
+	return &w, nil
+}
+
+func (s *ledgerStorage) GetTransaction(ctx context.Context, id int) (*models.Transaction, error) {
+	transaction, ok := s.transactions[id]
+	if !ok {
+		return nil, storage.ErrTxNotFound
+	}
+	return &transaction, nil
+}
+
+// recordingAlerter передаёт полученные предупреждения в канал.
+type recordingAlerter chan alerts.ThresholdAlert
+
+func (a recordingAlerter) AlertThreshold(ctx context.Context, alert alerts.ThresholdAlert) error {
+	a <- alert
+	return nil
+}
+
+// expectAlert ждёт предупреждение, отправленное в фоне, и возвращает его.
+func expectAlert(t *testing.T, step string, alerter recordingAlerter) alerts.ThresholdAlert {
+	t.Helper()
+	select {
+	case alert := <-alerter:
+		return alert
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s: предупреждение не отправлено", step)
+		return alerts.ThresholdAlert{}
+	}
+}
+
+// expectNoAlert проверяет, что шаг не отправил предупреждений. Отправка идёт в
+// фоне, поэтому пауза даёт ей время появиться.
+func expectNoAlert(t *testing.T, step string, alerter recordingAlerter) {
+	t.Helper()
+	select {
+	case alert := <-alerter:
+		t.Fatalf("%s: лишнее предупреждение по правилу %d, баланс %v", step, alert.ThresholdID, alert.Balance)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestThresholdHysteresis(t *testing.T) {
+	ctx := context.Background()
+	db := newLedgerStorage(map[string]float64{wallet: 100, clearing: 0}, models.BalanceThreshold{
+		ID: 1, Wallet: wallet, Direction: models.ThresholdBelow, Amount: 50, Hysteresis: 10,
+		Target: models.ThresholdTargetAlerter,
+	})
+	alerter := make(recordingAlerter, 10)
+	s := Wrap(db, alerter)
+	send := func(amount float64) {
+		t.Helper()
+		if _, err := s.Execute(ctx, models.Transfer{From: wallet, To: clearing, Amount: amount}); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+	}
+	receive := func(amount float64) {
+		t.Helper()
+		if _, err := s.Execute(ctx, models.Transfer{From: clearing, To: wallet, Amount: amount}); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+	}
+
+	send(40) // 60: выше порога
+	expectNoAlert(t, "баланс выше порога", alerter)
+
+	send(20) // 40: переход через порог
+	alert := expectAlert(t, "переход через порог", alerter)
+	if alert.ThresholdID != 1 || alert.Balance != 40 || alert.Direction != models.ThresholdBelow || alert.Threshold != 50 {
+		t.Errorf("предупреждение %+v, want правило 1, баланс 40, below 50", alert)
+	}
+	if want := db.transactions[2]; alert.Transaction.ID != want.ID || !alert.Transaction.Timestamp.Equal(want.Timestamp) {
+		t.Errorf("перевод предупреждения %d в %v, want %d в %v",
+			alert.Transaction.ID, alert.Transaction.Timestamp, want.ID, want.Timestamp)
+	}
+
+	send(10) // 30: остаётся за порогом
+	expectNoAlert(t, "баланс остаётся за порогом", alerter)
+
+	receive(25) // 55: вернулся за порог, но меньше чем на гистерезис
+	expectNoAlert(t, "возврат в пределах гистерезиса", alerter)
+	send(10) // 45: снова ниже порога, правило ещё не готово
+	expectNoAlert(t, "повторный переход без возврата за гистерезис", alerter)
+
+	receive(15) // 60: возврат на гистерезис, правило снова активно
+	expectNoAlert(t, "возврат за гистерезис", alerter)
+	if !db.rules[0].LastCrossedAt.Equal(db.now) || db.rules[0].Triggered {
+		t.Errorf("правило после возврата: сработало %v, переключено %v, want активно с %v",
+			db.rules[0].Triggered, db.rules[0].LastCrossedAt, db.now)
+	}
+
+	send(11) // 49: новый переход
+	alert = expectAlert(t, "переход после возврата за гистерезис", alerter)
+	if alert.Balance != 49 || alert.Transaction.ID != 7 {
+		t.Errorf("предупреждение: баланс %v, перевод %d, want 49, 7", alert.Balance, alert.Transaction.ID)
+	}
+}
+
+func TestThresholdWithdrawals(t *testing.T) {
+	ctx := context.Background()
+	db := newLedgerStorage(map[string]float64{wallet: 100, clearing: 0},
+		models.BalanceThreshold{ID: 1, Wallet: wallet, Direction: models.ThresholdBelow, Amount: 50, Hysteresis: 10,
+			Target: models.ThresholdTargetAlerter},
+		models.BalanceThreshold{ID: 2, Wallet: clearing, Direction: models.ThresholdAbove, Amount: 20,
+			Target: models.ThresholdTargetAlerter},
+	)
+	alerter := make(recordingAlerter, 10)
+	s := Wrap(db, alerter)
+
+	// Списание вывода переводит кошелёк ниже порога, а клиринговый - выше.
+	w, err := s.CreateWithdrawal(ctx, models.Withdrawal{Wallet: wallet, ClearingWallet: clearing, Amount: 70}, "")
+	if err != nil {
+		t.Fatalf("CreateWithdrawal: %v", err)
+	}
+	got := map[int]alerts.ThresholdAlert{}
+	for range 2 {
+		alert := expectAlert(t, "списание вывода", alerter)
+		got[alert.ThresholdID] = alert
+	}
+	expectNoAlert(t, "списание вывода", alerter)
+	if a := got[1]; a.Balance != 30 || a.Transaction.ID != w.TransactionID || a.Transaction.To != clearing {
+		t.Errorf("предупреждение кошелька %+v, want баланс 30 после перевода %d", a, w.TransactionID)
+	}
+	if a := got[2]; a.Balance != 70 || a.Transaction.ID != w.TransactionID {
+		t.Errorf("предупреждение клирингового кошелька %+v, want баланс 70 после перевода %d", a, w.TransactionID)
+	}
+
+	// Возврат вывода снова активирует оба правила, но не отправляет предупреждений.
+	if _, err := s.FailWithdrawal(ctx, w.ID, "банк отклонил"); err != nil {
+		t.Fatalf("FailWithdrawal: %v", err)
+	}
+	expectNoAlert(t, "возврат вывода", alerter)
+	if db.rules[0].Triggered || db.rules[1].Triggered {
+		t.Errorf("правила после возврата сработали: %v, %v, want активны", db.rules[0].Triggered, db.rules[1].Triggered)
+	}
+
+	// Возврат, переводящий кошелёк через порог, тоже отправляет предупреждение.
+	db.rules[0].Direction, db.rules[0].Amount = models.ThresholdAbove, 90
+	w, err = s.CreateWithdrawal(ctx, models.Withdrawal{Wallet: wallet, ClearingWallet: clearing, Amount: 20}, "")
+	if err != nil {
+		t.Fatalf("CreateWithdrawal: %v", err)
+	}
+	expectNoAlert(t, "списание без перехода порога", alerter)
+	failed, err := s.FailWithdrawal(ctx, w.ID, "банк отклонил")
+	if err != nil {
+		t.Fatalf("FailWithdrawal: %v", err)
+	}
+	alert := expectAlert(t, "возврат вывода через порог", alerter)
+	if alert.ThresholdID != 1 || alert.Balance != 100 || alert.Transaction.ID != *failed.ReversalTransactionID || alert.Transaction.To != wallet {
+		t.Errorf("предупреждение возврата %+v, want правило 1, баланс 100 после перевода %d", alert, *failed.ReversalTransactionID)
+	}
+}
@@ -0,0 +1,100 @@
+/*
+txcache кэширует неизменяемые транзакции в памяти процесса.
+
+Клиенты постоянно перечитывают GET /api/transactions/{id}, хотя завершённая и
+подтверждённая получателем транзакция больше не меняется. Wrap оборачивает
+хранилище так, что GetTransaction для таких транзакций (models.Transaction.Immutable)
+отвечает из LRU-кэша по идентификатору и не обращается к базе данных. Изменяемые
+транзакции не кэшируются, поэтому инвалидация не нужна: транзакция попадает в кэш
+только после последнего возможного изменения.
+
+Обращения к кэшу учитываются в метрике `payments_transaction_cache_requests_total`
+с меткой `result` (hit, miss).
+*/
+package txcache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"go-payments/internal/api"
+	"go-payments/internal/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var requests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "payments_transaction_cache_requests_total",
+	Help: "Обращения к кэшу неизменяемых транзакций.",
+}, []string{"result"})
+
+// cachingStorage отвечает на GetTransaction из LRU-кэша неизменяемых транзакций.
+type cachingStorage struct {
+	api.Storage
+	size int
+
+	mu      sync.Mutex
+	order   *list.List // элементы - *models.Transaction, недавние в начале
+	entries map[int]*list.Element
+}
+
+// Wrap возвращает хранилище с кэшем не больше size неизменяемых транзакций.
+func Wrap(next api.Storage, size int) api.Storage {
+	return &cachingStorage{
+		Storage: next,
+		size:    size,
+		order:   list.New(),
+		entries: make(map[int]*list.Element, size),
+	}
+}
+
+func (s *cachingStorage) GetTransaction(ctx context.Context, id int) (*models.Transaction, error) {
+	if t, ok := s.get(id); ok {
+		requests.WithLabelValues("hit").Inc()
+		return t, nil
+	}
+	requests.WithLabelValues("miss").Inc()
+
+	t, err := s.Storage.GetTransaction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if t.Immutable() {
+		s.put(t)
+	}
+	return t, nil
+}
+
+// get возвращает копию транзакции из кэша. Метаданные копии общие с кэшем и
+// не должны изменяться вызывающим.
+func (s *cachingStorage) get(id int) (*models.Transaction, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[id]
+	if !ok {
+		return nil, false
+	}
+	s.order.MoveToFront(el)
+	t := *el.Value.(*models.Transaction)
+	return &t, true
+}
+
+// put сохраняет копию транзакции, вытесняя давно не запрошенные.
+func (s *cachingStorage) put(t *models.Transaction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[t.ID]; ok {
+		return
+	}
+	stored := *t
+	s.entries[t.ID] = s.order.PushFront(&stored)
+	for s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*models.Transaction).ID)
+	}
+}
@@ -0,0 +1,185 @@
+package txcache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-payments/internal/api"
+	"go-payments/internal/config"
+	"go-payments/internal/models"
+	"go-payments/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	sender    = fmt.Sprintf("%064x", 1)
+	recipient = fmt.Sprintf("%064x", 2)
+)
+
+// memStorage хранит транзакции в памяти и считает чтения GetTransaction.
+type memStorage struct {
+	api.Storage
+
+	mu           sync.Mutex
+	transactions map[int]models.Transaction
+	reads        int
+}
+
+func (s *memStorage) GetTransaction(ctx context.Context, id int) (*models.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reads++
+	t, ok := s.transactions[id]
+	if !ok {
+		return nil, storage.ErrTxNotFound
+	}
+	return &t, nil
+}
+
+func (s *memStorage) AcknowledgeTransaction(ctx context.Context, id int, address string) (*models.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.transactions[id]
+	if !ok {
+		return nil, storage.ErrTxNotFound
+	}
+	if t.To != address || t.Status != models.StatusSuccess {
+		return nil, storage.ErrNotRecipient
+	}
+	if t.AcknowledgedAt == nil {
+		at := time.Date(2026, 1, 2, 3, 5, 0, 0, time.UTC)
+		t.AcknowledgedAt = &at
+		s.transactions[id] = t
+	}
+	return &t, nil
+}
+
+func (s *memStorage) readCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reads
+}
+
+func newTestServer(size int) (*memStorage, http.Handler) {
+	db := &memStorage{transactions: map[int]models.Transaction{
+		1: {ID: 1, From: sender, To: recipient, Amount: 10, Status: models.StatusSuccess,
+			Timestamp: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+		2: {ID: 2, From: sender, To: recipient, Amount: 20, Status: models.StatusSuccess,
+			Timestamp: time.Date(2026, 1, 2, 3, 4, 6, 0, time.UTC)},
+		3: {ID: 3, From: sender, To: recipient, Amount: 30, Status: models.StatusSuccess,
+			Timestamp: time.Date(2026, 1, 2, 3, 4, 7, 0, time.UTC)},
+	}}
+	cfg := &config.Config{
+		HTTP:          config.HTTP{MaxBodyBytes: 1 << 20},
+		CursorSecret:  "test-cursor-secret",
+		MaxAmount:     1000000000,
+		AddressScheme: "hex64",
+	}
+	r := chi.NewRouter()
+	api.New(Wrap(db, size), cfg).RegisterRoutes(r)
+	return db, r
+}
+
+func get(h http.Handler, id int, ifNoneMatch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/transactions/%d", id), nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func ack(h http.Handler, id int) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/transactions/%d/ack", id),
+		strings.NewReader(`{"address":"`+recipient+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestTransactionCache(t *testing.T) {
+	db, h := newTestServer(10)
+
+	// Неподтверждённая транзакция ещё изменится: она не кэшируется и отдаётся
+	// с no-cache, каждый запрос читает базу.
+	first := get(h, 1, "")
+	if first.Code != http.StatusOK || first.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatalf("изменяемая транзакция: %d, Cache-Control %q", first.Code, first.Header().Get("Cache-Control"))
+	}
+	mutableETag := first.Header().Get("ETag")
+	if mutableETag == "" {
+		t.Fatal("ответ без ETag")
+	}
+	get(h, 1, "")
+	if n := db.readCount(); n != 2 {
+		t.Errorf("чтений изменяемой транзакции %d, want 2", n)
+	}
+
+	// If-None-Match с текущим ETag - 304 без тела.
+	w := get(h, 1, mutableETag)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("ETag") != mutableETag {
+		t.Errorf("If-None-Match с текущим ETag: %d, тело %q, ETag %q", w.Code, w.Body, w.Header().Get("ETag"))
+	}
+
+	// Подтверждение - последнее изменение транзакции: ETag меняется, старый ETag
+	// больше не даёт 304, а транзакция становится неизменяемой.
+	if w := ack(h, 1); w.Code != http.StatusOK {
+		t.Fatalf("подтверждение: %d %s", w.Code, w.Body)
+	}
+	w = get(h, 1, mutableETag)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"acknowledged_at"`) {
+		t.Fatalf("после подтверждения со старым ETag: %d %s, want 200 с acknowledged_at", w.Code, w.Body)
+	}
+	immutableETag := w.Header().Get("ETag")
+	if immutableETag == mutableETag {
+		t.Errorf("ETag не изменился после подтверждения: %s", immutableETag)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "public, max-age=86400" {
+		t.Errorf("Cache-Control неизменяемой транзакции %q", cc)
+	}
+
+	// Повторные запросы, в том числе условные, отвечают из кэша без чтения базы.
+	reads := db.readCount()
+	for range 5 {
+		if w := get(h, 1, ""); w.Code != http.StatusOK || w.Header().Get("ETag") != immutableETag {
+			t.Fatalf("повтор из кэша: %d, ETag %q, want %s", w.Code, w.Header().Get("ETag"), immutableETag)
+		}
+	}
+	if w := get(h, 1, `W/"other", `+immutableETag); w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match из списка с текущим ETag: %d, want 304", w.Code)
+	}
+	if n := db.readCount(); n != reads {
+		t.Errorf("чтений базы после кэширования %d, want %d", n-reads, 0)
+	}
+}
+
+func TestTransactionCacheEviction(t *testing.T) {
+	db, h := newTestServer(2)
+	for id := 1; id <= 3; id++ {
+		if w := ack(h, id); w.Code != http.StatusOK {
+			t.Fatalf("подтверждение %d: %d %s", id, w.Code, w.Body)
+		}
+		get(h, id, "")
+	}
+
+	// В кэше на две транзакции остались 2 и 3; 1 вытеснена и читается из базы.
+	reads := db.readCount()
+	get(h, 3, "")
+	get(h, 2, "")
+	if n := db.readCount() - reads; n != 0 {
+		t.Errorf("чтений недавних транзакций %d, want 0", n)
+	}
+	get(h, 1, "")
+	if n := db.readCount() - reads; n != 1 {
+		t.Errorf("чтений вытесненной транзакции %d, want 1", n)
+	}
+}
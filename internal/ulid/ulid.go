@@ -0,0 +1,135 @@
+/*
+ulid создаёт ULID - 26-символьные идентификаторы в base32 Крокфорда из 48 бит
+времени в миллисекундах и 80 случайных бит. ULID сортируются по времени создания
+и, в отличие от порядковых номеров, не раскрывают количество записей и не
+совпадают у записей разных окружений.
+
+Functions:
+  - NewGenerator: Создаёт генератор с заданными часами и источником случайности;
+    тесты передают фиксированные часы и детерминированный источник.
+  - Generator.New: Возвращает следующий ULID. В пределах одной миллисекунды
+    генератор увеличивает случайную часть предыдущего ULID, поэтому порядок
+    создания сохраняется и внутри миллисекунды.
+  - Normalize: Проверяет строку и приводит её к каноническому виду (верхний регистр).
+*/
+package ulid
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Length - длина ULID в символах.
+const Length = 26
+
+// Алфавит base32 Крокфорда: без I, L, O и U.
+const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ErrOverflow - в одной миллисекунде создано больше ULID, чем вмещает случайная часть.
+var ErrOverflow = errors.New("случайная часть ULID переполнена в пределах миллисекунды")
+
+// Clock возвращает текущее время.
+type Clock func() time.Time
+
+// Generator создаёт монотонно возрастающие ULID; безопасен для параллельного использования.
+type Generator struct {
+	clock   Clock
+	entropy io.Reader
+
+	mu     sync.Mutex
+	lastMs uint64
+	last   [10]byte
+}
+
+// NewGenerator создаёт генератор. Без clock используется time.Now, без entropy -
+// crypto/rand.
+func NewGenerator(clock Clock, entropy io.Reader) *Generator {
+	if clock == nil {
+		clock = time.Now
+	}
+	if entropy == nil {
+		entropy = rand.Reader
+	}
+	return &Generator{clock: clock, entropy: entropy}
+}
+
+// New возвращает следующий ULID. Если часы не сдвинулись (или отстали) с
+// предыдущего вызова, время остаётся прежним, а случайная часть увеличивается на 1.
+func (g *Generator) New() (string, error) {
+	ms := uint64(g.clock().UnixMilli())
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.lastMs != 0 && ms <= g.lastMs {
+		if !increment(&g.last) {
+			return "", ErrOverflow
+		}
+		ms = g.lastMs
+	} else {
+		if _, err := io.ReadFull(g.entropy, g.last[:]); err != nil {
+			return "", fmt.Errorf("не удалось получить случайную часть ULID: %w", err)
+		}
+		g.lastMs = ms
+	}
+	return encode(ms, g.last), nil
+}
+
+// increment увеличивает случайную часть на 1; false - часть переполнилась.
+func increment(b *[10]byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// encode кодирует 128 бит ULID (время и случайную часть) в 26 символов по 5 бит,
+// начиная со старших; старшие 2 бита первого символа всегда нулевые.
+func encode(ms uint64, random [10]byte) string {
+	hi := ms<<16 | uint64(random[0])<<8 | uint64(random[1])
+	var lo uint64
+	for _, b := range random[2:] {
+		lo = lo<<8 | uint64(b)
+	}
+
+	var out [Length]byte
+	for i := range out {
+		shift := uint(5 * (Length - 1 - i))
+		var v uint64
+		switch {
+		case shift >= 64:
+			v = hi >> (shift - 64)
+		case shift+5 <= 64:
+			v = lo >> shift
+		default:
+			v = lo>>shift | hi<<(64-shift)
+		}
+		out[i] = alphabet[v&31]
+	}
+	return string(out[:])
+}
+
+// Normalize возвращает ULID s в верхнем регистре или false, если s - не ULID.
+func Normalize(s string) (string, bool) {
+	if len(s) != Length {
+		return "", false
+	}
+	s = strings.ToUpper(s)
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(alphabet, s[i]) < 0 {
+			return "", false
+		}
+	}
+	// Первый символ несёт старшие 3 бита 48-битного времени.
+	if s[0] > '7' {
+		return "", false
+	}
+	return s, true
+}
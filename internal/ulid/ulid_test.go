@@ -0,0 +1,85 @@
+package ulid
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fixedClock - часы теста, которые двигает сам тест.
+type fixedClock struct{ now time.Time }
+
+func (c *fixedClock) Now() time.Time { return c.now }
+
+func TestGenerator(t *testing.T) {
+	// Время из примера спецификации ULID: 1469918176385 мс - "01ARYZ6S41".
+	clock := &fixedClock{now: time.UnixMilli(1469918176385)}
+	g := NewGenerator(clock.Now, bytes.NewReader(make([]byte, 100)))
+
+	first, err := g.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "01ARYZ6S41" + strings.Repeat("0", 16); first != want {
+		t.Fatalf("первый ULID %s, want %s", first, want)
+	}
+
+	// В той же миллисекунде и при отставших часах случайная часть растёт на 1.
+	second, _ := g.New()
+	clock.now = clock.now.Add(-time.Second)
+	third, _ := g.New()
+	if second != "01ARYZ6S41"+strings.Repeat("0", 15)+"1" || third != "01ARYZ6S41"+strings.Repeat("0", 15)+"2" {
+		t.Errorf("ULID в той же миллисекунде: %s, %s", second, third)
+	}
+
+	// Новая миллисекунда - новое время и новая случайная часть.
+	clock.now = time.UnixMilli(1469918176386)
+	fourth, _ := g.New()
+	if fourth[:10] != "01ARYZ6S42" || fourth <= third {
+		t.Errorf("ULID следующей миллисекунды %s, want больше %s с временем 01ARYZ6S42", fourth, third)
+	}
+	for _, id := range []string{first, second, third, fourth} {
+		if got, ok := Normalize(id); !ok || got != id {
+			t.Errorf("Normalize(%s) = %s, %v", id, got, ok)
+		}
+	}
+}
+
+func TestGeneratorOverflow(t *testing.T) {
+	clock := &fixedClock{now: time.UnixMilli(1)}
+	g := NewGenerator(clock.Now, bytes.NewReader(bytes.Repeat([]byte{0xff}, 10)))
+	if _, err := g.New(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.New(); !errors.Is(err, ErrOverflow) {
+		t.Errorf("ULID после максимальной случайной части: %v, want ErrOverflow", err)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"01ARZ3NDEKTSV4RRFFQ69G5FAV", "01ARZ3NDEKTSV4RRFFQ69G5FAV", true},
+		{"01arz3ndektsv4rrffq69g5fav", "01ARZ3NDEKTSV4RRFFQ69G5FAV", true},
+		{"7ZZZZZZZZZZZZZZZZZZZZZZZZZ", "7ZZZZZZZZZZZZZZZZZZZZZZZZZ", true},
+		// Время больше 48 бит.
+		{"8ZZZZZZZZZZZZZZZZZZZZZZZZZ", "", false},
+		// Символы вне алфавита Крокфорда.
+		{"01ARZ3NDEKTSV4RRFFQ69G5FAU", "", false},
+		{"01ARZ3NDEKTSV4RRFFQ69G5FAI", "", false},
+		{"01ARZ3NDEKTSV4RRFFQ69G5FA", "", false},
+		{"42", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Normalize(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Normalize(%q) = %q, %v, want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -2,32 +2,86 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
+	"flag"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"go-payments/internal/address"
+	"go-payments/internal/alerts"
 	"go-payments/internal/api"
+	"go-payments/internal/check"
+	"go-payments/internal/checkpoint"
+	"go-payments/internal/config"
+	"go-payments/internal/dedup"
+	"go-payments/internal/faults"
+	"go-payments/internal/instrumented"
+	"go-payments/internal/kpi"
+	"go-payments/internal/leader"
+	"go-payments/internal/notify"
+	"go-payments/internal/redact"
 	"go-payments/internal/storage"
+	"go-payments/internal/thresholds"
+	"go-payments/internal/txcache"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 const dbFileName = "payments.db"
 
+// Как часто незавершённые выводы средств проверяются на истечение WITHDRAWAL_REVIEW_AFTER.
+const withdrawalReviewInterval = time.Minute
+
+// Как часто проверяется, создана ли контрольная точка балансов за текущие сутки (UTC).
+const checkpointInterval = 10 * time.Minute
+
 func main() {
+	checkOnly := flag.Bool("check", false, "проверить конфигурацию, подключение к базе и версию схемы, не запуская сервер")
+	fsckOnly := flag.Bool("fsck", false, "проверить согласованность данных в базе, не запуская сервер")
+	flag.Parse()
+
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	if *checkOnly {
+		if !check.Run(ctx, os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+	if *fsckOnly {
+		if !check.Fsck(ctx, os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+
 	log.Printf("запуск приложения...")
 
-	db, err := storage.New()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("ошибка при загрузке конфигурации: %v", err)
+	}
+
+	db, err := storage.New(cfg.Database)
 	if err != nil {
 		log.Fatalf("ошибка при инициализации storage: %v", err)
 	}
+	scheme, err := address.Lookup(cfg.AddressScheme)
+	if err != nil {
+		log.Fatalf("ошибка при выборе схемы адресов: %v", err)
+	}
+	db.SetAddressScheme(scheme)
+	db.SetAutoCreateRecipients(cfg.AutoCreateRecipients)
 
 	if err := db.Init(ctx); err != nil {
 		log.Fatalf("ошибка при инициализации данных")
@@ -35,24 +89,154 @@ func main() {
 
 	log.Println("инициализация базы данных прошла успешно")
 
-	r := chi.NewRouter()
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
+	if cfg.WithdrawalWallet != "" {
+		clearing, err := address.Normalize(cfg.WithdrawalWallet)
+		if err != nil {
+			log.Fatalf("некорректный адрес WITHDRAWAL_WALLET: %v", err)
+		}
+		cfg.WithdrawalWallet = clearing
+		wallet, err := db.GetWalletBalance(ctx, clearing)
+		if err != nil {
+			log.Fatalf("клиринговый кошелёк WITHDRAWAL_WALLET %s недоступен: %v", redact.Address(clearing), err)
+		}
+		if !wallet.System {
+			log.Printf("ВНИМАНИЕ: клиринговый кошелёк %s не отмечен системным, и клиенты могут переводить с него выведенные средства", redact.Address(clearing))
+		}
+	}
+	var checkpointKey ed25519.PrivateKey
+	if cfg.CheckpointSigningKey != "" {
+		checkpointKey, err = checkpoint.ParseSigningKey(cfg.CheckpointSigningKey)
+		if err != nil {
+			log.Fatalf("некорректный CHECKPOINT_SIGNING_KEY: %v", err)
+		}
+		log.Printf("контрольные точки балансов подписываются ключом %x", checkpointKey.Public())
+	}
 
-	appAPI := api.New(db)
-	appAPI.RegisterRoutes(r)
+	renderer, err := notify.NewRenderer(cfg.Notify.TemplatesDir)
+	if err != nil {
+		log.Fatalf("ошибка при загрузке шаблонов уведомлений: %v", err)
+	}
+	var notifier notify.Notifier = &notify.LogNotifier{Renderer: renderer}
+	if cfg.Notify.WebhookURL != "" {
+		notifier = &notify.HTTPNotifier{URL: cfg.Notify.WebhookURL, Renderer: renderer}
+	}
+	var digester *notify.Digester
+	if cfg.Notify.DigestWindow > 0 {
+		digester = notify.NewDigester(notifier, cfg.Notify.DigestWindow, cfg.Notify.DigestMaxEvents)
+		notifier = digester
+	}
+	var eventTypes []string
+	if cfg.Notify.EventTypes != "" {
+		for _, t := range strings.Split(cfg.Notify.EventTypes, ",") {
+			eventTypes = append(eventTypes, strings.TrimSpace(t))
+		}
+	}
+	notifier, err = notify.Filter(notifier, eventTypes)
+	if err != nil {
+		log.Fatalf("ошибка в NOTIFY_EVENT_TYPES: %v", err)
+	}
 
-	server := &http.Server{
-		Addr: ":8080",
-		Handler: r,
+	var appStorage api.Storage = db
+	var injector *faults.Injector
+	if cfg.FaultInjection {
+		if !faults.Enabled {
+			log.Fatal("FAULT_INJECTION=true допустимо только в сборке с тегом faults (go build -tags faults)")
+		}
+		log.Println("ВНИМАНИЕ: включено внедрение отказов хранилища, правила - /debug/faults")
+		injector = faults.NewInjector()
+		appStorage = faults.Wrap(appStorage, injector)
+	}
+	if cfg.SandboxMode {
+		log.Println("ВНИМАНИЕ: включён режим песочницы, переводы на магические адреса (/api/meta/sandbox) завершаются предопределёнными исходами")
+	}
+	appStorage = instrumented.New(appStorage, cfg.SlowQueryThreshold)
+	if cfg.DedupBalanceReads {
+		appStorage = dedup.Wrap(appStorage)
+	}
+	if cfg.TransactionCacheSize > 0 {
+		appStorage = txcache.Wrap(appStorage, cfg.TransactionCacheSize)
+	}
+	appStorage = kpi.Wrap(appStorage)
+
+	var alerter alerts.Channel = alerts.LogAlerter{}
+	if cfg.Alerts.WebhookURL != "" {
+		alerter = &alerts.WebhookAlerter{URL: cfg.Alerts.WebhookURL}
+	}
+	appStorage = thresholds.Wrap(appStorage, alerter)
+	// Уведомления оборачивают хранилище и для API, и для периодических задач:
+	// перевод выводов средств на разбор тоже порождает события.
+	appStorage = notify.Wrap(appStorage, notifier)
+	appAPI := api.New(appStorage, cfg)
+	if err := appAPI.LoadMaintenance(ctx); err != nil {
+		log.Fatalf("ошибка при загрузке режима обслуживания: %v", err)
+	}
+	if err := appAPI.CheckSchema(ctx); err != nil {
+		log.Fatalf("ошибка при проверке схемы базы данных: %v", err)
+	}
+	if err := appAPI.LogFeatures(ctx); err != nil {
+		log.Println(err)
 	}
 
-	go func ()  {
-		log.Println("сервер запущен на http://localhost:8080")
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("ошибка запуска сервера: %v", err)
+	jobs := leader.New(db)
+	go jobs.Run(ctx, "drain_alerts", cfg.Alerts.CheckInterval, 2*cfg.Alerts.CheckInterval, alerts.NewChecker(db, alerter).Check)
+	go jobs.Run(ctx, "refresh_wallet_summary", cfg.Stats.RefreshInterval, 2*cfg.Stats.RefreshInterval, appStorage.RefreshWalletSummary)
+	go jobs.Run(ctx, "review_withdrawals", withdrawalReviewInterval, 2*withdrawalReviewInterval, func(ctx context.Context) error {
+		reviewed, err := appStorage.ReviewExpiredWithdrawals(ctx, cfg.WithdrawalReviewAfter)
+		if len(reviewed) > 0 {
+			log.Printf("на разбор передано выводов средств без подтверждения: %d", len(reviewed))
+		}
+		return err
+	})
+	if checkpointKey != nil {
+		go jobs.Run(ctx, "create_checkpoint", checkpointInterval, 2*checkpointInterval, func(ctx context.Context) error {
+			c, created, err := appStorage.CreateCheckpoint(ctx, time.Now().UTC(), checkpointKey)
+			if created {
+				log.Printf("создана контрольная точка балансов за %s: кошельков %d, корень %s", c.Date, c.WalletCount, c.Root)
+			}
+			return err
+		})
+	}
+
+	public := newRouter(os.Stdout)
+	publicRoutes := api.NewRouteRecorder(public)
+	servers := []*http.Server{{Addr: cfg.HTTP.PublicAddr, Handler: public}}
+	if cfg.HTTP.SeparateInternal {
+		// Административные и отладочные маршруты доступны только на внутреннем адресе.
+		appAPI.RegisterPublicRoutes(publicRoutes)
+
+		internal := newRouter(os.Stdout)
+		internalRoutes := api.NewRouteRecorder(internal)
+		appAPI.RegisterInternalRoutes(internalRoutes)
+		internalRoutes.Handle("/metrics", promhttp.Handler())
+		internalRoutes.Mount("/debug", middleware.Profiler())
+		registerFaultRoutes(internalRoutes, injector)
+		if err := internalRoutes.Err(); err != nil {
+			log.Fatalf("ошибка в маршрутах внутреннего слушателя: %v", err)
+		}
+		if err := api.CheckPublicRoutes(public); err != nil {
+			log.Fatalf("ошибка в маршрутах публичного слушателя: %v", err)
 		}
-	} ()
+		servers = append(servers, &http.Server{Addr: cfg.HTTP.InternalAddr, Handler: internal})
+	} else {
+		appAPI.RegisterRoutes(publicRoutes)
+		publicRoutes.Handle("/metrics", promhttp.Handler())
+		registerFaultRoutes(publicRoutes, injector)
+	}
+	if err := publicRoutes.Err(); err != nil {
+		log.Fatalf("ошибка в маршрутах публичного слушателя: %v", err)
+	}
+	if err := api.CheckExamples(public); err != nil {
+		log.Fatalf("ошибка в примерах маршрутов: %v", err)
+	}
+
+	for _, server := range servers {
+		go func() {
+			log.Printf("сервер запущен на %s", server.Addr)
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("ошибка запуска сервера %s: %v", server.Addr, err)
+			}
+		}()
+	}
 
 	<-ctx.Done()
 
@@ -61,8 +245,55 @@ func main() {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Printf("ошибка при остановке сервера: %v", err)
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				log.Printf("ошибка при остановке сервера %s: %v", server.Addr, err)
+			}
+		}()
+	}
+	wg.Wait()
+	// Запросы завершены: накопленные сводки уведомлений отправляются сразу.
+	if digester != nil {
+		if err := digester.Close(shutdownCtx); err != nil {
+			log.Printf("ошибка отправки сводок уведомлений: %v", err)
+		}
 	}
 	log.Println("сервер остановлен")
-}
\ No newline at end of file
+}
+
+// registerFaultRoutes регистрирует управление внедрением отказов, если оно включено.
+func registerFaultRoutes(r chi.Router, injector *faults.Injector) {
+	if injector == nil {
+		return
+	}
+	h := faults.Handler(injector)
+	r.Method(http.MethodGet, "/debug/faults", h)
+	r.Method(http.MethodPut, "/debug/faults", h)
+	r.Method(http.MethodDelete, "/debug/faults", h)
+}
+
+// newRouter создаёт маршрутизатор, который пишет журнал запросов в logOutput.
+func newRouter(logOutput io.Writer) *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(middleware.RequestLogger(redactingLogFormatter{
+		&middleware.DefaultLogFormatter{Logger: log.New(logOutput, "", log.LstdFlags), NoColor: false},
+	}))
+	r.Use(middleware.Recoverer)
+	return r
+}
+
+// redactingLogFormatter пишет журнал запросов chi с сокращёнными адресами
+// кошельков в URI; сам запрос обработчики получают без изменений.
+type redactingLogFormatter struct {
+	middleware.LogFormatter
+}
+
+func (f redactingLogFormatter) NewLogEntry(r *http.Request) middleware.LogEntry {
+	logged := r.WithContext(r.Context())
+	logged.RequestURI = redact.Text(r.RequestURI)
+	return f.LogFormatter.NewLogEntry(logged)
+}
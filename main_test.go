@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-payments/internal/api"
+	"go-payments/internal/config"
+	"go-payments/internal/instrumented"
+	"go-payments/internal/models"
+	"go-payments/internal/money"
+	"go-payments/internal/notify"
+	"go-payments/internal/redact"
+	"go-payments/internal/storage"
+)
+
+// Полный адрес схемы hex64 или UUID - то, чего не должно быть в логе.
+var reFullAddress = regexp.MustCompile(`[0-9a-fA-F]{64}|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+
+// syncBuffer - буфер лога, в который пишут несколько горутин.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// flowStorage - хранилище сквозного сценария: переводы на failing и чтение его
+// баланса завершаются внутренней ошибкой с сокращённым адресом в тексте, как у
+// настоящего хранилища.
+type flowStorage struct {
+	api.Storage
+	failing string
+}
+
+func (s *flowStorage) GetWalletBalance(ctx context.Context, address string) (*models.Wallet, error) {
+	if address == s.failing {
+		return nil, &storage.TransactionError{Code: storage.CodeInternalError,
+			OriginalErr: fmt.Errorf("ошибка получения баланса кошелька %s: connection reset", redact.Address(address))}
+	}
+	return &models.Wallet{Address: address, Balance: 100}, nil
+}
+
+func (s *flowStorage) Execute(ctx context.Context, t models.Transfer) (*models.Transaction, error) {
+	time.Sleep(2 * time.Millisecond)
+	if t.To == s.failing {
+		return nil, &storage.TransactionError{Code: storage.CodeInternalError,
+			OriginalErr: fmt.Errorf("ошибка перевода на кошелёк %s: connection reset", redact.Address(t.To))}
+	}
+	return &models.Transaction{ID: 1, From: t.From, To: t.To, Amount: money.Amount(t.Amount),
+		Timestamp: time.Now().UTC(), Status: models.StatusSuccess}, nil
+}
+
+// signalNotifier передаёт события дальше и сообщает об их отправке: уведомления
+// пишутся в лог асинхронно.
+type signalNotifier struct {
+	notify.Notifier
+	sent chan struct{}
+}
+
+func (n signalNotifier) Notify(ctx context.Context, event notify.TransferEvent) error {
+	defer func() { n.sent <- struct{}{} }()
+	return n.Notifier.Notify(ctx, event)
+}
+
+func TestLogsRedactAddresses(t *testing.T) {
+	var logs syncBuffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	from, to, failing := strings.Repeat("a1", 32), strings.Repeat("b2", 32), strings.Repeat("c3", 32)
+	renderer, err := notify.NewRenderer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifier := signalNotifier{Notifier: &notify.LogNotifier{Renderer: renderer}, sent: make(chan struct{}, 10)}
+
+	// Хранилище обёрнуто так же, как в main: медленные вызовы и уведомления
+	// пишутся в лог.
+	var db api.Storage = &flowStorage{failing: failing}
+	db = instrumented.New(db, time.Nanosecond)
+	db = notify.Wrap(db, notifier)
+	cfg := &config.Config{
+		HTTP:          config.HTTP{MaxBodyBytes: 1 << 20},
+		CursorSecret:  "test-cursor-secret",
+		MaxAmount:     1000000000,
+		AddressScheme: "hex64",
+	}
+	r := newRouter(&logs)
+	api.New(db, cfg).RegisterRoutes(r)
+
+	requests := []struct {
+		method, path, body string
+		status             int
+	}{
+		{http.MethodGet, "/api/wallet/" + from + "/balance", "", http.StatusOK},
+		{http.MethodGet, "/api/wallet/" + failing + "/balance", "", http.StatusInternalServerError},
+		{http.MethodPost, "/api/send", `{"from":"` + from + `","to":"` + to + `","amount":"10"}`, http.StatusOK},
+		{http.MethodPost, "/api/send", `{"from":"` + from + `","to":"` + failing + `","amount":"10"}`, http.StatusInternalServerError},
+	}
+	for _, tt := range requests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Fatalf("%s %s: статус %d, want %d; тело %s", tt.method, tt.path, w.Code, tt.status, w.Body)
+		}
+	}
+	for range 2 {
+		select {
+		case <-notifier.sent:
+		case <-time.After(5 * time.Second):
+			t.Fatal("уведомления о переводах не отправлены")
+		}
+	}
+
+	out := logs.String()
+	// Сценарий действительно пишет в лог запросы, ошибки, медленные вызовы и уведомления.
+	for _, want := range []string{"/api/wallet/a1a1a1…a1a1/balance", "ошибка получения баланса для кошелька c3c3c3…c3c3",
+		"ошибка при переводе средств от a1a1a1…a1a1 к c3c3c3…c3c3", "медленный вызов хранилища", "уведомление:"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("в логе нет %q:\n%s", want, out)
+		}
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if reFullAddress.MatchString(line) {
+			t.Errorf("полный адрес в логе: %s", line)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+/*
+client содержит код, которым сторонние клиенты сервиса проверяют его ответы, не
+доверяя базе данных и не подключая остальной код сервиса.
+
+Контрольная точка фиксирует балансы всех кошельков на момент AsOf: пары (адрес,
+баланс), упорядоченные по байтам адреса, хешируются в дерево Меркла, а корень
+вместе с датой, моментом снимка и количеством кошельков подписывается ключом
+Ed25519 сервера. Дерево строится как в RFC 6962 (Certificate Transparency), поэтому
+доказательства включения можно проверить и сторонними реализациями:
+
+  - лист: SHA-256(0x00 || адрес || 0x00 || баланс), где баланс - строка с фиксированной
+    точкой и 8 знаками после запятой, например "100.00000000";
+  - узел: SHA-256(0x01 || левый || правый); дерево из n > 1 листьев делится на
+    первые k листьев (k - наибольшая степень двойки меньше n) и остальные;
+  - подписывается Message: строки "go-payments checkpoint v1", дата, AsOf в RFC 3339,
+    количество кошельков и корень в hex, каждая с переводом строки.
+
+Пакет зависит только от стандартной библиотеки, чтобы его можно было передать
+аудитору отдельно.
+
+Functions:
+  - LeafHash, Root, InclusionProof: Хеш листа, корень дерева и путь доказательства.
+  - Message: Подписываемое представление контрольной точки.
+  - Checkpoint.Verify: Проверяет подпись контрольной точки доверенным ключом.
+  - Bundle.Verify: Дополнительно сверяет корень с полным списком балансов.
+  - Proof.Verify: Проверяет, что баланс кошелька входит в подписанную контрольную точку.
+*/
+package client
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// messageHeader - первая строка подписываемого сообщения; меняется вместе с форматом.
+const messageHeader = "go-payments checkpoint v1"
+
+var (
+	// ErrBadSignature - подпись не соответствует контрольной точке и ключу.
+	ErrBadSignature = errors.New("подпись контрольной точки недействительна")
+	// ErrRootMismatch - корень, вычисленный по балансам или доказательству, не совпадает с подписанным.
+	ErrRootMismatch = errors.New("корень дерева не совпадает с подписанным")
+)
+
+// Checkpoint - подписанная контрольная точка в том виде, в котором её отдаёт API.
+type Checkpoint struct {
+	Date        string    `json:"date"`
+	AsOf        time.Time `json:"as_of"`
+	WalletCount int       `json:"wallet_count"`
+	Root        string    `json:"root"`
+	Signature   string    `json:"signature"`
+	PublicKey   string    `json:"public_key"`
+}
+
+// Leaf - баланс одного кошелька в контрольной точке.
+type Leaf struct {
+	Address string `json:"address"`
+	Balance string `json:"balance"`
+}
+
+// Bundle - контрольная точка вместе с балансами всех кошельков в порядке листьев.
+type Bundle struct {
+	Checkpoint
+	Balances []Leaf `json:"balances,omitempty"`
+}
+
+// Proof - доказательство включения баланса кошелька в контрольную точку.
+type Proof struct {
+	Checkpoint Checkpoint `json:"checkpoint"`
+	Leaf
+	LeafIndex int      `json:"leaf_index"`
+	Path      []string `json:"path"`
+}
+
+// LeafHash возвращает хеш листа для баланса кошелька.
+func LeafHash(l Leaf) []byte {
+	h := sha256.New()
+	h.Write([]byte{0x00})
+	h.Write([]byte(l.Address))
+	h.Write([]byte{0x00})
+	h.Write([]byte(l.Balance))
+	return h.Sum(nil)
+}
+
+func nodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0x01})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// split возвращает наибольшую степень двойки, меньшую n (n > 1).
+func split(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// Root возвращает корень дерева над хешами листьев; для пустого дерева - SHA-256
+// пустой строки.
+func Root(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	case 1:
+		return leaves[0]
+	}
+	k := split(len(leaves))
+	return nodeHash(Root(leaves[:k]), Root(leaves[k:]))
+}
+
+// InclusionProof возвращает путь доказательства для листа index: хеши соседних
+// поддеревьев от листа к корню.
+func InclusionProof(leaves [][]byte, index int) [][]byte {
+	if len(leaves) <= 1 {
+		return nil
+	}
+	k := split(len(leaves))
+	if index < k {
+		return append(InclusionProof(leaves[:k], index), Root(leaves[k:]))
+	}
+	return append(InclusionProof(leaves[k:], index-k), Root(leaves[:k]))
+}
+
+// Message возвращает подписываемое представление контрольной точки.
+func Message(date string, asOf time.Time, walletCount int, root string) []byte {
+	var b bytes.Buffer
+	for _, line := range []string{messageHeader, date, asOf.UTC().Format(time.RFC3339Nano), strconv.Itoa(walletCount), root} {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// Verify проверяет подпись контрольной точки ключом publicKey (hex). Ключ должен
+// быть получен от оператора заранее: поле PublicKey самой контрольной точки
+// доверия не добавляет и не используется.
+func (c Checkpoint) Verify(publicKey string) error {
+	key, err := hex.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("открытый ключ должен быть %d байтами в hex", ed25519.PublicKeySize)
+	}
+	signature, err := hex.DecodeString(c.Signature)
+	if err != nil {
+		return ErrBadSignature
+	}
+	if !ed25519.Verify(key, Message(c.Date, c.AsOf, c.WalletCount, c.Root), signature) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// Verify проверяет подпись и то, что корень и количество кошельков совпадают с
+// переданными балансами.
+func (b Bundle) Verify(publicKey string) error {
+	if err := b.Checkpoint.Verify(publicKey); err != nil {
+		return err
+	}
+	if len(b.Balances) != b.WalletCount {
+		return fmt.Errorf("%w: балансов %d, в контрольной точке %d", ErrRootMismatch, len(b.Balances), b.WalletCount)
+	}
+	leaves := make([][]byte, len(b.Balances))
+	for i, l := range b.Balances {
+		leaves[i] = LeafHash(l)
+	}
+	if hex.EncodeToString(Root(leaves)) != b.Root {
+		return ErrRootMismatch
+	}
+	return nil
+}
+
+// Verify проверяет подпись контрольной точки и то, что баланс p.Balance кошелька
+// p.Address входит в её дерево на позиции p.LeafIndex (алгоритм RFC 9162, 2.1.3.2).
+func (p Proof) Verify(publicKey string) error {
+	if err := p.Checkpoint.Verify(publicKey); err != nil {
+		return err
+	}
+	root, err := hex.DecodeString(p.Checkpoint.Root)
+	if err != nil {
+		return ErrRootMismatch
+	}
+	if p.LeafIndex < 0 || p.LeafIndex >= p.Checkpoint.WalletCount {
+		return fmt.Errorf("%w: позиция листа %d вне дерева из %d", ErrRootMismatch, p.LeafIndex, p.Checkpoint.WalletCount)
+	}
+
+	fn, sn := p.LeafIndex, p.Checkpoint.WalletCount-1
+	hash := LeafHash(p.Leaf)
+	for _, s := range p.Path {
+		sibling, err := hex.DecodeString(s)
+		if err != nil || sn == 0 {
+			return ErrRootMismatch
+		}
+		if fn&1 == 1 || fn == sn {
+			hash = nodeHash(sibling, hash)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			hash = nodeHash(hash, sibling)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 || !bytes.Equal(hash, root) {
+		return ErrRootMismatch
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// testKey - ключ подписи тестовых контрольных точек (зерно из RFC 8032, тест 1).
+var testKey = ed25519.NewKeyFromSeed(mustHex("9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60"))
+
+var testPublicKey = hex.EncodeToString(testKey.Public().(ed25519.PublicKey))
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// testLeaves возвращает n балансов, упорядоченных по адресу.
+func testLeaves(n int) []Leaf {
+	leaves := make([]Leaf, n)
+	for i := range leaves {
+		leaves[i] = Leaf{Address: fmt.Sprintf("%064x", i+1), Balance: fmt.Sprintf("%d.00000000", (i+1)*10)}
+	}
+	return leaves
+}
+
+// signBundle подписывает контрольную точку над balances так же, как сервер.
+func signBundle(balances []Leaf) Bundle {
+	hashes := leafHashes(balances)
+	c := Checkpoint{
+		Date:        "2026-01-02",
+		AsOf:        time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+		WalletCount: len(balances),
+		Root:        hex.EncodeToString(Root(hashes)),
+		PublicKey:   testPublicKey,
+	}
+	c.Signature = hex.EncodeToString(ed25519.Sign(testKey, Message(c.Date, c.AsOf, c.WalletCount, c.Root)))
+	return Bundle{Checkpoint: c, Balances: balances}
+}
+
+func leafHashes(balances []Leaf) [][]byte {
+	hashes := make([][]byte, len(balances))
+	for i, l := range balances {
+		hashes[i] = LeafHash(l)
+	}
+	return hashes
+}
+
+// proofFor строит доказательство включения листа index контрольной точки b.
+func proofFor(b Bundle, index int) Proof {
+	p := Proof{Checkpoint: b.Checkpoint, Leaf: b.Balances[index], LeafIndex: index}
+	for _, h := range InclusionProof(leafHashes(b.Balances), index) {
+		p.Path = append(p.Path, hex.EncodeToString(h))
+	}
+	return p
+}
+
+func TestProofVerify(t *testing.T) {
+	for n := 1; n <= 17; n++ {
+		b := signBundle(testLeaves(n))
+		if err := b.Verify(testPublicKey); err != nil {
+			t.Errorf("контрольная точка из %d листьев: %v", n, err)
+		}
+		for i := range n {
+			if err := proofFor(b, i).Verify(testPublicKey); err != nil {
+				t.Errorf("доказательство листа %d из %d: %v", i, n, err)
+			}
+		}
+	}
+}
+
+func TestProofVerifyTampered(t *testing.T) {
+	b := signBundle(testLeaves(7))
+	otherKey := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	tests := []struct {
+		name    string
+		tamper  func(p *Proof)
+		key     string
+		wantErr error
+	}{
+		{"изменён баланс", func(p *Proof) { p.Balance = "1000000.00000000" }, testPublicKey, ErrRootMismatch},
+		{"изменён адрес", func(p *Proof) { p.Address = fmt.Sprintf("%064x", 99) }, testPublicKey, ErrRootMismatch},
+		{"чужая позиция", func(p *Proof) { p.LeafIndex = 4 }, testPublicKey, ErrRootMismatch},
+		{"позиция вне дерева", func(p *Proof) { p.LeafIndex = 7 }, testPublicKey, ErrRootMismatch},
+		{"отрицательная позиция", func(p *Proof) { p.LeafIndex = -1 }, testPublicKey, ErrRootMismatch},
+		{"изменён путь", func(p *Proof) { p.Path[0] = hex.EncodeToString(LeafHash(Leaf{})) }, testPublicKey, ErrRootMismatch},
+		{"путь не hex", func(p *Proof) { p.Path[1] = "zz" }, testPublicKey, ErrRootMismatch},
+		{"путь укорочен", func(p *Proof) { p.Path = p.Path[:len(p.Path)-1] }, testPublicKey, ErrRootMismatch},
+		{"путь удлинён", func(p *Proof) { p.Path = append(p.Path, p.Path[0]) }, testPublicKey, ErrRootMismatch},
+		{"изменён байт подписи", func(p *Proof) {
+			sig := mustHex(p.Checkpoint.Signature)
+			sig[0] ^= 1
+			p.Checkpoint.Signature = hex.EncodeToString(sig)
+		}, testPublicKey, ErrBadSignature},
+		{"подпись не hex", func(p *Proof) { p.Checkpoint.Signature = "zz" }, testPublicKey, ErrBadSignature},
+		{"изменён корень", func(p *Proof) {
+			p.Checkpoint.Root = hex.EncodeToString(Root(leafHashes(testLeaves(6))))
+		}, testPublicKey, ErrBadSignature},
+		{"изменено количество кошельков", func(p *Proof) { p.Checkpoint.WalletCount = 8 }, testPublicKey, ErrBadSignature},
+		{"изменена дата", func(p *Proof) { p.Checkpoint.Date = "2026-01-03" }, testPublicKey, ErrBadSignature},
+		{"чужой ключ", func(p *Proof) {}, hex.EncodeToString(otherKey.Public().(ed25519.PublicKey)), ErrBadSignature},
+	}
+	for _, tt := range tests {
+		p := proofFor(b, 2)
+		tt.tamper(&p)
+		if err := p.Verify(tt.key); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	// Поле public_key ответа не влияет на проверку: подпись сверяется с
+	// переданным ключом.
+	p := proofFor(b, 2)
+	p.Checkpoint.PublicKey = hex.EncodeToString(otherKey.Public().(ed25519.PublicKey))
+	if err := p.Verify(testPublicKey); err != nil {
+		t.Errorf("доказательство с чужим public_key: %v", err)
+	}
+	if err := p.Verify("abc"); err == nil || errors.Is(err, ErrBadSignature) {
+		t.Errorf("некорректный ключ: %v, want ошибку формата ключа", err)
+	}
+}
+
+func TestBundleVerifyTampered(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(b *Bundle)
+	}{
+		{"изменён баланс", func(b *Bundle) { b.Balances[3].Balance = "0.00000001" }},
+		{"переставлены листья", func(b *Bundle) { b.Balances[0], b.Balances[1] = b.Balances[1], b.Balances[0] }},
+		{"удалён баланс", func(b *Bundle) { b.Balances = b.Balances[:len(b.Balances)-1] }},
+		{"добавлен баланс", func(b *Bundle) {
+			b.Balances = append(b.Balances, Leaf{Address: fmt.Sprintf("%064x", 99), Balance: "1.00000000"})
+		}},
+	}
+	for _, tt := range tests {
+		b := signBundle(testLeaves(5))
+		tt.tamper(&b)
+		if err := b.Verify(testPublicKey); !errors.Is(err, ErrRootMismatch) {
+			t.Errorf("%s: %v, want %v", tt.name, err, ErrRootMismatch)
+		}
+	}
+}